@@ -0,0 +1,103 @@
+// Package archive provides the command for verifying the invoice archive.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/service"
+)
+
+var (
+	knownHead  string
+	jsonOutput bool
+)
+
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
+	logger := logging.NewLogger()
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+// verifyArchiveCmd represents the verify-archive command
+var verifyArchiveCmd = &cobra.Command{
+	Use:   "verify-archive",
+	Short: "Verify that archived invoices are complete and unaltered",
+	Long: `Verify the GoBD archive of issued invoices.
+
+Invoices are archived with their PDF, embedded XML and source data when they are
+marked as sent. This command recomputes every file hash and the hash chain of the
+archive log and reports:
+• modified or deleted archive files
+• modified, removed or reordered log entries
+• gaps in the invoice number sequence
+• issued invoices that are missing from the archive
+
+The printed head hash identifies the current state of the log. Keep it outside the
+archive and pass it with --head next time to also detect a truncated log.
+
+Examples:
+  invoicegen verify-archive
+  invoicegen verify-archive --head 3f2a...`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+		report, err := invoiceService.VerifyArchive(knownHead)
+		if err != nil {
+			if appErr, ok := err.(*appErrs.AppError); ok {
+				fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
+				if appErr.Cause != nil {
+					fmt.Fprintf(os.Stderr, "Cause: %v\n", appErr.Cause)
+				}
+			}
+			return err
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("Archived invoices: %d\n", report.Entries)
+			fmt.Printf("Head: %s\n", report.Head)
+			for _, p := range report.Problems {
+				fmt.Printf("  %s\n", p)
+			}
+		}
+		if !report.OK() {
+			return fmt.Errorf("archive verification failed: %d problem(s)", len(report.Problems))
+		}
+		if !jsonOutput {
+			fmt.Println("Archive verified, no problems found")
+		}
+		return nil
+	},
+}
+
+// VerifyArchiveCmd is the exported verify-archive command
+var VerifyArchiveCmd = verifyArchiveCmd
+
+func init() {
+	verifyArchiveCmd.Flags().StringVar(&knownHead, "head", "", "head hash from an earlier verification, to detect a truncated log")
+	verifyArchiveCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the report as JSON")
+}
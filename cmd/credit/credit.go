@@ -0,0 +1,185 @@
+// Package credit provides the command for issuing credit notes against invoices.
+package credit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/service"
+)
+
+var (
+	number     string
+	series     string
+	lines      string
+	percent    float64
+	reason     string
+	outputFile string
+	dataFile   string
+	template   string
+	locale     string
+)
+
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
+	logger := logging.NewLogger()
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+// creditCmd represents the credit command
+var creditCmd = &cobra.Command{
+	Use:   "credit [invoice-file]",
+	Short: "Issue a credit note for an invoice",
+	Long: `Derive a credit note (document type 381) from an existing invoice.
+
+The credit note references the original invoice number and date, repeats the
+credited lines with their tax treatment and is saved as a new data file next to
+the generated PDF, so the original invoice stays untouched.
+
+Without --lines the whole invoice is credited and the original invoice is marked
+cancelled in the invoice repository.
+
+Examples:
+  # Cancel an invoice completely
+  invoicegen credit glpx.yaml --reason "Invoice issued in error"
+
+  # Credit lines 2 and 3 only
+  invoicegen credit glpx.yaml --lines 2,3
+
+  # Grant a 10% price reduction on line 1
+  invoicegen credit glpx.yaml --lines 1 --percent 10 --number CN-2025-001`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := logging.NewLogger()
+
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+
+		inputFile := args[0]
+		if !filepath.IsAbs(inputFile) && !strings.HasPrefix(inputFile, "invoices/") {
+			inputFile = filepath.Join("invoices", inputFile)
+		}
+		original, err := invoiceService.LoadInvoiceData(inputFile)
+		if err != nil {
+			return fmt.Errorf("failed to load invoice data: %w", err)
+		}
+
+		selected, err := parseLines(lines)
+		if err != nil {
+			return err
+		}
+		credit, err := invoiceService.CreateCreditNote(original, service.CreditNoteOptions{
+			Number:  number,
+			Lines:   selected,
+			Percent: percent,
+			Reason:  reason,
+		})
+		if err != nil {
+			return printError(err)
+		}
+
+		base := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile)) + "-credit"
+		if dataFile == "" {
+			dataFile = filepath.Join(filepath.Dir(inputFile), base+".yaml")
+		}
+		if outputFile == "" {
+			outputFile = filepath.Join("invoices/pdf", base+".pdf")
+		}
+
+		opts := &service.GenerateOptions{
+			OutputFile:    outputFile,
+			Template:      template,
+			Locale:        locale,
+			EnableZUGFeRD: credit.EmbeddedData == models.EmbeddedDataZUGFeRD,
+			Series:        series,
+		}
+		if opts.Template == "" {
+			opts.Template = "pkg/render/templates/invoice.html.tmpl"
+		}
+		if err := invoiceService.GenerateInvoice(cmd.Context(), credit, opts); err != nil {
+			return printError(err)
+		}
+
+		out, err := yaml.Marshal(credit)
+		if err != nil {
+			return fmt.Errorf("failed to encode credit note: %w", err)
+		}
+		if err := os.WriteFile(dataFile, out, 0644); err != nil {
+			return fmt.Errorf("failed to write credit note data: %w", err)
+		}
+
+		// A full credit cancels the original invoice in the repository
+		if len(selected) == 0 && (percent == 0 || percent == 100) {
+			if _, err := invoiceService.CancelInvoice(original.Invoice.Number, credit.Invoice.Number, credit.Invoice.Date); err != nil {
+				logger.Warn("Original invoice not cancelled", &logging.LogFields{InvoiceNum: original.Invoice.Number, Error: err.Error()})
+			}
+		}
+
+		logger.Info("Credit note generated successfully", &logging.LogFields{File: outputFile, InvoiceNum: credit.Invoice.Number})
+		logger.Info("Credit note data saved", &logging.LogFields{File: dataFile})
+		return nil
+	},
+}
+
+var CreditCmd = creditCmd
+
+// parseLines parses a comma-separated list of 1-based line numbers
+func parseLines(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var result []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid line number %q", part)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// printError reports application errors like the generate command does
+func printError(err error) error {
+	if appErr, ok := err.(*appErrs.AppError); ok {
+		fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
+		if appErr.Cause != nil {
+			fmt.Fprintf(os.Stderr, "Cause: %v\n", appErr.Cause)
+		}
+		return err
+	}
+	return fmt.Errorf("failed to generate credit note: %w", err)
+}
+
+func init() {
+	creditCmd.Flags().StringVar(&number, "number", "", "credit note number (default: next sequential number, or invoice number with -CN suffix)")
+	creditCmd.Flags().StringVar(&series, "series", "", "number series for the credit note (default: credit_note series if configured)")
+	creditCmd.Flags().StringVar(&lines, "lines", "", "comma-separated invoice line numbers to credit (default: all)")
+	creditCmd.Flags().Float64Var(&percent, "percent", 0, "percentage of the selected lines to credit (default: 100)")
+	creditCmd.Flags().StringVar(&reason, "reason", "", "reason for the credit, shown in the notes")
+	creditCmd.Flags().StringVarP(&outputFile, "output", "o", "", "output PDF file path")
+	creditCmd.Flags().StringVar(&dataFile, "data-output", "", "credit note data file (default: <invoice>-credit.yaml)")
+	creditCmd.Flags().StringVarP(&template, "template", "t", "", "template theme to use")
+	creditCmd.Flags().StringVar(&locale, "locale", "", "custom locale JSON file to use for translations")
+}
@@ -0,0 +1,114 @@
+// Package dunning provides the command for issuing payment reminders and dunning notices.
+package dunning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/service"
+)
+
+var (
+	date       string
+	outputDir  string
+	localeFile string
+	dryRun     bool
+	jsonOutput bool
+)
+
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
+	logger := logging.NewLogger()
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+// dunningCmd represents the dunning command
+var dunningCmd = &cobra.Command{
+	Use:   "dunning",
+	Short: "Issue payment reminders and dunning notices for overdue invoices",
+	Long: `Mark sent invoices past their due date as overdue and issue the notices that are due.
+
+Notices escalate from a payment reminder to dunning notices and a final notice. Each
+level waits a number of days after the due date and after the previous notice, may
+charge a fee and may claim default interest under § 288 BGB (base rate plus 9
+percentage points for business clients, 5 for consumers) and, for business clients,
+the EUR 40 late payment lump sum. Notices are printed to PDF in the invoice language
+and recorded with the invoice.
+
+Examples:
+  invoicegen dunning --dry-run
+  invoicegen dunning --date 2025-08-31 --output-dir letters/`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+		opts := &service.DunningOptions{OutputDir: outputDir, Locale: localeFile, DryRun: dryRun}
+		if date != "" {
+			if opts.Date, err = time.Parse("2006-01-02", date); err != nil {
+				return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+			}
+		}
+
+		notices, err := invoiceService.RunDunning(cmd.Context(), opts)
+		if err != nil {
+			if appErr, ok := err.(*appErrs.AppError); ok {
+				fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
+				if appErr.Cause != nil {
+					fmt.Fprintf(os.Stderr, "Cause: %v\n", appErr.Cause)
+				}
+			}
+			return err
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(notices)
+		}
+		if len(notices) == 0 {
+			fmt.Println("No notices due")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "INVOICE\tLEVEL\tOVERDUE\tOUTSTANDING\tFEES\tINTEREST\tTOTAL\tPAY BY")
+		for _, n := range notices {
+			fmt.Fprintf(w, "%s\t%d %s\t%d days\t%s\t%s\t%s\t%s %s\t%s\n", n.Number, n.Level, n.Step.Name, n.DaysOverdue,
+				n.Outstanding.StringFixed(2), n.Fees.Add(n.LumpSum).StringFixed(2), n.Interest.StringFixed(2),
+				n.Total.StringFixed(2), n.Currency, n.PayBy.Format("2006-01-02"))
+		}
+		if dryRun {
+			fmt.Fprintln(w, "\nDry run, no notices issued")
+		}
+		return w.Flush()
+	},
+}
+
+// DunningCmd is the exported dunning command
+var DunningCmd = dunningCmd
+
+func init() {
+	dunningCmd.Flags().StringVar(&date, "date", "", "reference date (YYYY-MM-DD), default today")
+	dunningCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "directory for the notice PDFs (default from config)")
+	dunningCmd.Flags().StringVar(&localeFile, "locale", "", "custom locale file")
+	dunningCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list the notices due")
+	dunningCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the notices as JSON")
+}
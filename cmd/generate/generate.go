@@ -2,16 +2,18 @@
 package generate
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"invoiceformats/internal/config"
 	appErrs "invoiceformats/pkg/errors"
-	"invoiceformats/pkg/loader"
+	"invoiceformats/pkg/exchange"
 	"invoiceformats/pkg/logging"
 	"invoiceformats/pkg/models"
 	"invoiceformats/pkg/render"
@@ -29,11 +31,14 @@ var (
 	validateOnly   bool
 	sample         bool
 	locale         string
+	ratesFile      string
+	series         string
+	timeout        time.Duration
 )
 
-// GetInvoiceService returns a default invoice service instance
-func GetInvoiceService() (*service.InvoiceService, error) {
-	cfg := config.DefaultConfig()
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
 	logger := logging.NewLogger()
 	// Load embedded locales.json
 	localeData, err := os.ReadFile("pkg/render/locales.json")
@@ -67,7 +72,16 @@ Examples:
   invoicegen generate data.yaml --validate-only
 
   # Dry run to see what would be generated
-  invoicegen generate data.yaml --dry-run`,
+  invoicegen generate data.yaml --dry-run
+
+  # Foreign-currency invoice with VAT converted using ECB reference rates
+  invoicegen generate usd-invoice.yaml --rates eurofxref-hist.xml
+
+  # Number an invoice without "number" from a configured series
+  invoicegen generate data.yaml --series export
+
+  # Give up if the PDF is not printed within a minute
+  invoicegen generate data.yaml --timeout 1m`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if !sample && len(args) == 0 {
 			return fmt.Errorf("requires a data file argument or --sample flag")
@@ -81,7 +95,7 @@ Examples:
 		logger := logging.NewLogger()
 
 		// Get invoice service
-		invoiceService, err := GetInvoiceService()
+		invoiceService, err := GetInvoiceService(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to create invoice service: %w", err)
 		}
@@ -102,7 +116,7 @@ Examples:
 
 			logger.Info("Loading invoice data", &logging.LogFields{File: inputFile})
 
-			data, err = loader.LoadInvoiceData(inputFile, logger)
+			data, err = invoiceService.LoadInvoiceData(inputFile)
 			if err != nil {
 				return fmt.Errorf("failed to load invoice data: %w", err)
 			}
@@ -128,6 +142,7 @@ Examples:
 			IncludeHTML:  includeHTML,
 			DryRun:       dryRun,
 			ValidateOnly: validateOnly,
+			Series:       series,
 		}
 
 		if opts.Template == "" {
@@ -139,13 +154,27 @@ Examples:
 			opts.TaxRate = &taxRate
 		}
 
+		if ratesFile != "" {
+			rates, err := exchange.LoadFile(ratesFile)
+			if err != nil {
+				return fmt.Errorf("failed to load exchange rates: %w", err)
+			}
+			opts.ExchangeRates = rates
+		}
+
 		// Enable ZUGFeRD embedding if requested in YAML
 		if data.EmbeddedData == models.EmbeddedDataZUGFeRD {
 			opts.EnableZUGFeRD = true
 		}
 
 		// Generate invoice
-		if err := invoiceService.GenerateInvoice(data, opts); err != nil {
+		ctx := cmd.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if err := invoiceService.GenerateInvoice(ctx, data, opts); err != nil {
 			if appErr, ok := err.(*appErrs.AppError); ok {
 				fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
 				if appErr.Cause != nil {
@@ -161,7 +190,7 @@ Examples:
 		} else if dryRun {
 			logger.Info("Dry run successful - would generate", &logging.LogFields{File: outputFile})
 		} else {
-			logger.Info("Invoice generated successfully", &logging.LogFields{File: outputFile})
+			logger.Info("Invoice generated successfully", &logging.LogFields{File: outputFile, InvoiceNum: data.Invoice.Number})
 		}
 
 		return nil
@@ -179,10 +208,13 @@ func init() {
 	generateCmd.Flags().StringVarP(&template, "template", "t", "", "template theme to use")
 	generateCmd.Flags().StringVarP(&currency, "currency", "c", "", "currency code (e.g., USD, EUR)")
 	generateCmd.Flags().Float64Var(&taxRate, "tax-rate", 0, "default tax rate percentage")
+	generateCmd.Flags().StringVar(&series, "series", "", "number series for invoices without a number (sequential numbering)")
+	generateCmd.Flags().StringVar(&ratesFile, "rates", "", "exchange rates file (ECB XML, YAML or JSON) for VAT in the tax currency")
 
 	// Generation options
 	generateCmd.Flags().BoolVar(&includeHTML, "include-html", false, "also save HTML output")
 	generateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate and process but don't generate files")
 	generateCmd.Flags().BoolVar(&validateOnly, "validate-only", false, "only validate the data, don't generate")
 	generateCmd.Flags().StringVar(&locale, "locale", "", "custom locale JSON file to use for translations")
+	generateCmd.Flags().DurationVar(&timeout, "timeout", 0, "abort the generation after this duration, e.g. 1m (default: 30s for printing the PDF)")
 }
@@ -0,0 +1,268 @@
+// Package invoices provides the commands for browsing recorded invoices and updating
+// their status.
+package invoices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/repository"
+	"invoiceformats/pkg/service"
+)
+
+var (
+	statusFilter string
+	typeFilter   string
+	clientFilter string
+	showJSON     bool
+	sentDate     string
+	paidDate     string
+	statusDate   string
+	note         string
+)
+
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
+	logger := logging.NewLogger()
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded invoices",
+	Long: `List the invoices recorded by generate and credit with their status.
+
+Sent invoices past their due date are marked overdue before listing.
+
+Examples:
+  # All invoices
+  invoicegen list
+
+  # Open invoices of one client
+  invoicegen list --status sent --client "Pixel Dynamics"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+		records, err := invoiceService.ListInvoices(repository.Filter{
+			Status: models.InvoiceStatus(statusFilter),
+			Type:   models.InvoiceType(typeFilter),
+			Client: clientFilter,
+		})
+		if err != nil {
+			return printError(err)
+		}
+		if showJSON {
+			return printJSON(records)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NUMBER\tDATE\tDUE\tCLIENT\tTOTAL\tSTATUS")
+		for _, rec := range records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s %s\t%s\n",
+				rec.Number, formatDate(rec.Date), formatDate(rec.DueDate), rec.Client,
+				rec.GrandTotal.StringFixed(2), rec.Currency, rec.Status)
+		}
+		return w.Flush()
+	},
+}
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show [invoice-number]",
+	Short: "Show a recorded invoice and its status history",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+		rec, err := invoiceService.GetInvoice(args[0])
+		if err != nil {
+			return printError(err)
+		}
+		if showJSON {
+			return printJSON(rec)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Number:\t%s\n", rec.Number)
+		if rec.Type != "" {
+			fmt.Fprintf(w, "Type:\t%s\n", rec.Type)
+		}
+		fmt.Fprintf(w, "Status:\t%s\n", rec.Status)
+		fmt.Fprintf(w, "Client:\t%s\n", rec.Client)
+		fmt.Fprintf(w, "Date:\t%s\n", formatDate(rec.Date))
+		fmt.Fprintf(w, "Due:\t%s\n", formatDate(rec.DueDate))
+		fmt.Fprintf(w, "Total:\t%s %s\n", rec.GrandTotal.StringFixed(2), rec.Currency)
+		if !rec.AmountDue.IsZero() && !rec.AmountDue.Equal(rec.GrandTotal) {
+			fmt.Fprintf(w, "Amount due:\t%s %s\n", rec.AmountDue.StringFixed(2), rec.Currency)
+		}
+		if rec.PDFFile != "" {
+			fmt.Fprintf(w, "PDF:\t%s\n", rec.PDFFile)
+		}
+		if rec.CreditNote != "" {
+			fmt.Fprintf(w, "Credit note:\t%s\n", rec.CreditNote)
+		}
+		if len(rec.Payments) > 0 {
+			fmt.Fprintln(w, "\nPayments:")
+			for _, p := range rec.Payments {
+				fmt.Fprintf(w, "  %s\t%s %s\t%s\n", formatDate(p.Date), p.Amount.StringFixed(2), rec.Currency, p.Reference)
+			}
+		}
+		if len(rec.Notices) > 0 {
+			fmt.Fprintln(w, "\nNotices:")
+			for _, n := range rec.Notices {
+				fmt.Fprintf(w, "  %s\t%d %s\t%s %s\t%s\n", formatDate(n.Date), n.Level, n.Name, n.Total.StringFixed(2), rec.Currency, n.File)
+			}
+		}
+		fmt.Fprintln(w, "\nHistory:")
+		for _, h := range rec.History {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", h.At.Format("2006-01-02 15:04"), h.To, h.Note)
+		}
+		return w.Flush()
+	},
+}
+
+// markSentCmd represents the mark-sent command
+var markSentCmd = &cobra.Command{
+	Use:   "mark-sent [invoice-number]",
+	Short: "Mark a draft invoice as sent",
+	Long: `Mark a draft invoice as sent to the client. From then on the invoice is issued
+and can no longer be regenerated; corrections require a credit note.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeStatus(cmd.Context(), args[0], sentDate, func(s *service.InvoiceService, at time.Time) (*repository.Record, error) {
+			return s.MarkSent(args[0], at, note)
+		})
+	},
+}
+
+// markPaidCmd represents the mark-paid command
+var markPaidCmd = &cobra.Command{
+	Use:   "mark-paid [invoice-number]",
+	Short: "Mark a sent invoice as paid",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeStatus(cmd.Context(), args[0], paidDate, func(s *service.InvoiceService, at time.Time) (*repository.Record, error) {
+			return s.MarkPaid(args[0], at, note)
+		})
+	},
+}
+
+// markAcceptedCmd represents the mark-accepted command
+var markAcceptedCmd = &cobra.Command{
+	Use:   "mark-accepted [quote-number]",
+	Short: "Mark a sent quote as accepted",
+	Long: `Mark a sent quote as accepted by the client. convert-quote does this when it
+turns the quote into an invoice or order confirmation.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeStatus(cmd.Context(), args[0], statusDate, func(s *service.InvoiceService, at time.Time) (*repository.Record, error) {
+			return s.MarkAccepted(args[0], at, note)
+		})
+	},
+}
+
+// markDeclinedCmd represents the mark-declined command
+var markDeclinedCmd = &cobra.Command{
+	Use:   "mark-declined [quote-number]",
+	Short: "Mark a sent quote as declined",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeStatus(cmd.Context(), args[0], statusDate, func(s *service.InvoiceService, at time.Time) (*repository.Record, error) {
+			return s.MarkDeclined(args[0], at, note)
+		})
+	},
+}
+
+var (
+	ListCmd         = listCmd
+	ShowCmd         = showCmd
+	MarkSentCmd     = markSentCmd
+	MarkPaidCmd     = markPaidCmd
+	MarkAcceptedCmd = markAcceptedCmd
+	MarkDeclinedCmd = markDeclinedCmd
+)
+
+// changeStatus runs a status change dated date (YYYY-MM-DD, default now)
+func changeStatus(ctx context.Context, number, date string, change func(*service.InvoiceService, time.Time) (*repository.Record, error)) error {
+	logger := logging.NewLogger()
+	invoiceService, err := GetInvoiceService(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create invoice service: %w", err)
+	}
+	var at time.Time
+	if date != "" {
+		if at, err = time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	rec, err := change(invoiceService, at)
+	if err != nil {
+		return printError(err)
+	}
+	logger.Info("Invoice status updated", &logging.LogFields{InvoiceNum: rec.Number, Status: string(rec.Status)})
+	return nil
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printError reports application errors like the generate command does
+func printError(err error) error {
+	if appErr, ok := err.(*appErrs.AppError); ok {
+		fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
+		if appErr.Cause != nil {
+			fmt.Fprintf(os.Stderr, "Cause: %v\n", appErr.Cause)
+		}
+	}
+	return err
+}
+
+func init() {
+	listCmd.Flags().StringVar(&statusFilter, "status", "", "only invoices with this status (draft, sent, paid, overdue, cancelled, accepted, declined)")
+	listCmd.Flags().StringVar(&typeFilter, "type", "", "only documents of this type (invoice, advance, partial, final, credit_note, quote, order_confirmation)")
+	listCmd.Flags().StringVar(&clientFilter, "client", "", "only invoices whose client name contains this text")
+	listCmd.Flags().BoolVar(&showJSON, "json", false, "print records as JSON")
+	showCmd.Flags().BoolVar(&showJSON, "json", false, "print the record as JSON")
+	markSentCmd.Flags().StringVar(&sentDate, "date", "", "date the invoice was sent, YYYY-MM-DD (default: now)")
+	markSentCmd.Flags().StringVar(&note, "note", "", "note for the status history, e.g. how it was sent")
+	markPaidCmd.Flags().StringVar(&paidDate, "date", "", "date the payment was received, YYYY-MM-DD (default: now)")
+	markPaidCmd.Flags().StringVar(&note, "note", "", "note for the status history, e.g. the payment reference")
+	markAcceptedCmd.Flags().StringVar(&statusDate, "date", "", "date the quote was accepted, YYYY-MM-DD (default: now)")
+	markAcceptedCmd.Flags().StringVar(&note, "note", "", "note for the status history, e.g. the client's order number")
+	markDeclinedCmd.Flags().StringVar(&statusDate, "date", "", "date the quote was declined, YYYY-MM-DD (default: now)")
+	markDeclinedCmd.Flags().StringVar(&note, "note", "", "note for the status history, e.g. the reason")
+}
@@ -0,0 +1,159 @@
+// Package quote provides the command for converting accepted quotes into invoices.
+package quote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/service"
+)
+
+var (
+	docType  string
+	number   string
+	dataFile string
+	note     string
+)
+
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
+	logger := logging.NewLogger()
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+// convertQuoteCmd represents the convert-quote command
+var convertQuoteCmd = &cobra.Command{
+	Use:   "convert-quote [quote-file | quote-number]",
+	Short: "Turn an accepted quote into a draft invoice",
+	Long: `Derive a draft invoice or order confirmation from an accepted quote.
+
+The new document keeps the parties, line items and terms of the quote and references
+the quote number as sales order reference (BT-14). It is saved as a new data file for
+review and generated with the generate command, which issues its number and dates.
+Instead of a data file, the number of a quote recorded in the invoice repository can be
+given. A sent quote recorded in the invoice repository is marked accepted.
+
+An order confirmation can be converted into an invoice the same way.
+
+Examples:
+  # Draft invoice from a quote
+  invoicegen convert-quote quote-acme.yaml
+  invoicegen convert-quote Q-2025-0007
+
+  # Confirm the order first, then invoice it
+  invoicegen convert-quote quote-acme.yaml --type order_confirmation
+  invoicegen convert-quote quote-acme-order_confirmation.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := logging.NewLogger()
+
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+
+		quote, inputFile, err := loadQuote(invoiceService, args[0])
+		if err != nil {
+			return err
+		}
+
+		target := models.InvoiceType(docType)
+		if target == models.InvoiceTypeStandard {
+			target = ""
+		}
+		converted, err := invoiceService.ConvertQuote(quote, service.ConvertQuoteOptions{Type: target, Number: number})
+		if err != nil {
+			return printError(err)
+		}
+
+		if dataFile == "" {
+			suffix := "-invoice"
+			if target != "" {
+				suffix = "-" + string(target)
+			}
+			base := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+			dataFile = filepath.Join(filepath.Dir(inputFile), base+suffix+".yaml")
+		}
+		out, err := yaml.Marshal(converted)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", converted.Invoice.Type.TitleKey(), err)
+		}
+		if err := os.WriteFile(dataFile, out, 0644); err != nil {
+			return fmt.Errorf("failed to write %s data: %w", converted.Invoice.Type.TitleKey(), err)
+		}
+
+		// A sent quote has now been accepted
+		if quote.Invoice.Type == models.InvoiceTypeQuote {
+			if _, err := invoiceService.MarkAccepted(quote.Invoice.Number, converted.Invoice.Date, note); err != nil {
+				var appErr *appErrs.AppError
+				if !errors.As(err, &appErr) || appErr.Code != appErrs.ErrInvoiceNotFound {
+					logger.Warn("Quote not marked accepted", &logging.LogFields{InvoiceNum: quote.Invoice.Number, Error: err.Error()})
+				}
+			}
+		}
+
+		logger.Info("Quote converted", &logging.LogFields{InvoiceNum: quote.Invoice.Number, File: dataFile, Status: converted.Invoice.Type.TitleKey()})
+		return nil
+	},
+}
+
+var ConvertQuoteCmd = convertQuoteCmd
+
+// loadQuote reads the quote from a data file or, if there is none, from the record of
+// the quote number in the invoice repository. It also returns the file the derived
+// document is saved next to.
+func loadQuote(s *service.InvoiceService, arg string) (*models.InvoiceData, string, error) {
+	inputFile := arg
+	if !filepath.IsAbs(inputFile) && !strings.HasPrefix(inputFile, "invoices/") {
+		inputFile = filepath.Join("invoices", inputFile)
+	}
+	if _, err := os.Stat(inputFile); err != nil {
+		rec, recErr := s.GetInvoice(arg)
+		if recErr == nil && rec.Data != nil {
+			return rec.Data, filepath.Join("invoices", arg+".yaml"), nil
+		}
+	}
+	quote, err := s.LoadInvoiceData(inputFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load quote data: %w", err)
+	}
+	return quote, inputFile, nil
+}
+
+// printError reports application errors like the generate command does
+func printError(err error) error {
+	if appErr, ok := err.(*appErrs.AppError); ok {
+		fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
+		if appErr.Cause != nil {
+			fmt.Fprintf(os.Stderr, "Cause: %v\n", appErr.Cause)
+		}
+		return err
+	}
+	return fmt.Errorf("failed to convert quote: %w", err)
+}
+
+func init() {
+	convertQuoteCmd.Flags().StringVar(&docType, "type", "", "document to create: invoice (default), advance, partial, final or order_confirmation")
+	convertQuoteCmd.Flags().StringVar(&number, "number", "", "number of the new document (default: issued on generation)")
+	convertQuoteCmd.Flags().StringVar(&dataFile, "data-output", "", "data file of the new document (default: <quote>-invoice.yaml)")
+	convertQuoteCmd.Flags().StringVar(&note, "note", "", "note for the quote's status history, e.g. the client's order number")
+}
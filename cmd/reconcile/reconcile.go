@@ -0,0 +1,153 @@
+// Package reconcile provides the command for importing bank statements.
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"invoiceformats/internal/config"
+	"invoiceformats/pkg/bank"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/reconcile"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/service"
+)
+
+var (
+	dryRun     bool
+	jsonOutput bool
+	reportFile string
+)
+
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
+	logger := logging.NewLogger()
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile [statement-file...]",
+	Short: "Record payments from CAMT.053 or MT940 bank statements",
+	Long: `Import bank statements and match incoming payments to open invoices.
+
+Payments are matched by the invoice number in the remittance information, or by
+amount and the client's IBAN. Partial payments are recorded and keep the invoice
+open; invoices with nothing outstanding are marked paid. Payments that cannot be
+attributed unambiguously are listed for manual review and left untouched.
+
+Statements can be imported repeatedly; transactions recorded before are skipped.
+
+Examples:
+  invoicegen reconcile statements/2025-07.xml
+  invoicegen reconcile statements/*.sta --dry-run
+  invoicegen reconcile camt053.xml --report review.json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+
+		var txs []bank.Transaction
+		for _, file := range args {
+			parsed, err := bank.LoadFile(file)
+			if err != nil {
+				return err
+			}
+			txs = append(txs, parsed...)
+		}
+
+		result, err := invoiceService.ReconcileStatement(txs, dryRun)
+		if err != nil {
+			if appErr, ok := err.(*appErrs.AppError); ok {
+				fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
+				if appErr.Cause != nil {
+					fmt.Fprintf(os.Stderr, "Cause: %v\n", appErr.Cause)
+				}
+			}
+			return err
+		}
+
+		if reportFile != "" {
+			if err := writeJSON(reportFile, result); err != nil {
+				return fmt.Errorf("failed to write reconciliation report: %w", err)
+			}
+		}
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+		printResult(result)
+		return nil
+	},
+}
+
+// ReconcileCmd is the exported reconcile command
+var ReconcileCmd = reconcileCmd
+
+func printResult(result *reconcile.Result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if dryRun {
+		fmt.Fprintln(w, "Dry run, no payments recorded")
+	}
+	if len(result.Matched) > 0 {
+		fmt.Fprintln(w, "MATCHED\tDATE\tAMOUNT\tINVOICE\tRULE")
+		for _, m := range result.Matched {
+			for _, a := range m.Allocations {
+				partial := ""
+				if a.Partial {
+					partial = " (partial)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s %s\t%s%s\t%s\n", m.Transaction.ID, m.Transaction.Date.Format("2006-01-02"),
+					a.Amount.StringFixed(2), m.Transaction.Currency, a.Number, partial, m.Rule)
+			}
+		}
+	}
+	if len(result.Review) > 0 {
+		fmt.Fprintln(w, "\nREVIEW\tDATE\tAMOUNT\tCANDIDATES\tREASON")
+		for _, r := range result.Review {
+			fmt.Fprintf(w, "%s\t%s\t%s %s\t%s\t%s\n", r.Transaction.ID, r.Transaction.Date.Format("2006-01-02"),
+				r.Transaction.Amount.StringFixed(2), r.Transaction.Currency, strings.Join(r.Candidates, ", "), r.Reason)
+		}
+	}
+	if len(result.Unmatched) > 0 {
+		fmt.Fprintln(w, "\nUNMATCHED\tDATE\tAMOUNT\tPAYER\tREMITTANCE")
+		for _, tx := range result.Unmatched {
+			fmt.Fprintf(w, "%s\t%s\t%s %s\t%s\t%s\n", tx.ID, tx.Date.Format("2006-01-02"),
+				tx.Amount.StringFixed(2), tx.Currency, tx.Name, tx.RemittanceInfo)
+		}
+	}
+	fmt.Fprintf(w, "\n%d matched, %d for review, %d unmatched, %d skipped\n",
+		len(result.Matched), len(result.Review), len(result.Unmatched), len(result.Skipped))
+	w.Flush()
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func init() {
+	reconcileCmd.Flags().BoolVar(&dryRun, "dry-run", false, "match only, don't record payments")
+	reconcileCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the result as JSON")
+	reconcileCmd.Flags().StringVar(&reportFile, "report", "", "also write the result, including the review list, to this JSON file")
+}
@@ -0,0 +1,165 @@
+// Package recurring provides the commands for recurring invoices.
+package recurring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/service"
+)
+
+var (
+	until      string
+	ids        []string
+	dryRun     bool
+	jsonOutput bool
+)
+
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
+	logger := logging.NewLogger()
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+func printError(err error) {
+	if appErr, ok := err.(*appErrs.AppError); ok {
+		fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
+		if appErr.Cause != nil {
+			fmt.Fprintf(os.Stderr, "Cause: %v\n", appErr.Cause)
+		}
+	}
+}
+
+// recurringCmd represents the recurring command
+var recurringCmd = &cobra.Command{
+	Use:   "recurring",
+	Short: "Generate invoices from recurring definitions",
+	Long: `Recurring definitions are YAML files in the recurring directory (default
+invoices/recurring). Each holds a schedule, either a cron expression such as
+"0 0 1 * *" or an RRULE such as "FREQ=MONTHLY;BYMONTHDAY=-1", a start date, the
+billing mode (advance or arrears) and the invoice to issue. Strings in the invoice may
+use {{period_start}}, {{period_end}}, {{date}}, {{month}}, {{month_name}}, {{quarter}}
+and {{year}}.`,
+}
+
+// runCmd represents the recurring run command
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Generate all recurring invoices due",
+	Long: `Generate every invoice of the recurring definitions that is due up to the given date
+and has not been generated yet. Each invoice gets the date of its occurrence, the service
+period it covers and the next invoice number. Issued occurrences are recorded, so running
+the command again only generates what is new.
+
+Examples:
+  invoicegen recurring run
+  invoicegen recurring run --until 2025-12-31 --dry-run
+  invoicegen recurring run --id hosting-acme`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+		opts := &service.RecurringOptions{IDs: ids, DryRun: dryRun}
+		if until != "" {
+			if opts.Until, err = time.Parse("2006-01-02", until); err != nil {
+				return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", until)
+			}
+		}
+
+		runs, err := invoiceService.RunRecurring(cmd.Context(), opts)
+		if err != nil {
+			printError(err)
+			if len(runs) == 0 {
+				return err
+			}
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if encErr := enc.Encode(runs); encErr != nil {
+				return encErr
+			}
+			return err
+		}
+		if len(runs) == 0 {
+			fmt.Println("No recurring invoices due")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DEFINITION\tDATE\tPERIOD\tNUMBER\tFILE")
+		for _, r := range runs {
+			fmt.Fprintf(w, "%s\t%s\t%s – %s\t%s\t%s\n", r.Definition, r.Date.Format("2006-01-02"),
+				r.Period.Start.Format("2006-01-02"), r.Period.End.Format("2006-01-02"), r.Number, r.File)
+		}
+		if dryRun {
+			fmt.Fprintln(w, "\nDry run, no invoices generated")
+		}
+		if flushErr := w.Flush(); flushErr != nil {
+			return flushErr
+		}
+		return err
+	},
+}
+
+// listCmd represents the recurring list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recurring invoice definitions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+		defs, err := invoiceService.RecurringDefinitions()
+		if err != nil {
+			printError(err)
+			return err
+		}
+		if len(defs) == 0 {
+			fmt.Println("No recurring invoices defined")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSCHEDULE\tPERIOD\tBILLING\tSTART\tEND")
+		for _, d := range defs {
+			end := "-"
+			if !d.End.IsZero() {
+				end = d.End.Format("2006-01-02")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", d.ID, d.Schedule, d.Period, d.Billing, d.Start.Format("2006-01-02"), end)
+		}
+		return w.Flush()
+	},
+}
+
+// RecurringCmd is the exported recurring command
+var RecurringCmd = recurringCmd
+
+func init() {
+	runCmd.Flags().StringVar(&until, "until", "", "generate occurrences up to this date (YYYY-MM-DD), default today")
+	runCmd.Flags().StringSliceVar(&ids, "id", nil, "only run these definitions")
+	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list the invoices due")
+	runCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the invoices as JSON")
+	recurringCmd.AddCommand(runCmd, listCmd)
+}
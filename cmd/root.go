@@ -1,20 +1,50 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"invoiceformats/internal/config"
 	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/pdf"
+	"invoiceformats/pkg/tracing"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	// Import subcommands directly
+	"invoiceformats/cmd/archive"
+	"invoiceformats/cmd/credit"
+	"invoiceformats/cmd/dunning"
 	"invoiceformats/cmd/generate"
+	"invoiceformats/cmd/invoices"
+	"invoiceformats/cmd/quote"
+	"invoiceformats/cmd/reconcile"
+	"invoiceformats/cmd/recurring"
+	"invoiceformats/cmd/schema"
+	"invoiceformats/cmd/serve"
 	"invoiceformats/cmd/validate"
+	"invoiceformats/cmd/webhooks"
 )
 
 var (
 	cfgFile string
 	verbose bool
 	logger logging.Logger // Use the Logger interface from pkg/logging
+
+	// appConfig is the configuration loaded by initConfig; configErr is why it could
+	// not be loaded
+	appConfig *config.AppConfig
+	configErr error
+
+	// shutdownTracing flushes the spans of the command; set up by initTracing
+	shutdownTracing = func(context.Context) error { return nil }
+
+	// pdfParallelism is the value of --pdf-parallelism
+	pdfParallelism int
 )
 
 var rootCmd = &cobra.Command{
@@ -31,16 +61,31 @@ Features:
 • Comprehensive validation and error handling
 • CLI and API modes
 • Docker containerization support`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if configErr != nil {
+			return configErr
+		}
+		cmd.SetContext(config.NewContext(cmd.Context(), appConfig))
 		initLogger()
+		initTracing(cmd.Context())
+		initPDF(cmd)
+		return nil
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by the main.main(). The rootCmd will then run the correct
-// handler depending on the command line arguments.
+// handler depending on the command line arguments. An interrupt cancels the context
+// of the command, so a running generation stops and hands back its invoice number.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	pdf.Close()
+	if serr := shutdownTracing(context.Background()); serr != nil && logger != nil {
+		logger.Warn("Failed to flush traces", &logging.LogFields{Error: serr.Error()})
+	}
+	if err != nil {
 		panic(err)
 	}
 }
@@ -52,6 +97,7 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().IntVar(&pdfParallelism, "pdf-parallelism", 0, "PDFs printed at the same time, each in its own browser tab (default pdf.parallelism)")
 
 	// Bind flags to viper
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
@@ -59,10 +105,27 @@ func init() {
 	// Register subcommands
 	rootCmd.AddCommand(generate.GenerateCmd)
 	rootCmd.AddCommand(validate.ValidateCmd)
+	rootCmd.AddCommand(credit.CreditCmd)
+	rootCmd.AddCommand(quote.ConvertQuoteCmd)
+	rootCmd.AddCommand(invoices.ListCmd)
+	rootCmd.AddCommand(invoices.ShowCmd)
+	rootCmd.AddCommand(invoices.MarkSentCmd)
+	rootCmd.AddCommand(invoices.MarkPaidCmd)
+	rootCmd.AddCommand(invoices.MarkAcceptedCmd)
+	rootCmd.AddCommand(invoices.MarkDeclinedCmd)
+	rootCmd.AddCommand(archive.VerifyArchiveCmd)
+	rootCmd.AddCommand(reconcile.ReconcileCmd)
+	rootCmd.AddCommand(dunning.DunningCmd)
+	rootCmd.AddCommand(recurring.RecurringCmd)
+	rootCmd.AddCommand(schema.SchemaCmd)
+	rootCmd.AddCommand(serve.ServeCmd)
+	rootCmd.AddCommand(webhooks.WebhooksCmd)
 	// TODO: Add other subcommands here
 }
 
-// initConfig reads in config file and ENV variables if set.
+// initConfig reads in config file and ENV variables if set, on top of the defaults.
+// Commands get the result from config.FromContext; a config file that cannot be read
+// or is invalid stops them.
 func initConfig() {
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
@@ -73,11 +136,38 @@ func initConfig() {
 	}
 	viper.SetEnvPrefix("INVOICEGEN")
 	viper.AutomaticEnv()
-	// TODO: Set defaults as needed
-	_ = viper.ReadInConfig()
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if cfgFile != "" || !errors.As(err, &notFound) {
+			configErr = fmt.Errorf("failed to read config file: %w", err)
+			return
+		}
+	}
+	appConfig, configErr = config.Load(viper.GetViper())
 }
 
 // initLogger initializes the logger
 func initLogger() {
 	logger = logging.NewLogger()
-}
\ No newline at end of file
+}
+
+// initTracing sets up the export of traces, configured by the tracing settings or the
+// OTEL_* environment variables. Commands still run if the exporter cannot be created.
+func initTracing(ctx context.Context) {
+	shutdown, err := tracing.Setup(ctx, config.FromContext(ctx).Tracing)
+	if err != nil {
+		logger.Warn("Tracing disabled", &logging.LogFields{Error: err.Error()})
+		return
+	}
+	shutdownTracing = shutdown
+}
+
+// initPDF sets the number of PDFs printed at the same time from --pdf-parallelism or
+// the pdf settings.
+func initPDF(cmd *cobra.Command) {
+	n := config.FromContext(cmd.Context()).PDF.Parallelism
+	if cmd.Flags().Changed("pdf-parallelism") {
+		n = pdfParallelism
+	}
+	pdf.SetParallelism(n)
+}
@@ -0,0 +1,65 @@
+// Package schema provides the command that prints the JSON Schema of invoice files and
+// the OpenAPI description of the HTTP API.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"invoiceformats/internal/config"
+	"invoiceformats/pkg/jsonschema"
+	"invoiceformats/pkg/server"
+)
+
+var (
+	openAPI bool
+	output  string
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of invoice files",
+	Long: `Print the JSON Schema (draft 2020-12) of invoice files, for editors to complete
+and check invoice YAML and JSON. It is generated from the invoice model, so it
+lists the supported enum values such as vat_exemption_type and tariff_type.
+
+With --openapi, print the OpenAPI 3.1 description of the HTTP API instead, which
+is also served by 'invoicegen serve' at /v1/openapi.json.
+
+Examples:
+  invoicegen schema --output invoice.schema.json
+  invoicegen schema --openapi > openapi.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var doc interface{} = jsonschema.InvoiceData()
+		if openAPI {
+			doc = server.OpenAPI(config.FromContext(cmd.Context()).Server)
+		}
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode schema: %w", err)
+		}
+		out = append(out, '\n')
+		if output == "" {
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+		if err := os.WriteFile(output, out, 0644); err != nil {
+			return fmt.Errorf("failed to write schema: %w", err)
+		}
+		fmt.Printf("✅ Schema written to %s\n", output)
+		return nil
+	},
+}
+
+// SchemaCmd is the exported schema command
+var SchemaCmd = schemaCmd
+
+func init() {
+	schemaCmd.Flags().BoolVar(&openAPI, "openapi", false, "print the OpenAPI description of the HTTP API")
+	schemaCmd.Flags().StringVarP(&output, "output", "o", "", "write to this file instead of stdout")
+}
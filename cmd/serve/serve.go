@@ -0,0 +1,135 @@
+// Package serve provides the command that runs the HTTP API server.
+package serve
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"invoiceformats/internal/config"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/server"
+	"invoiceformats/pkg/service"
+)
+
+var (
+	host       string
+	port       int
+	grpcPort   int
+	outputDir  string
+	noCORS     bool
+	jobsDir    string
+	jobWorkers int
+)
+
+// newInvoiceService returns an invoice service for the given configuration
+func newInvoiceService(cfg *config.AppConfig, logger logging.Logger) (*service.InvoiceService, error) {
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the HTTP API server",
+	Long: `Serve the invoice functions as a REST API.
+
+Endpoints:
+  GET    /v1/templates            embedded invoice templates
+  GET    /v1/locales              available languages
+  GET    /v1/openapi.json         OpenAPI description of the API
+  POST   /v1/invoices/validate    validate invoice JSON; returns the completed invoice
+  POST   /v1/invoices/html        HTML preview
+  POST   /v1/invoices/pdf         generate the PDF; issues the invoice number
+  POST   /v1/invoices/xml         ZUGFeRD/Factur-X XML
+  POST   /v1/jobs                 queue a generation (kind=pdf|xml|html, callback_url)
+  GET    /v1/jobs/{id}            job status
+  GET    /v1/jobs/{id}/artifact   generated file of a finished job
+  DELETE /v1/jobs/{id}            cancel a job
+  GET    /healthz                 liveness; fails while the browser hangs
+  GET    /readyz                  readiness; checks that headless Chrome starts
+  GET    /metrics                 Prometheus metrics
+
+The invoice is the request body, as JSON in the format of invoice files; provider and
+client may reference profiles. The query parameters template, lang, currency and
+series select the template, language, currency and number series. Errors are returned
+as JSON with the error code, e.g. {"code": "VALIDATION_FAILED", "message": "..."}.
+
+If tenants are configured under server.auth.tenants, requests other than the probes,
+the metrics and the OpenAPI description must carry an API key in X-API-Key or a bearer
+token (an API key or an HS256 JWT with a tenant claim). Each
+tenant has its own profiles, numbering, storage and jobs, and its own rate limit.
+Without tenants the API is open, and serve refuses to listen on an address other
+than localhost.
+
+With --grpc-port, the gRPC service invoicegen.v1.InvoiceService (Validate, RenderHTML,
+GeneratePDF, GenerateXML) is served as well; see pkg/server/invoicepb/invoicegen.proto.
+
+Examples:
+  invoicegen serve
+  invoicegen serve --host 0.0.0.0 --port 9000
+  invoicegen serve --grpc-port 9090
+  curl -X POST --data @invoice.json localhost:8080/v1/invoices/pdf?template=modern-dark.html.tmpl -o invoice.pdf`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := logging.NewLogger()
+		cfg := config.FromContext(cmd.Context())
+		if cmd.Flags().Changed("host") {
+			cfg.Server.Host = host
+		}
+		if cmd.Flags().Changed("port") {
+			cfg.Server.Port = port
+		}
+		if cmd.Flags().Changed("grpc-port") {
+			cfg.Server.GRPCPort = grpcPort
+		}
+		if cmd.Flags().Changed("output-dir") {
+			cfg.Server.OutputDir = outputDir
+		}
+		if cmd.Flags().Changed("jobs-dir") {
+			cfg.Server.JobsDir = jobsDir
+		}
+		if cmd.Flags().Changed("job-workers") {
+			cfg.Server.JobWorkers = jobWorkers
+		}
+		if noCORS {
+			cfg.Server.EnableCORS = false
+		}
+
+		invoiceService, err := newInvoiceService(cfg, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		srv, err := server.New(cfg.Server, invoiceService, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create server: %w", err)
+		}
+		return srv.ListenAndServe(ctx)
+	},
+}
+
+// ServeCmd is the exported serve command
+var ServeCmd = serveCmd
+
+func init() {
+	serveCmd.Flags().StringVar(&host, "host", "", "address to listen on (default from config: localhost)")
+	serveCmd.Flags().IntVar(&port, "port", 0, "port to listen on (default from config: 8080)")
+	serveCmd.Flags().IntVar(&grpcPort, "grpc-port", 0, "port of the gRPC API; 0 disables it (default from config: 0)")
+	serveCmd.Flags().StringVar(&outputDir, "output-dir", "", "directory where generated PDFs are kept; empty keeps none (default from config: invoices/pdf)")
+	serveCmd.Flags().StringVar(&jobsDir, "jobs-dir", "", "directory of the job queue; empty disables jobs (default from config: .invoicegen/jobs)")
+	serveCmd.Flags().IntVar(&jobWorkers, "job-workers", 0, "jobs run at the same time (default from config: 2)")
+	serveCmd.Flags().BoolVar(&noCORS, "no-cors", false, "do not send CORS headers")
+}
@@ -2,6 +2,7 @@
 package validate
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -9,7 +10,6 @@ import (
 
 	"invoiceformats/internal/config"
 	appErrs "invoiceformats/pkg/errors"
-	loader "invoiceformats/pkg/loader"
 	"invoiceformats/pkg/logging"
 	"invoiceformats/pkg/render"
 	"invoiceformats/pkg/service"
@@ -51,13 +51,13 @@ Examples:
 		logger.Info("Validating invoice data", &logging.LogFields{File: inputFile})
 
 		// Get invoice service
-		invoiceService, err := GetInvoiceService()
+		invoiceService, err := GetInvoiceService(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to create invoice service: %w", err)
 		}
 
 		// Load invoice data
-		data, err := loader.LoadInvoiceData(inputFile, logger)
+		data, err := invoiceService.LoadInvoiceData(inputFile)
 		if err != nil {
 			return fmt.Errorf("failed to load invoice data: %w", err)
 		}
@@ -123,9 +123,9 @@ func GetLogger() logging.Logger {
 	return logging.NewLogger()
 }
 
-// GetInvoiceService returns a default invoice service instance
-func GetInvoiceService() (*service.InvoiceService, error) {
-	cfg := config.DefaultConfig()
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
 	logger := GetLogger()
 	// Load embedded locales.json
 	localeData, err := os.ReadFile("pkg/render/locales.json")
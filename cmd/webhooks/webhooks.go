@@ -0,0 +1,201 @@
+// Package webhooks provides the commands for webhook notifications.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/service"
+	"invoiceformats/pkg/webhook"
+)
+
+var (
+	event      string
+	failedOnly bool
+	limit      int
+	addr       string
+	secret     string
+	jsonOutput bool
+)
+
+// GetInvoiceService returns an invoice service with the configuration of the command
+func GetInvoiceService(ctx context.Context) (*service.InvoiceService, error) {
+	cfg := config.FromContext(ctx)
+	logger := logging.NewLogger()
+	// Load embedded locales.json
+	localeData, err := os.ReadFile("pkg/render/locales.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded locales.json: %w", err)
+	}
+	loader := render.NewLocaleLoader(localeData)
+	return service.NewInvoiceService(cfg, logger, loader), nil
+}
+
+func printError(err error) {
+	if appErr, ok := err.(*appErrs.AppError); ok {
+		fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", appErr.Code, appErr.Message)
+		if appErr.Cause != nil {
+			fmt.Fprintf(os.Stderr, "Cause: %v\n", appErr.Cause)
+		}
+	}
+}
+
+// webhooksCmd represents the webhooks command
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Inspect and test webhook notifications",
+	Long: `Webhook endpoints configured under webhooks.endpoints are notified of the invoice
+lifecycle events they subscribe to: invoice.generated, invoice.sent, invoice.paid,
+invoice.overdue, invoice.cancelled, invoice.accepted and invoice.declined, or "*" for
+all. Deliveries are signed with the endpoint secret, retried with exponential backoff
+and recorded in the delivery log.`,
+}
+
+// logCmd represents the webhooks log command
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show the webhook delivery log",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+		deliveries, err := invoiceService.WebhookDeliveries()
+		if err != nil {
+			printError(err)
+			return err
+		}
+		var shown []webhook.Delivery
+		for _, d := range deliveries {
+			if (!failedOnly || !d.Delivered) && (event == "" || string(d.Event) == event) {
+				shown = append(shown, d)
+			}
+		}
+		if limit > 0 && len(shown) > limit {
+			shown = shown[len(shown)-limit:]
+		}
+
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(shown)
+		}
+		if len(shown) == 0 {
+			fmt.Println("No webhook deliveries")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tEVENT\tINVOICE\tURL\tATTEMPT\tRESULT")
+		for _, d := range shown {
+			result := "delivered"
+			if !d.Delivered {
+				result = d.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", d.At.Local().Format("2006-01-02 15:04:05"), d.Event, d.Invoice, d.URL, d.Attempt, result)
+		}
+		return w.Flush()
+	},
+}
+
+// testCmd represents the webhooks test command
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a sample event to the configured endpoints",
+	Long: `Send an event for the sample invoice to every endpoint that subscribes to it, with
+signing, retries and the delivery log as for real events.
+
+Examples:
+  invoicegen webhooks test
+  invoicegen webhooks test --event invoice.overdue`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceService, err := GetInvoiceService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to create invoice service: %w", err)
+		}
+		deliveries, err := invoiceService.TestWebhooks(webhook.EventType(event))
+		for _, d := range deliveries {
+			if d.Delivered {
+				fmt.Printf("%s: delivered (HTTP %d, attempt %d)\n", d.URL, d.StatusCode, d.Attempt)
+			} else {
+				fmt.Printf("%s: failed after %d attempts: %s\n", d.URL, d.Attempt, d.Error)
+			}
+		}
+		if err != nil {
+			printError(err)
+			return err
+		}
+		if len(deliveries) == 0 {
+			fmt.Printf("No endpoint subscribes to %s\n", event)
+		}
+		return nil
+	},
+}
+
+// receiveCmd represents the webhooks receive command
+var receiveCmd = &cobra.Command{
+	Use:   "receive",
+	Short: "Run a local endpoint that prints received events",
+	Long: `Run a local HTTP endpoint that prints the events it receives, as a stand-in for the
+real receiver while setting up webhooks. With --secret, signatures are verified and
+deliveries with invalid signatures are rejected with 401.
+
+Examples:
+  invoicegen webhooks receive --addr localhost:9090 --secret s3cret`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			signature := "unsigned"
+			if secret != "" {
+				if err := webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), body, 5*time.Minute); err != nil {
+					fmt.Printf("%s rejected: %v\n", r.Header.Get(webhook.EventHeader), err)
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				signature = "signature valid"
+			}
+			var evt webhook.Event
+			if err := json.Unmarshal(body, &evt); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Printf("%s %s %s (%s %s, %s), %s\n", time.Now().Format("15:04:05"), evt.Type, evt.Invoice.Number,
+				evt.Invoice.GrandTotal.StringFixed(2), evt.Invoice.Currency, evt.Invoice.Status, signature)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		fmt.Printf("Receiving webhooks on http://%s/\n", addr)
+		return http.ListenAndServe(addr, handler)
+	},
+}
+
+// WebhooksCmd is the exported webhooks command
+var WebhooksCmd = webhooksCmd
+
+func init() {
+	logCmd.Flags().StringVar(&event, "event", "", "only show deliveries of this event")
+	logCmd.Flags().BoolVar(&failedOnly, "failed", false, "only show failed attempts")
+	logCmd.Flags().IntVar(&limit, "limit", 50, "show at most this many of the latest deliveries, 0 for all")
+	logCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the deliveries as JSON")
+	testCmd.Flags().StringVar(&event, "event", string(webhook.EventGenerated), "event type to send")
+	receiveCmd.Flags().StringVar(&addr, "addr", "localhost:9090", "address to listen on")
+	receiveCmd.Flags().StringVar(&secret, "secret", "", "verify signatures with this secret")
+	webhooksCmd.AddCommand(logCmd, testCmd, receiveCmd)
+}
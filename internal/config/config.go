@@ -3,6 +3,7 @@
 package config
 
 import (
+	"path/filepath"
 	"time"
 )
 
@@ -12,25 +13,69 @@ type AppConfig struct {
     Invoice  InvoiceConfig  `yaml:"invoice" json:"invoice" mapstructure:"invoice"`
     PDF      PDFConfig      `yaml:"pdf" json:"pdf" mapstructure:"pdf"`
     Template TemplateConfig `yaml:"template" json:"template" mapstructure:"template"`
+    Dunning  DunningConfig  `yaml:"dunning" json:"dunning" mapstructure:"dunning"`
+    Webhooks WebhooksConfig `yaml:"webhooks" json:"webhooks" mapstructure:"webhooks"`
     Logging  LoggingConfig  `yaml:"logging" json:"logging" mapstructure:"logging"`
+    Tracing  TracingConfig  `yaml:"tracing" json:"tracing" mapstructure:"tracing"`
 }
 
 // ServerConfig represents server configuration for API mode
 type ServerConfig struct {
-    Port         int           `yaml:"port" json:"port" mapstructure:"port" validate:"min=1,max=65535"`
-    Host         string        `yaml:"host" json:"host" mapstructure:"host"`
-    ReadTimeout  time.Duration `yaml:"read_timeout" json:"read_timeout" mapstructure:"read_timeout"`
-    WriteTimeout time.Duration `yaml:"write_timeout" json:"write_timeout" mapstructure:"write_timeout"`
-    EnableCORS   bool          `yaml:"enable_cors" json:"enable_cors" mapstructure:"enable_cors"`
+    Port           int           `yaml:"port" json:"port" mapstructure:"port" validate:"min=1,max=65535"`
+    Host           string        `yaml:"host" json:"host" mapstructure:"host"`
+    GRPCPort       int           `yaml:"grpc_port" json:"grpc_port" mapstructure:"grpc_port" validate:"min=0,max=65535"` // Port of the gRPC API on Host; 0 disables it
+    ReadTimeout    time.Duration `yaml:"read_timeout" json:"read_timeout" mapstructure:"read_timeout"`
+    WriteTimeout   time.Duration `yaml:"write_timeout" json:"write_timeout" mapstructure:"write_timeout"`
+    EnableCORS     bool          `yaml:"enable_cors" json:"enable_cors" mapstructure:"enable_cors"`
+    OutputDir      string        `yaml:"output_dir" json:"output_dir" mapstructure:"output_dir"` // Where PDFs generated through the API are kept; empty keeps none
+    JobsDir        string        `yaml:"jobs_dir" json:"jobs_dir" mapstructure:"jobs_dir"` // Queue of asynchronous generation jobs; empty disables jobs
+    JobWorkers     int           `yaml:"job_workers" json:"job_workers" mapstructure:"job_workers" validate:"min=0"`
+    JobMaxAttempts int           `yaml:"job_max_attempts" json:"job_max_attempts" mapstructure:"job_max_attempts" validate:"min=0"`
+    JobRetryDelay  time.Duration `yaml:"job_retry_delay" json:"job_retry_delay" mapstructure:"job_retry_delay"` // Doubled for each further retry
+    Auth           AuthConfig    `yaml:"auth" json:"auth" mapstructure:"auth"`
+}
+
+// AuthConfig represents authentication, tenants and rate limits of the API. The API
+// is open while no tenants are configured.
+type AuthConfig struct {
+    Tenants      []TenantConfig `yaml:"tenants" json:"tenants" mapstructure:"tenants" validate:"dive"`
+    JWTSecret    string         `yaml:"jwt_secret" json:"jwt_secret" mapstructure:"jwt_secret"` // HMAC secret of HS256 bearer tokens; empty disables tokens
+    JWTSecretEnv string         `yaml:"jwt_secret_env" json:"jwt_secret_env" mapstructure:"jwt_secret_env"` // Environment variable holding the JWT secret
+    RateLimit    int            `yaml:"rate_limit" json:"rate_limit" mapstructure:"rate_limit" validate:"gte=0"` // Requests per minute per API key or token subject; 0 is unlimited
+    RateBurst    int            `yaml:"rate_burst" json:"rate_burst" mapstructure:"rate_burst" validate:"gte=0"` // Requests allowed at once; defaults to RateLimit
+}
+
+// TenantConfig represents a tenant of the API with its own profiles, catalog,
+// numbering and storage under Dir
+type TenantConfig struct {
+    ID           string                  `yaml:"id" json:"id" mapstructure:"id" validate:"required"`
+    APIKeys      []string                `yaml:"api_keys" json:"api_keys" mapstructure:"api_keys"` // Keys in plain text or as "sha256:<hex digest>"
+    APIKeysEnv   string                  `yaml:"api_keys_env" json:"api_keys_env" mapstructure:"api_keys_env"` // Environment variable holding comma-separated keys
+    Dir          string                  `yaml:"dir" json:"dir" mapstructure:"dir"` // Defaults to .invoicegen/tenants/<id>
+    RateLimit    int                     `yaml:"rate_limit" json:"rate_limit" mapstructure:"rate_limit" validate:"gte=0"` // Overrides auth.rate_limit
+    MaxBodyBytes int64                   `yaml:"max_body_bytes" json:"max_body_bytes" mapstructure:"max_body_bytes" validate:"gte=0"` // Request size limit; 0 uses the server limit
+    Webhooks     []WebhookEndpointConfig `yaml:"webhooks" json:"webhooks" mapstructure:"webhooks" validate:"dive"` // Replace the global webhook endpoints for the tenant
 }
 
 // InvoiceConfig represents invoice-specific configuration
 type InvoiceConfig struct {
     DefaultCurrency    string  `yaml:"default_currency" json:"default_currency" mapstructure:"default_currency" validate:"len=3"`
     DefaultDueDays     int     `yaml:"default_due_days" json:"default_due_days" mapstructure:"default_due_days" validate:"gte=0"`
-    NumberingStrategy  string  `yaml:"numbering_strategy" json:"numbering_strategy" mapstructure:"numbering_strategy" validate:"oneof=sequential date-based custom"`
+    NumberingStrategy  string  `yaml:"numbering_strategy" json:"numbering_strategy" mapstructure:"numbering_strategy" validate:"oneof=sequential date timestamp"`
     NumberPrefix       string  `yaml:"number_prefix" json:"number_prefix" mapstructure:"number_prefix"`
+    NumberPattern      string  `yaml:"number_pattern" json:"number_pattern" mapstructure:"number_pattern"` // Sequential pattern, e.g. "RE-{YYYY}-{SEQ:4}"; defaults to NumberPrefix + "{YYYY}-{SEQ:4}"
+    NumberSeries       map[string]string `yaml:"number_series" json:"number_series" mapstructure:"number_series"` // Additional named series and their patterns
+    NumberingFile      string  `yaml:"numbering_file" json:"numbering_file" mapstructure:"numbering_file"` // Counter storage for sequential numbering
+    RepositoryDir      string  `yaml:"repository_dir" json:"repository_dir" mapstructure:"repository_dir"` // Where generated invoices are recorded; empty disables the repository
+    ArchiveDir         string  `yaml:"archive_dir" json:"archive_dir" mapstructure:"archive_dir"` // GoBD archive for issued invoices; empty disables archiving
     DefaultTaxRate     float64 `yaml:"default_tax_rate" json:"default_tax_rate" mapstructure:"default_tax_rate" validate:"gte=0,lte=100"`
+    TaxCurrency        string  `yaml:"tax_currency" json:"tax_currency" mapstructure:"tax_currency" validate:"omitempty,len=3"` // VAT accounting currency (BT-6); empty disables conversion
+    ExchangeRatesFile  string  `yaml:"exchange_rates_file" json:"exchange_rates_file" mapstructure:"exchange_rates_file"` // ECB XML or YAML/JSON rates file
+    RecurringDir       string  `yaml:"recurring_dir" json:"recurring_dir" mapstructure:"recurring_dir"` // Recurring invoice definitions
+    RecurringStateFile string  `yaml:"recurring_state_file" json:"recurring_state_file" mapstructure:"recurring_state_file"` // Occurrences already invoiced
+    ProfilesDir        string  `yaml:"profiles_dir" json:"profiles_dir" mapstructure:"profiles_dir"` // Provider and client profiles referenced from invoice files
+    CatalogFile        string  `yaml:"catalog_file" json:"catalog_file" mapstructure:"catalog_file"` // Products and services invoice lines reference by SKU
+    QuoteValidDays     int     `yaml:"quote_valid_days" json:"quote_valid_days" mapstructure:"quote_valid_days" validate:"gte=0"` // Default validity of quotes; 0 leaves valid_until open
 }
 
 // PDFConfig represents PDF generation configuration
@@ -42,6 +87,7 @@ type PDFConfig struct {
     MarginBottom string `yaml:"margin_bottom" json:"margin_bottom" mapstructure:"margin_bottom"`
     MarginLeft   string `yaml:"margin_left" json:"margin_left" mapstructure:"margin_left"`
     MarginRight  string `yaml:"margin_right" json:"margin_right" mapstructure:"margin_right"`
+    Parallelism  int    `yaml:"parallelism" json:"parallelism" mapstructure:"parallelism" validate:"min=0"` // PDFs printed at the same time, each in its own browser tab; 0 means 4
 }
 
 // TemplateConfig represents template configuration
@@ -52,6 +98,41 @@ type TemplateConfig struct {
     CustomVariables map[string]string `yaml:"custom_variables" json:"custom_variables" mapstructure:"custom_variables"`
 }
 
+// DunningConfig represents reminder and dunning configuration
+type DunningConfig struct {
+    OutputDir string               `yaml:"output_dir" json:"output_dir" mapstructure:"output_dir"`
+    BaseRate  float64              `yaml:"base_rate" json:"base_rate" mapstructure:"base_rate"` // Base rate in percent (§ 247 BGB); update each 1 January and 1 July
+    Levels    []DunningLevelConfig `yaml:"levels" json:"levels" mapstructure:"levels" validate:"dive"` // Escalation levels; defaults to reminder, dunning and final notice
+}
+
+// DunningLevelConfig represents one escalation level
+type DunningLevelConfig struct {
+    Name              string  `yaml:"name" json:"name" mapstructure:"name" validate:"required"` // Locale key of the notice title
+    DaysAfterDue      int     `yaml:"days_after_due" json:"days_after_due" mapstructure:"days_after_due" validate:"gte=0"`
+    DaysAfterPrevious int     `yaml:"days_after_previous" json:"days_after_previous" mapstructure:"days_after_previous" validate:"gte=0"`
+    PaymentDays       int     `yaml:"payment_days" json:"payment_days" mapstructure:"payment_days" validate:"gte=0"`
+    Fee               float64 `yaml:"fee" json:"fee" mapstructure:"fee" validate:"gte=0"`
+    Interest          bool    `yaml:"interest" json:"interest" mapstructure:"interest"`
+    Template          string  `yaml:"template" json:"template" mapstructure:"template"` // Embedded template name or file path
+}
+
+// WebhooksConfig represents outbound webhook configuration
+type WebhooksConfig struct {
+    Endpoints   []WebhookEndpointConfig `yaml:"endpoints" json:"endpoints" mapstructure:"endpoints" validate:"dive"`
+    MaxAttempts int                     `yaml:"max_attempts" json:"max_attempts" mapstructure:"max_attempts" validate:"gte=0"`
+    RetryDelay  time.Duration           `yaml:"retry_delay" json:"retry_delay" mapstructure:"retry_delay"` // Doubled for each further retry
+    Timeout     time.Duration           `yaml:"timeout" json:"timeout" mapstructure:"timeout"`
+    LogFile     string                  `yaml:"log_file" json:"log_file" mapstructure:"log_file"` // Delivery log; empty keeps none
+}
+
+// WebhookEndpointConfig represents one webhook endpoint
+type WebhookEndpointConfig struct {
+    URL       string   `yaml:"url" json:"url" mapstructure:"url" validate:"required,url"`
+    Events    []string `yaml:"events" json:"events" mapstructure:"events" validate:"required"` // Event types, e.g. invoice.paid, or "*" for all
+    Secret    string   `yaml:"secret" json:"secret" mapstructure:"secret"`                     // HMAC secret
+    SecretEnv string   `yaml:"secret_env" json:"secret_env" mapstructure:"secret_env"`         // Environment variable holding the secret
+}
+
 // LoggingConfig represents logging configuration
 type LoggingConfig struct {
     Level      string `yaml:"level" json:"level" mapstructure:"level" validate:"oneof=debug info warn error"`
@@ -59,21 +140,44 @@ type LoggingConfig struct {
     OutputPath string `yaml:"output_path" json:"output_path" mapstructure:"output_path"`
 }
 
+// TracingConfig represents OpenTelemetry tracing configuration
+type TracingConfig struct {
+    Exporter    string  `yaml:"exporter" json:"exporter" mapstructure:"exporter" validate:"omitempty,oneof=none stdout otlp"` // Empty uses OTEL_TRACES_EXPORTER, or none
+    Endpoint    string  `yaml:"endpoint" json:"endpoint" mapstructure:"endpoint"` // OTLP collector, e.g. localhost:4318; empty uses OTEL_EXPORTER_OTLP_ENDPOINT
+    Protocol    string  `yaml:"protocol" json:"protocol" mapstructure:"protocol" validate:"omitempty,oneof=grpc http/protobuf"` // OTLP protocol; empty uses OTEL_EXPORTER_OTLP_PROTOCOL, or http/protobuf
+    Insecure    bool    `yaml:"insecure" json:"insecure" mapstructure:"insecure"` // OTLP without TLS
+    SampleRatio float64 `yaml:"sample_ratio" json:"sample_ratio" mapstructure:"sample_ratio" validate:"gte=0,lte=1"` // Fraction of traces recorded
+    ServiceName string  `yaml:"service_name" json:"service_name" mapstructure:"service_name"`
+}
+
 // DefaultConfig returns a default configuration
 func DefaultConfig() *AppConfig {
     return &AppConfig{
         Server: ServerConfig{
-            Port:         8080,
-            Host:         "localhost",
-            ReadTimeout:  30 * time.Second,
-            WriteTimeout: 30 * time.Second,
-            EnableCORS:   true,
+            Port:           8080,
+            Host:           "localhost",
+            ReadTimeout:    30 * time.Second,
+            WriteTimeout:   30 * time.Second,
+            EnableCORS:     true,
+            OutputDir:      "invoices/pdf",
+            JobsDir:        ".invoicegen/jobs",
+            JobWorkers:     2,
+            JobMaxAttempts: 3,
+            JobRetryDelay:  5 * time.Second,
         },
         Invoice: InvoiceConfig{
             DefaultCurrency:   "EUR",
             DefaultDueDays:    30,
             NumberingStrategy: "sequential",
             NumberPrefix:      "",
+            NumberingFile:     ".invoicegen/numbering.json",
+            RepositoryDir:     ".invoicegen/invoices",
+            ArchiveDir:        ".invoicegen/archive",
+            RecurringDir:      "invoices/recurring",
+            RecurringStateFile: ".invoicegen/recurring.json",
+            ProfilesDir:       "invoices/profiles",
+            CatalogFile:       "invoices/catalog.yaml",
+            QuoteValidDays:    30,
             DefaultTaxRate:    19.0, // 19% VAT for Germany
         },
         PDF: PDFConfig{
@@ -84,16 +188,65 @@ func DefaultConfig() *AppConfig {
             MarginBottom: "1cm",
             MarginLeft:  "1cm",
             MarginRight: "1cm",
+            Parallelism: 4,
         },
         Template: TemplateConfig{
             Theme:           "modern",
             ShowLogo:        true,
             CustomVariables: make(map[string]string),
         },
+        Dunning: DunningConfig{
+            OutputDir: "invoices/dunning",
+            BaseRate:  1.27, // Since 1 July 2025
+        },
+        Webhooks: WebhooksConfig{
+            MaxAttempts: 4,
+            RetryDelay:  time.Second,
+            Timeout:     10 * time.Second,
+            LogFile:     ".invoicegen/webhooks.jsonl",
+        },
         Logging: LoggingConfig{
             Level:      "info",
             Format:     "json",
             OutputPath: "stdout",
         },
+        Tracing: TracingConfig{
+            SampleRatio: 1,
+            ServiceName: "invoicegen",
+        },
+    }
+}
+
+// TenantDir returns the directory of a tenant's data
+func (t TenantConfig) TenantDir() string {
+    if t.Dir != "" {
+        return t.Dir
+    }
+    return filepath.Join(".invoicegen", "tenants", t.ID)
+}
+
+// ForTenant returns a copy of the configuration for a tenant. Profiles, catalog,
+// numbering, repository, archive, recurring invoices, dunning notices and the webhook
+// log move into the tenant directory; features disabled by an empty path stay
+// disabled. The tenant's webhook endpoints replace the global ones.
+func (c *AppConfig) ForTenant(t TenantConfig) *AppConfig {
+    cfg := *c
+    dir := t.TenantDir()
+    rebase := func(path *string, name string) {
+        if *path != "" {
+            *path = filepath.Join(dir, name)
+        }
     }
+    rebase(&cfg.Invoice.ProfilesDir, "profiles")
+    rebase(&cfg.Invoice.CatalogFile, "catalog.yaml")
+    rebase(&cfg.Invoice.NumberingFile, "numbering.json")
+    rebase(&cfg.Invoice.RepositoryDir, "invoices")
+    rebase(&cfg.Invoice.ArchiveDir, "archive")
+    rebase(&cfg.Invoice.RecurringDir, "recurring")
+    rebase(&cfg.Invoice.RecurringStateFile, "recurring.json")
+    rebase(&cfg.Dunning.OutputDir, "dunning")
+    rebase(&cfg.Webhooks.LogFile, "webhooks.jsonl")
+    rebase(&cfg.Server.OutputDir, "pdf")
+    cfg.Webhooks.Endpoints = t.Webhooks
+    return &cfg
 }
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 )
 
@@ -57,3 +62,99 @@ invoice:
 	assert.Equal(t, 10*time.Second, cfg.Server.WriteTimeout)
 	assert.True(t, cfg.Server.EnableCORS)
 }
+
+func TestAppConfig_ForTenant(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Invoice.ArchiveDir = ""
+	cfg.Webhooks.Endpoints = []WebhookEndpointConfig{{URL: "https://global.example/hook"}}
+	tenant := TenantConfig{ID: "acme", Webhooks: []WebhookEndpointConfig{{URL: "https://acme.example/hook"}}}
+
+	tc := cfg.ForTenant(tenant)
+	dir := filepath.Join(".invoicegen", "tenants", "acme")
+	assert.Equal(t, filepath.Join(dir, "profiles"), tc.Invoice.ProfilesDir)
+	assert.Equal(t, filepath.Join(dir, "numbering.json"), tc.Invoice.NumberingFile)
+	assert.Equal(t, filepath.Join(dir, "invoices"), tc.Invoice.RepositoryDir)
+	assert.Equal(t, filepath.Join(dir, "pdf"), tc.Server.OutputDir)
+	assert.Empty(t, tc.Invoice.ArchiveDir, "disabled features stay disabled")
+	assert.Equal(t, "https://acme.example/hook", tc.Webhooks.Endpoints[0].URL)
+
+	assert.Equal(t, ".invoicegen/numbering.json", cfg.Invoice.NumberingFile, "the original is unchanged")
+	assert.Equal(t, "https://global.example/hook", cfg.Webhooks.Endpoints[0].URL)
+	assert.Equal(t, "data/acme", TenantConfig{ID: "acme", Dir: "data/acme"}.TenantDir())
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+server:
+  host: 0.0.0.0
+  read_timeout: 5s
+  auth:
+    jwt_secret: s3cret
+    tenants:
+      - id: acme
+        api_keys: [key-1]
+pdf:
+  parallelism: 8
+tracing:
+  exporter: stdout
+invoice:
+  tax_currency: EUR
+  exchange_rates_file: rates/eurofxref-hist.xml
+dunning:
+  base_rate: 2.27
+  levels:
+    - name: reminder
+      days_after_due: 10
+      fee: 2.5
+      interest: true
+webhooks:
+  retry_delay: 2s
+  endpoints:
+    - url: https://erp.example/hooks/invoice
+      events: [invoice.paid]
+      secret_env: ERP_WEBHOOK_SECRET
+`), 0o644))
+	v := viper.New()
+	v.SetConfigFile(file)
+	require.NoError(t, v.ReadInConfig())
+
+	cfg, err := Load(v)
+	require.NoError(t, err)
+	assert.Equal(t, "0.0.0.0", cfg.Server.Host)
+	assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, "s3cret", cfg.Server.Auth.JWTSecret)
+	require.Len(t, cfg.Server.Auth.Tenants, 1)
+	assert.Equal(t, []string{"key-1"}, cfg.Server.Auth.Tenants[0].APIKeys)
+	assert.Equal(t, 8, cfg.PDF.Parallelism)
+	assert.Equal(t, "stdout", cfg.Tracing.Exporter)
+	assert.Equal(t, "EUR", cfg.Invoice.TaxCurrency)
+	assert.Equal(t, "rates/eurofxref-hist.xml", cfg.Invoice.ExchangeRatesFile)
+	assert.Equal(t, 2.27, cfg.Dunning.BaseRate)
+	assert.Equal(t, []DunningLevelConfig{{Name: "reminder", DaysAfterDue: 10, Fee: 2.5, Interest: true}}, cfg.Dunning.Levels)
+	assert.Equal(t, 2*time.Second, cfg.Webhooks.RetryDelay)
+	assert.Equal(t, []WebhookEndpointConfig{{URL: "https://erp.example/hooks/invoice", Events: []string{"invoice.paid"}, SecretEnv: "ERP_WEBHOOK_SECRET"}}, cfg.Webhooks.Endpoints)
+	// Settings missing from the file keep their defaults
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, 30*time.Second, cfg.Server.WriteTimeout)
+	assert.Equal(t, "invoicegen", cfg.Tracing.ServiceName)
+	assert.Equal(t, "A4", cfg.PDF.PageSize)
+	assert.Equal(t, "invoices/dunning", cfg.Dunning.OutputDir)
+	assert.Equal(t, 4, cfg.Webhooks.MaxAttempts)
+
+	cfg, err = Load(viper.New())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), cfg)
+
+	v = viper.New()
+	v.Set("invoice.numbering_strategy", "random")
+	_, err = Load(v)
+	assert.ErrorContains(t, err, "invalid configuration")
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), FromContext(context.Background()))
+	cfg := DefaultConfig()
+	cfg.Server.Port = 9000
+	assert.Same(t, cfg, FromContext(NewContext(context.Background(), cfg)))
+}
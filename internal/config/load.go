@@ -0,0 +1,40 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+// Load returns the configuration read by v on top of DefaultConfig: settings missing
+// from the config file and the environment keep their defaults. The result is checked
+// against the validation tags.
+func Load(v *viper.Viper) (*AppConfig, error) {
+	cfg := DefaultConfig()
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := validator.New().Struct(cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+type configKey struct{}
+
+// NewContext returns a copy of ctx that carries cfg, so commands get the loaded
+// configuration from the context of the command.
+func NewContext(ctx context.Context, cfg *AppConfig) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// FromContext returns the configuration carried by ctx, or DefaultConfig if it carries
+// none.
+func FromContext(ctx context.Context) *AppConfig {
+	if cfg, ok := ctx.Value(configKey{}).(*AppConfig); ok {
+		return cfg
+	}
+	return DefaultConfig()
+}
@@ -0,0 +1,3 @@
+// Package filelock serializes access to files shared by several processes. Lock uses
+// flock where the platform has it and an exclusively created lock file elsewhere.
+package filelock
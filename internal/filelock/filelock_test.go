@@ -0,0 +1,53 @@
+package filelock
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock_Exclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.lock")
+	unlock, err := Lock(path, time.Second, time.Minute)
+	require.NoError(t, err)
+
+	_, err = Lock(path, 50*time.Millisecond, time.Minute)
+	assert.ErrorContains(t, err, "timed out")
+
+	unlock()
+	unlock, err = Lock(path, time.Second, time.Minute)
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestLock_Serializes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.lock")
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	holders, most := 0, 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := Lock(path, 5*time.Second, time.Minute)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			holders++
+			most = max(most, holders)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, most)
+}
@@ -0,0 +1,60 @@
+//go:build !unix
+
+package filelock
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Lock acquires the file at lockPath by exclusive creation, waiting for other holders
+// up to timeout. The file holds a token of its owner: a lock older than staleAfter is
+// only removed if it still holds the token seen when it was found stale, and the
+// owner only removes a lock holding its own token, so a fresh lock is never broken.
+func Lock(lockPath string, timeout, staleAfter time.Duration) (func(), error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("lock %s: %w", lockPath, err)
+	}
+	token := []byte(fmt.Sprintf("%d %s\n", os.Getpid(), hex.EncodeToString(raw)))
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, werr := f.Write(token)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(lockPath)
+				return nil, fmt.Errorf("lock %s: %w", lockPath, werr)
+			}
+			return func() { removeIfOwned(lockPath, token) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("lock %s: %w", lockPath, err)
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleAfter {
+			if owner, readErr := os.ReadFile(lockPath); readErr == nil {
+				removeIfOwned(lockPath, owner)
+				continue
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("lock %s: timed out waiting for another process", lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// removeIfOwned removes the lock file if it still holds token.
+func removeIfOwned(lockPath string, token []byte) {
+	if current, err := os.ReadFile(lockPath); err == nil && bytes.Equal(current, token) {
+		os.Remove(lockPath)
+	}
+}
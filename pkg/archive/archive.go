@@ -0,0 +1,250 @@
+// Package archive keeps issued invoices unaltered for the statutory retention period
+// (GoBD, § 147 AO).
+//
+// Each archived invoice is a directory of write-once files (PDF, embedded XML, source
+// data). Their SHA-256 hashes are appended to a log in which every entry also hashes
+// its predecessor, so changing, removing or reordering any document or log entry is
+// detected by Verify.
+package archive
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"invoiceformats/internal/filelock"
+	"invoiceformats/pkg/numbering"
+)
+
+// RetentionYears is the retention period for invoices under § 147 AO.
+const RetentionYears = 10
+
+// chainFile is the name of the hash-chained log inside the archive directory.
+const chainFile = "chain.jsonl"
+
+// ErrAlreadyArchived is returned when an invoice number is archived twice.
+var ErrAlreadyArchived = errors.New("invoice already archived")
+
+// File is a document to archive.
+type File struct {
+	Name string // File name inside the invoice directory, e.g. "invoice.pdf"
+	Data []byte
+}
+
+// FileHash records an archived file.
+type FileHash struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Entry is a log entry for an archived invoice.
+type Entry struct {
+	Seq         int        `json:"seq"`
+	Number      string     `json:"number"`
+	ArchivedAt  time.Time  `json:"archived_at"`
+	RetainUntil time.Time  `json:"retain_until"`
+	Files       []FileHash `json:"files"`
+	PrevHash    string     `json:"prev_hash"`
+	Hash        string     `json:"hash"`
+}
+
+// computeHash hashes the entry's content and its predecessor's hash.
+func (e Entry) computeHash() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\n%s\n%s\n%s\n%s\n", e.Seq, e.Number,
+		e.ArchivedAt.UTC().Format(time.RFC3339Nano), e.RetainUntil.UTC().Format("2006-01-02"), e.PrevHash)
+	for _, f := range e.Files {
+		fmt.Fprintf(&b, "%s %s %d\n", f.Name, f.SHA256, f.Size)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// retainUntil returns the end of the retention period, which starts at the end of the
+// calendar year the invoice was archived in.
+func retainUntil(archived time.Time) time.Time {
+	return time.Date(archived.Year()+RetentionYears, 12, 31, 0, 0, 0, 0, time.UTC)
+}
+
+// Archive is an archive directory. A lock on a file next to the log serializes
+// access across processes, so two processes never append entries with the same
+// predecessor.
+type Archive struct {
+	dir string
+	mu  sync.Mutex
+	now func() time.Time
+
+	LockTimeout time.Duration // How long to wait for another process; default 10s
+	StaleAfter  time.Duration // Age after which a lock file is considered abandoned where flock is unavailable; default 1m
+
+	NumberSeries map[string]numbering.Pattern // Patterns of the numbers Verify checks for gaps, by series name
+}
+
+// New opens the archive in dir. The directory is created on first use.
+func New(dir string) *Archive {
+	return &Archive{dir: dir, now: time.Now, LockTimeout: 10 * time.Second, StaleAfter: time.Minute}
+}
+
+// lock takes the process and file lock of the log. The caller must call the returned
+// function to release both.
+func (a *Archive) lock() (func(), error) {
+	a.mu.Lock()
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+	unlock, err := filelock.Lock(filepath.Join(a.dir, chainFile+".lock"), a.LockTimeout, a.StaleAfter)
+	if err != nil {
+		a.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		a.mu.Unlock()
+	}, nil
+}
+
+// Store archives the files of an invoice and appends them to the log.
+func (a *Archive) Store(number string, files []File) (*Entry, error) {
+	unlock, err := a.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := a.readLog()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Number == number {
+			return nil, fmt.Errorf("%w: %s", ErrAlreadyArchived, number)
+		}
+	}
+
+	docDir := a.documentDir(number)
+	if _, err := os.Stat(docDir); err == nil {
+		return nil, fmt.Errorf("%w: %s exists without log entry", ErrAlreadyArchived, docDir)
+	}
+	if err := os.MkdirAll(docDir, 0755); err != nil {
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+	// Documents without a log entry would block the number, so remove them on failure
+	logged := false
+	defer func() {
+		if !logged {
+			os.RemoveAll(docDir)
+		}
+	}()
+	now := a.now().UTC()
+	entry := Entry{Seq: len(entries) + 1, Number: number, ArchivedAt: now, RetainUntil: retainUntil(now)}
+	if len(entries) > 0 {
+		entry.PrevHash = entries[len(entries)-1].Hash
+	}
+	for _, f := range files {
+		if err := writeOnce(filepath.Join(docDir, f.Name), f.Data); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(f.Data)
+		entry.Files = append(entry.Files, FileHash{Name: f.Name, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(f.Data))})
+	}
+	entry.Hash = entry.computeHash()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	log, err := os.OpenFile(filepath.Join(a.dir, chainFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open archive log: %w", err)
+	}
+	defer log.Close()
+	if _, err := log.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("append archive log: %w", err)
+	}
+	logged = true
+	if err := log.Sync(); err != nil {
+		return nil, fmt.Errorf("sync archive log: %w", err)
+	}
+	return &entry, nil
+}
+
+// Entries returns the log entries in order.
+func (a *Archive) Entries() ([]Entry, error) {
+	unlock, err := a.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return a.readLog()
+}
+
+// Open returns the content of an archived file.
+func (a *Archive) Open(number, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(a.documentDir(number), name))
+}
+
+func (a *Archive) readLog() ([]Entry, error) {
+	f, err := os.Open(filepath.Join(a.dir, chainFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open archive log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("archive log line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// documentDir maps an invoice number to its directory, replacing characters that are
+// not safe in file names.
+func (a *Archive) documentDir(number string) string {
+	name := strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+			return c
+		}
+		return '_'
+	}, number)
+	return filepath.Join(a.dir, "documents", name)
+}
+
+// writeOnce creates a read-only file and fails if it already exists.
+func writeOnce(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0444)
+	if err != nil {
+		return fmt.Errorf("archive %s: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("archive %s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("archive %s: %w", path, err)
+	}
+	return f.Close()
+}
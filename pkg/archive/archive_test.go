@@ -0,0 +1,150 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/internal/filelock"
+	"invoiceformats/pkg/numbering"
+)
+
+func store(t *testing.T, a *Archive, numbers ...string) {
+	t.Helper()
+	for _, n := range numbers {
+		_, err := a.Store(n, []File{{Name: "invoice.pdf", Data: []byte("%PDF " + n)}, {Name: "data.json", Data: []byte(`{"number":"` + n + `"}`)}})
+		require.NoError(t, err)
+	}
+}
+
+func kinds(r *Report) []ProblemKind {
+	var result []ProblemKind
+	for _, p := range r.Problems {
+		result = append(result, p.Kind)
+	}
+	return result
+}
+
+func TestArchive_StoreAndVerify(t *testing.T) {
+	a := New(t.TempDir())
+	a.now = func() time.Time { return time.Date(2025, 7, 14, 10, 0, 0, 0, time.UTC) }
+	store(t, a, "RE-2025-0001", "RE-2025-0002")
+
+	entries, err := a.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+	assert.Equal(t, time.Date(2035, 12, 31, 0, 0, 0, 0, time.UTC), entries[0].RetainUntil)
+
+	data, err := a.Open("RE-2025-0002", "invoice.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF RE-2025-0002", string(data))
+
+	_, err = a.Store("RE-2025-0001", nil)
+	assert.True(t, errors.Is(err, ErrAlreadyArchived))
+
+	report, err := a.Verify("")
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%v", report.Problems)
+	assert.Equal(t, entries[1].Hash, report.Head)
+
+	report, err = a.Verify(entries[0].Hash)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+}
+
+func TestArchive_DetectsModifiedAndDeletedFiles(t *testing.T) {
+	a := New(t.TempDir())
+	store(t, a, "RE-1", "RE-2")
+
+	path := filepath.Join(a.documentDir("RE-1"), "invoice.pdf")
+	require.NoError(t, os.Chmod(path, 0644))
+	require.NoError(t, os.WriteFile(path, []byte("%PDF forged"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(a.documentDir("RE-2"), "data.json")))
+
+	report, err := a.Verify("")
+	require.NoError(t, err)
+	assert.Equal(t, []ProblemKind{ProblemModifiedFile, ProblemMissingFile}, kinds(report))
+}
+
+func TestArchive_DetectsLogTampering(t *testing.T) {
+	a := New(t.TempDir())
+	a.NumberSeries = map[string]numbering.Pattern{numbering.DefaultSeries: "RE-{SEQ}"}
+	store(t, a, "RE-1", "RE-2", "RE-3")
+	before, err := a.Verify("")
+	require.NoError(t, err)
+
+	logPath := filepath.Join(a.dir, chainFile)
+	raw, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+
+	// Removing an entry with its documents breaks the chain and leaves a number gap
+	require.NoError(t, os.WriteFile(logPath, []byte(lines[0]+"\n"+lines[2]+"\n"), 0644))
+	require.NoError(t, os.RemoveAll(a.documentDir("RE-2")))
+	report, err := a.Verify("")
+	require.NoError(t, err)
+	assert.Contains(t, kinds(report), ProblemBrokenChain)
+	assert.Contains(t, kinds(report), ProblemSequence)
+	assert.Contains(t, kinds(report), ProblemNumberGap)
+
+	// Rewriting an entry is detected by its own hash
+	var e Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &e))
+	e.Files[0].SHA256 = strings.Repeat("0", 64)
+	forged, _ := json.Marshal(e)
+	require.NoError(t, os.WriteFile(logPath, []byte(lines[0]+"\n"+string(forged)+"\n"+lines[2]+"\n"), 0644))
+	report, err = a.Verify("")
+	require.NoError(t, err)
+	assert.Contains(t, report.Problems, Problem{Kind: ProblemBrokenChain, Number: "RE-2", Seq: 2, Detail: "entry was modified"})
+
+	// Truncation leaves a consistent chain and is only visible against a known head
+	require.NoError(t, os.WriteFile(logPath, []byte(lines[0]+"\n"+lines[1]+"\n"), 0644))
+	report, err = a.Verify("")
+	require.NoError(t, err)
+	assert.NotContains(t, kinds(report), ProblemBrokenChain)
+	report, err = a.Verify(before.Head)
+	require.NoError(t, err)
+	assert.Contains(t, kinds(report), ProblemBrokenChain)
+}
+
+func TestNumberGaps(t *testing.T) {
+	series := map[string]numbering.Pattern{numbering.DefaultSeries: "RE-{YYYY}-{SEQ:4}", "credit_note": "GS-{YYYY}-{SEQ:4}"}
+	gaps := NumberGaps([]string{"RE-2025-0001", "RE-2025-0004", "RE-2025-0002", "GS-2025-0001", "RE-2025-0001-CN", "RE-2026-0001"}, series)
+	assert.Equal(t, []string{"RE-2025-0003"}, gaps)
+}
+
+func TestNumberGaps_PatternWithoutSeparator(t *testing.T) {
+	// Year and sequence run together, so only the pattern tells them apart
+	series := map[string]numbering.Pattern{numbering.DefaultSeries: "RE{YYYY}{SEQ:4}"}
+	gaps := NumberGaps([]string{"RE20240001", "RE20240002", "RE20250001", "RE20250003", "RE2025-0001"}, series)
+	assert.Equal(t, []string{"RE20250002"}, gaps)
+	assert.Empty(t, NumberGaps([]string{"RE20250001", "RE20250003"}, nil))
+}
+
+func TestArchive_StoreWaitsForOtherProcess(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	unlock, err := filelock.Lock(filepath.Join(dir, chainFile+".lock"), time.Second, time.Minute)
+	require.NoError(t, err)
+
+	a := New(dir)
+	a.LockTimeout = 50 * time.Millisecond
+	_, err = a.Store("RE-2025-0001", []File{{Name: "invoice.pdf", Data: []byte("%PDF")}})
+	assert.ErrorContains(t, err, "timed out")
+	_, err = os.Stat(a.documentDir("RE-2025-0001"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	unlock()
+	store(t, a, "RE-2025-0001")
+	entries, err := a.Entries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"invoiceformats/pkg/numbering"
+)
+
+// ProblemKind classifies verification findings.
+type ProblemKind string
+
+const (
+	ProblemBrokenChain  ProblemKind = "broken_chain"  // Entry hash or link to the predecessor does not match
+	ProblemSequence     ProblemKind = "sequence"      // Log entries missing or reordered
+	ProblemMissingFile  ProblemKind = "missing_file"  // Archived file deleted
+	ProblemModifiedFile ProblemKind = "modified_file" // Archived file changed
+	ProblemUnlogged     ProblemKind = "unlogged"      // Document directory without log entry
+	ProblemNumberGap    ProblemKind = "number_gap"    // Invoice number missing from the sequence
+	ProblemNotArchived  ProblemKind = "not_archived"  // Issued invoice missing from the archive
+)
+
+// Problem is a verification finding.
+type Problem struct {
+	Kind   ProblemKind `json:"kind"`
+	Number string      `json:"number,omitempty"`
+	Seq    int         `json:"seq,omitempty"`
+	Detail string      `json:"detail"`
+}
+
+func (p Problem) String() string {
+	if p.Number != "" {
+		return fmt.Sprintf("%s: %s: %s", p.Kind, p.Number, p.Detail)
+	}
+	return fmt.Sprintf("%s: %s", p.Kind, p.Detail)
+}
+
+// Report is the result of Verify.
+type Report struct {
+	Entries  int       `json:"entries"`
+	Head     string    `json:"head"` // Hash of the last entry; keep a copy elsewhere to detect truncation
+	Problems []Problem `json:"problems"`
+}
+
+// OK reports whether no problems were found.
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify checks the log chain, every archived file and the number sequences of
+// NumberSeries.
+// knownHead is an optional head hash from an earlier report kept outside the archive;
+// it reveals entries removed from the end of the log, which the chain alone cannot.
+func (a *Archive) Verify(knownHead string) (*Report, error) {
+	unlock, err := a.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := a.readLog()
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Entries: len(entries)}
+	prev := ""
+	headFound := knownHead == ""
+	logged := make(map[string]bool)
+	numbers := make([]string, 0, len(entries))
+	for i, e := range entries {
+		if e.Seq != i+1 {
+			report.add(Problem{Kind: ProblemSequence, Number: e.Number, Seq: e.Seq, Detail: fmt.Sprintf("entry %d has sequence number %d", i+1, e.Seq)})
+		}
+		if e.PrevHash != prev {
+			report.add(Problem{Kind: ProblemBrokenChain, Number: e.Number, Seq: e.Seq, Detail: "link to the previous entry does not match"})
+		}
+		if e.computeHash() != e.Hash {
+			report.add(Problem{Kind: ProblemBrokenChain, Number: e.Number, Seq: e.Seq, Detail: "entry was modified"})
+		}
+		prev = e.Hash
+		if e.Hash == knownHead {
+			headFound = true
+		}
+
+		dir := a.documentDir(e.Number)
+		logged[filepath.Base(dir)] = true
+		numbers = append(numbers, e.Number)
+		for _, f := range e.Files {
+			data, err := os.ReadFile(filepath.Join(dir, f.Name))
+			if err != nil {
+				report.add(Problem{Kind: ProblemMissingFile, Number: e.Number, Seq: e.Seq, Detail: f.Name})
+				continue
+			}
+			sum := sha256.Sum256(data)
+			if hex.EncodeToString(sum[:]) != f.SHA256 || int64(len(data)) != f.Size {
+				report.add(Problem{Kind: ProblemModifiedFile, Number: e.Number, Seq: e.Seq, Detail: f.Name})
+			}
+		}
+	}
+	report.Head = prev
+	if !headFound {
+		report.add(Problem{Kind: ProblemBrokenChain, Detail: "known head " + knownHead + " not found, the log was truncated or rewritten"})
+	}
+
+	docs, err := os.ReadDir(filepath.Join(a.dir, "documents"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read archive documents: %w", err)
+	}
+	for _, d := range docs {
+		if !logged[d.Name()] {
+			report.add(Problem{Kind: ProblemUnlogged, Detail: "documents/" + d.Name()})
+		}
+	}
+
+	for _, gap := range NumberGaps(numbers, a.NumberSeries) {
+		report.add(Problem{Kind: ProblemNumberGap, Number: gap, Detail: "number missing from the archive"})
+	}
+	return report, nil
+}
+
+func (r *Report) add(p Problem) {
+	r.Problems = append(r.Problems, p)
+}
+
+// NumberGaps returns the numbers missing between the lowest and highest number of each
+// sequence, e.g. RE-2025-0003 for RE-2025-0002 and RE-2025-0004. Sequences are told
+// apart by the patterns of series, so RE20250003 of "RE{YYYY}{SEQ:4}" is number 3 of
+// 2025. Numbers matching none of the patterns are ignored.
+func NumberGaps(numbers []string, series map[string]numbering.Pattern) []string {
+	type sequence struct {
+		series string
+		seen   map[int]bool
+		min    int
+		max    int
+	}
+	names := make([]string, 0, len(series))
+	for name := range series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sequences := make(map[numbering.Pattern]*sequence)
+	for _, n := range numbers {
+		for _, name := range names {
+			key, v, ok := series[name].Match(name, n)
+			if !ok {
+				continue
+			}
+			s := sequences[key]
+			if s == nil {
+				s = &sequence{series: name, seen: make(map[int]bool), min: v, max: v}
+				sequences[key] = s
+			}
+			s.seen[v] = true
+			s.min = min(s.min, v)
+			s.max = max(s.max, v)
+			break
+		}
+	}
+
+	var gaps []string
+	for key, s := range sequences {
+		for v := s.min + 1; v < s.max; v++ {
+			if !s.seen[v] {
+				gaps = append(gaps, key.Format(s.series, time.Time{}, v))
+			}
+		}
+	}
+	sort.Strings(gaps)
+	return gaps
+}
@@ -0,0 +1,69 @@
+// Package bank reads bank statements for payment reconciliation.
+//
+// Supported formats are ISO 20022 CAMT.053 (XML) and SWIFT MT940. Both are read into
+// a flat list of transactions; batch bookings are split into their single transactions.
+package bank
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Transaction is a booked statement line.
+type Transaction struct {
+	ID             string          `json:"id"` // Bank reference, used to recognize transactions imported before
+	Date           time.Time       `json:"date"`
+	Amount         decimal.Decimal `json:"amount"` // Always positive; see Credit
+	Currency       string          `json:"currency"`
+	Credit         bool            `json:"credit"` // Incoming payment
+	RemittanceInfo string          `json:"remittance_info"`
+	Name           string          `json:"name"`    // Debtor for credits, creditor for debits
+	IBAN           string          `json:"iban"`    // Counterparty account
+	Account        string          `json:"account"` // Statement account
+}
+
+// Parse reads a CAMT.053 or MT940 statement, detected from its content.
+func Parse(r io.Reader) ([]Transaction, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return ParseCAMT053(bytes.NewReader(data))
+	}
+	return ParseMT940(bytes.NewReader(data))
+}
+
+// LoadFile reads a statement file.
+func LoadFile(path string) ([]Transaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open statement %s: %w", path, err)
+	}
+	defer f.Close()
+	txs, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse statement %s: %w", path, err)
+	}
+	return txs, nil
+}
+
+// NormalizeIBAN removes spaces and upper-cases an IBAN for comparison.
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+// fallbackID derives a stable ID for transactions without a bank reference.
+func fallbackID(tx Transaction) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%t|%s|%s|%s",
+		tx.Account, tx.Date.Format("2006-01-02"), tx.Amount.String(), tx.Credit, tx.Currency, tx.IBAN, tx.RemittanceInfo)))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,140 @@
+package bank
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const camtSample = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Acct><Id><IBAN>DE89 3704 0044 0532 0130 00</IBAN></Id></Acct>
+      <Ntry>
+        <Amt Ccy="EUR">1190.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-07-14</Dt></BookgDt>
+        <AcctSvcrRef>2025071400001</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+          <RltdPties>
+            <Dbtr><Pty><Nm>Pixel Dynamics GmbH</Nm></Pty></Dbtr>
+            <DbtrAcct><Id><IBAN>DE02120300000000202051</IBAN></Id></DbtrAcct>
+          </RltdPties>
+          <RmtInf><Ustrd>Rechnung RE-2025-0042</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">300.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-07-15</Dt></BookgDt>
+        <AcctSvcrRef>2025071500007</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <Amt Ccy="EUR">100.00</Amt>
+            <RmtInf><Ustrd>RE-2025-0043</Ustrd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <Refs><EndToEndId>E2E-778</EndToEndId></Refs>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">200.00</Amt></TxAmt></AmtDtls>
+            <RmtInf><Strd><CdtrRefInf><Ref>RF18539007547034</Ref></CdtrRefInf></Strd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">50.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2025-07-16</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">19.99</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-07-16</Dt></BookgDt>
+        <NtryDtls><TxDtls>
+          <RltdPties><Cdtr><Nm>Hosting AG</Nm></Cdtr></RltdPties>
+        </TxDtls></NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+const mt940Sample = `:20:STARTUMSE
+:25:37040044/0532013000
+:28C:00001/001
+:60F:C250713EUR1000,00
+:61:2507140714CR595,00NTRFNONREF//BANKREF1
+:86:166?00GUTSCHRIFT?20Rechnung RE 2025 0042 ?21Teilzahlung?31DE021203000000
+00202051?32Pixel Dynamics GmbH
+:61:250715D19,99NDDTNONREF
+:86:Lastschrift Hosting AG
+:62F:C250715EUR1575,01
+-`
+
+func TestParseCAMT053(t *testing.T) {
+	txs, err := Parse(strings.NewReader(camtSample))
+	require.NoError(t, err)
+	// The pending entry is skipped and the batch is split
+	require.Len(t, txs, 4)
+
+	tx := txs[0]
+	assert.Equal(t, "2025071400001", tx.ID)
+	assert.True(t, tx.Credit)
+	assert.True(t, decimal.NewFromInt(1190).Equal(tx.Amount))
+	assert.Equal(t, "EUR", tx.Currency)
+	assert.Equal(t, "2025-07-14", tx.Date.Format("2006-01-02"))
+	assert.Equal(t, "Pixel Dynamics GmbH", tx.Name)
+	assert.Equal(t, "DE02120300000000202051", tx.IBAN)
+	assert.Equal(t, "DE89370400440532013000", tx.Account)
+	assert.Equal(t, "Rechnung RE-2025-0042", tx.RemittanceInfo)
+
+	assert.Equal(t, "2025071500007/1", txs[1].ID)
+	assert.True(t, decimal.NewFromInt(100).Equal(txs[1].Amount))
+	assert.Equal(t, "E2E-778", txs[2].ID)
+	assert.True(t, decimal.NewFromInt(200).Equal(txs[2].Amount))
+	assert.Equal(t, "RF18539007547034", txs[2].RemittanceInfo)
+
+	debit := txs[3]
+	assert.False(t, debit.Credit)
+	assert.Equal(t, "Hosting AG", debit.Name)
+	assert.True(t, strings.HasPrefix(debit.ID, "sha256:"))
+
+	// IDs are stable across imports
+	again, err := Parse(strings.NewReader(camtSample))
+	require.NoError(t, err)
+	assert.Equal(t, debit.ID, again[3].ID)
+}
+
+func TestParseMT940(t *testing.T) {
+	txs, err := Parse(strings.NewReader(mt940Sample))
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+
+	tx := txs[0]
+	assert.Equal(t, "BANKREF1", tx.ID)
+	assert.True(t, tx.Credit)
+	assert.True(t, decimal.NewFromInt(595).Equal(tx.Amount))
+	assert.Equal(t, "EUR", tx.Currency)
+	assert.Equal(t, "2025-07-14", tx.Date.Format("2006-01-02"))
+	assert.Equal(t, "Rechnung RE 2025 0042 Teilzahlung", tx.RemittanceInfo)
+	assert.Equal(t, "DE02120300000000202051", tx.IBAN)
+	assert.Equal(t, "Pixel Dynamics GmbH", tx.Name)
+	assert.Equal(t, "0532013000", tx.Account)
+
+	debit := txs[1]
+	assert.False(t, debit.Credit)
+	assert.Equal(t, "Lastschrift Hosting AG", debit.RemittanceInfo)
+	assert.True(t, strings.HasPrefix(debit.ID, "sha256:"))
+}
+
+func TestParseMT940_InvalidLine(t *testing.T) {
+	_, err := ParseMT940(strings.NewReader(":20:X\n:61:garbage\n-"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package bank
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// camtDocument covers the parts of camt.053.001.02 to .08 needed for reconciliation.
+// Elements are matched by local name, so all schema versions are read alike.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	IBAN    string      `xml:"Acct>Id>IBAN"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtEntry struct {
+	Amount      camtAmount   `xml:"Amt"`
+	CdtDbtInd   string       `xml:"CdtDbtInd"`
+	Status      camtStatus   `xml:"Sts"`
+	BookingDate camtDate     `xml:"BookgDt"`
+	ValueDate   camtDate     `xml:"ValDt"`
+	Reference   string       `xml:"AcctSvcrRef"`
+	Details     []camtTxDtls `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTxDtls struct {
+	Reference    string      `xml:"Refs>AcctSvcrRef"`
+	EndToEndID   string      `xml:"Refs>EndToEndId"`
+	Amount       *camtAmount `xml:"Amt"`
+	TxAmount     *camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	DebtorName   string      `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPty    string      `xml:"RltdPties>Dbtr>Pty>Nm"`
+	DebtorIBAN   string      `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+	CreditorName string      `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty  string      `xml:"RltdPties>Cdtr>Pty>Nm"`
+	CreditorIBAN string      `xml:"RltdPties>CdtrAcct>Id>IBAN"`
+	Unstructured []string    `xml:"RmtInf>Ustrd"`
+	CreditorRefs []string    `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+}
+
+// camtStatus is plain text up to version .07 and a code element from .08 on.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) time() (time.Time, bool) {
+	if d.Date != "" {
+		t, err := time.Parse("2006-01-02", d.Date)
+		return t, err == nil
+	}
+	if d.DateTime != "" {
+		if t, err := time.Parse(time.RFC3339, d.DateTime); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse("2006-01-02T15:04:05", d.DateTime); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseCAMT053 reads booked entries from an ISO 20022 bank-to-customer statement.
+// Pending entries are skipped.
+func ParseCAMT053(r io.Reader) ([]Transaction, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode CAMT.053: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, fmt.Errorf("no CAMT.053 statement found")
+	}
+
+	var txs []Transaction
+	for _, stmt := range doc.Statements {
+		for i, e := range stmt.Entries {
+			status := firstOf(e.Status.Code, e.Status.Text)
+			if status != "" && status != "BOOK" {
+				continue
+			}
+			date, ok := e.BookingDate.time()
+			if !ok {
+				if date, ok = e.ValueDate.time(); !ok {
+					return nil, fmt.Errorf("entry %d: missing booking date", i+1)
+				}
+			}
+			base := Transaction{
+				Date:     date,
+				Currency: e.Amount.Currency,
+				Credit:   e.CdtDbtInd == "CRDT",
+				Account:  NormalizeIBAN(stmt.IBAN),
+			}
+			details := e.Details
+			if len(details) == 0 {
+				details = []camtTxDtls{{}}
+			}
+			for j, d := range details {
+				tx := base
+				amount := e.Amount
+				if len(details) > 1 {
+					switch {
+					case d.Amount != nil:
+						amount = *d.Amount
+					case d.TxAmount != nil:
+						amount = *d.TxAmount
+					default:
+						return nil, fmt.Errorf("entry %d: batch transaction %d without amount", i+1, j+1)
+					}
+				}
+				value, err := decimal.NewFromString(strings.TrimSpace(amount.Value))
+				if err != nil {
+					return nil, fmt.Errorf("entry %d: invalid amount %q", i+1, amount.Value)
+				}
+				tx.Amount = value.Abs()
+				if amount.Currency != "" {
+					tx.Currency = amount.Currency
+				}
+				tx.RemittanceInfo = strings.TrimSpace(strings.Join(append(d.Unstructured, d.CreditorRefs...), " "))
+				if tx.Credit {
+					tx.Name, tx.IBAN = firstOf(d.DebtorName, d.DebtorPty), d.DebtorIBAN
+				} else {
+					tx.Name, tx.IBAN = firstOf(d.CreditorName, d.CreditorPty), d.CreditorIBAN
+				}
+				tx.IBAN = NormalizeIBAN(tx.IBAN)
+
+				tx.ID = firstOf(d.Reference, endToEnd(d.EndToEndID))
+				if tx.ID == "" && e.Reference != "" {
+					tx.ID = e.Reference
+					if len(details) > 1 {
+						tx.ID = fmt.Sprintf("%s/%d", e.Reference, j+1)
+					}
+				}
+				if tx.ID == "" {
+					tx.ID = fallbackID(tx)
+				}
+				txs = append(txs, tx)
+			}
+		}
+	}
+	return txs, nil
+}
+
+// endToEnd ignores the placeholder banks use for missing end-to-end references.
+func endToEnd(id string) string {
+	if id == "NOTPROVIDED" {
+		return ""
+	}
+	return id
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
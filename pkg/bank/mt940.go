@@ -0,0 +1,151 @@
+package bank
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// mt940Line parses a :61: statement line: value date, optional entry date, debit/credit
+// mark, optional funds code, amount, transaction type, customer and bank reference.
+var mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([NFS][A-Z0-9]{3})([^/]*)(?://(.*))?$`)
+
+// mt940Balance extracts the currency from an opening balance (:60F:/:60M:).
+var mt940Balance = regexp.MustCompile(`^[CD]\d{6}([A-Z]{3})`)
+
+// ParseMT940 reads the statement lines of a SWIFT MT940 file. Structured :86: fields
+// as used by German banks (?20–?29 remittance, ?31 IBAN, ?32/?33 name) are recognized;
+// other :86: content is taken as remittance information.
+func ParseMT940(r io.Reader) ([]Transaction, error) {
+	var (
+		txs      []Transaction
+		account  string
+		currency string
+		tag      string
+		content  []string
+	)
+
+	flush := func() error {
+		value := strings.Join(content, "\n")
+		switch tag {
+		case "25":
+			// Either an IBAN or bank code/account number
+			parts := strings.Split(value, "/")
+			account = NormalizeIBAN(parts[len(parts)-1])
+		case "60F", "60M":
+			if m := mt940Balance.FindStringSubmatch(value); m != nil {
+				currency = m[1]
+			}
+		case "61":
+			tx, err := parseMT940Line(content[0])
+			if err != nil {
+				return err
+			}
+			tx.Currency = currency
+			tx.Account = account
+			txs = append(txs, tx)
+		case "86":
+			if len(txs) == 0 {
+				return fmt.Errorf(":86: without preceding :61:")
+			}
+			tx := &txs[len(txs)-1]
+			parseMT940Details(tx, content)
+		}
+		tag, content = "", nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, ":") {
+			if end := strings.Index(line[1:], ":"); end > 0 {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+				tag = line[1 : end+1]
+				content = []string{line[end+2:]}
+				continue
+			}
+		}
+		if line == "-" || strings.HasPrefix(line, "{") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if tag != "" {
+			content = append(content, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	for i := range txs {
+		if txs[i].ID == "" {
+			txs[i].ID = fallbackID(txs[i])
+		}
+	}
+	return txs, nil
+}
+
+func parseMT940Line(line string) (Transaction, error) {
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Transaction{}, fmt.Errorf("invalid :61: line %q", line)
+	}
+	date, err := time.Parse("060102", m[1])
+	if err != nil {
+		return Transaction{}, fmt.Errorf("invalid :61: date %q", m[1])
+	}
+	amount, err := decimal.NewFromString(strings.Replace(m[5], ",", ".", 1))
+	if err != nil {
+		return Transaction{}, fmt.Errorf("invalid :61: amount %q", m[5])
+	}
+	tx := Transaction{
+		Date:   date,
+		Amount: amount,
+		// A reversed debit returns money to the account
+		Credit: m[3] == "C" || m[3] == "RD",
+	}
+	if ref := strings.TrimSpace(m[8]); ref != "" {
+		tx.ID = ref
+	} else if ref := strings.TrimSpace(m[7]); ref != "" && ref != "NONREF" {
+		tx.ID = ref
+	}
+	return tx, nil
+}
+
+func parseMT940Details(tx *Transaction, lines []string) {
+	joined := strings.Join(lines, "")
+	if len(joined) < 4 || joined[3] != '?' {
+		tx.RemittanceInfo = strings.TrimSpace(strings.Join(lines, " "))
+		return
+	}
+	var remittance, name []string
+	for _, field := range strings.Split(joined[4:], "?") {
+		if len(field) < 2 {
+			continue
+		}
+		code, value := field[:2], field[2:]
+		switch {
+		case code >= "20" && code <= "29", code >= "60" && code <= "63":
+			remittance = append(remittance, value)
+		case code == "31":
+			tx.IBAN = NormalizeIBAN(value)
+		case code == "32", code == "33":
+			name = append(name, value)
+		}
+	}
+	tx.RemittanceInfo = strings.TrimSpace(strings.Join(remittance, ""))
+	tx.Name = strings.TrimSpace(strings.Join(name, ""))
+}
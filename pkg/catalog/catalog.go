@@ -0,0 +1,174 @@
+// Package catalog provides the product and service catalog that invoice lines
+// reference by SKU instead of repeating descriptions, prices and tax details.
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/validation"
+)
+
+// ErrUnknownItem is returned for lines that reference a SKU not in the catalog.
+var ErrUnknownItem = errors.New("unknown catalog item")
+
+// Item is a product or service that invoice lines can reference by SKU.
+type Item struct {
+	SKU            string             `json:"sku" yaml:"sku" validate:"required"`
+	Description    string             `json:"description" yaml:"description" validate:"required"`
+	Descriptions   map[string]string  `json:"descriptions" yaml:"descriptions"`                                           // Translations by invoice language
+	Unit           string             `json:"unit" yaml:"unit" validate:"omitempty,alphanum,max=3"`                       // BT-130: UN/ECE Rec. 20 unit code
+	Price          decimal.Decimal    `json:"price" yaml:"price"`                                                         // Default unit price in the invoice currency
+	TaxRate        decimal.Decimal    `json:"tax_rate" yaml:"tax_rate"`                                                   // Explicit rate; decided by the tax rules if zero
+	TaxCategory    models.TaxCategory `json:"tax_category" yaml:"tax_category" validate:"omitempty,oneof=S Z E AE K G O"` // BT-151
+	TaxClass       models.TaxClass    `json:"tax_class" yaml:"tax_class" validate:"omitempty,oneof=standard reduced second_reduced zero exempt"`
+	ProductType    models.ProductType `json:"product_type" yaml:"product_type" validate:"omitempty,oneof=goods services digital"`
+	SellerID       string             `json:"seller_id" yaml:"seller_id"`             // BT-155; defaults to the SKU
+	BuyerID        string             `json:"buyer_id" yaml:"buyer_id"`               // BT-156
+	StandardID     string             `json:"standard_id" yaml:"standard_id"`         // BT-157, e.g. a GTIN
+	StandardScheme string             `json:"standard_scheme" yaml:"standard_scheme"` // BT-157-1, e.g. 0160 for GTIN
+}
+
+// DescriptionIn returns the description in the given language, falling back from a
+// regional language such as de-AT to de and then to the default description.
+func (it Item) DescriptionIn(lang string) string {
+	if d, ok := it.Descriptions[lang]; ok && d != "" {
+		return d
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		if d, ok := it.Descriptions[base]; ok && d != "" {
+			return d
+		}
+	}
+	return it.Description
+}
+
+// Catalog holds the catalog items, keyed by SKU.
+type Catalog struct {
+	items map[string]Item
+	skus  []string // In file order
+}
+
+// Load reads and validates a catalog file. A missing file, or an empty path, is an
+// empty catalog.
+func Load(path string) (*Catalog, error) {
+	c := &Catalog{items: make(map[string]Item)}
+	if path == "" {
+		return c, nil
+	}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Items []Item `json:"items" yaml:"items"`
+	}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(raw, &file)
+	} else {
+		err = yaml.Unmarshal(raw, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	v := validation.NewValidator()
+	for i, it := range file.Items {
+		if err := v.ValidateStruct(&it); err != nil {
+			return nil, fmt.Errorf("%s: item %d (%s): %w", path, i+1, it.SKU, err)
+		}
+		if it.Price.IsNegative() {
+			return nil, fmt.Errorf("%s: item %s: negative price", path, it.SKU)
+		}
+		if it.TaxCategory == models.TaxCategoryStandard && it.TaxRate.IsZero() {
+			return nil, fmt.Errorf("%s: item %s: tax category S needs a tax_rate", path, it.SKU)
+		}
+		if _, ok := c.items[it.SKU]; ok {
+			return nil, fmt.Errorf("%s: duplicate SKU %q", path, it.SKU)
+		}
+		if it.SellerID == "" {
+			it.SellerID = it.SKU
+		}
+		c.items[it.SKU] = it
+		c.skus = append(c.skus, it.SKU)
+	}
+	return c, nil
+}
+
+// Item returns the item with the given SKU.
+func (c *Catalog) Item(sku string) (Item, bool) {
+	if c == nil {
+		return Item{}, false
+	}
+	it, ok := c.items[sku]
+	return it, ok
+}
+
+// Items returns all items in file order.
+func (c *Catalog) Items() []Item {
+	if c == nil {
+		return nil
+	}
+	items := make([]Item, len(c.skus))
+	for i, sku := range c.skus {
+		items[i] = c.items[sku]
+	}
+	return items
+}
+
+// Apply fills in the lines that reference a SKU from their catalog item. Values the
+// line states itself are kept, so a line may override the price or description; the
+// description is taken in the invoice language. A nil catalog has no items.
+func (c *Catalog) Apply(inv *models.InvoiceDetails) error {
+	for i := range inv.Lines {
+		line := &inv.Lines[i]
+		if line.SKU == "" {
+			continue
+		}
+		it, ok := c.Item(line.SKU)
+		if !ok {
+			return fmt.Errorf("line %d: %w %q", i+1, ErrUnknownItem, line.SKU)
+		}
+		if line.Description == "" {
+			line.Description = it.DescriptionIn(inv.Language)
+		}
+		if line.UnitPrice.IsZero() {
+			line.UnitPrice = it.Price
+		}
+		if line.Unit == "" {
+			line.Unit = it.Unit
+		}
+		if line.TaxRate.IsZero() && line.TaxCategory == "" {
+			line.TaxRate = it.TaxRate
+			line.TaxCategory = it.TaxCategory
+		}
+		if line.TaxClass == "" {
+			line.TaxClass = it.TaxClass
+		}
+		if line.ProductType == "" {
+			line.ProductType = it.ProductType
+		}
+		if line.SellerItemID == "" {
+			line.SellerItemID = it.SellerID
+		}
+		if line.BuyerItemID == "" {
+			line.BuyerItemID = it.BuyerID
+		}
+		if line.StandardItemID == "" {
+			line.StandardItemID = it.StandardID
+			line.StandardItemScheme = it.StandardScheme
+		}
+	}
+	return nil
+}
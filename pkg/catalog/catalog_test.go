@@ -0,0 +1,113 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/pkg/models"
+)
+
+const catalogYAML = `items:
+  - sku: CONS-H
+    description: "Consulting"
+    descriptions:
+      de: "Beratung"
+    unit: HUR
+    price: 120.00
+    product_type: services
+  - sku: DOCK-1
+    description: "USB-C docking station"
+    unit: C62
+    price: 189.90
+    tax_rate: 19
+    tax_category: S
+    product_type: goods
+    buyer_id: "MAT-4711"
+    standard_id: "4012345678901"
+    standard_scheme: "0160"
+`
+
+func writeCatalog(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	c, err := Load(writeCatalog(t, catalogYAML))
+	require.NoError(t, err)
+	items := c.Items()
+	require.Len(t, items, 2)
+	assert.Equal(t, "CONS-H", items[0].SKU)
+	assert.Equal(t, "CONS-H", items[0].SellerID, "the seller ID defaults to the SKU")
+
+	it, ok := c.Item("DOCK-1")
+	require.True(t, ok)
+	assert.Equal(t, "189.9", it.Price.String())
+	_, ok = c.Item("NOPE")
+	assert.False(t, ok)
+
+	empty, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, empty.Items())
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"no sku":     "items:\n  - description: \"X\"\n",
+		"no desc":    "items:\n  - sku: A\n",
+		"duplicate":  "items:\n  - sku: A\n    description: \"X\"\n  - sku: A\n    description: \"Y\"\n",
+		"negative":   "items:\n  - sku: A\n    description: \"X\"\n    price: -1\n",
+		"category":   "items:\n  - sku: A\n    description: \"X\"\n    tax_category: Q\n",
+		"no rate":    "items:\n  - sku: A\n    description: \"X\"\n    tax_category: S\n",
+		"unit":       "items:\n  - sku: A\n    description: \"X\"\n    unit: HOURS\n",
+		"not a list": "items: 5\n",
+	} {
+		_, err := Load(writeCatalog(t, content))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestApply(t *testing.T) {
+	c, err := Load(writeCatalog(t, catalogYAML))
+	require.NoError(t, err)
+
+	inv := models.InvoiceDetails{
+		Language: "de-AT",
+		Lines: []models.InvoiceLine{
+			{SKU: "CONS-H", Quantity: decimal.NewFromInt(8)},
+			{SKU: "DOCK-1", Quantity: decimal.NewFromInt(2), Description: "Docking station (demo unit)", UnitPrice: decimal.NewFromInt(150)},
+			{Description: "Travel expenses", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromInt(80)},
+		},
+	}
+	require.NoError(t, c.Apply(&inv))
+
+	cons := inv.Lines[0]
+	assert.Equal(t, "Beratung", cons.Description, "translated for the base language")
+	assert.Equal(t, "120", cons.UnitPrice.String())
+	assert.Equal(t, "HUR", cons.Unit)
+	assert.Equal(t, models.ProductServices, cons.ProductType)
+	assert.True(t, cons.TaxRate.IsZero(), "left to the tax rules")
+	assert.Equal(t, "CONS-H", cons.SellerItemID)
+
+	dock := inv.Lines[1]
+	assert.Equal(t, "Docking station (demo unit)", dock.Description, "line values win")
+	assert.Equal(t, "150", dock.UnitPrice.String())
+	assert.Equal(t, models.TaxCategoryStandard, dock.TaxCategory)
+	assert.Equal(t, "19", dock.TaxRate.String())
+	assert.Equal(t, "MAT-4711", dock.BuyerItemID)
+	assert.Equal(t, "4012345678901", dock.StandardItemID)
+	assert.Equal(t, "0160", dock.StandardItemScheme)
+
+	assert.Equal(t, "Travel expenses", inv.Lines[2].Description)
+	assert.Empty(t, inv.Lines[2].SellerItemID)
+
+	unknown := models.InvoiceDetails{Lines: []models.InvoiceLine{{SKU: "NOPE", Quantity: decimal.NewFromInt(1)}}}
+	assert.ErrorIs(t, c.Apply(&unknown), ErrUnknownItem)
+}
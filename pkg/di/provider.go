@@ -1,9 +1,12 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"invoiceformats/pkg/interfaces"
 	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/tax"
+	"invoiceformats/pkg/tracing"
 	"invoiceformats/providers/zugferd"
 	"os"
 )
@@ -14,8 +17,10 @@ type zugferdEmbeddedDataProvider struct {
 	builder interfaces.ZUGFeRDInvoiceXMLBuilder
 }
 
-func (p *zugferdEmbeddedDataProvider) Generate(data models.InvoiceData, opts any) (string, string, error) {
+func (p *zugferdEmbeddedDataProvider) Generate(ctx context.Context, data models.InvoiceData, opts any) (string, string, error) {
+	_, span := tracing.Start(ctx, "xml.build", tracing.InvoiceNumber.String(data.Invoice.Number))
 	xmlBytes, err := p.builder.BuildXML(data)
+	tracing.End(span, err)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to build ZUGFeRD XML: %w", err)
 	}
@@ -41,4 +46,9 @@ func ProvideZUGFeRDInvoiceXMLBuilder() interfaces.ZUGFeRDInvoiceXMLBuilder {
 	return zugferd.ZUGFeRDBasicXMLBuilder{}
 }
 
+// ProvideTaxEngine returns the tax engine with all built-in rule sets registered
+func ProvideTaxEngine() *tax.Engine {
+	return tax.DefaultEngine()
+}
+
 // TODO [context: DI, priority: high, effort: medium]: Add more providers for other interfaces and implementations as refactor progresses
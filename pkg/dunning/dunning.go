@@ -0,0 +1,203 @@
+// Package dunning finds overdue invoices and prepares reminders and dunning notices.
+//
+// Notices escalate through the levels of a Policy: each level becomes due a number of
+// days after the invoice due date and after the previous notice, may charge a fee and
+// may claim default interest. Interest follows § 288 BGB: the base rate (§ 247 BGB)
+// plus 9 percentage points for business clients and 5 for consumers, accrued daily
+// from the day after the due date. Business clients additionally owe a one-off lump
+// sum of EUR 40 (§ 288 (5) BGB), claimed with the first notice that charges interest.
+package dunning
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/repository"
+)
+
+var (
+	// BusinessMargin is added to the base rate for business clients (§ 288 (2) BGB).
+	BusinessMargin = decimal.NewFromInt(9)
+	// ConsumerMargin is added to the base rate for consumers (§ 288 (1) BGB).
+	ConsumerMargin = decimal.NewFromInt(5)
+	// LumpSum is the late payment compensation for business clients (§ 288 (5) BGB).
+	LumpSum = decimal.NewFromInt(40)
+)
+
+// Level is an escalation level.
+type Level struct {
+	Name              string          `json:"name"`                // Locale key of the notice title, e.g. "reminder"
+	DaysAfterDue      int             `json:"days_after_due"`      // Earliest day after the due date
+	DaysAfterPrevious int             `json:"days_after_previous"` // Earliest day after the previous notice
+	PaymentDays       int             `json:"payment_days"`        // New payment deadline after the notice date
+	Fee               decimal.Decimal `json:"fee"`
+	Interest          bool            `json:"interest"` // Claim default interest and, for businesses, the lump sum
+	Template          string          `json:"template"` // Embedded template name or file path
+}
+
+// Policy defines the escalation levels and the interest base rate.
+type Policy struct {
+	Levels   []Level
+	BaseRate decimal.Decimal // Base rate in percent (§ 247 BGB), published each 1 January and 1 July
+}
+
+// DefaultLevels are a reminder followed by two dunning notices.
+func DefaultLevels() []Level {
+	return []Level{
+		{Name: "reminder", DaysAfterDue: 7, PaymentDays: 7, Template: "reminder.html.tmpl"},
+		{Name: "dunning", DaysAfterDue: 21, DaysAfterPrevious: 14, PaymentDays: 10, Fee: decimal.NewFromInt(5), Interest: true, Template: "dunning.html.tmpl"},
+		{Name: "final_notice", DaysAfterDue: 35, DaysAfterPrevious: 14, PaymentDays: 7, Fee: decimal.NewFromInt(10), Interest: true, Template: "dunning.html.tmpl"},
+	}
+}
+
+// Validate checks that the policy has levels with sensible deadlines.
+func (p Policy) Validate() error {
+	if len(p.Levels) == 0 {
+		return fmt.Errorf("no dunning levels configured")
+	}
+	for i, l := range p.Levels {
+		if l.Name == "" {
+			return fmt.Errorf("dunning level %d has no name", i+1)
+		}
+		if l.DaysAfterDue < 0 || l.DaysAfterPrevious < 0 || l.PaymentDays < 0 {
+			return fmt.Errorf("dunning level %d (%s) has negative days", i+1, l.Name)
+		}
+		if l.Fee.IsNegative() {
+			return fmt.Errorf("dunning level %d (%s) has a negative fee", i+1, l.Name)
+		}
+	}
+	return nil
+}
+
+// Notice is a notice due for an invoice.
+type Notice struct {
+	Number       string              `json:"number"`
+	Level        int                 `json:"level"` // 1-based
+	Step         Level               `json:"step"`
+	Date         time.Time           `json:"date"`
+	PayBy        time.Time           `json:"pay_by"`
+	DueDate      time.Time           `json:"due_date"`
+	DaysOverdue  int                 `json:"days_overdue"`
+	Currency     string              `json:"currency"`
+	Outstanding  decimal.Decimal     `json:"outstanding"`
+	InterestRate decimal.Decimal     `json:"interest_rate"` // Percent per year; zero if the level claims no interest
+	Interest     decimal.Decimal     `json:"interest"`
+	Fee          decimal.Decimal     `json:"fee"`      // Fee of this notice
+	Fees         decimal.Decimal     `json:"fees"`     // Fees of all notices so far, including this one
+	LumpSum      decimal.Decimal     `json:"lump_sum"` // § 288 (5) BGB compensation
+	Total        decimal.Decimal     `json:"total"`    // Outstanding plus fees, lump sum and interest
+	Previous     []repository.Notice `json:"previous"` // Notices sent before
+	Data         *models.InvoiceData `json:"-"`        // Invoice data for rendering
+}
+
+// Plan returns the notices due at now: for every open invoice past its due date the
+// next level whose waiting periods have passed. Invoices at the last level are left
+// alone; they are a case for collection.
+func Plan(records []*repository.Record, now time.Time, policy Policy) []Notice {
+	var notices []Notice
+	for _, rec := range records {
+		if !rec.IsOpen() || rec.Type == models.InvoiceTypeCreditNote || !rec.Outstanding().IsPositive() {
+			continue
+		}
+		if rec.DueDate.IsZero() || !rec.DueDate.Before(now) {
+			continue
+		}
+		level := rec.DunningLevel()
+		if level >= len(policy.Levels) {
+			continue
+		}
+		step := policy.Levels[level]
+		overdue := days(rec.DueDate, now)
+		if overdue < step.DaysAfterDue {
+			continue
+		}
+		if len(rec.Notices) > 0 && days(rec.Notices[len(rec.Notices)-1].Date, now) < step.DaysAfterPrevious {
+			continue
+		}
+		notices = append(notices, newNotice(rec, level+1, step, now, overdue, policy))
+	}
+	return notices
+}
+
+func newNotice(rec *repository.Record, level int, step Level, now time.Time, overdue int, policy Policy) Notice {
+	n := Notice{
+		Number:      rec.Number,
+		Level:       level,
+		Step:        step,
+		Date:        now,
+		PayBy:       now.AddDate(0, 0, step.PaymentDays),
+		DueDate:     rec.DueDate,
+		DaysOverdue: overdue,
+		Currency:    rec.Currency,
+		Outstanding: rec.Outstanding(),
+		Fee:         step.Fee,
+		Fees:        step.Fee,
+		Previous:    rec.Notices,
+		Data:        rec.Data,
+	}
+	lumpSum := false
+	for _, prev := range rec.Notices {
+		n.Fees = n.Fees.Add(prev.Fee)
+		lumpSum = lumpSum || !prev.Interest.IsZero()
+	}
+	business := rec.Data != nil && rec.Data.Client.IsBusiness()
+	if step.Interest {
+		n.InterestRate = InterestRate(policy.BaseRate, business)
+		n.Interest = Interest(n.Outstanding, n.InterestRate, overdue)
+		lumpSum = true
+	}
+	if lumpSum && business && rec.Currency == "EUR" {
+		n.LumpSum = LumpSum
+	}
+	n.Total = n.Outstanding.Add(n.Fees).Add(n.LumpSum).Add(n.Interest)
+	return n
+}
+
+// InterestRate returns the statutory default interest rate in percent per year.
+// The rate never falls below zero.
+func InterestRate(baseRate decimal.Decimal, business bool) decimal.Decimal {
+	margin := ConsumerMargin
+	if business {
+		margin = BusinessMargin
+	}
+	rate := baseRate.Add(margin)
+	if rate.IsNegative() {
+		return decimal.Zero
+	}
+	return rate
+}
+
+// Interest returns the simple interest on amount at rate percent per year for the
+// given number of days (act/365), rounded to cents.
+func Interest(amount, rate decimal.Decimal, days int) decimal.Decimal {
+	if days <= 0 {
+		return decimal.Zero
+	}
+	return amount.Mul(rate).Mul(decimal.NewFromInt(int64(days))).Div(decimal.NewFromInt(36500)).Round(2)
+}
+
+// Record returns the repository entry for the notice.
+func (n Notice) Record(file string) repository.Notice {
+	return repository.Notice{
+		Level:    n.Level,
+		Name:     n.Step.Name,
+		Date:     n.Date,
+		PayBy:    n.PayBy,
+		Fee:      n.Fee,
+		Interest: n.Interest,
+		Total:    n.Total,
+		File:     file,
+	}
+}
+
+// days counts the calendar days from one date to another.
+func days(from, to time.Time) int {
+	y1, m1, d1 := from.Date()
+	y2, m2, d2 := to.Date()
+	a := time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC)
+	b := time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
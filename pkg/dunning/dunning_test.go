@@ -0,0 +1,114 @@
+package dunning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/repository"
+)
+
+var due = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+func record(number string, business bool) *repository.Record {
+	client := models.ClientInfo{Name: "Pixel Dynamics GmbH", Business: business}
+	return &repository.Record{
+		Number:    number,
+		Status:    models.StatusOverdue,
+		Currency:  "EUR",
+		DueDate:   due,
+		AmountDue: decimal.NewFromInt(1000),
+		Data:      &models.InvoiceData{Client: client},
+	}
+}
+
+func policy() Policy {
+	return Policy{Levels: DefaultLevels(), BaseRate: decimal.RequireFromString("1.27")}
+}
+
+func TestInterest(t *testing.T) {
+	assert.True(t, decimal.RequireFromString("10.27").Equal(InterestRate(decimal.RequireFromString("1.27"), true)))
+	assert.True(t, decimal.RequireFromString("6.27").Equal(InterestRate(decimal.RequireFromString("1.27"), false)))
+	assert.True(t, InterestRate(decimal.NewFromInt(-6), false).IsZero())
+
+	// 1000 at 10.27% for 365 days
+	assert.Equal(t, "102.70", Interest(decimal.NewFromInt(1000), decimal.RequireFromString("10.27"), 365).StringFixed(2))
+	// 1000 at 10.27% for 30 days: 8.441...
+	assert.Equal(t, "8.44", Interest(decimal.NewFromInt(1000), decimal.RequireFromString("10.27"), 30).StringFixed(2))
+	assert.True(t, Interest(decimal.NewFromInt(1000), decimal.NewFromInt(10), 0).IsZero())
+}
+
+func TestPlan_Escalation(t *testing.T) {
+	rec := record("RE-1", true)
+
+	// Not yet due for a reminder
+	assert.Empty(t, Plan([]*repository.Record{rec}, due.AddDate(0, 0, 6), policy()))
+
+	notices := Plan([]*repository.Record{rec}, due.AddDate(0, 0, 7), policy())
+	require.Len(t, notices, 1)
+	reminder := notices[0]
+	assert.Equal(t, 1, reminder.Level)
+	assert.Equal(t, "reminder", reminder.Step.Name)
+	assert.Equal(t, 7, reminder.DaysOverdue)
+	assert.True(t, reminder.Interest.IsZero())
+	assert.True(t, reminder.LumpSum.IsZero())
+	assert.True(t, decimal.NewFromInt(1000).Equal(reminder.Total))
+	assert.Equal(t, due.AddDate(0, 0, 14), reminder.PayBy)
+	rec.Notices = append(rec.Notices, reminder.Record("r.pdf"))
+
+	// The dunning notice waits for 14 days after the reminder
+	assert.Empty(t, Plan([]*repository.Record{rec}, due.AddDate(0, 0, 20), policy()))
+	notices = Plan([]*repository.Record{rec}, due.AddDate(0, 0, 30), policy())
+	require.Len(t, notices, 1)
+	dunning := notices[0]
+	assert.Equal(t, 2, dunning.Level)
+	assert.True(t, decimal.RequireFromString("10.27").Equal(dunning.InterestRate))
+	assert.Equal(t, "8.44", dunning.Interest.StringFixed(2))
+	assert.True(t, LumpSum.Equal(dunning.LumpSum))
+	assert.Equal(t, "1053.44", dunning.Total.StringFixed(2))
+	rec.Notices = append(rec.Notices, dunning.Record("d.pdf"))
+
+	// Fees accumulate and the lump sum is only claimed once
+	notices = Plan([]*repository.Record{rec}, due.AddDate(0, 0, 44), policy())
+	require.Len(t, notices, 1)
+	final := notices[0]
+	assert.Equal(t, "final_notice", final.Step.Name)
+	assert.True(t, decimal.NewFromInt(15).Equal(final.Fees))
+	assert.True(t, LumpSum.Equal(final.LumpSum))
+	assert.Len(t, final.Previous, 2)
+	rec.Notices = append(rec.Notices, final.Record("f.pdf"))
+
+	// Nothing after the last level
+	assert.Empty(t, Plan([]*repository.Record{rec}, due.AddDate(0, 0, 90), policy()))
+}
+
+func TestPlan_SkipsSettledAndConsumers(t *testing.T) {
+	paid := record("RE-1", true)
+	paid.Status = models.StatusPaid
+	partly := record("RE-2", false)
+	partly.PaidAmount = decimal.NewFromInt(400)
+	partly.Notices = []repository.Notice{{Level: 1, Name: "reminder", Date: due.AddDate(0, 0, 7)}}
+	credit := record("GS-1", true)
+	credit.Type = models.InvoiceTypeCreditNote
+	draft := record("RE-3", true)
+	draft.Status = models.StatusDraft
+
+	notices := Plan([]*repository.Record{paid, partly, credit, draft}, due.AddDate(0, 0, 30), policy())
+	require.Len(t, notices, 1)
+	n := notices[0]
+	assert.Equal(t, "RE-2", n.Number)
+	assert.True(t, decimal.NewFromInt(600).Equal(n.Outstanding))
+	assert.True(t, decimal.RequireFromString("6.27").Equal(n.InterestRate))
+	assert.True(t, n.LumpSum.IsZero(), "consumers owe no lump sum")
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	assert.NoError(t, policy().Validate())
+	assert.Error(t, Policy{}.Validate())
+	assert.Error(t, Policy{Levels: []Level{{Name: "reminder", Fee: decimal.NewFromInt(-1)}}}.Validate())
+	assert.Error(t, Policy{Levels: []Level{{DaysAfterDue: 7}}}.Validate())
+}
@@ -1,7 +1,11 @@
 // Package errors defines domain-specific error types for InvoiceGen.
 package errors
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 // ErrorCode represents a machine-readable error code.
 type ErrorCode string
@@ -12,6 +16,16 @@ const (
 	ErrInvoiceNotFound    ErrorCode = "INVOICE_NOT_FOUND"
 	ErrPDFGeneration      ErrorCode = "PDF_GENERATION_ERROR"
 	ErrCurrencyUnsupported ErrorCode = "CURRENCY_UNSUPPORTED"
+	ErrExchangeRate       ErrorCode = "EXCHANGE_RATE_UNAVAILABLE"
+	ErrNumbering          ErrorCode = "NUMBERING_ERROR"
+	ErrStatusTransition   ErrorCode = "INVALID_STATUS_TRANSITION"
+	ErrArchive            ErrorCode = "ARCHIVE_ERROR"
+	ErrJobNotFound        ErrorCode = "JOB_NOT_FOUND"
+	ErrJobState           ErrorCode = "INVALID_JOB_STATE"
+	ErrUnauthorized       ErrorCode = "UNAUTHORIZED"
+	ErrRateLimited        ErrorCode = "RATE_LIMITED"
+	ErrTimeout            ErrorCode = "TIMEOUT"
+	ErrCanceled           ErrorCode = "CANCELED"
 	ErrUnknown            ErrorCode = "UNKNOWN"
 )
 
@@ -49,6 +63,55 @@ func NewPDFGenerationError(msg string, cause error) *AppError {
 func NewCurrencyUnsupportedError(msg string) *AppError {
 	return &AppError{Code: ErrCurrencyUnsupported, Message: msg}
 }
+func NewExchangeRateError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrExchangeRate, Message: msg, Cause: cause}
+}
+func NewNumberingError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrNumbering, Message: msg, Cause: cause}
+}
+func NewStatusTransitionError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrStatusTransition, Message: msg, Cause: cause}
+}
+func NewArchiveError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrArchive, Message: msg, Cause: cause}
+}
+func NewJobNotFoundError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrJobNotFound, Message: msg, Cause: cause}
+}
+func NewJobStateError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrJobState, Message: msg, Cause: cause}
+}
+func NewUnauthorizedError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrUnauthorized, Message: msg, Cause: cause}
+}
+func NewRateLimitedError(msg string) *AppError {
+	return &AppError{Code: ErrRateLimited, Message: msg}
+}
+func NewTimeoutError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrTimeout, Message: msg, Cause: cause}
+}
+func NewCanceledError(msg string, cause error) *AppError {
+	return &AppError{Code: ErrCanceled, Message: msg, Cause: cause}
+}
 func NewAppError(code ErrorCode, msg string, cause error) *AppError {
 	return &AppError{Code: code, Message: msg, Cause: cause}
 }
+
+// FromContext returns the timeout or cancellation error of op if err is, or was caused
+// by, the end of a context: ErrTimeout for context.DeadlineExceeded and ErrCanceled for
+// context.Canceled. Other errors, and errors that already are one of the two, are
+// returned unchanged.
+func FromContext(op string, err error) error {
+	var appErr *AppError
+	if errors.As(err, &appErr) && (appErr.Code == ErrTimeout || appErr.Code == ErrCanceled) {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewTimeoutError(op+" timed out", err)
+	case errors.Is(err, context.Canceled):
+		return NewCanceledError(op+" canceled", err)
+	default:
+		return err
+	}
+}
@@ -1,7 +1,9 @@
 package errors
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,4 +35,35 @@ func TestAppError_Constructors(t *testing.T) {
 	cur := NewCurrencyUnsupportedError("bad currency")
 	assert.Equal(t, ErrCurrencyUnsupported, cur.Code)
 	assert.Equal(t, "bad currency", cur.Message)
+
+	num := NewNumberingError("sequence locked", nil)
+	assert.Equal(t, ErrNumbering, num.Code)
+	assert.Equal(t, "sequence locked", num.Message)
+
+	st := NewStatusTransitionError("already paid", nil)
+	assert.Equal(t, ErrStatusTransition, st.Code)
+	assert.Equal(t, "already paid", st.Message)
+
+	ar := NewArchiveError("log corrupt", nil)
+	assert.Equal(t, ErrArchive, ar.Code)
+	assert.Equal(t, "log corrupt", ar.Message)
+}
+
+func TestFromContext(t *testing.T) {
+	timeout := FromContext("PDF generation", fmt.Errorf("print: %w", context.DeadlineExceeded))
+	var appErr *AppError
+	assert.ErrorAs(t, timeout, &appErr)
+	assert.Equal(t, ErrTimeout, appErr.Code)
+	assert.Equal(t, "PDF generation timed out", appErr.Message)
+	assert.ErrorIs(t, timeout, context.DeadlineExceeded)
+
+	canceled := FromContext("rendering", NewPDFGenerationError("failed to render HTML", context.Canceled))
+	assert.ErrorAs(t, canceled, &appErr)
+	assert.Equal(t, ErrCanceled, appErr.Code)
+
+	// Typed errors and other errors are kept
+	assert.Same(t, timeout, FromContext("invoice generation", timeout))
+	other := errors.New("chrome not found")
+	assert.Same(t, other, FromContext("PDF generation", other))
+	assert.Nil(t, FromContext("PDF generation", nil))
 }
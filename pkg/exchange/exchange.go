@@ -0,0 +1,198 @@
+// Package exchange provides currency exchange rate sources for foreign-currency invoicing.
+package exchange
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrRateNotFound is returned when no rate is known for a currency pair and date.
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// rateScale is the number of decimal places kept for derived cross rates.
+const rateScale = 8
+
+// RateSource looks up exchange rates.
+type RateSource interface {
+	// Rate returns how many units of `to` one unit of `from` is worth on the given date.
+	Rate(from, to string, on time.Time) (decimal.Decimal, error)
+}
+
+// Table is an in-memory RateSource holding daily reference rates against a base currency.
+// A lookup uses the most recent published day on or before the requested date, which
+// covers weekends and holidays for which reference rates are not published.
+type Table struct {
+	Base string
+	days []day
+}
+
+type day struct {
+	date  time.Time
+	rates map[string]decimal.Decimal
+}
+
+// NewTable creates a Table for the given base currency. Each rate is expressed as units
+// of the quoted currency per one unit of base.
+func NewTable(base string, rates map[time.Time]map[string]decimal.Decimal) *Table {
+	t := &Table{Base: strings.ToUpper(base)}
+	for date, quotes := range rates {
+		d := day{date: truncateDay(date), rates: make(map[string]decimal.Decimal, len(quotes))}
+		for code, rate := range quotes {
+			d.rates[strings.ToUpper(code)] = rate
+		}
+		t.days = append(t.days, d)
+	}
+	sort.Slice(t.days, func(i, j int) bool { return t.days[i].date.Before(t.days[j].date) })
+	return t
+}
+
+// Rate implements RateSource. Cross rates between two non-base currencies are derived
+// through the base currency.
+func (t *Table) Rate(from, to string, on time.Time) (decimal.Decimal, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	d, ok := t.dayFor(on)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: no rates published on or before %s", ErrRateNotFound, on.Format("2006-01-02"))
+	}
+	fromRate, err := t.quote(d, from)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	toRate, err := t.quote(d, to)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toRate.DivRound(fromRate, rateScale), nil
+}
+
+func (t *Table) dayFor(on time.Time) (day, bool) {
+	on = truncateDay(on)
+	i := sort.Search(len(t.days), func(i int) bool { return t.days[i].date.After(on) })
+	if i == 0 {
+		return day{}, false
+	}
+	return t.days[i-1], true
+}
+
+func (t *Table) quote(d day, code string) (decimal.Decimal, error) {
+	if code == t.Base {
+		return decimal.NewFromInt(1), nil
+	}
+	rate, ok := d.rates[code]
+	if !ok || !rate.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w: %s/%s on %s", ErrRateNotFound, t.Base, code, d.date.Format("2006-01-02"))
+	}
+	return rate, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// rateFile is the on-disk format for locally maintained rates (YAML or JSON).
+//
+//	base: EUR
+//	rates:
+//	  "2025-07-14":
+//	    USD: 1.1689
+//	    CHF: 0.9335
+type rateFile struct {
+	Base  string                                `json:"base" yaml:"base"`
+	Rates map[string]map[string]decimal.Decimal `json:"rates" yaml:"rates"`
+}
+
+// ecbEnvelope mirrors the European Central Bank eurofxref XML feeds
+// (eurofxref-daily.xml, eurofxref-hist.xml).
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// LoadFile loads a rate table from disk. Files ending in .xml are parsed as ECB reference
+// rate feeds, .yaml/.yml/.json files use the local rate file format.
+func LoadFile(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return ParseECB(data)
+	case ".yaml", ".yml":
+		var f rateFile
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse rates file: %w", err)
+		}
+		return f.table()
+	case ".json":
+		var f rateFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse rates file: %w", err)
+		}
+		return f.table()
+	default:
+		return nil, fmt.Errorf("unsupported rates file format %q (supported: .xml, .yaml, .yml, .json)", filepath.Ext(path))
+	}
+}
+
+// ParseECB parses an ECB eurofxref XML document. ECB rates are quoted against EUR.
+func ParseECB(data []byte) (*Table, error) {
+	var env ecbEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse ECB rates: %w", err)
+	}
+	rates := make(map[time.Time]map[string]decimal.Decimal, len(env.Days))
+	for _, d := range env.Days {
+		date, err := time.Parse("2006-01-02", d.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ECB rate date %q: %w", d.Time, err)
+		}
+		quotes := make(map[string]decimal.Decimal, len(d.Rates))
+		for _, r := range d.Rates {
+			rate, err := decimal.NewFromString(r.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ECB rate for %s on %s: %w", r.Currency, d.Time, err)
+			}
+			quotes[r.Currency] = rate
+		}
+		rates[date] = quotes
+	}
+	if len(rates) == 0 {
+		return nil, errors.New("ECB rates document contains no rates")
+	}
+	return NewTable("EUR", rates), nil
+}
+
+func (f rateFile) table() (*Table, error) {
+	if f.Base == "" {
+		return nil, errors.New("rates file is missing base currency")
+	}
+	rates := make(map[time.Time]map[string]decimal.Decimal, len(f.Rates))
+	for ds, quotes := range f.Rates {
+		date, err := time.Parse("2006-01-02", ds)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate date %q: %w", ds, err)
+		}
+		rates[date] = quotes
+	}
+	return NewTable(f.Base, rates), nil
+}
@@ -0,0 +1,99 @@
+package exchange
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ecbSample = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2025-07-14">
+			<Cube currency="USD" rate="1.1680"/>
+			<Cube currency="CHF" rate="0.9310"/>
+		</Cube>
+		<Cube time="2025-07-11">
+			<Cube currency="USD" rate="1.1700"/>
+			<Cube currency="CHF" rate="0.9320"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestParseECB_RateToBase(t *testing.T) {
+	table, err := ParseECB([]byte(ecbSample))
+	require.NoError(t, err)
+
+	rate, err := table.Rate("USD", "EUR", date("2025-07-14"))
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("0.85616438").Equal(rate), "got %s", rate)
+
+	rate, err = table.Rate("EUR", "CHF", date("2025-07-14"))
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("0.931").Equal(rate), "got %s", rate)
+}
+
+func TestTable_UsesPreviousPublishedDay(t *testing.T) {
+	table, err := ParseECB([]byte(ecbSample))
+	require.NoError(t, err)
+
+	// 2025-07-13 is a Sunday; the Friday rate applies.
+	rate, err := table.Rate("EUR", "USD", date("2025-07-13"))
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("1.17").Equal(rate), "got %s", rate)
+
+	_, err = table.Rate("EUR", "USD", date("2025-07-01"))
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+}
+
+func TestTable_CrossRateAndUnknownCurrency(t *testing.T) {
+	table, err := ParseECB([]byte(ecbSample))
+	require.NoError(t, err)
+
+	rate, err := table.Rate("USD", "CHF", date("2025-07-14"))
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("0.79708904").Equal(rate), "got %s", rate)
+
+	same, err := table.Rate("usd", "USD", date("2000-01-01"))
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1).Equal(same))
+
+	_, err = table.Rate("JPY", "EUR", date("2025-07-14"))
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+}
+
+func TestLoadFile_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.yaml")
+	content := `
+base: EUR
+rates:
+  "2025-07-14":
+    USD: 1.25
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	table, err := LoadFile(path)
+	require.NoError(t, err)
+	rate, err := table.Rate("USD", "EUR", date("2025-07-20"))
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("0.8").Equal(rate), "got %s", rate)
+}
+
+func TestLoadFile_UnsupportedFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	require.NoError(t, os.WriteFile(path, []byte("USD,1.1"), 0644))
+	_, err := LoadFile(path)
+	assert.Error(t, err)
+}
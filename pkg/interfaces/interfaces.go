@@ -1,12 +1,16 @@
 // Package interfaces defines public interfaces for invoice generation and embedding.
 package interfaces
 
-import "invoiceformats/pkg/models"
+import (
+	"context"
+
+	"invoiceformats/pkg/models"
+)
 
 // PDFEmbeddedDataProvider provides embedded data for PDF invoices.
 type PDFEmbeddedDataProvider interface {
 	// Generate creates embedded data for the invoice and returns the file path, description, and error.
-	Generate(data models.InvoiceData, opts any) (filePath string, description string, err error)
+	Generate(ctx context.Context, data models.InvoiceData, opts any) (filePath string, description string, err error)
 	// TODO: [context: interface, priority: low, effort: 15m] Consider splitting for different invoice types.
 }
 
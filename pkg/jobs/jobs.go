@@ -0,0 +1,190 @@
+// Package jobs provides the persistent queue for asynchronous invoice generation.
+//
+// A job carries an invoice and what to generate from it. Jobs are kept as JSON
+// documents in a directory, so queued jobs survive a restart, and run on a bounded
+// number of workers. Failed attempts are retried with exponential backoff unless the
+// error is permanent, e.g. an invalid invoice. When a job finishes, its callback URL
+// is notified with the job document.
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for unknown job IDs.
+	ErrNotFound = errors.New("job not found")
+	// ErrFinished is returned when cancelling a job that has already finished.
+	ErrFinished = errors.New("job already finished")
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// IsFinished reports whether the job will not run again.
+func (s Status) IsFinished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// Kind is the artifact a job generates.
+type Kind string
+
+const (
+	KindPDF  Kind = "pdf"
+	KindXML  Kind = "xml"
+	KindHTML Kind = "html"
+)
+
+// IsValid reports whether the kind is known.
+func (k Kind) IsValid() bool {
+	return k == KindPDF || k == KindXML || k == KindHTML
+}
+
+// Request is what a job generates.
+type Request struct {
+	Kind        Kind            `json:"kind"`
+	Invoice     json.RawMessage `json:"invoice"` // Invoice JSON as submitted
+	Template    string          `json:"template,omitempty"`
+	Lang        string          `json:"lang,omitempty"`
+	Currency    string          `json:"currency,omitempty"`
+	Series      string          `json:"series,omitempty"`
+	CallbackURL string          `json:"callback_url,omitempty"` // Notified when the job finishes
+	Tenant      string          `json:"tenant,omitempty"`       // Tenant that submitted the job; only it may access the job
+}
+
+// Job is a queued, running or finished generation.
+type Job struct {
+	ID            string    `json:"id"`
+	Status        Status    `json:"status"`
+	Request       Request   `json:"request"`
+	Attempts      int       `json:"attempts"`
+	MaxAttempts   int       `json:"max_attempts"`
+	NextAttempt   time.Time `json:"next_attempt,omitempty"` // Earliest start of a retry
+	Error         string    `json:"error,omitempty"`        // Error of the last attempt
+	ErrorCode     string    `json:"error_code,omitempty"`
+	Artifact      string    `json:"artifact,omitempty"` // Generated file
+	ContentType   string    `json:"content_type,omitempty"`
+	InvoiceNumber string    `json:"invoice_number,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	FinishedAt    time.Time `json:"finished_at,omitempty"`
+}
+
+// Store keeps one JSON document per job in a directory, named after the job ID.
+// Documents are written to a temporary file and renamed into place.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a store in dir. The directory is created on first write.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the store, where runners may keep artifacts.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Save writes a job.
+func (s *Store) Save(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("create job store %s: %w", s.dir, err)
+	}
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.path(job.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// Get returns the job with the given ID.
+func (s *Store) Get(id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	job, err := s.read(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return job, err
+}
+
+// List returns all jobs, oldest first.
+func (s *Store) List() ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read job store %s: %w", s.dir, err)
+	}
+	var jobs []*Job
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		job, err := s.read(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs, nil
+}
+
+func (s *Store) read(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &job, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,188 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/testutils"
+)
+
+// waitFor polls the store until the job has finished.
+func waitFor(t *testing.T, q *Queue, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(id)
+		require.NoError(t, err)
+		if job.Status.IsFinished() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func startQueue(t *testing.T, store *Store, run Runner, opts Options) *Queue {
+	t.Helper()
+	q := NewQueue(store, run, opts, &testutils.TestLogger{})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		q.Wait()
+	})
+	require.NoError(t, q.Start(ctx))
+	return q
+}
+
+func TestQueue_RunsJobs(t *testing.T) {
+	var calls int32
+	q := startQueue(t, NewStore(t.TempDir()), func(ctx context.Context, job *Job) (*Result, error) {
+		atomic.AddInt32(&calls, 1)
+		return &Result{File: "out.pdf", ContentType: "application/pdf", InvoiceNumber: "RE-1"}, nil
+	}, Options{})
+
+	job, err := q.Submit(Request{Kind: KindPDF, Invoice: json.RawMessage(`{}`)})
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, job.Status)
+
+	done := waitFor(t, q, job.ID)
+	assert.Equal(t, StatusSucceeded, done.Status)
+	assert.Equal(t, "out.pdf", done.Artifact)
+	assert.Equal(t, "RE-1", done.InvoiceNumber)
+	assert.Equal(t, 1, done.Attempts)
+	assert.False(t, done.FinishedAt.IsZero())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	_, err = q.Submit(Request{Kind: "docx"})
+	assert.Error(t, err)
+	_, err = q.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = q.Cancel(job.ID)
+	assert.ErrorIs(t, err, ErrFinished)
+}
+
+func TestQueue_Retries(t *testing.T) {
+	var calls int32
+	q := startQueue(t, NewStore(t.TempDir()), func(ctx context.Context, job *Job) (*Result, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, errors.New("browser crashed")
+		}
+		return &Result{File: "out.pdf"}, nil
+	}, Options{MaxAttempts: 3, RetryDelay: time.Millisecond})
+
+	job, err := q.Submit(Request{Kind: KindPDF})
+	require.NoError(t, err)
+	done := waitFor(t, q, job.ID)
+	assert.Equal(t, StatusSucceeded, done.Status)
+	assert.Equal(t, 3, done.Attempts)
+	assert.Empty(t, done.Error)
+
+	// Exhausted and permanent failures
+	failing := startQueue(t, NewStore(t.TempDir()), func(ctx context.Context, job *Job) (*Result, error) {
+		if job.Request.Template == "invalid" {
+			return nil, Permanent(errors.New("invalid invoice"))
+		}
+		return nil, errors.New("browser crashed")
+	}, Options{MaxAttempts: 2, RetryDelay: time.Millisecond, ErrorCode: func(error) string { return "PDF_GENERATION_ERROR" }})
+
+	job, err = failing.Submit(Request{Kind: KindPDF})
+	require.NoError(t, err)
+	done = waitFor(t, failing, job.ID)
+	assert.Equal(t, StatusFailed, done.Status)
+	assert.Equal(t, 2, done.Attempts)
+	assert.Equal(t, "browser crashed", done.Error)
+	assert.Equal(t, "PDF_GENERATION_ERROR", done.ErrorCode)
+
+	job, err = failing.Submit(Request{Kind: KindPDF, Template: "invalid"})
+	require.NoError(t, err)
+	done = waitFor(t, failing, job.ID)
+	assert.Equal(t, StatusFailed, done.Status)
+	assert.Equal(t, 1, done.Attempts)
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	q := startQueue(t, NewStore(t.TempDir()), func(ctx context.Context, job *Job) (*Result, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, Options{Workers: 1})
+
+	running, err := q.Submit(Request{Kind: KindPDF})
+	require.NoError(t, err)
+	<-started
+	queued, err := q.Submit(Request{Kind: KindXML})
+	require.NoError(t, err)
+
+	// The queued job waits for the only worker and is cancelled at once
+	job, err := q.Cancel(queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, job.Status)
+
+	job, err = q.Cancel(running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, job.Status)
+	assert.Equal(t, StatusCancelled, waitFor(t, q, running.ID).Status)
+}
+
+func TestQueue_ResumesAfterRestart(t *testing.T) {
+	store := NewStore(t.TempDir())
+	started := make(chan struct{})
+	first := NewQueue(store, func(ctx context.Context, job *Job) (*Result, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, Options{Workers: 1}, &testutils.TestLogger{})
+	ctx, stop := context.WithCancel(context.Background())
+	require.NoError(t, first.Start(ctx))
+	job, err := first.Submit(Request{Kind: KindPDF})
+	require.NoError(t, err)
+	<-started
+	stop()
+	first.Wait()
+
+	interrupted, err := store.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, interrupted.Status)
+	assert.Equal(t, 0, interrupted.Attempts, "interrupted attempts do not count")
+
+	second := startQueue(t, store, func(ctx context.Context, job *Job) (*Result, error) {
+		return &Result{File: "out.pdf"}, nil
+	}, Options{})
+	assert.Equal(t, StatusSucceeded, waitFor(t, second, job.ID).Status)
+}
+
+func TestQueue_Callback(t *testing.T) {
+	received := make(chan Job, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var job Job
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&job))
+		received <- job
+	}))
+	defer srv.Close()
+
+	q := startQueue(t, NewStore(t.TempDir()), func(ctx context.Context, job *Job) (*Result, error) {
+		return &Result{File: "out.xml", InvoiceNumber: "RE-7"}, nil
+	}, Options{})
+	job, err := q.Submit(Request{Kind: KindXML, CallbackURL: srv.URL})
+	require.NoError(t, err)
+
+	select {
+	case got := <-received:
+		assert.Equal(t, job.ID, got.ID)
+		assert.Equal(t, StatusSucceeded, got.Status)
+		assert.Equal(t, "RE-7", got.InvoiceNumber)
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback not received")
+	}
+}
@@ -0,0 +1,353 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"invoiceformats/pkg/logging"
+)
+
+// Result is the artifact of a successful run.
+type Result struct {
+	File          string
+	ContentType   string
+	InvoiceNumber string
+}
+
+// Runner generates the artifact of a job. It should stop when ctx is done. Progress
+// that must survive a crash, such as an issued invoice number, is saved with
+// Queue.Checkpoint.
+type Runner func(ctx context.Context, job *Job) (*Result, error)
+
+// permanentError marks errors that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error of a Runner as permanent, so the job fails without retries.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked by Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Options configure a Queue.
+type Options struct {
+	Workers     int           // Jobs run at the same time; default 2
+	MaxAttempts int           // Attempts before a job fails; default 3
+	RetryDelay  time.Duration // Delay before the first retry, doubled for each further one; default 5s
+	// ErrorCode returns the code recorded for a failed attempt; optional.
+	ErrorCode func(err error) string
+}
+
+// Queue runs jobs from a Store.
+type Queue struct {
+	store  *Store
+	run    Runner
+	opts   Options
+	logger logging.Logger
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending []string                      // IDs of jobs ready to run, in order
+	running map[string]context.CancelFunc // Cancels the attempts of running jobs
+	notify  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewQueue creates a queue that runs the jobs of store with run.
+func NewQueue(store *Store, run Runner, opts Options, logger logging.Logger) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 5 * time.Second
+	}
+	return &Queue{
+		store:   store,
+		run:     run,
+		opts:    opts,
+		logger:  logger,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+		running: make(map[string]context.CancelFunc),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Start resumes the unfinished jobs of the store and starts the workers, which run
+// until ctx is done. Jobs interrupted by the end of ctx are queued again, so they
+// resume on the next Start. Wait waits for the workers to stop.
+func (q *Queue) Start(ctx context.Context) error {
+	jobs, err := q.store.List()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.Status.IsFinished() {
+			continue
+		}
+		if job.Status == StatusRunning {
+			job.Status = StatusQueued
+			if err := q.store.Save(job); err != nil {
+				return err
+			}
+		}
+		q.schedule(job)
+	}
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+	return nil
+}
+
+// Wait blocks until the workers have stopped.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// Submit queues a new job for the request.
+func (q *Queue) Submit(req Request) (*Job, error) {
+	if !req.Kind.IsValid() {
+		return nil, fmt.Errorf("unknown job kind %q", req.Kind)
+	}
+	now := q.now()
+	job := &Job{
+		ID:          uuid.NewString(),
+		Status:      StatusQueued,
+		Request:     req,
+		MaxAttempts: q.opts.MaxAttempts,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := q.store.Save(job); err != nil {
+		return nil, err
+	}
+	q.enqueue(job.ID)
+	return job, nil
+}
+
+// Get returns the job with the given ID.
+func (q *Queue) Get(id string) (*Job, error) {
+	return q.store.Get(id)
+}
+
+// Cancel cancels a job. A queued job is cancelled at once; a running job when its
+// runner returns. Finished jobs fail with ErrFinished.
+func (q *Queue) Cancel(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, err := q.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.IsFinished() {
+		return nil, fmt.Errorf("%w: %s is %s", ErrFinished, id, job.Status)
+	}
+	if cancel, ok := q.running[id]; ok {
+		cancel()
+		return job, nil
+	}
+	q.finish(job, StatusCancelled)
+	if err := q.store.Save(job); err != nil {
+		return nil, err
+	}
+	go q.callback(job)
+	return job, nil
+}
+
+// Checkpoint saves a running job that its runner has updated, e.g. with the number of
+// the invoice it issued, so that a run resumed after a crash can pick up from there.
+func (q *Queue) Checkpoint(job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.UpdatedAt = q.now()
+	return q.store.Save(job)
+}
+
+// schedule queues a job now or, for a retry, once its backoff has passed.
+func (q *Queue) schedule(job *Job) {
+	if delay := job.NextAttempt.Sub(q.now()); delay > 0 {
+		time.AfterFunc(delay, func() { q.enqueue(job.ID) })
+		return
+	}
+	q.enqueue(job.ID)
+}
+
+func (q *Queue) enqueue(id string) {
+	q.mu.Lock()
+	q.pending = append(q.pending, id)
+	q.mu.Unlock()
+	q.wake()
+}
+
+func (q *Queue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		job, attemptCtx, ok := q.next(ctx)
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.notify:
+			}
+			continue
+		}
+		q.attempt(ctx, attemptCtx, job)
+	}
+}
+
+// next takes the next queued job and marks it running.
+func (q *Queue) next(ctx context.Context) (*Job, context.Context, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) > 0 {
+		id := q.pending[0]
+		q.pending = q.pending[1:]
+		if _, ok := q.running[id]; ok {
+			continue
+		}
+		job, err := q.store.Get(id)
+		if err != nil {
+			q.logger.Error("Failed to load job", &logging.LogFields{JobID: id, Error: err.Error()})
+			continue
+		}
+		if job.Status != StatusQueued {
+			continue // Cancelled while waiting
+		}
+		job.Status = StatusRunning
+		job.Attempts++
+		job.UpdatedAt = q.now()
+		if err := q.store.Save(job); err != nil {
+			q.logger.Error("Failed to start job", &logging.LogFields{JobID: id, Error: err.Error()})
+			continue
+		}
+		attemptCtx, cancel := context.WithCancel(ctx)
+		q.running[id] = cancel
+		if len(q.pending) > 0 {
+			q.wake()
+		}
+		return job, attemptCtx, true
+	}
+	return nil, nil, false
+}
+
+// attempt runs a job once and records the outcome.
+func (q *Queue) attempt(ctx, attemptCtx context.Context, job *Job) {
+	result, err := q.run(attemptCtx, job)
+
+	q.mu.Lock()
+	cancel := q.running[job.ID]
+	delete(q.running, job.ID)
+	cancelled := attemptCtx.Err() != nil && ctx.Err() == nil
+	cancel()
+
+	job.Error, job.ErrorCode = "", ""
+	switch {
+	case err == nil:
+		job.Artifact, job.ContentType, job.InvoiceNumber = result.File, result.ContentType, result.InvoiceNumber
+		q.finish(job, StatusSucceeded)
+	case cancelled:
+		q.recordError(job, err)
+		q.finish(job, StatusCancelled)
+	case ctx.Err() != nil:
+		// Shutting down: the attempt does not count and the job resumes on restart
+		job.Attempts--
+		job.Status = StatusQueued
+		job.UpdatedAt = q.now()
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		q.recordError(job, err)
+		q.finish(job, StatusFailed)
+	default:
+		q.recordError(job, err)
+		job.Status = StatusQueued
+		job.NextAttempt = q.now().Add(q.opts.RetryDelay << (job.Attempts - 1))
+		job.UpdatedAt = q.now()
+	}
+	saveErr := q.store.Save(job)
+	q.mu.Unlock()
+
+	if saveErr != nil {
+		q.logger.Error("Failed to save job", &logging.LogFields{JobID: job.ID, Error: saveErr.Error()})
+		return
+	}
+	switch {
+	case job.Status.IsFinished():
+		q.logger.Info("Job finished", &logging.LogFields{JobID: job.ID, Status: string(job.Status), Error: job.Error})
+		q.callback(job)
+	case ctx.Err() == nil:
+		q.logger.Warn("Job attempt failed, retrying", &logging.LogFields{JobID: job.ID, Error: job.Error, Status: "attempt " + strconv.Itoa(job.Attempts)})
+		q.schedule(job)
+	}
+}
+
+func (q *Queue) recordError(job *Job, err error) {
+	job.Error = err.Error()
+	if q.opts.ErrorCode != nil {
+		job.ErrorCode = q.opts.ErrorCode(err)
+	}
+}
+
+func (q *Queue) finish(job *Job, status Status) {
+	now := q.now()
+	job.Status = status
+	job.NextAttempt = time.Time{}
+	job.UpdatedAt = now
+	job.FinishedAt = now
+}
+
+// callback posts the finished job to its callback URL. Failures are logged only;
+// clients can always poll the job.
+func (q *Queue) callback(job *Job) {
+	url := job.Request.CallbackURL
+	if url == "" {
+		return
+	}
+	body, err := json.Marshal(job)
+	if err != nil {
+		return
+	}
+	resp, err := q.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		q.logger.Warn("Job callback failed", &logging.LogFields{JobID: job.ID, URL: url, Error: err.Error()})
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		q.logger.Warn("Job callback rejected", &logging.LogFields{JobID: job.ID, URL: url, Status: resp.Status})
+	}
+}
@@ -0,0 +1,338 @@
+// Package jsonschema generates JSON Schemas (draft 2020-12) from Go types.
+//
+// Property names come from the json tags and constraints from the validate tags
+// (required, min, max, len, gt, gte, lt, lte, oneof, email, url, alphanum). String
+// types with a Values method, such as models.TaxCategory, become enums. Named structs
+// are defined once under $defs and referenced. InvoiceData returns the schema of
+// invoice files, which also covers what the loader fills in: profile references,
+// catalog SKUs and the defaults of generation.
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/profile"
+)
+
+// Draft is the JSON Schema dialect of generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema or subschema.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"` // A type name or a list of them
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              json.Number        `json:"minimum,omitempty"`
+	Maximum              json.Number        `json:"maximum,omitempty"`
+	ExclusiveMinimum     json.Number        `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     json.Number        `json:"exclusiveMaximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // A schema or false
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// enum is implemented by string types that list their values.
+type enum interface {
+	Values() []string
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	uuidType    = reflect.TypeOf(uuid.UUID{})
+	rawType     = reflect.TypeOf(json.RawMessage{})
+	enumType    = reflect.TypeOf((*enum)(nil)).Elem()
+)
+
+// Generator generates schemas, collecting the definitions of named structs.
+type Generator struct {
+	// RefPrefix is prepended to definition names in references; default "#/$defs/".
+	// OpenAPI documents use "#/components/schemas/".
+	RefPrefix string
+	// Defs holds the definitions of the named structs generated so far.
+	Defs map[string]*Schema
+}
+
+// NewGenerator creates a generator with references into $defs.
+func NewGenerator() *Generator {
+	return &Generator{RefPrefix: "#/$defs/", Defs: make(map[string]*Schema)}
+}
+
+// For returns the schema of v's type; named structs are referenced.
+func (g *Generator) For(v interface{}) *Schema {
+	return g.typeSchema(reflect.TypeOf(v))
+}
+
+// Document returns the schema of v's type as a standalone document with the collected
+// definitions.
+func (g *Generator) Document(v interface{}, title string) *Schema {
+	s := g.For(v)
+	if s.Ref != "" {
+		// Inline the root definition
+		name := strings.TrimPrefix(s.Ref, g.RefPrefix)
+		root := *g.Defs[name]
+		delete(g.Defs, name)
+		s = &root
+	}
+	s.Schema = Draft
+	s.Title = title
+	s.Defs = g.Defs
+	return s
+}
+
+func (g *Generator) typeSchema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		return g.typeSchema(t.Elem())
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", AnyOf: []*Schema{{Format: "date"}, {Format: "date-time"}}}
+	case decimalType:
+		return &Schema{Type: []string{"number", "string"}, Pattern: `^-?[0-9]+(\.[0-9]+)?$`}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawType:
+		return &Schema{}
+	}
+	if t.Implements(enumType) && t.Kind() == reflect.String {
+		values := reflect.Zero(t).Interface().(enum).Values()
+		return &Schema{Type: "string", Enum: append([]string{""}, values...)}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t == reflect.TypeOf(time.Duration(0)) {
+			return &Schema{Type: []string{"string", "integer"}, Description: "Duration such as 30s or 5m, or nanoseconds"}
+		}
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.Defs[t.Name()]; !ok {
+			g.Defs[t.Name()] = nil // Guards recursive types
+			g.Defs[t.Name()] = g.structSchema(t)
+		}
+		return &Schema{Ref: g.RefPrefix + t.Name()}
+	}
+	return &Schema{}
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := g.typeSchema(f.Type)
+		if required := applyRules(prop, f.Type, f.Tag.Get("validate")); required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+// applyRules adds the constraints of a validate tag to a property schema and reports
+// whether the property is required. Rules after dive apply to the elements.
+func applyRules(s *Schema, t reflect.Type, tag string) bool {
+	if tag == "" || tag == "-" {
+		return false
+	}
+	rules := strings.Split(tag, ",")
+	required := false
+	for i, rule := range rules {
+		key, param, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			if s.Items != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+				applyRules(s.Items, t.Elem(), strings.Join(rules[i+1:], ","))
+			}
+			return required
+		case "required":
+			required = true
+			if len(s.Enum) > 0 && s.Enum[0] == "" {
+				s.Enum = s.Enum[1:]
+			}
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "alphanum":
+			s.Pattern = "^[a-zA-Z0-9]*$"
+		case "oneof":
+			s.Enum = strings.Fields(param)
+			if !required {
+				s.Enum = append([]string{""}, s.Enum...)
+			}
+		case "len", "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			setBound(s, t, key, n)
+		case "gt", "gte", "lt", "lte":
+			if !isNumeric(t) {
+				continue
+			}
+			num := json.Number(param)
+			switch key {
+			case "gt":
+				s.ExclusiveMinimum = num
+			case "gte":
+				s.Minimum = num
+			case "lt":
+				s.ExclusiveMaximum = num
+			case "lte":
+				s.Maximum = num
+			}
+		}
+	}
+	return required
+}
+
+// setBound applies len, min or max, which bound the length of strings, the number of
+// elements of lists and the value of numbers.
+func setBound(s *Schema, t reflect.Type, key string, n int) {
+	lower, upper := key == "len" || key == "min", key == "len" || key == "max"
+	switch {
+	case t.Kind() == reflect.String:
+		if lower {
+			s.MinLength = &n
+		}
+		if upper {
+			s.MaxLength = &n
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if lower {
+			s.MinItems = &n
+		}
+		if upper {
+			s.MaxItems = &n
+		}
+	case isNumeric(t):
+		if lower {
+			s.Minimum = json.Number(strconv.Itoa(n))
+		}
+		if upper {
+			s.Maximum = json.Number(strconv.Itoa(n))
+		}
+	}
+}
+
+func isNumeric(t reflect.Type) bool {
+	if t == decimalType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// InvoiceData returns the schema of invoice files (YAML or JSON). Beyond the model it
+// allows what the loader resolves before validation: a provider or client given by
+// profile ID or as a profile reference with overrides, lines that name a catalog SKU
+// instead of a description and price, and an invoice number and currency left to the
+// numbering and default currency.
+func InvoiceData() *Schema {
+	g := NewGenerator()
+	g.For(models.InvoiceData{})
+	g.AddInvoiceInputRules()
+	doc := g.Document(models.InvoiceData{}, "Invoice")
+	doc.Description = "Invoice data file of invoicegen"
+	return doc
+}
+
+// AddInvoiceInputRules relaxes the collected definitions of the invoice model to the
+// input accepted by the loader; see InvoiceData.
+func (g *Generator) AddInvoiceInputRules() {
+	for _, party := range []string{"provider", "client"} {
+		for _, def := range g.Defs {
+			if def == nil || def.Properties[party] == nil || def.Properties[party].Ref == "" {
+				continue
+			}
+			def.Properties[party] = &Schema{AnyOf: []*Schema{
+				def.Properties[party],
+				{Type: "string", Description: "ID of a " + party + " profile"},
+				{
+					Type:        "object",
+					Description: "Reference to a " + party + " profile with fields overriding it",
+					Properties:  map[string]*Schema{profile.ReferenceKey: {Type: "string"}},
+					Required:    []string{profile.ReferenceKey},
+				},
+			}}
+		}
+	}
+	if details := g.Defs["InvoiceDetails"]; details != nil {
+		details.Required = without(details.Required, "number", "currency")
+	}
+	if line := g.Defs["InvoiceLine"]; line != nil {
+		line.Required = without(line.Required, "description", "unit_price")
+		line.AnyOf = []*Schema{
+			{Required: []string{"sku"}},
+			{Required: []string{"description", "unit_price"}},
+		}
+	}
+}
+
+// without returns the sorted list without the removed entries.
+func without(list []string, remove ...string) []string {
+	var out []string
+	for _, s := range list {
+		keep := true
+		for _, r := range remove {
+			if s == r {
+				keep = false
+			}
+		}
+		if keep {
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,171 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"invoiceformats/pkg/models"
+)
+
+// check reports the first violation of v against s, covering the keywords the
+// generator emits except formats and patterns.
+func check(root, s *Schema, v interface{}, path string) error {
+	if s.Ref != "" {
+		return check(root, root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")], v, path)
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, alt := range s.AnyOf {
+			if check(root, alt, v, path) == nil {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: matches no alternative", path)
+		}
+	}
+	if s.Type != nil {
+		types, ok := s.Type.([]string)
+		if !ok {
+			types = []string{s.Type.(string)}
+		}
+		matched := false
+		for _, typ := range types {
+			switch v.(type) {
+			case string:
+				matched = matched || typ == "string"
+			case float64:
+				matched = matched || typ == "number" || typ == "integer"
+			case bool:
+				matched = matched || typ == "boolean"
+			case []interface{}:
+				matched = matched || typ == "array"
+			case map[string]interface{}:
+				matched = matched || typ == "object"
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v is not of type %v", path, v, s.Type)
+		}
+	}
+	if len(s.Enum) > 0 {
+		str, _ := v.(string)
+		found := false
+		for _, e := range s.Enum {
+			found = found || e == str
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, v, s.Enum)
+		}
+	}
+	switch v := v.(type) {
+	case []interface{}:
+		for i, item := range v {
+			if err := check(root, s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				return fmt.Errorf("%s: missing %s", path, key)
+			}
+		}
+		for key, value := range v {
+			prop := s.Properties[key]
+			if prop == nil {
+				if s.AdditionalProperties == false {
+					return fmt.Errorf("%s: unknown property %s", path, key)
+				}
+				continue
+			}
+			if err := check(root, prop, value, path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// load reads a YAML or JSON document as JSON values.
+func load(t *testing.T, src []byte) interface{} {
+	t.Helper()
+	var doc interface{}
+	require.NoError(t, yaml.Unmarshal(src, &doc))
+	raw, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var v interface{}
+	require.NoError(t, json.Unmarshal(raw, &v))
+	return v
+}
+
+func TestInvoiceData(t *testing.T) {
+	s := InvoiceData()
+	assert.Equal(t, Draft, s.Schema)
+	assert.ElementsMatch(t, []string{"provider", "client", "invoice"}, s.Required)
+
+	details := s.Defs["InvoiceDetails"]
+	require.NotNil(t, details)
+	assert.Contains(t, details.Properties["vat_exemption_type"].Enum, string(models.VATExemptionSmallBusiness))
+	assert.Contains(t, details.Properties["tariff_type"].Enum, "")
+	assert.Len(t, details.Properties["tariff_type"].Enum, len(models.TariffType("").Values())+1)
+	assert.Equal(t, []string{"lines"}, details.Required, "number and currency are filled in")
+	assert.Equal(t, 1, *details.Properties["lines"].MinItems)
+
+	currency := s.Defs["Currency"]
+	assert.Equal(t, 3, *currency.Properties["code"].MinLength)
+	assert.Equal(t, 3, *currency.Properties["code"].MaxLength)
+
+	line := s.Defs["InvoiceLine"]
+	assert.Equal(t, json.Number("0"), line.Properties["quantity"].ExclusiveMinimum)
+	assert.Equal(t, json.Number("100"), line.Properties["tax_rate"].Maximum)
+	assert.Equal(t, "email", s.Defs["CompanyInfo"].Properties["email"].Format)
+
+	raw, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), `"$ref":"#/$defs/InvoiceData"`)
+}
+
+func TestInvoiceData_InvoiceFiles(t *testing.T) {
+	s := InvoiceData()
+	files, err := filepath.Glob("../../invoices/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.NoError(t, check(s, s, load(t, src), "$"), file)
+	}
+
+	// Profile references and catalog lines
+	ref := load(t, []byte(`
+provider: techcorp
+client: {profile: acme, vat_id: US123}
+invoice:
+  lines:
+    - sku: CONSULT-H
+      quantity: 3
+`))
+	assert.NoError(t, check(s, s, ref, "$"))
+
+	invalid := load(t, []byte(`
+provider: techcorp
+client: acme
+invoice:
+  tariff_type: flat
+  lines:
+    - description: Consulting
+      quantity: 3
+      unit_price: 120
+`))
+	err = check(s, s, invalid, "$")
+	assert.ErrorContains(t, err, "tariff_type")
+}
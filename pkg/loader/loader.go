@@ -2,7 +2,7 @@
 package loader
 
 import (
-	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,10 +12,17 @@ import (
 	appErrs "invoiceformats/pkg/errors"
 	"invoiceformats/pkg/logging"
 	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/profile"
 )
 
 // LoadInvoiceData loads and validates invoice data from a YAML or JSON file
 func LoadInvoiceData(filename string, logger logging.Logger) (*models.InvoiceData, error) {
+	return LoadInvoiceDataWithProfiles(filename, nil, logger)
+}
+
+// LoadInvoiceDataWithProfiles loads and validates invoice data like LoadInvoiceData,
+// resolving provider and client references to the profiles of the registry
+func LoadInvoiceDataWithProfiles(filename string, profiles *profile.Registry, logger logging.Logger) (*models.InvoiceData, error) {
 	logger.Info("Attempting to load invoice file", &logging.LogFields{File: filename})
 	cwd, cwdErr := os.Getwd()
 	logger.Info("Current working directory", &logging.LogFields{Status: cwd, Error: func() string { if cwdErr != nil { return cwdErr.Error() } else { return "" } }()})
@@ -37,14 +44,22 @@ func LoadInvoiceData(filename string, logger logging.Logger) (*models.InvoiceDat
 
 	switch ext {
 	case ".yaml", ".yml":
-		unmarshalErr = yaml.Unmarshal(data, &invoiceData)
+		var doc yaml.Node
+		unmarshalErr = yaml.Unmarshal(data, &doc)
+		if unmarshalErr == nil && doc.Kind != 0 {
+			unmarshalErr = profiles.DecodeYAML(&doc, &invoiceData)
+		}
 	case ".json":
-		unmarshalErr = json.Unmarshal(data, &invoiceData)
+		unmarshalErr = profiles.DecodeJSON(data, &invoiceData)
 	default:
 		logger.Error("Unsupported file format", &logging.LogFields{File: filename, Error: "unsupported format", Status: ext})
 		return nil, appErrs.NewAppError(appErrs.ErrUnknown, "unsupported file format (supported: .yaml, .yml, .json)", nil)
 	}
 
+	if errors.Is(unmarshalErr, profile.ErrUnknownProfile) {
+		logger.Error("Invalid profile reference in invoice data", &logging.LogFields{File: filename, Error: unmarshalErr.Error()})
+		return nil, appErrs.NewAppError(appErrs.ErrValidationFailed, "invalid profile reference", unmarshalErr)
+	}
 	if unmarshalErr != nil {
 		logger.Error("Failed to unmarshal invoice data", &logging.LogFields{File: filename, Error: unmarshalErr.Error()})
 		return nil, appErrs.NewAppError(appErrs.ErrUnknown, "failed to parse invoice data", unmarshalErr)
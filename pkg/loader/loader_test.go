@@ -126,3 +126,21 @@ func TestLoadInvoiceData_MalformedJSON(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to parse invoice data")
 }
+
+func TestLoadInvoiceData_UnknownProfile(t *testing.T) {
+	yaml := `
+provider: techcorp
+client: pixel-dynamics
+invoice:
+  number: "INV-003"
+  lines:
+    - description: "Service"
+      quantity: 1
+      unit_price: 100
+`
+	file := writeTempFile(t, yaml, ".yaml")
+	defer os.Remove(file)
+	_, err := LoadInvoiceData(file, &testutils.TestLogger{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid profile reference")
+}
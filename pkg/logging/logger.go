@@ -20,6 +20,8 @@ type LogFields struct {
 	Currency     string
 	Lines        int
 	EmbeddedData string
+	JobID        string
+	Tenant       string
 	// Extend with more fields as needed
 }
 
@@ -128,6 +130,12 @@ func fieldsToMap(fields *LogFields) map[string]interface{} {
 	if fields.EmbeddedData != "" {
 		m["embedded_data"] = fields.EmbeddedData
 	}
+	if fields.JobID != "" {
+		m["job_id"] = fields.JobID
+	}
+	if fields.Tenant != "" {
+		m["tenant"] = fields.Tenant
+	}
 	return m
 }
 
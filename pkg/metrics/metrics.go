@@ -0,0 +1,116 @@
+// Package metrics provides the Prometheus metrics of invoice generation.
+//
+// The collectors are registered in Registry, which the API server exposes on
+// /metrics. They are updated by the invoice service and the PDF generator, so they
+// also count generations of the CLI, which simply never exposes them.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	appErrs "invoiceformats/pkg/errors"
+)
+
+// Namespace prefixes the names of all metrics.
+const Namespace = "invoicegen"
+
+// OutcomeSuccess is the outcome label of operations that succeeded; failed ones are
+// labelled with their error code.
+const OutcomeSuccess = "success"
+
+// Registry holds the metrics of this package and the Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Generations counts the invoices validated, previewed or generated, by template,
+	// output format (validation, html, xml or pdf) and outcome.
+	Generations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "generations_total",
+		Help:      "Invoice generations by template, output format and outcome (success or error code).",
+	}, []string{"template", "format", "outcome"})
+
+	// RenderDuration observes the rendering of invoice HTML.
+	RenderDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "render_duration_seconds",
+		Help:      "Time to render the HTML of an invoice.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"template"})
+
+	// PDFDuration observes the printing of HTML to PDF in the headless browser.
+	PDFDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "pdf_duration_seconds",
+		Help:      "Time to print HTML to PDF in the headless browser, by outcome (success or error).",
+		Buckets:   []float64{.25, .5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"outcome"})
+
+	// PDFPrinting is the number of PDFs being printed in the browser pool.
+	PDFPrinting = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "pdf_printing",
+		Help:      "PDFs being printed in the browser pool.",
+	})
+
+	// ValidationErrors counts the invoices rejected before rendering.
+	ValidationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "validation_errors_total",
+		Help:      "Invoices rejected before rendering, by error code.",
+	}, []string{"code"})
+
+	// BrowserUp is the result of the last browser check of /readyz.
+	BrowserUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "browser_up",
+		Help:      "Whether the headless browser answered the last readiness check (1) or not (0).",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Generations,
+		RenderDuration,
+		PDFDuration,
+		PDFPrinting,
+		ValidationErrors,
+		BrowserUp,
+	)
+}
+
+// Handler returns the HTTP handler exposing Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Outcome returns the outcome label of an operation: OutcomeSuccess, or the code of
+// the AppError it failed with (UNKNOWN for other errors).
+func Outcome(err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	var appErr *appErrs.AppError
+	if errors.As(err, &appErr) {
+		return string(appErr.Code)
+	}
+	return string(appErrs.ErrUnknown)
+}
+
+// Template returns the template label of a template name. Template files are labelled
+// with their base name, so labels stay few; the theme template is "default".
+func Template(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appErrs "invoiceformats/pkg/errors"
+)
+
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, OutcomeSuccess, Outcome(nil))
+	assert.Equal(t, "VALIDATION_FAILED", Outcome(appErrs.NewValidationError("invalid", nil)))
+	assert.Equal(t, "PDF_GENERATION_ERROR", Outcome(errors.Join(errors.New("context"), appErrs.NewPDFGenerationError("failed", nil))))
+	assert.Equal(t, "UNKNOWN", Outcome(errors.New("plain")))
+}
+
+func TestTemplate(t *testing.T) {
+	assert.Equal(t, "default", Template(""))
+	assert.Equal(t, "modern-dark.html.tmpl", Template("modern-dark.html.tmpl"))
+	assert.Equal(t, "custom.html.tmpl", Template("/srv/templates/custom.html.tmpl"))
+	assert.Equal(t, "custom.html.tmpl", Template(`C:\templates\custom.html.tmpl`))
+}
+
+func TestHandler(t *testing.T) {
+	Generations.WithLabelValues("default", "xml", OutcomeSuccess).Inc()
+	ValidationErrors.WithLabelValues("VALIDATION_FAILED").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `invoicegen_generations_total{format="xml",outcome="success",template="default"}`)
+	assert.Contains(t, body, `invoicegen_validation_errors_total{code="VALIDATION_FAILED"}`)
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,7 +12,7 @@ import (
 type Currency struct {
     Code   string          `json:"code" yaml:"code" validate:"required,len=3"`
     Symbol string          `json:"symbol" yaml:"symbol" validate:"required"`
-    Rate   decimal.Decimal `json:"rate" yaml:"rate"`
+    Rate   decimal.Decimal `json:"rate" yaml:"rate"` // Units of the tax currency per unit of Code, used when InvoiceDetails.TaxCurrency differs
 }
 
 // Address represents a structured address
@@ -58,6 +59,13 @@ type ClientInfo struct {
     Email   string    `json:"email" yaml:"email" validate:"required,email"`
     Phone   string    `json:"phone" yaml:"phone"`
     VATID   string    `json:"vat_id" yaml:"vat_id"`
+    Business bool     `json:"business" yaml:"business"` // Buyer is a business (B2B); implied when VATID is set
+    IBAN    string    `json:"iban" yaml:"iban"` // Account the client pays from, used to match bank statements
+}
+
+// IsBusiness reports whether the client is treated as a business customer (B2B)
+func (c ClientInfo) IsBusiness() bool {
+    return c.Business || c.VATID != ""
 }
 
 // InvoiceLine represents a single line item on an invoice
@@ -71,6 +79,37 @@ type InvoiceLine struct {
     TaxAmount   decimal.Decimal `json:"tax_amount" yaml:"tax_amount"`
     Discount    decimal.Decimal `json:"discount" yaml:"discount" validate:"gte=0,lte=100"`
     Period      string          `json:"period" yaml:"period"` // For recurring/periodic services
+    ProductType ProductType     `json:"product_type" yaml:"product_type"` // goods, services or digital; drives tax rules
+    TaxClass    TaxClass        `json:"tax_class" yaml:"tax_class"` // Rate class within the seller's tax rules (default: standard)
+    TaxCategory TaxCategory     `json:"tax_category" yaml:"tax_category"` // BT-151: VAT category code, decided by the tax engine if empty
+    TaxExemptionReason string   `json:"tax_exemption_reason" yaml:"tax_exemption_reason"` // BT-120: Exemption reason for non-standard categories
+    TaxExemptionCode   string   `json:"tax_exemption_code" yaml:"tax_exemption_code"` // BT-121: VATEX exemption reason code
+    SKU         string          `json:"sku" yaml:"sku"` // Catalog item the line is filled in from
+    Unit        string          `json:"unit" yaml:"unit"` // BT-130: UN/ECE Rec. 20 unit code, e.g. HUR or C62
+    SellerItemID   string       `json:"seller_item_id" yaml:"seller_item_id"` // BT-155: Item seller's identifier
+    BuyerItemID    string       `json:"buyer_item_id" yaml:"buyer_item_id"` // BT-156: Item buyer's identifier
+    StandardItemID string       `json:"standard_item_id" yaml:"standard_item_id"` // BT-157: Item standard identifier, e.g. a GTIN
+    StandardItemScheme string   `json:"standard_item_scheme" yaml:"standard_item_scheme"` // BT-157-1: ISO 6523 scheme of the standard identifier, e.g. 0160 for GTIN
+}
+
+// VATCategory returns the line's VAT category, defaulting to standard rated or zero rated
+// by tax rate when none was decided
+func (il InvoiceLine) VATCategory() TaxCategory {
+    if il.TaxCategory != "" {
+        return il.TaxCategory
+    }
+    if il.TaxRate.IsZero() {
+        return TaxCategoryZero
+    }
+    return TaxCategoryStandard
+}
+
+// VATExemptionCode returns the line's VATEX code, defaulting to the code implied by its category
+func (il InvoiceLine) VATExemptionCode() string {
+    if il.TaxExemptionCode != "" {
+        return il.TaxExemptionCode
+    }
+    return il.VATCategory().ExemptionCode()
 }
 
 // CalculateTotal calculates the total for this line item
@@ -100,14 +139,108 @@ const (
     StatusPaid      InvoiceStatus = "paid"
     StatusOverdue   InvoiceStatus = "overdue"
     StatusCancelled InvoiceStatus = "cancelled"
+    StatusAccepted  InvoiceStatus = "accepted" // Quote accepted by the client
+    StatusDeclined  InvoiceStatus = "declined" // Quote declined by the client
 )
 
+// Values lists the invoice statuses
+func (InvoiceStatus) Values() []string {
+    return []string{string(StatusDraft), string(StatusSent), string(StatusPaid), string(StatusOverdue), string(StatusCancelled), string(StatusAccepted), string(StatusDeclined)}
+}
+
+// statusTransitions lists the statuses an invoice may move to from each status.
+// Issued invoices are cancelled by a credit note, see the repository package.
+var statusTransitions = map[InvoiceStatus][]InvoiceStatus{
+    StatusDraft:   {StatusSent, StatusCancelled},
+    StatusSent:    {StatusPaid, StatusOverdue, StatusCancelled, StatusAccepted, StatusDeclined},
+    StatusOverdue: {StatusPaid, StatusCancelled},
+}
+
+// CanTransitionTo reports whether an invoice may move from s to the given status
+func (s InvoiceStatus) CanTransitionTo(to InvoiceStatus) bool {
+    for _, next := range statusTransitions[s] {
+        if next == to {
+            return true
+        }
+    }
+    return false
+}
+
+// IsIssued reports whether the invoice has left draft state and must no longer change
+func (s InvoiceStatus) IsIssued() bool {
+    return s != "" && s != StatusDraft
+}
+
 // PaymentTerms represents payment terms
 type PaymentTerms struct {
     DueDays     int    `json:"due_days" yaml:"due_days" validate:"gte=0"`
     Description string `json:"description" yaml:"description"`
 }
 
+// ProductType classifies what a line item supplies for tax purposes
+type ProductType string
+
+const (
+    ProductGoods    ProductType = "goods"
+    ProductServices ProductType = "services"
+    ProductDigital  ProductType = "digital" // Electronically supplied services
+)
+
+// Values lists the product types
+func (ProductType) Values() []string {
+    return []string{string(ProductGoods), string(ProductServices), string(ProductDigital)}
+}
+
+// TaxClass selects a rate within a country's tax rules
+type TaxClass string
+
+const (
+    TaxClassStandard      TaxClass = "standard"
+    TaxClassReduced       TaxClass = "reduced"
+    TaxClassSecondReduced TaxClass = "second_reduced"
+    TaxClassZero          TaxClass = "zero"
+    TaxClassExempt        TaxClass = "exempt"
+)
+
+// Values lists the tax classes
+func (TaxClass) Values() []string {
+    return []string{string(TaxClassStandard), string(TaxClassReduced), string(TaxClassSecondReduced), string(TaxClassZero), string(TaxClassExempt)}
+}
+
+// TaxCategory is the UNTDID 5305 VAT category code used by EN 16931
+type TaxCategory string
+
+const (
+    TaxCategoryStandard       TaxCategory = "S"
+    TaxCategoryZero           TaxCategory = "Z"
+    TaxCategoryExempt         TaxCategory = "E"
+    TaxCategoryReverseCharge  TaxCategory = "AE"
+    TaxCategoryIntraCommunity TaxCategory = "K"
+    TaxCategoryExport         TaxCategory = "G"
+    TaxCategoryOutOfScope     TaxCategory = "O"
+)
+
+// Values lists the VAT category codes
+func (TaxCategory) Values() []string {
+    return []string{string(TaxCategoryStandard), string(TaxCategoryZero), string(TaxCategoryExempt), string(TaxCategoryReverseCharge), string(TaxCategoryIntraCommunity), string(TaxCategoryExport), string(TaxCategoryOutOfScope)}
+}
+
+// ExemptionCode returns the VATEX code (BT-121) implied by the category, or "" for
+// categories whose exemption reason depends on the legal basis
+func (c TaxCategory) ExemptionCode() string {
+    switch c {
+    case TaxCategoryReverseCharge:
+        return "VATEX-EU-AE"
+    case TaxCategoryIntraCommunity:
+        return "VATEX-EU-IC"
+    case TaxCategoryExport:
+        return "VATEX-EU-G"
+    case TaxCategoryOutOfScope:
+        return "VATEX-EU-O"
+    }
+    return ""
+}
+
 // VATExemptionType represents types of VAT exemptions
 type VATExemptionType string
 
@@ -124,6 +257,11 @@ const (
     VATExemptionOther       VATExemptionType = "other"
 )
 
+// Values lists the VAT exemption types
+func (VATExemptionType) Values() []string {
+    return []string{string(VATExemptionNone), string(VATExemptionSmallBusiness), string(VATExemptionNonEU), string(VATExemptionReverseCharge), string(VATExemptionExport), string(VATExemptionIntraCommunity), string(VATExemptionEducation), string(VATExemptionMedical), string(VATExemptionFinancial), string(VATExemptionOther)}
+}
+
 // TariffType represents types of tariffs
 type TariffType string
 
@@ -137,6 +275,11 @@ const (
     TariffOther       TariffType = "other"
 )
 
+// Values lists the tariff types
+func (TariffType) Values() []string {
+    return []string{string(TariffNone), string(TariffImport), string(TariffCustoms), string(TariffExcise), string(TariffEnvironmental), string(TariffLuxury), string(TariffOther)}
+}
+
 // EmbeddedDataType specifies what kind of data to embed in the PDF (e.g., "zugferd", "none").
 type EmbeddedDataType string
 
@@ -146,6 +289,11 @@ const (
 	// TODO: Add more types as needed (e.g., xrechnung, peppol, custom)
 )
 
+// Values lists the supported embedded data types
+func (EmbeddedDataType) Values() []string {
+	return []string{string(EmbeddedDataNone), string(EmbeddedDataZUGFeRD)}
+}
+
 // InvoiceDetails represents the main invoice information
 type InvoiceDetails struct {
     ID           uuid.UUID       `json:"id" yaml:"id"`
@@ -153,6 +301,7 @@ type InvoiceDetails struct {
     Date         time.Time       `json:"date" yaml:"date"`
     DueDate      time.Time       `json:"due_date" yaml:"due_date"`
     Status       InvoiceStatus   `json:"status" yaml:"status"`
+    Type         InvoiceType     `json:"type" yaml:"type"` // Empty for a regular invoice
     Currency     Currency        `json:"currency" yaml:"currency" validate:"required"`
     Lines        []InvoiceLine   `json:"lines" yaml:"lines" validate:"required,min=1"`
     PaymentTerms PaymentTerms    `json:"payment_terms" yaml:"payment_terms"`
@@ -170,6 +319,185 @@ type InvoiceDetails struct {
     VATExemptionReason string `json:"vat_exemption_reason" yaml:"vat_exemption_reason"` // Custom text if type is 'other'
     TariffType       TariffType       `json:"tariff_type" yaml:"tariff_type"` // Use 'other' and set AdditionalTariffs for custom
     AdditionalTariffs   string `json:"additional_tariffs" yaml:"additional_tariffs"`   // Custom text if type is 'other'
+    TaxCurrency      string          `json:"tax_currency" yaml:"tax_currency"` // BT-6: VAT accounting currency, if different from the invoice currency
+    TotalTaxAccounting decimal.Decimal `json:"total_tax_accounting" yaml:"total_tax_accounting"` // BT-111: total VAT expressed in TaxCurrency
+    Withholdings     []Withholding   `json:"withholdings" yaml:"withholdings"` // Taxes withheld by the buyer (IRPF, ritenuta d'acconto)
+    TotalWithholding decimal.Decimal `json:"total_withholding" yaml:"total_withholding"`
+    AmountDue        decimal.Decimal `json:"amount_due" yaml:"amount_due"` // Grand total less prepayments and withholdings
+    PrecedingInvoices []InvoiceReference `json:"preceding_invoices" yaml:"preceding_invoices"` // BG-3: earlier invoices, e.g. down payments settled by a final invoice
+    PrepaidAmount    decimal.Decimal `json:"prepaid_amount" yaml:"prepaid_amount"` // BT-113: sum of the amounts paid on preceding invoices
+    BillingPeriod    BillingPeriod   `json:"billing_period" yaml:"billing_period"` // BG-14: period the invoiced services were rendered in
+    OrderReference   string          `json:"order_reference" yaml:"order_reference"` // BT-14: sales order reference, e.g. the accepted quote
+    ValidUntil       time.Time       `json:"valid_until" yaml:"valid_until"` // Quotes: date until which the offer is binding
+}
+
+// InvoiceType distinguishes regular invoices from installment invoices
+type InvoiceType string
+
+const (
+    InvoiceTypeStandard InvoiceType = "invoice"
+    InvoiceTypeAdvance  InvoiceType = "advance" // Down payment requested before delivery
+    InvoiceTypePartial  InvoiceType = "partial" // Installment for work delivered so far
+    InvoiceTypeFinal    InvoiceType = "final"   // Settles the project less earlier installments
+    InvoiceTypeCreditNote InvoiceType = "credit_note" // Credits all or part of a preceding invoice
+    InvoiceTypeQuote    InvoiceType = "quote"              // Offer sent before an order; not an invoice
+    InvoiceTypeOrderConfirmation InvoiceType = "order_confirmation" // Confirms an accepted order; not an invoice
+)
+
+// Values lists the document types; an empty type is a regular invoice
+func (InvoiceType) Values() []string {
+    return []string{string(InvoiceTypeStandard), string(InvoiceTypeAdvance), string(InvoiceTypePartial), string(InvoiceTypeFinal), string(InvoiceTypeCreditNote), string(InvoiceTypeQuote), string(InvoiceTypeOrderConfirmation)}
+}
+
+// IsInvoice reports whether the document is an invoice or credit note rather than a
+// quote or order confirmation, which are not due for payment
+func (t InvoiceType) IsInvoice() bool {
+    return t != InvoiceTypeQuote && t != InvoiceTypeOrderConfirmation
+}
+
+// AllowsStatus reports whether documents of this type can have the given status:
+// only invoices are paid or overdue, and only quotes are accepted or declined
+func (t InvoiceType) AllowsStatus(s InvoiceStatus) bool {
+    switch s {
+    case StatusPaid, StatusOverdue:
+        return t.IsInvoice()
+    case StatusAccepted, StatusDeclined:
+        return t == InvoiceTypeQuote
+    }
+    return true
+}
+
+// TypeCode returns the UNTDID 1001 document type code (BT-3)
+func (t InvoiceType) TypeCode() string {
+    switch t {
+    case InvoiceTypeAdvance:
+        return "386"
+    case InvoiceTypePartial:
+        return "326"
+    case InvoiceTypeCreditNote:
+        return "381"
+    }
+    return "380"
+}
+
+// TitleKey returns the locale key of the document title
+func (t InvoiceType) TitleKey() string {
+    switch t {
+    case InvoiceTypeAdvance:
+        return "advance_invoice"
+    case InvoiceTypePartial:
+        return "partial_invoice"
+    case InvoiceTypeFinal:
+        return "final_invoice"
+    case InvoiceTypeCreditNote:
+        return "credit_note"
+    case InvoiceTypeQuote:
+        return "quote"
+    case InvoiceTypeOrderConfirmation:
+        return "order_confirmation"
+    }
+    return "invoice"
+}
+
+// TextKey returns the locale key of the terms printed on quotes and order
+// confirmations, empty for invoices
+func (t InvoiceType) TextKey() string {
+    switch t {
+    case InvoiceTypeQuote:
+        return "quote_text"
+    case InvoiceTypeOrderConfirmation:
+        return "order_confirmation_text"
+    }
+    return ""
+}
+
+// InvoiceReference refers to a preceding invoice (BG-3)
+type InvoiceReference struct {
+    Number string          `json:"number" yaml:"number"` // BT-25
+    Date   time.Time       `json:"date" yaml:"date"`     // BT-26
+    Amount decimal.Decimal `json:"amount" yaml:"amount"` // Gross amount paid on that invoice, deducted as prepayment
+}
+
+// BillingPeriod is the invoicing period (BG-14), both dates inclusive
+type BillingPeriod struct {
+    Start time.Time `json:"start" yaml:"start"` // BT-73
+    End   time.Time `json:"end" yaml:"end"`     // BT-74
+}
+
+// IsZero reports whether no period is set
+func (p BillingPeriod) IsZero() bool {
+    return p.Start.IsZero() && p.End.IsZero()
+}
+
+// IsCreditNote reports whether the document credits a preceding invoice. Credit note
+// amounts are positive; the document type gives them their sign.
+func (inv InvoiceDetails) IsCreditNote() bool {
+    return inv.Type == InvoiceTypeCreditNote
+}
+
+// HasPrepayment reports whether preceding invoices are deducted from the amount due
+func (inv InvoiceDetails) HasPrepayment() bool {
+    return !inv.PrepaidAmount.IsZero()
+}
+
+// HasDeductions reports whether the amount due differs from the grand total
+func (inv InvoiceDetails) HasDeductions() bool {
+    return inv.HasPrepayment() || inv.HasWithholding()
+}
+
+// DuePayableAmount returns the amount due for payment (BT-115): the grand total less
+// prepayments. Withholdings are paid to the tax authority and not deducted here.
+func (inv InvoiceDetails) DuePayableAmount() decimal.Decimal {
+    return inv.GrandTotal.Sub(inv.PrepaidAmount)
+}
+
+// WithholdingType identifies a withholding tax regime
+type WithholdingType string
+
+const (
+    WithholdingIRPF      WithholdingType = "irpf"      // Spain: Impuesto sobre la Renta de las Personas Físicas
+    WithholdingRitenuta  WithholdingType = "ritenuta"  // Italy: ritenuta d'acconto
+    WithholdingOther     WithholdingType = "other"
+)
+
+// Values lists the withholding regimes
+func (WithholdingType) Values() []string {
+    return []string{string(WithholdingIRPF), string(WithholdingRitenuta), string(WithholdingOther)}
+}
+
+// Withholding is a tax the buyer withholds from the payment and remits to the tax
+// authority on the seller's behalf. It reduces the amount due, not the invoice total.
+type Withholding struct {
+    Type        WithholdingType `json:"type" yaml:"type"`
+    Description string          `json:"description" yaml:"description"` // Shown instead of the type, e.g. "IRPF"
+    Rate        decimal.Decimal `json:"rate" yaml:"rate"` // Percent of the base
+    Base        decimal.Decimal `json:"base" yaml:"base"` // Optional fixed base; defaults to the invoice subtotal
+    BasisAmount decimal.Decimal `json:"basis_amount" yaml:"basis_amount"` // Base the amount was calculated on
+    Amount      decimal.Decimal `json:"amount" yaml:"amount"`
+}
+
+// Label returns the name shown for the withholding
+func (w Withholding) Label() string {
+    if w.Description != "" {
+        return w.Description
+    }
+    switch w.Type {
+    case WithholdingIRPF:
+        return "IRPF"
+    case WithholdingRitenuta:
+        return "Ritenuta d'acconto"
+    }
+    return string(w.Type)
+}
+
+// HasWithholding reports whether any tax is withheld from the amount due
+func (inv InvoiceDetails) HasWithholding() bool {
+    return len(inv.Withholdings) > 0
+}
+
+// HasTaxCurrency reports whether VAT must also be stated in a separate accounting currency
+func (inv InvoiceDetails) HasTaxCurrency() bool {
+    return inv.TaxCurrency != "" && !strings.EqualFold(inv.TaxCurrency, inv.Currency.Code)
 }
 
 // CalculateTotals calculates all totals for the invoice
@@ -201,6 +529,33 @@ func (inv *InvoiceDetails) CalculateTotals() {
     inv.TotalTax = totalTax
     inv.TotalDiscount = totalDiscount
     inv.GrandTotal = subtotal.Add(totalTax)
+
+    // Convert VAT into the accounting currency using the invoice exchange rate
+    inv.TotalTaxAccounting = decimal.Zero
+    if inv.HasTaxCurrency() {
+        inv.TotalTaxAccounting = totalTax.Mul(inv.Currency.Rate).Round(2)
+    }
+
+    // Withholdings reduce the payable amount only
+    var totalWithholding decimal.Decimal
+    for i := range inv.Withholdings {
+        w := &inv.Withholdings[i]
+        w.BasisAmount = subtotal
+        if !w.Base.IsZero() {
+            w.BasisAmount = w.Base
+        }
+        w.Amount = w.BasisAmount.Mul(w.Rate).Div(decimal.NewFromInt(100)).Round(2)
+        totalWithholding = totalWithholding.Add(w.Amount)
+    }
+    inv.TotalWithholding = totalWithholding
+
+    // Amounts paid on preceding invoices are deducted from the amount due
+    var prepaid decimal.Decimal
+    for _, ref := range inv.PrecedingInvoices {
+        prepaid = prepaid.Add(ref.Amount)
+    }
+    inv.PrepaidAmount = prepaid
+    inv.AmountDue = inv.DuePayableAmount().Sub(totalWithholding)
 }
 
 // InvoiceData represents the complete invoice data structure
@@ -113,6 +113,40 @@ func TestInvoiceDetails_CalculateTotals(t *testing.T) {
 		"Expected total discount %s, got %s", expectedTotalDiscount, invoice.TotalDiscount)
 }
 
+func TestInvoiceDetails_CalculateTotalsWithholding(t *testing.T) {
+	invoice := InvoiceDetails{
+		Currency: Currency{Code: "EUR", Symbol: "€", Rate: decimal.NewFromInt(1)},
+		Lines: []InvoiceLine{
+			{
+				Description: "Consultoría",
+				Quantity:    decimal.NewFromInt(10),
+				UnitPrice:   decimal.NewFromFloat(100.0),
+				TaxRate:     decimal.NewFromFloat(21.0),
+			},
+		},
+		Withholdings: []Withholding{
+			{Type: WithholdingIRPF, Rate: decimal.NewFromInt(15)},
+		},
+	}
+
+	invoice.CalculateTotals()
+
+	// Subtotal 1000, VAT 210, total 1210, IRPF 15% of 1000 = 150, payable 1060
+	w := invoice.Withholdings[0]
+	assert.True(t, decimal.NewFromInt(1000).Equal(w.BasisAmount), "got %s", w.BasisAmount)
+	assert.True(t, decimal.NewFromInt(150).Equal(w.Amount), "got %s", w.Amount)
+	assert.True(t, decimal.NewFromInt(1210).Equal(invoice.GrandTotal), "got %s", invoice.GrandTotal)
+	assert.True(t, decimal.NewFromInt(150).Equal(invoice.TotalWithholding), "got %s", invoice.TotalWithholding)
+	assert.True(t, decimal.NewFromInt(1060).Equal(invoice.AmountDue), "got %s", invoice.AmountDue)
+	assert.Equal(t, "IRPF", w.Label())
+
+	// A fixed base overrides the subtotal
+	invoice.Withholdings[0].Base = decimal.NewFromInt(400)
+	invoice.CalculateTotals()
+	assert.True(t, decimal.NewFromInt(60).Equal(invoice.Withholdings[0].Amount), "got %s", invoice.Withholdings[0].Amount)
+	assert.True(t, decimal.NewFromInt(1150).Equal(invoice.AmountDue), "got %s", invoice.AmountDue)
+}
+
 func TestAddress_String(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -218,3 +252,41 @@ func TestInvoiceData_Complete(t *testing.T) {
 	expectedTotal := decimal.NewFromFloat(110.0) // 100 + 10% tax
 	assert.True(t, expectedTotal.Equal(invoiceData.Invoice.GrandTotal))
 }
+
+func TestInvoiceType_TypeCode(t *testing.T) {
+	assert.Equal(t, "380", InvoiceType("").TypeCode())
+	assert.Equal(t, "386", InvoiceTypeAdvance.TypeCode())
+	assert.Equal(t, "326", InvoiceTypePartial.TypeCode())
+	assert.Equal(t, "380", InvoiceTypeFinal.TypeCode())
+	assert.Equal(t, "final_invoice", InvoiceTypeFinal.TitleKey())
+}
+
+func TestInvoiceStatus_CanTransitionTo(t *testing.T) {
+	assert.True(t, StatusDraft.CanTransitionTo(StatusSent))
+	assert.True(t, StatusSent.CanTransitionTo(StatusPaid))
+	assert.True(t, StatusSent.CanTransitionTo(StatusOverdue))
+	assert.True(t, StatusOverdue.CanTransitionTo(StatusPaid))
+	assert.False(t, StatusDraft.CanTransitionTo(StatusPaid))
+	assert.False(t, StatusPaid.CanTransitionTo(StatusSent))
+	assert.False(t, StatusCancelled.CanTransitionTo(StatusDraft))
+
+	assert.False(t, StatusDraft.IsIssued())
+	assert.False(t, InvoiceStatus("").IsIssued())
+	assert.True(t, StatusSent.IsIssued())
+}
+
+func TestInvoiceType_Quotes(t *testing.T) {
+	assert.True(t, InvoiceType("").IsInvoice())
+	assert.True(t, InvoiceTypeCreditNote.IsInvoice())
+	assert.False(t, InvoiceTypeQuote.IsInvoice())
+	assert.False(t, InvoiceTypeOrderConfirmation.IsInvoice())
+	assert.Equal(t, "quote", InvoiceTypeQuote.TitleKey())
+	assert.Equal(t, "order_confirmation_text", InvoiceTypeOrderConfirmation.TextKey())
+	assert.Empty(t, InvoiceTypeFinal.TextKey())
+
+	assert.True(t, InvoiceTypeQuote.AllowsStatus(StatusAccepted))
+	assert.False(t, InvoiceTypeQuote.AllowsStatus(StatusPaid))
+	assert.False(t, InvoiceTypeOrderConfirmation.AllowsStatus(StatusDeclined))
+	assert.False(t, InvoiceType("").AllowsStatus(StatusAccepted))
+	assert.True(t, InvoiceType("").AllowsStatus(StatusOverdue))
+}
@@ -37,7 +37,10 @@ type TaxDetail struct {
 	Type   string
 	Amount float64
 	Rate   float64
-	// TODO [context: Tax details, priority: medium, effort: low]: Add support for multi-rate VAT, exemptions, etc.
+	Category            string // EN16931: VAT category code (BT-118)
+	ExemptionReason     string // EN16931: VAT exemption reason text (BT-120)
+	ExemptionReasonCode string // EN16931: VAT exemption reason code (BT-121)
+	// TODO [context: Tax details, priority: medium, effort: low]: Add support for multi-rate VAT.
 }
 
 // ZUGFeRDInvoiceBuilder defines the interface for building ZUGFeRD invoices.
@@ -0,0 +1,105 @@
+// Package numbering issues gapless sequential invoice numbers.
+//
+// Numbers are built from a Pattern per series and a counter per series and period
+// kept in a Store. A number that was issued for a document that then failed to
+// generate can be released again, so the sequence has neither gaps nor duplicates.
+package numbering
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultSeries is the series used when none is requested.
+const DefaultSeries = "default"
+
+// Allocation is an issued invoice number.
+type Allocation struct {
+	Number string
+	Series string
+	Key    string // Store counter key (series and period)
+	Seq    int
+}
+
+// Numberer issues invoice numbers for a set of series.
+type Numberer struct {
+	store  Store
+	series map[string]Pattern
+}
+
+// NewNumberer creates a numberer. series maps series names to patterns and must
+// contain DefaultSeries.
+func NewNumberer(store Store, series map[string]Pattern) (*Numberer, error) {
+	if _, ok := series[DefaultSeries]; !ok {
+		return nil, fmt.Errorf("no pattern for the %s series", DefaultSeries)
+	}
+	for name, p := range series {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("series %s: %w", name, err)
+		}
+	}
+	return &Numberer{store: store, series: series}, nil
+}
+
+// Series returns the configured series names.
+func (n *Numberer) Series() []string {
+	names := make([]string, 0, len(n.series))
+	for name := range n.series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Next issues the next number of the series for an invoice dated date.
+func (n *Numberer) Next(series string, date time.Time) (Allocation, error) {
+	series, p, err := n.pattern(series)
+	if err != nil {
+		return Allocation{}, err
+	}
+	key := counterKey(series, p, date)
+	seq, err := n.store.Next(key)
+	if err != nil {
+		return Allocation{}, err
+	}
+	return Allocation{Number: p.Format(series, date, seq), Series: series, Key: key, Seq: seq}, nil
+}
+
+// Peek returns the number Next would issue without issuing it.
+func (n *Numberer) Peek(series string, date time.Time) (string, error) {
+	series, p, err := n.pattern(series)
+	if err != nil {
+		return "", err
+	}
+	seq, err := n.store.Peek(counterKey(series, p, date))
+	if err != nil {
+		return "", err
+	}
+	return p.Format(series, date, seq), nil
+}
+
+// Release hands an issued number back. It fails with ErrNotLast if later numbers of
+// the same sequence were issued in the meantime.
+func (n *Numberer) Release(a Allocation) error {
+	return n.store.Release(a.Key, a.Seq)
+}
+
+func (n *Numberer) pattern(series string) (string, Pattern, error) {
+	if series == "" {
+		series = DefaultSeries
+	}
+	p, ok := n.series[series]
+	if !ok {
+		return "", "", fmt.Errorf("unknown number series %q", series)
+	}
+	return series, p, nil
+}
+
+// counterKey scopes a counter to the series and the pattern's period.
+func counterKey(series string, p Pattern, date time.Time) string {
+	if period := p.Period(date); period != "" {
+		return series + "/" + period
+	}
+	return series
+}
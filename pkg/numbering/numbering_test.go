@@ -0,0 +1,186 @@
+package numbering
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPattern_Format(t *testing.T) {
+	date := time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		pattern Pattern
+		want    string
+		period  string
+	}{
+		{"RE-{YYYY}-{SEQ:4}", "RE-2025-0042", "2025"},
+		{"{YY}{MM}-{SEQ}", "2503-42", "2025-03"},
+		{"{SERIES}/{YYYY}{MM}{DD}/{SEQ:3}", "export/20250307/042", "2025-03-07"},
+		{"INV{SEQ:6}", "INV000042", ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.pattern), func(t *testing.T) {
+			assert.NoError(t, tt.pattern.Validate())
+			assert.Equal(t, tt.want, tt.pattern.Format("export", date, 42))
+			assert.Equal(t, tt.period, tt.pattern.Period(date))
+		})
+	}
+}
+
+func TestPattern_Validate(t *testing.T) {
+	assert.Error(t, Pattern("RE-{YYYY}").Validate())
+	assert.Error(t, Pattern("{SEQ}-{SEQ}").Validate())
+	assert.Error(t, Pattern("{YEAR}-{SEQ}").Validate())
+	assert.Error(t, Pattern("{YYYY:2}-{SEQ}").Validate())
+}
+
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern Pattern
+		number  string
+		want    Pattern
+		seq     int
+		ok      bool
+	}{
+		{"RE-{YYYY}-{SEQ:4}", "RE-2025-0042", "RE-2025-{SEQ:4}", 42, true},
+		{"RE{YYYY}{SEQ:4}", "RE20250042", "RE2025{SEQ:4}", 42, true},
+		{"RE{YYYY}{SEQ:4}", "RE202510000", "RE2025{SEQ:4}", 10000, true},
+		{"{YY}{MM}-{SEQ}", "2503-42", "2503-{SEQ}", 42, true},
+		{"{SERIES}/{YYYY}{MM}{DD}/{SEQ:3}", "export/20250307/042", "export/20250307/{SEQ:3}", 42, true},
+		{"RE{YYYY}{SEQ:4}", "RE2025042", "", 0, false},
+		{"RE-{YYYY}-{SEQ:4}", "RE-2025-0042-CN", "", 0, false},
+		{"{SERIES}/{YYYY}{MM}{DD}/{SEQ:3}", "other/20250307/042", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			got, seq, ok := tt.pattern.Match("export", tt.number)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.seq, seq)
+			if ok {
+				assert.Equal(t, tt.number, got.Format("export", time.Time{}, seq))
+			}
+		})
+	}
+}
+
+func TestNumberer_SequencePerSeriesAndYear(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "numbering.json"))
+	n, err := NewNumberer(store, map[string]Pattern{
+		DefaultSeries: "RE-{YYYY}-{SEQ:4}",
+		"credit_note": "GS-{YYYY}-{SEQ:4}",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"credit_note", DefaultSeries}, n.Series())
+
+	d2025 := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+	d2026 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := func(series string, date time.Time) string {
+		a, err := n.Next(series, date)
+		require.NoError(t, err)
+		return a.Number
+	}
+
+	assert.Equal(t, "RE-2025-0001", next("", d2025))
+	assert.Equal(t, "RE-2025-0002", next(DefaultSeries, d2025))
+	assert.Equal(t, "GS-2025-0001", next("credit_note", d2025))
+	assert.Equal(t, "RE-2026-0001", next("", d2026))
+
+	peek, err := n.Peek("", d2025)
+	require.NoError(t, err)
+	assert.Equal(t, "RE-2025-0003", peek)
+
+	_, err = n.Next("export", d2025)
+	assert.Error(t, err)
+
+	// Counters survive a restart
+	n2, err := NewNumberer(NewFileStore(store.path), map[string]Pattern{DefaultSeries: "RE-{YYYY}-{SEQ:4}"})
+	require.NoError(t, err)
+	a, err := n2.Next("", d2025)
+	require.NoError(t, err)
+	assert.Equal(t, "RE-2025-0003", a.Number)
+}
+
+func TestNumberer_Release(t *testing.T) {
+	n, err := NewNumberer(NewFileStore(filepath.Join(t.TempDir(), "numbering.json")), map[string]Pattern{DefaultSeries: "{SEQ}"})
+	require.NoError(t, err)
+
+	a1, _ := n.Next("", time.Now())
+	a2, _ := n.Next("", time.Now())
+
+	// Only the last number can be handed back without leaving a gap
+	assert.True(t, errors.Is(n.Release(a1), ErrNotLast))
+	assert.NoError(t, n.Release(a2))
+
+	a3, err := n.Next("", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "2", a3.Number)
+}
+
+func TestFileStore_ConcurrentNextIsGapless(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "numbering.json")
+	const workers, perWorker = 8, 25
+
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Separate stores share only the file, like separate processes
+			store := NewFileStore(path)
+			for i := 0; i < perWorker; i++ {
+				seq, err := store.Next("default")
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				assert.False(t, seen[seq], "duplicate sequence %d", seq)
+				seen[seq] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for seq := 1; seq <= workers*perWorker; seq++ {
+		assert.True(t, seen[seq], "missing sequence %d", seq)
+	}
+}
+
+func TestFileStore_RemovesStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "numbering.json")
+	store := NewFileStore(path)
+	store.StaleAfter = 0
+	store.LockTimeout = time.Second
+
+	require.NoError(t, os.WriteFile(path+".lock", []byte("1\n"), 0644))
+	seq, err := store.Next("default")
+	require.NoError(t, err)
+	assert.Equal(t, 1, seq)
+}
+
+func TestFileStore_WaitsForLockHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "numbering.json")
+	unlock, err := NewFileStore(path).lock()
+	require.NoError(t, err)
+
+	store := NewFileStore(path)
+	store.LockTimeout = 50 * time.Millisecond
+	_, err = store.Next("default")
+	assert.ErrorContains(t, err, "timed out")
+
+	unlock()
+	seq, err := store.Next("default")
+	require.NoError(t, err)
+	assert.Equal(t, 1, seq)
+	_, err = os.Stat(path + ".tmp")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
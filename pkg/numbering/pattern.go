@@ -0,0 +1,134 @@
+package numbering
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenPattern matches placeholders such as {YYYY} or {SEQ:4}.
+var tokenPattern = regexp.MustCompile(`\{([A-Z]+)(?::(\d+))?\}`)
+
+// Pattern is an invoice number format such as "RE-{YYYY}-{SEQ:4}".
+//
+// Supported placeholders:
+//
+//	{YYYY} {YY}   year of the invoice date
+//	{MM} {DD}     month and day of the invoice date
+//	{SERIES}      series name
+//	{SEQ} {SEQ:n} sequence number, zero-padded to n digits
+//
+// The sequence restarts whenever the date placeholders used by the pattern change,
+// so "RE-{YYYY}-{SEQ:4}" counts per year and "{YYYY}{MM}-{SEQ}" per month.
+type Pattern string
+
+// Validate checks that the pattern contains exactly one sequence placeholder and
+// only known placeholders.
+func (p Pattern) Validate() error {
+	seq := 0
+	for _, m := range tokenPattern.FindAllStringSubmatch(string(p), -1) {
+		switch m[1] {
+		case "SEQ":
+			seq++
+		case "YYYY", "YY", "MM", "DD", "SERIES":
+			if m[2] != "" {
+				return fmt.Errorf("pattern %q: {%s} takes no width", p, m[1])
+			}
+		default:
+			return fmt.Errorf("pattern %q: unknown placeholder {%s}", p, m[1])
+		}
+	}
+	if seq != 1 {
+		return fmt.Errorf("pattern %q must contain exactly one {SEQ} placeholder", p)
+	}
+	return nil
+}
+
+// Period returns the part of the date the sequence is scoped to, e.g. "2025" for a
+// yearly pattern. It is empty for patterns without date placeholders.
+func (p Pattern) Period(date time.Time) string {
+	s := string(p)
+	switch {
+	case strings.Contains(s, "{DD}"):
+		return date.Format("2006-01-02")
+	case strings.Contains(s, "{MM}"):
+		return date.Format("2006-01")
+	case strings.Contains(s, "{YYYY}"), strings.Contains(s, "{YY}"):
+		return date.Format("2006")
+	}
+	return ""
+}
+
+// Format renders the number for a series, date and sequence.
+func (p Pattern) Format(series string, date time.Time, seq int) string {
+	return tokenPattern.ReplaceAllStringFunc(string(p), func(tok string) string {
+		m := tokenPattern.FindStringSubmatch(tok)
+		switch m[1] {
+		case "YYYY":
+			return date.Format("2006")
+		case "YY":
+			return date.Format("06")
+		case "MM":
+			return date.Format("01")
+		case "DD":
+			return date.Format("02")
+		case "SERIES":
+			return series
+		case "SEQ":
+			width, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		}
+		return tok
+	})
+}
+
+// Match reports whether number was rendered by the pattern for series. It returns the
+// sequence number and the pattern with everything but the sequence filled in, e.g.
+// "RE2025{SEQ:4}" and 3 for "RE20250003" and "RE{YYYY}{SEQ:4}". Numbers of the same
+// sequence share that pattern, and its Format renders the other numbers of it.
+func (p Pattern) Match(series, number string) (Pattern, int, bool) {
+	s := string(p)
+	var expr strings.Builder
+	expr.WriteString("^")
+	seqToken := ""
+	last := 0
+	for _, loc := range tokenPattern.FindAllStringSubmatchIndex(s, -1) {
+		expr.WriteString(regexp.QuoteMeta(s[last:loc[0]]))
+		last = loc[1]
+		switch s[loc[2]:loc[3]] {
+		case "YYYY":
+			expr.WriteString(`\d{4}`)
+		case "YY", "MM", "DD":
+			expr.WriteString(`\d{2}`)
+		case "SERIES":
+			expr.WriteString(regexp.QuoteMeta(series))
+		case "SEQ":
+			seqToken = s[loc[0]:loc[1]]
+			width := 1
+			if loc[4] >= 0 {
+				width, _ = strconv.Atoi(s[loc[4]:loc[5]])
+				width = max(width, 1)
+			}
+			fmt.Fprintf(&expr, `(\d{%d,})`, width)
+		default:
+			return "", 0, false
+		}
+	}
+	expr.WriteString(regexp.QuoteMeta(s[last:]))
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil || seqToken == "" {
+		return "", 0, false
+	}
+	m := re.FindStringSubmatchIndex(number)
+	if m == nil {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(number[m[2]:m[3]])
+	if err != nil {
+		return "", 0, false
+	}
+	return Pattern(number[:m[2]] + seqToken + number[m[3]:]), seq, true
+}
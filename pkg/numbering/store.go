@@ -0,0 +1,165 @@
+package numbering
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"invoiceformats/internal/filelock"
+)
+
+// ErrNotLast is returned by Release when later numbers have already been issued, so
+// the released number can no longer be handed back without leaving a gap.
+var ErrNotLast = errors.New("sequence number is not the last one issued")
+
+// Store persists sequence counters.
+type Store interface {
+	// Next issues the next sequence number for key.
+	Next(key string) (int, error)
+	// Peek returns the number Next would issue, without issuing it.
+	Peek(key string) (int, error)
+	// Release hands back seq if it is still the last number issued for key.
+	Release(key string, seq int) error
+}
+
+// FileStore keeps counters in a JSON file. A lock on a file next to it serializes
+// access across processes, and updates are written to a temporary file, synced and
+// renamed into place, so a crash never leaves a half-written counter file.
+type FileStore struct {
+	path        string
+	mu          sync.Mutex
+	LockTimeout time.Duration // How long to wait for another process; default 10s
+	StaleAfter  time.Duration // Age after which a lock file is considered abandoned where flock is unavailable; default 1m
+}
+
+// NewFileStore creates a store backed by the file at path. The file and its directory
+// are created on first use.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path, LockTimeout: 10 * time.Second, StaleAfter: time.Minute}
+}
+
+// counterFile is the on-disk format.
+type counterFile struct {
+	Counters map[string]int `json:"counters"`
+}
+
+// Next implements Store.
+func (s *FileStore) Next(key string) (int, error) {
+	var seq int
+	err := s.update(func(f *counterFile) bool {
+		f.Counters[key]++
+		seq = f.Counters[key]
+		return true
+	})
+	return seq, err
+}
+
+// Peek implements Store.
+func (s *FileStore) Peek(key string) (int, error) {
+	var seq int
+	err := s.update(func(f *counterFile) bool {
+		seq = f.Counters[key] + 1
+		return false
+	})
+	return seq, err
+}
+
+// Release implements Store.
+func (s *FileStore) Release(key string, seq int) error {
+	var last int
+	err := s.update(func(f *counterFile) bool {
+		last = f.Counters[key]
+		if last != seq {
+			return false
+		}
+		f.Counters[key] = seq - 1
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if last != seq {
+		return fmt.Errorf("%w: released %d, last issued %d for %s", ErrNotLast, seq, last, key)
+	}
+	return nil
+}
+
+// lock takes the lock file next to the counters.
+func (s *FileStore) lock() (func(), error) {
+	return filelock.Lock(s.path+".lock", s.LockTimeout, s.StaleAfter)
+}
+
+// update runs fn on the counters under the process and file lock and writes them back
+// if fn reports a change.
+func (s *FileStore) update(fn func(*counterFile) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("create numbering directory: %w", err)
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f := counterFile{Counters: make(map[string]int)}
+	data, err := os.ReadFile(s.path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("parse %s: %w", s.path, err)
+		}
+		if f.Counters == nil {
+			f.Counters = make(map[string]int)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("read %s: %w", s.path, err)
+	}
+
+	if !fn(&f) {
+		return nil
+	}
+
+	out, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := writeSynced(tmp, out); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replace %s: %w", s.path, err)
+	}
+	// Persist the rename; not all platforms can sync a directory
+	if dir, err := os.Open(filepath.Dir(s.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// writeSynced writes data to the file at path and flushes it to disk before closing it.
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var _ Store = (*FileStore)(nil)
@@ -1,27 +1,84 @@
 // Package pdf provides utilities for generating PDF files from HTML using headless Chrome (chromedp).
+// PDFs are printed in a pool of reusable browser tabs, see Pool.
 package pdf
 
 import (
 	"context"
 	"errors"
-	"io"
 	"os"
 	"sync"
 	"time"
 
 	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/tracing"
+)
+
+// RenderTimeout bounds the printing of a PDF in the browser if the context passed to
+// GeneratePDFChromedp has no deadline of its own.
+const RenderTimeout = 30 * time.Second
 
-	"github.com/chromedp/cdproto/page"
-	"github.com/chromedp/chromedp"
+var (
+	poolMu sync.Mutex
+	pool   *Pool // Used by GeneratePDFChromedp; created on first use
 )
 
-var pdfLock sync.Mutex
+// defaultPool returns the pool of GeneratePDFChromedp, creating it if needed.
+func defaultPool() *Pool {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	if pool == nil {
+		pool = NewPool(DefaultParallelism)
+	}
+	return pool
+}
+
+// SetParallelism sets the number of PDFs GeneratePDFChromedp prints at the same time,
+// DefaultParallelism if n is not positive. The previous pool is closed once its PDFs
+// are printed.
+func SetParallelism(n int) {
+	if n <= 0 {
+		n = DefaultParallelism
+	}
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	if pool != nil {
+		if pool.Size() == n {
+			return
+		}
+		go pool.Close()
+	}
+	pool = NewPool(n)
+}
+
+// Close stops the browser of GeneratePDFChromedp after the PDFs being printed. A later
+// call of GeneratePDFChromedp starts a new one.
+func Close() {
+	poolMu.Lock()
+	p := pool
+	pool = nil
+	poolMu.Unlock()
+	if p != nil {
+		p.Close()
+	}
+}
+
+// CheckBrowser opens a tab in the browser of GeneratePDFChromedp and loads a blank page,
+// which fails if Chrome is missing or does not answer before ctx is done. The browser is
+// started if it is not running, so a successful check also warms it up.
+func CheckBrowser(ctx context.Context) error {
+	return defaultPool().Check(ctx)
+}
 
 // GeneratePDFChromedp renders the given HTML string to a PDF file using headless Chrome via chromedp.
+// The PDF is printed in a tab of the shared browser pool, waiting for a free tab if
+// as many PDFs as the parallelism allows are being printed; the span of ctx is the
+// parent of the pdf.print span. Waiting and printing stop when ctx is done, returning
+// an error that wraps ctx.Err(); without a deadline in ctx, printing is bounded by
+// RenderTimeout.
 // Returns error if PDF generation fails or output file cannot be written.
-func GeneratePDFChromedp(html, outputPath string, logger logging.Logger) error {
-	pdfLock.Lock()
-	defer pdfLock.Unlock()
+func GeneratePDFChromedp(ctx context.Context, html, outputPath string, logger logging.Logger) (err error) {
+	ctx, span := tracing.Start(ctx, "pdf.print")
+	defer func() { tracing.End(span, err) }()
 
 	if html == "" {
 		return errors.New("input HTML is empty")
@@ -29,45 +86,16 @@ func GeneratePDFChromedp(html, outputPath string, logger logging.Logger) error {
 	if outputPath == "" {
 		return errors.New("output path is empty")
 	}
-
-	ctx, cancel := chromedp.NewContext(context.Background())
-	defer cancel()
-
-	// Create a temporary HTML file
-	tmpFile, tmpErr := os.CreateTemp("", "invoice-*.html")
-	if tmpErr != nil {
-		return tmpErr
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, RenderTimeout)
+		defer cancel()
 	}
-	defer os.Remove(tmpFile.Name())
-	if _, writeErr := io.WriteString(tmpFile, html); writeErr != nil {
-		tmpFile.Close()
-		return writeErr
-	}
-	tmpFile.Close()
-
-	var pdfBuf []byte
-	url := "file://" + tmpFile.Name()
-
-	timeoutCtx, cancelTimeout := context.WithTimeout(ctx, 30*time.Second)
-	defer cancelTimeout()
-
-	logger.Debug("Navigating to HTML file", &logging.LogFields{URL: url})
 
-	err := chromedp.Run(timeoutCtx,
-		chromedp.Navigate(url),
-		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			buf, _, pdfErr := page.PrintToPDF().WithPrintBackground(true).Do(ctx)
-			if pdfErr != nil {
-				logger.Error("PrintToPDF error", &logging.LogFields{Error: pdfErr.Error()})
-				return pdfErr
-			}
-			pdfBuf = buf
-			return nil
-		}),
-	)
+	logger.Debug("Printing HTML in browser pool", &logging.LogFields{File: outputPath})
+	pdfBuf, err := defaultPool().Print(ctx, html)
 	if err != nil {
-		logger.Error("chromedp.Run error", &logging.LogFields{Error: err.Error()})
+		logger.Error("Printing PDF failed", &logging.LogFields{Error: err.Error()})
 		return err
 	}
 
@@ -1,6 +1,9 @@
 package pdf_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"invoiceformats/pkg/logging"
 	"invoiceformats/pkg/pdf"
 	"invoiceformats/testutils"
@@ -13,22 +16,76 @@ func getTestLogger() logging.Logger {
 }
 
 func TestGeneratePDFChromedp_EmptyInput(t *testing.T) {
-	err := pdf.GeneratePDFChromedp("", "out.pdf", getTestLogger())
+	err := pdf.GeneratePDFChromedp(context.Background(), "", "out.pdf", getTestLogger())
 	if err == nil {
 		t.Error("expected error for empty HTML input")
 	}
 }
 
 func TestGeneratePDFChromedp_EmptyOutputPath(t *testing.T) {
-	err := pdf.GeneratePDFChromedp("<html></html>", "", getTestLogger())
+	err := pdf.GeneratePDFChromedp(context.Background(), "<html></html>", "", getTestLogger())
 	if err == nil {
 		t.Error("expected error for empty output path")
 	}
 }
 
+func TestPool_Size(t *testing.T) {
+	if got := pdf.NewPool(0).Size(); got != pdf.DefaultParallelism {
+		t.Errorf("expected default parallelism %d, got %d", pdf.DefaultParallelism, got)
+	}
+	if got := pdf.NewPool(2).Size(); got != 2 {
+		t.Errorf("expected size 2, got %d", got)
+	}
+}
+
+func TestPool_PrintCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := pdf.NewPool(1).Print(ctx, "<html></html>")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPool_PrintAfterClose(t *testing.T) {
+	p := pdf.NewPool(1)
+	p.Close()
+	p.Close()
+	_, err := p.Print(context.Background(), "<html></html>")
+	if !errors.Is(err, pdf.ErrPoolClosed) {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+	if pdf.Busy() != 0 || pdf.Overdue() != 0 {
+		t.Error("expected no PDF being printed")
+	}
+}
+
+func TestZugferdEmbedder_ExtractXML(t *testing.T) {
+	embedder := &pdf.ZugferdEmbedder{}
+	xml := []byte("<rsm:CrossIndustryInvoice>\nstream\n</rsm:CrossIndustryInvoice>")
+	out, err := embedder.EmbedXML([]byte("%PDF-1.7\n"), xml, "invoice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := embedder.ExtractXML(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, xml) {
+		t.Errorf("extracted %q, want %q", got, xml)
+	}
+
+	if _, err := embedder.ExtractXML([]byte("%PDF-1.7\n")); !errors.Is(err, pdf.ErrNoEmbeddedXML) {
+		t.Errorf("expected ErrNoEmbeddedXML, got %v", err)
+	}
+	if _, err := embedder.ExtractXML(out[:bytes.Index(out, xml)+10]); !errors.Is(err, pdf.ErrNoEmbeddedXML) {
+		t.Errorf("expected ErrNoEmbeddedXML for a truncated PDF, got %v", err)
+	}
+}
+
 func TestGeneratePDFChromedp_BasicHTML(t *testing.T) {
 	outPath := "test_invoice.pdf"
-	err := pdf.GeneratePDFChromedp("<html><body><h1>Test</h1></body></html>", outPath, getTestLogger())
+	err := pdf.GeneratePDFChromedp(context.Background(), "<html><body><h1>Test</h1></body></html>", outPath, getTestLogger())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -13,6 +13,9 @@ type Embedder interface {
 	EmbedXML(pdf []byte, xml []byte, description string) ([]byte, error)
 }
 
+// ErrNoEmbeddedXML is returned by ExtractXML for a PDF without an embedded XML file.
+var ErrNoEmbeddedXML = errors.New("PDF carries no embedded XML")
+
 // ZugferdEmbedder implements PDF embedding for ZUGFeRD XML.
 type ZugferdEmbedder struct{}
 
@@ -67,4 +70,28 @@ endstream
 	return out.Bytes(), nil
 }
 
+// ExtractXML returns the XML file embedded by EmbedXML, the last one if the PDF had XML
+// embedded more than once.
+func (e *ZugferdEmbedder) ExtractXML(pdf []byte) ([]byte, error) {
+	start := bytes.LastIndex(pdf, []byte("/Type /EmbeddedFile"))
+	if start < 0 {
+		return nil, ErrNoEmbeddedXML
+	}
+	obj := pdf[start:]
+	lengthAt := bytes.Index(obj, []byte("/Length "))
+	streamAt := bytes.Index(obj, []byte("stream\n"))
+	if lengthAt < 0 || streamAt < lengthAt {
+		return nil, fmt.Errorf("%w: malformed embedded file", ErrNoEmbeddedXML)
+	}
+	var length int
+	if _, err := fmt.Sscanf(string(obj[lengthAt+len("/Length "):streamAt]), "%d", &length); err != nil {
+		return nil, fmt.Errorf("%w: malformed embedded file length: %v", ErrNoEmbeddedXML, err)
+	}
+	data := obj[streamAt+len("stream\n"):]
+	if length <= 0 || length > len(data) {
+		return nil, fmt.Errorf("%w: embedded file is truncated", ErrNoEmbeddedXML)
+	}
+	return data[:length], nil
+}
+
 var _ Embedder = (*ZugferdEmbedder)(nil)
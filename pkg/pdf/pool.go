@@ -0,0 +1,357 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"invoiceformats/pkg/metrics"
+
+	"github.com/chromedp/cdproto/inspector"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultParallelism is the number of PDFs printed at the same time if no other
+// parallelism is configured.
+const DefaultParallelism = 4
+
+// ErrTabCrashed is returned when the browser tab printing a PDF crashed. Print retries
+// such PDFs once in a new tab.
+var ErrTabCrashed = errors.New("browser tab crashed")
+
+// ErrPoolClosed is returned by Print after Close.
+var ErrPoolClosed = errors.New("browser pool closed")
+
+// waitForContent resolves once the document set by page.SetDocumentContent has loaded
+// its stylesheets, images and fonts, as navigating to a page would have waited for.
+const waitForContent = `new Promise(resolve => {
+	const check = () => document.readyState === "complete"
+		? document.fonts.ready.then(() => resolve(true))
+		: setTimeout(check, 20);
+	check();
+})`
+
+// Pool prints PDFs in the tabs of a shared headless browser. The browser is started on
+// the first Print and kept running, and so are the tabs, so later PDFs are printed
+// without starting Chrome. Up to Size PDFs are printed at the same time, each in its
+// own tab; tabs that crashed or failed are closed and replaced, and the browser is
+// restarted if it exited. A Pool is safe for concurrent use.
+type Pool struct {
+	size      int
+	allocOpts []chromedp.ExecAllocatorOption
+	slots     chan struct{} // Held while printing; limits the PDFs printed at once
+	closeOnce sync.Once
+
+	mu            sync.Mutex
+	browser       context.Context // nil until started; done once the browser exited
+	cancelBrowser context.CancelFunc
+	idle          []*tab
+	closed        bool
+}
+
+// NewPool creates a pool that prints up to size PDFs at the same time, DefaultParallelism
+// if size is not positive. Without allocator options, Chrome is started with the
+// chromedp defaults.
+func NewPool(size int, opts ...chromedp.ExecAllocatorOption) *Pool {
+	if size <= 0 {
+		size = DefaultParallelism
+	}
+	if len(opts) == 0 {
+		opts = chromedp.DefaultExecAllocatorOptions[:]
+	}
+	return &Pool{size: size, allocOpts: opts, slots: make(chan struct{}, size)}
+}
+
+// Size returns the number of PDFs the pool prints at the same time.
+func (p *Pool) Size() int {
+	return p.size
+}
+
+// Print renders html in a tab of the browser and prints it to a PDF, waiting for a free
+// tab if Size PDFs are being printed. It stops when ctx is done, returning an error
+// that wraps ctx.Err().
+func (p *Pool) Print(ctx context.Context, html string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	trace.SpanFromContext(ctx).AddEvent("browser slot acquired") // Time before it was spent waiting for other PDFs
+
+	done := startPrinting(ctx)
+	var err error
+	defer func() { done(err) }()
+	for retried := false; ; retried = true {
+		var t *tab
+		t, err = p.acquire()
+		if err != nil {
+			return nil, err
+		}
+		var buf []byte
+		buf, err = t.print(ctx, html)
+		if err == nil {
+			p.release(t)
+			return buf, nil
+		}
+		t.close()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if !errors.Is(err, ctxErr) {
+				// The browser reports the end of ctx as its own error
+				err = fmt.Errorf("%w: %w", ctxErr, err)
+			}
+			return nil, err
+		}
+		if retried || !errors.Is(err, ErrTabCrashed) {
+			return nil, err
+		}
+	}
+}
+
+// Check opens a tab in the browser of the pool and loads a blank page, which fails if
+// Chrome is missing or does not answer before ctx is done. It does not wait for a free
+// tab, so a pool busy printing still passes.
+func (p *Pool) Check(ctx context.Context) error {
+	t, err := p.acquire()
+	if err != nil {
+		return err
+	}
+	runCtx, cancel := t.run(ctx)
+	defer cancel()
+	if err := chromedp.Run(runCtx, chromedp.Navigate("about:blank")); err != nil {
+		t.close()
+		return err
+	}
+	p.release(t)
+	return nil
+}
+
+// Close waits for the PDFs being printed, then closes the tabs and stops the browser.
+// Print fails with ErrPoolClosed afterwards.
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() {
+		for i := 0; i < p.size; i++ {
+			p.slots <- struct{}{}
+		}
+		p.mu.Lock()
+		p.closed = true
+		p.stopBrowser()
+		p.mu.Unlock()
+		for i := 0; i < p.size; i++ {
+			<-p.slots
+		}
+	})
+}
+
+// acquire returns an idle tab or opens a new one, starting the browser if it is not
+// running.
+func (p *Pool) acquire() (*tab, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	if p.browser == nil || p.browser.Err() != nil {
+		if err := p.startBrowser(); err != nil {
+			return nil, err
+		}
+	}
+	for len(p.idle) > 0 {
+		t := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if t.ctx.Err() == nil && !t.crashed.Load() {
+			return t, nil
+		}
+		t.close()
+	}
+	return newTab(p.browser)
+}
+
+// release returns a tab to the idle tabs, unless the browser has been replaced or
+// stopped meanwhile.
+func (p *Pool) release(t *tab) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || t.browser != p.browser || len(p.idle) >= p.size {
+		t.close()
+		return
+	}
+	p.idle = append(p.idle, t)
+}
+
+// startBrowser starts Chrome, replacing a browser that exited. The caller holds p.mu.
+func (p *Pool) startBrowser() error {
+	p.stopBrowser()
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), p.allocOpts...)
+	browser, cancel := chromedp.NewContext(allocCtx)
+	if err := chromedp.Run(browser); err != nil {
+		cancel()
+		cancelAlloc()
+		return fmt.Errorf("start browser: %w", err)
+	}
+	p.browser = browser
+	p.cancelBrowser = func() {
+		cancel()
+		cancelAlloc()
+	}
+	return nil
+}
+
+// stopBrowser closes the idle tabs and stops the browser. The caller holds p.mu.
+func (p *Pool) stopBrowser() {
+	for _, t := range p.idle {
+		t.close()
+	}
+	p.idle = nil
+	if p.cancelBrowser != nil {
+		p.cancelBrowser()
+		p.browser, p.cancelBrowser = nil, nil
+	}
+}
+
+// tab is a browser tab that prints one PDF at a time.
+type tab struct {
+	browser context.Context // The browser the tab was opened in
+	ctx     context.Context
+	cancel  context.CancelFunc
+	crashed atomic.Bool
+
+	mu    sync.Mutex
+	abort context.CancelFunc // Cancels the running print
+}
+
+func newTab(browser context.Context) (*tab, error) {
+	ctx, cancel := chromedp.NewContext(browser)
+	t := &tab{browser: browser, ctx: ctx, cancel: cancel}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if _, ok := ev.(*inspector.EventTargetCrashed); ok {
+			t.crashed.Store(true)
+			t.mu.Lock()
+			if t.abort != nil {
+				t.abort()
+			}
+			t.mu.Unlock()
+		}
+	})
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, fmt.Errorf("open browser tab: %w", err)
+	}
+	return t, nil
+}
+
+// run returns the context of an action in the tab, which ends with ctx and when the
+// tab crashes.
+func (t *tab) run(ctx context.Context) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithCancel(t.ctx)
+	stop := context.AfterFunc(ctx, cancel)
+	t.mu.Lock()
+	t.abort = cancel
+	t.mu.Unlock()
+	return runCtx, func() {
+		stop()
+		cancel()
+		t.mu.Lock()
+		t.abort = nil
+		t.mu.Unlock()
+	}
+}
+
+// print sets the document of the tab to html and prints it.
+func (t *tab) print(ctx context.Context, html string) (pdf []byte, err error) {
+	runCtx, cancel := t.run(ctx)
+	defer cancel()
+	err = chromedp.Run(runCtx,
+		chromedp.Navigate("about:blank"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			tree, err := page.GetFrameTree().Do(ctx)
+			if err != nil {
+				return err
+			}
+			return page.SetDocumentContent(tree.Frame.ID, html).Do(ctx)
+		}),
+		chromedp.Evaluate(waitForContent, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			pdf, _, err = page.PrintToPDF().WithPrintBackground(true).Do(ctx)
+			return err
+		}),
+	)
+	if t.crashed.Load() {
+		return nil, fmt.Errorf("%w: %v", ErrTabCrashed, err)
+	}
+	return pdf, err
+}
+
+func (t *tab) close() {
+	t.cancel()
+}
+
+// printing records the PDFs being printed for Busy and Overdue.
+type printing struct {
+	start, deadline time.Time
+}
+
+var (
+	busyMu sync.Mutex
+	busy   = make(map[*printing]struct{})
+)
+
+// startPrinting records a PDF as being printed until the returned function is called
+// with the outcome.
+func startPrinting(ctx context.Context) func(err error) {
+	pr := &printing{start: time.Now()}
+	pr.deadline, _ = ctx.Deadline()
+	busyMu.Lock()
+	busy[pr] = struct{}{}
+	busyMu.Unlock()
+	metrics.PDFPrinting.Inc()
+	return func(err error) {
+		busyMu.Lock()
+		delete(busy, pr)
+		busyMu.Unlock()
+		metrics.PDFPrinting.Dec()
+		outcome := metrics.OutcomeSuccess
+		if err != nil {
+			outcome = "error"
+		}
+		metrics.PDFDuration.WithLabelValues(outcome).Observe(time.Since(pr.start).Seconds())
+	}
+}
+
+// Busy returns how long the longest-running PDF being printed has taken so far, or 0 if
+// no PDF is being printed.
+func Busy() time.Duration {
+	busyMu.Lock()
+	defer busyMu.Unlock()
+	var longest time.Duration
+	for pr := range busy {
+		longest = max(longest, time.Since(pr.start))
+	}
+	return longest
+}
+
+// Overdue returns how long a PDF being printed has run past the deadline of its
+// context, the longest if several have, or 0 if none has. Printing stops at the
+// deadline, so a value well above 0 means the browser hangs.
+func Overdue() time.Duration {
+	busyMu.Lock()
+	defer busyMu.Unlock()
+	var overdue time.Duration
+	for pr := range busy {
+		if !pr.deadline.IsZero() {
+			overdue = max(overdue, time.Since(pr.deadline))
+		}
+	}
+	return overdue
+}
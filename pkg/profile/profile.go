@@ -0,0 +1,323 @@
+// Package profile provides the registry of provider and client profiles that invoice
+// files reference by ID instead of repeating the party details.
+//
+// Profiles are stored one per file in the providers/ and clients/ subdirectories of the
+// profile directory; the file name is the profile ID. An invoice references a profile
+// either by ID alone or with overrides:
+//
+//	provider: techcorp
+//	client:
+//	  profile: pixel-dynamics
+//	  email: accounts@pixel-dynamics.example
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/validation"
+)
+
+// ReferenceKey is the key of a party mapping that names the profile it overrides.
+const ReferenceKey = "profile"
+
+// ErrUnknownProfile is returned for references to profiles that do not exist.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// namespace derives stable party IDs for profiles that do not set one.
+var namespace = uuid.MustParse("3f0b6a52-7c1e-4d3a-9a53-2b8c4e6f1d07")
+
+// Registry holds the provider and client profiles, keyed by ID.
+type Registry struct {
+	providers map[string]models.CompanyInfo
+	clients   map[string]models.ClientInfo
+}
+
+// Load reads and validates all profiles below dir. A missing directory, or an empty
+// dir, holds no profiles.
+func Load(dir string) (*Registry, error) {
+	r := &Registry{providers: map[string]models.CompanyInfo{}, clients: map[string]models.ClientInfo{}}
+	if dir == "" {
+		return r, nil
+	}
+	v := validation.NewValidator()
+	err := loadKind(filepath.Join(dir, "providers"), func(id, path string, raw []byte) error {
+		var p models.CompanyInfo
+		if err := unmarshal(path, raw, &p); err != nil {
+			return err
+		}
+		if p.ID == uuid.Nil {
+			p.ID = uuid.NewSHA1(namespace, []byte("providers/"+id))
+		}
+		if err := v.ValidateStruct(&p); err != nil {
+			return err
+		}
+		r.providers[id] = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = loadKind(filepath.Join(dir, "clients"), func(id, path string, raw []byte) error {
+		var c models.ClientInfo
+		if err := unmarshal(path, raw, &c); err != nil {
+			return err
+		}
+		if c.ID == uuid.Nil {
+			c.ID = uuid.NewSHA1(namespace, []byte("clients/"+id))
+		}
+		if err := v.ValidateStruct(&c); err != nil {
+			return err
+		}
+		r.clients[id] = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// loadKind calls add for each profile file in dir.
+func loadKind(dir string, add func(id, path string, raw []byte) error) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]string)
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		id := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		if other, ok := seen[id]; ok {
+			return fmt.Errorf("duplicate profile %q in %s and %s", id, other, path)
+		}
+		seen[id] = path
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := add(id, path, raw); err != nil {
+			return fmt.Errorf("profile %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func unmarshal(path string, raw []byte, v interface{}) error {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return json.Unmarshal(raw, v)
+	}
+	return yaml.Unmarshal(raw, v)
+}
+
+// Provider returns a copy of the provider profile with the given ID.
+func (r *Registry) Provider(id string) (models.CompanyInfo, error) {
+	if r != nil {
+		if p, ok := r.providers[id]; ok {
+			return p, nil
+		}
+	}
+	return models.CompanyInfo{}, fmt.Errorf("%w: provider %q", ErrUnknownProfile, id)
+}
+
+// Client returns a copy of the client profile with the given ID.
+func (r *Registry) Client(id string) (models.ClientInfo, error) {
+	if r != nil {
+		if c, ok := r.clients[id]; ok {
+			return c, nil
+		}
+	}
+	return models.ClientInfo{}, fmt.Errorf("%w: client %q", ErrUnknownProfile, id)
+}
+
+// ProviderIDs returns the IDs of all provider profiles, sorted.
+func (r *Registry) ProviderIDs() []string {
+	if r == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(r.providers))
+	for id := range r.providers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ClientIDs returns the IDs of all client profiles, sorted.
+func (r *Registry) ClientIDs() []string {
+	if r == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(r.clients))
+	for id := range r.clients {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// reference is a party given as a profile ID, with the overrides if there are any.
+type reference struct {
+	id       string
+	override func(v interface{}) error // Decodes the overrides into v; nil without overrides
+}
+
+// DecodeYAML decodes invoice data from a YAML node, resolving profile references of
+// the provider and client. The node is left unchanged. A nil registry has no profiles.
+func (r *Registry) DecodeYAML(n *yaml.Node, data *models.InvoiceData) error {
+	doc := n
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return n.Decode(data)
+	}
+
+	rest := *doc
+	rest.Content = nil
+	refs := make(map[string]reference)
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, value := doc.Content[i], doc.Content[i+1]
+		if key.Value == "provider" || key.Value == "client" {
+			if ref, ok := yamlReference(value); ok {
+				refs[key.Value] = ref
+				continue
+			}
+		}
+		rest.Content = append(rest.Content, key, value)
+	}
+	if err := rest.Decode(data); err != nil {
+		return err
+	}
+	return r.apply(refs, data)
+}
+
+// yamlReference reports whether a party node references a profile.
+func yamlReference(n *yaml.Node) (reference, bool) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == "!!str" && n.Value != "" {
+			return reference{id: n.Value}, true
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value != ReferenceKey {
+				continue
+			}
+			override := *n
+			override.Content = append(append([]*yaml.Node{}, n.Content[:i]...), n.Content[i+2:]...)
+			return reference{id: n.Content[i+1].Value, override: override.Decode}, true
+		}
+	}
+	return reference{}, false
+}
+
+// DecodeJSON decodes invoice data from JSON, resolving profile references of the
+// provider and client. A nil registry has no profiles.
+func (r *Registry) DecodeJSON(raw []byte, data *models.InvoiceData) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return json.Unmarshal(raw, data)
+	}
+	refs := make(map[string]reference)
+	for _, key := range []string{"provider", "client"} {
+		ref, ok, err := jsonReference(doc[key])
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if ok {
+			refs[key] = ref
+			delete(doc, key)
+		}
+	}
+	if len(refs) == 0 {
+		return json.Unmarshal(raw, data)
+	}
+	rest, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rest, data); err != nil {
+		return err
+	}
+	return r.apply(refs, data)
+}
+
+// jsonReference reports whether a party value references a profile.
+func jsonReference(raw json.RawMessage) (reference, bool, error) {
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) > 0 && raw[0] == '"':
+		var id string
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return reference{}, false, err
+		}
+		return reference{id: id}, id != "", nil
+	case len(raw) > 0 && raw[0] == '{':
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return reference{}, false, err
+		}
+		idRaw, ok := fields[ReferenceKey]
+		if !ok {
+			return reference{}, false, nil
+		}
+		var id string
+		if err := json.Unmarshal(idRaw, &id); err != nil {
+			return reference{}, false, fmt.Errorf("%s: %w", ReferenceKey, err)
+		}
+		delete(fields, ReferenceKey)
+		override, err := json.Marshal(fields)
+		if err != nil {
+			return reference{}, false, err
+		}
+		return reference{id: id, override: func(v interface{}) error { return json.Unmarshal(override, v) }}, true, nil
+	}
+	return reference{}, false, nil
+}
+
+// apply replaces the referenced parties by their profiles with the overrides applied.
+func (r *Registry) apply(refs map[string]reference, data *models.InvoiceData) error {
+	if ref, ok := refs["provider"]; ok {
+		p, err := r.Provider(ref.id)
+		if err != nil {
+			return err
+		}
+		if ref.override != nil {
+			if err := ref.override(&p); err != nil {
+				return fmt.Errorf("provider %s: %w", ref.id, err)
+			}
+		}
+		data.Provider = p
+	}
+	if ref, ok := refs["client"]; ok {
+		c, err := r.Client(ref.id)
+		if err != nil {
+			return err
+		}
+		if ref.override != nil {
+			if err := ref.override(&c); err != nil {
+				return fmt.Errorf("client %s: %w", ref.id, err)
+			}
+		}
+		data.Client = c
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"invoiceformats/pkg/models"
+)
+
+func writeProfiles(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"providers/techcorp.yaml": `name: "TechCorp Solutions Ltd"
+email: "billing@techcorp.example"
+vat_id: "DE123456789"
+iban: "DE89370400440532013000"
+address:
+  street: "Hauptstr. 1"
+  city: "Berlin"
+  postal_code: "10115"
+  country: "DE"
+`,
+		"clients/pixel-dynamics.yml": `name: "Pixel Dynamics GmbH"
+email: "office@pixel-dynamics.example"
+business: true
+address:
+  street: "Marktplatz 5"
+  city: "Hamburg"
+  postal_code: "20095"
+  country: "DE"
+`,
+		"clients/acme.json": `{"name": "ACME Corp", "email": "ap@acme.example", "address": {"street": "1 Main St", "city": "Springfield", "country": "US"}}`,
+		"clients/notes.txt": "ignored",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	r, err := Load(writeProfiles(t))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"techcorp"}, r.ProviderIDs())
+	assert.Equal(t, []string{"acme", "pixel-dynamics"}, r.ClientIDs())
+
+	c, err := r.Client("pixel-dynamics")
+	require.NoError(t, err)
+	assert.Equal(t, "Hamburg", c.Address.City)
+	again, _ := r.Client("pixel-dynamics")
+	assert.Equal(t, c.ID, again.ID, "profiles get a stable party ID")
+	assert.NotEqual(t, c.ID.String(), "00000000-0000-0000-0000-000000000000")
+
+	_, err = r.Provider("unknown")
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+
+	empty, err := Load(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, empty.ClientIDs())
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "clients"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "clients", "broken.yaml"), []byte("name: \"No Address\"\nemail: \"not-an-email\"\n"), 0644))
+	_, err := Load(dir)
+	assert.ErrorContains(t, err, "broken.yaml")
+
+	dup := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dup, "providers"), 0755))
+	for _, name := range []string{"same.yaml", "same.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dup, "providers", name), []byte(`{"name": "A", "email": "a@example.com", "address": {"street": "s", "city": "c", "country": "DE"}}`), 0644))
+	}
+	_, err = Load(dup)
+	assert.ErrorContains(t, err, "duplicate")
+}
+
+func TestDecodeYAML(t *testing.T) {
+	r, err := Load(writeProfiles(t))
+	require.NoError(t, err)
+
+	src := `provider: techcorp
+client:
+  profile: pixel-dynamics
+  email: "accounts@pixel-dynamics.example"
+  address:
+    city: "Altona"
+invoice:
+  number: "RE-1"
+`
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(src), &doc))
+	var data models.InvoiceData
+	require.NoError(t, r.DecodeYAML(&doc, &data))
+	assert.Equal(t, "TechCorp Solutions Ltd", data.Provider.Name)
+	assert.Equal(t, "DE89370400440532013000", data.Provider.IBAN)
+	assert.Equal(t, "Pixel Dynamics GmbH", data.Client.Name)
+	assert.Equal(t, "accounts@pixel-dynamics.example", data.Client.Email)
+	assert.Equal(t, "Altona", data.Client.Address.City)
+	assert.Equal(t, "Marktplatz 5", data.Client.Address.Street, "nested overrides keep the other fields")
+	assert.Equal(t, "RE-1", data.Invoice.Number)
+
+	// Overrides do not leak into the registry
+	c, _ := r.Client("pixel-dynamics")
+	assert.Equal(t, "Hamburg", c.Address.City)
+
+	// Inline parties are decoded as before
+	require.NoError(t, yaml.Unmarshal([]byte("client:\n  name: \"Inline\"\n"), &doc))
+	data = models.InvoiceData{}
+	require.NoError(t, r.DecodeYAML(&doc, &data))
+	assert.Equal(t, "Inline", data.Client.Name)
+
+	require.NoError(t, yaml.Unmarshal([]byte("client: nobody\n"), &doc))
+	assert.ErrorIs(t, r.DecodeYAML(&doc, &models.InvoiceData{}), ErrUnknownProfile)
+	assert.ErrorIs(t, (*Registry)(nil).DecodeYAML(&doc, &models.InvoiceData{}), ErrUnknownProfile)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	r, err := Load(writeProfiles(t))
+	require.NoError(t, err)
+
+	var data models.InvoiceData
+	src := `{"provider": "techcorp", "client": {"profile": "acme", "vat_id": "US123"}, "invoice": {"number": "RE-2"}}`
+	require.NoError(t, r.DecodeJSON([]byte(src), &data))
+	assert.Equal(t, "TechCorp Solutions Ltd", data.Provider.Name)
+	assert.Equal(t, "ACME Corp", data.Client.Name)
+	assert.Equal(t, "US123", data.Client.VATID)
+	assert.Equal(t, "RE-2", data.Invoice.Number)
+
+	err = r.DecodeJSON([]byte(`{"provider": "nobody"}`), &models.InvoiceData{})
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+}
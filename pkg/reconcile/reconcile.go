@@ -0,0 +1,218 @@
+// Package reconcile matches bank transactions to open invoices.
+//
+// An incoming payment is matched in this order:
+//
+//  1. by invoice numbers quoted in the remittance information; the amount may be less
+//     than outstanding (partial payment) or cover several quoted invoices exactly
+//  2. by amount and the client's IBAN when no number is quoted
+//
+// Everything that cannot be attributed unambiguously, such as overpayments, several
+// candidates or an amount match from an unknown account, is returned for review.
+package reconcile
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"invoiceformats/pkg/bank"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/repository"
+)
+
+// Rule names how a match was found.
+type Rule string
+
+const (
+	RuleNumber     Rule = "number"      // Invoice number in the remittance information
+	RuleNumbers    Rule = "numbers"     // Several invoice numbers settled by one transfer
+	RuleAmountIBAN Rule = "amount_iban" // Amount and client IBAN
+)
+
+// Allocation assigns (part of) a transaction to an invoice.
+type Allocation struct {
+	Number  string          `json:"number"`
+	Amount  decimal.Decimal `json:"amount"`
+	Partial bool            `json:"partial"` // Invoice remains open after this payment
+}
+
+// Match is a transaction attributed to invoices.
+type Match struct {
+	Transaction bank.Transaction `json:"transaction"`
+	Rule        Rule             `json:"rule"`
+	Allocations []Allocation     `json:"allocations"`
+}
+
+// Review is a transaction that needs a manual decision.
+type Review struct {
+	Transaction bank.Transaction `json:"transaction"`
+	Candidates  []string         `json:"candidates"`
+	Reason      string           `json:"reason"`
+}
+
+// Result is the outcome of matching a statement.
+type Result struct {
+	Matched   []Match            `json:"matched"`
+	Review    []Review           `json:"review"`
+	Unmatched []bank.Transaction `json:"unmatched"` // Incoming payments without any candidate
+	Skipped   []bank.Transaction `json:"skipped"`   // Debits, other currencies and payments recorded before
+}
+
+// Reconcile matches transactions against the invoice records. Credit notes and records
+// that are not open are only used to recognize payments imported before. Allocations
+// of earlier transactions are taken into account for later ones in the same statement.
+func Reconcile(txs []bank.Transaction, records []*repository.Record) *Result {
+	result := &Result{}
+	outstanding := make(map[string]decimal.Decimal)
+	var open []*repository.Record
+	for _, rec := range records {
+		if rec.IsOpen() && rec.Type != models.InvoiceTypeCreditNote && rec.Outstanding().IsPositive() {
+			open = append(open, rec)
+			outstanding[rec.Number] = rec.Outstanding()
+		}
+	}
+
+	for _, tx := range txs {
+		if !tx.Credit || !tx.Amount.IsPositive() || recorded(records, tx.ID) {
+			result.Skipped = append(result.Skipped, tx)
+			continue
+		}
+		var candidates []*repository.Record
+		for _, rec := range open {
+			if outstanding[rec.Number].IsPositive() && (tx.Currency == "" || rec.Currency == tx.Currency) {
+				candidates = append(candidates, rec)
+			}
+		}
+
+		match, review := matchTransaction(tx, candidates, outstanding)
+		switch {
+		case match != nil:
+			for _, a := range match.Allocations {
+				outstanding[a.Number] = outstanding[a.Number].Sub(a.Amount)
+			}
+			result.Matched = append(result.Matched, *match)
+		case review != nil:
+			result.Review = append(result.Review, *review)
+		default:
+			result.Unmatched = append(result.Unmatched, tx)
+		}
+	}
+	return result
+}
+
+func matchTransaction(tx bank.Transaction, candidates []*repository.Record, outstanding map[string]decimal.Decimal) (*Match, *Review) {
+	// 1. Invoice numbers in the remittance information
+	var quoted []*repository.Record
+	for _, rec := range candidates {
+		if mentions(tx.RemittanceInfo, rec.Number) {
+			quoted = append(quoted, rec)
+		}
+	}
+	if len(quoted) == 1 {
+		rec := quoted[0]
+		open := outstanding[rec.Number]
+		if tx.Amount.GreaterThan(open) {
+			return nil, &Review{Transaction: tx, Candidates: []string{rec.Number},
+				Reason: "overpayment: " + tx.Amount.StringFixed(2) + " received, " + open.StringFixed(2) + " outstanding"}
+		}
+		return &Match{Transaction: tx, Rule: RuleNumber, Allocations: []Allocation{
+			{Number: rec.Number, Amount: tx.Amount, Partial: tx.Amount.LessThan(open)},
+		}}, nil
+	}
+	if len(quoted) > 1 {
+		total := decimal.Zero
+		for _, rec := range quoted {
+			total = total.Add(outstanding[rec.Number])
+		}
+		if !total.Equal(tx.Amount) {
+			return nil, &Review{Transaction: tx, Candidates: numbers(quoted),
+				Reason: "several invoices quoted and the amount does not settle them exactly"}
+		}
+		match := &Match{Transaction: tx, Rule: RuleNumbers}
+		for _, rec := range quoted {
+			match.Allocations = append(match.Allocations, Allocation{Number: rec.Number, Amount: outstanding[rec.Number]})
+		}
+		return match, nil
+	}
+
+	// 2. Amount, confirmed by the client's account
+	var byAmount, byAmountAndIBAN []*repository.Record
+	for _, rec := range candidates {
+		if !outstanding[rec.Number].Equal(tx.Amount) {
+			continue
+		}
+		byAmount = append(byAmount, rec)
+		if tx.IBAN != "" && clientIBAN(rec) == tx.IBAN {
+			byAmountAndIBAN = append(byAmountAndIBAN, rec)
+		}
+	}
+	switch {
+	case len(byAmountAndIBAN) == 1:
+		return &Match{Transaction: tx, Rule: RuleAmountIBAN, Allocations: []Allocation{
+			{Number: byAmountAndIBAN[0].Number, Amount: tx.Amount},
+		}}, nil
+	case len(byAmountAndIBAN) > 1:
+		return nil, &Review{Transaction: tx, Candidates: numbers(byAmountAndIBAN), Reason: "several open invoices of this client with the same amount"}
+	case len(byAmount) > 0:
+		return nil, &Review{Transaction: tx, Candidates: numbers(byAmount), Reason: "amount matches but neither invoice number nor account"}
+	}
+	return nil, nil
+}
+
+// mentions reports whether text quotes the invoice number. Separators in the number may
+// be written differently or left out, e.g. "RE 2025 0042" or "RE20250042" for
+// RE-2025-0042, but the number must not be part of a longer number.
+func mentions(text, number string) bool {
+	if text == "" || number == "" {
+		return false
+	}
+	var pattern strings.Builder
+	pattern.WriteString(`(?i)(?:^|[^A-Z0-9])`)
+	sep := false
+	for _, c := range number {
+		if isAlnum(c) {
+			if sep {
+				pattern.WriteString(`[\s\-/_.]*`)
+				sep = false
+			}
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		} else {
+			sep = true
+		}
+	}
+	pattern.WriteString(`(?:$|[^A-Z0-9])`)
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(text)
+}
+
+func isAlnum(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func clientIBAN(rec *repository.Record) string {
+	if rec.Data == nil {
+		return ""
+	}
+	return bank.NormalizeIBAN(rec.Data.Client.IBAN)
+}
+
+func recorded(records []*repository.Record, id string) bool {
+	for _, rec := range records {
+		if rec.HasPayment(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func numbers(records []*repository.Record) []string {
+	result := make([]string, len(records))
+	for i, rec := range records {
+		result[i] = rec.Number
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/pkg/bank"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/repository"
+)
+
+func record(number string, due int64, iban string) *repository.Record {
+	return &repository.Record{
+		Number:    number,
+		Status:    models.StatusSent,
+		Currency:  "EUR",
+		AmountDue: decimal.NewFromInt(due),
+		Data:      &models.InvoiceData{Client: models.ClientInfo{IBAN: iban}},
+	}
+}
+
+func credit(id string, amount int64, info, iban string) bank.Transaction {
+	return bank.Transaction{
+		ID:             id,
+		Date:           time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC),
+		Amount:         decimal.NewFromInt(amount),
+		Currency:       "EUR",
+		Credit:         true,
+		RemittanceInfo: info,
+		IBAN:           iban,
+	}
+}
+
+func TestMentions(t *testing.T) {
+	assert.True(t, mentions("Rechnung RE-2025-0042", "RE-2025-0042"))
+	assert.True(t, mentions("re 2025 0042, danke", "RE-2025-0042"))
+	assert.True(t, mentions("RE20250042", "RE-2025-0042"))
+	assert.False(t, mentions("RE-2025-00421", "RE-2025-0042"))
+	assert.False(t, mentions("XRE-2025-0042", "RE-2025-0042"))
+	assert.False(t, mentions("", "RE-2025-0042"))
+}
+
+func TestReconcile(t *testing.T) {
+	paid := record("RE-9", 100, "")
+	paid.Status = models.StatusPaid
+	paid.Payments = []repository.Payment{{Reference: "TX-OLD", Amount: decimal.NewFromInt(100)}}
+	records := []*repository.Record{
+		record("RE-1", 1000, ""),
+		record("RE-2", 500, "DE02120300000000202051"),
+		record("RE-3", 200, ""),
+		record("RE-4", 300, ""),
+		record("RE-5", 700, "DE02120300000000202051"),
+		record("RE-6", 700, "DE02120300000000202051"),
+		paid,
+	}
+
+	txs := []bank.Transaction{
+		credit("TX-1", 400, "Teilzahlung RE-1", ""),                   // partial
+		credit("TX-2", 600, "RE 1", ""),                               // rest of RE-1
+		credit("TX-3", 500, "Danke", "DE02120300000000202051"),        // amount and IBAN
+		credit("TX-4", 500, "RE-3 and RE-4", ""),                      // settles both
+		credit("TX-5", 700, "", "DE02120300000000202051"),             // two candidates
+		credit("TX-6", 50, "Spende", ""),                              // no candidate
+		credit("TX-OLD", 100, "RE-9", ""),                             // imported before
+		{ID: "TX-7", Amount: decimal.NewFromInt(10), Currency: "EUR"}, // debit
+	}
+
+	result := Reconcile(txs, records)
+	require.Len(t, result.Matched, 4)
+	assert.Equal(t, RuleNumber, result.Matched[0].Rule)
+	assert.Equal(t, []Allocation{{Number: "RE-1", Amount: decimal.NewFromInt(400), Partial: true}}, result.Matched[0].Allocations)
+	assert.False(t, result.Matched[1].Allocations[0].Partial)
+	assert.Equal(t, RuleAmountIBAN, result.Matched[2].Rule)
+	assert.Equal(t, "RE-2", result.Matched[2].Allocations[0].Number)
+	assert.Equal(t, RuleNumbers, result.Matched[3].Rule)
+	require.Len(t, result.Matched[3].Allocations, 2)
+
+	require.Len(t, result.Review, 1)
+	assert.Equal(t, []string{"RE-5", "RE-6"}, result.Review[0].Candidates)
+	require.Len(t, result.Unmatched, 1)
+	assert.Equal(t, "TX-6", result.Unmatched[0].ID)
+	assert.Len(t, result.Skipped, 2)
+}
+
+func TestReconcile_Review(t *testing.T) {
+	records := []*repository.Record{record("RE-1", 100, ""), record("RE-2", 100, ""), record("RE-3", 80, "")}
+
+	result := Reconcile([]bank.Transaction{
+		credit("TX-1", 150, "RE-1", ""),        // overpayment
+		credit("TX-2", 150, "RE-1 RE-2", ""),   // does not settle both
+		credit("TX-3", 80, "", "DE0000000000"), // amount only
+	}, records)
+
+	assert.Empty(t, result.Matched)
+	require.Len(t, result.Review, 3)
+	assert.Contains(t, result.Review[0].Reason, "overpayment")
+	assert.Equal(t, []string{"RE-1", "RE-2"}, result.Review[1].Candidates)
+	assert.Equal(t, []string{"RE-3"}, result.Review[2].Candidates)
+}
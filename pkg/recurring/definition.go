@@ -0,0 +1,224 @@
+package recurring
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/profile"
+)
+
+// DefaultOutput is the output path pattern used when a definition sets none.
+const DefaultOutput = "invoices/pdf/{{number}}.pdf"
+
+// Definition describes a recurring invoice: when it is issued, which period each
+// invoice covers and the invoice itself. String values in the invoice may use the
+// placeholders {{period_start}}, {{period_end}}, {{date}}, {{month}}, {{month_name}},
+// {{quarter}} and {{year}}, which are filled in per occurrence.
+type Definition struct {
+	ID       string    `yaml:"id"`       // Defaults to the file name
+	Schedule string    `yaml:"schedule"` // Cron expression or RRULE
+	Start    time.Time `yaml:"start"`    // First possible issue date (DTSTART)
+	End      time.Time `yaml:"end"`      // Optional last possible issue date
+	Period   Unit      `yaml:"period"`   // Billing period length; implied by the schedule if empty
+	Billing  Billing   `yaml:"billing"`  // advance (default) or arrears
+	Series   string    `yaml:"series"`   // Number series for sequential numbering
+	Template string    `yaml:"template"` // Invoice template
+	Output   string    `yaml:"output"`   // Output path pattern; may use {{number}} and the placeholders
+	Invoice  yaml.Node `yaml:"invoice"`  // Invoice data as in an invoice file, without a number
+
+	File     string `yaml:"-"` // File the definition was loaded from
+	schedule Schedule
+	profiles *profile.Registry
+}
+
+// Occurrence is one due invoice of a definition.
+type Occurrence struct {
+	Date   time.Time            // Invoice date
+	Period models.BillingPeriod // Period the invoice covers
+}
+
+// Load reads a definition from a YAML file and validates it. Provider and client of
+// the invoice may reference the profiles of the registry.
+func Load(path string, profiles *profile.Registry) (*Definition, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var d Definition
+	if err := yaml.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	d.File = path
+	d.profiles = profiles
+	if d.ID == "" {
+		d.ID = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := d.init(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &d, nil
+}
+
+// LoadDir loads all .yaml and .yml definitions in dir, sorted by ID. A missing
+// directory holds no definitions.
+func LoadDir(dir string, profiles *profile.Registry) ([]*Definition, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var defs []*Definition
+	seen := make(map[string]string)
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		d, err := Load(filepath.Join(dir, e.Name()), profiles)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[d.ID]; ok {
+			return nil, fmt.Errorf("duplicate recurring invoice id %q in %s and %s", d.ID, other, d.File)
+		}
+		seen[d.ID] = d.File
+		defs = append(defs, d)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].ID < defs[j].ID })
+	return defs, nil
+}
+
+// init applies defaults and checks the definition.
+func (d *Definition) init() error {
+	if d.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	s, err := ParseSchedule(d.Schedule)
+	if err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	d.schedule = s
+	if d.Start.IsZero() {
+		return fmt.Errorf("start is required")
+	}
+	if !d.End.IsZero() && d.End.Before(d.Start) {
+		return fmt.Errorf("end %s is before start %s", d.End.Format("2006-01-02"), d.Start.Format("2006-01-02"))
+	}
+	if d.Period == "" {
+		d.Period = s.Unit()
+	}
+	if !d.Period.Valid() {
+		return fmt.Errorf("unknown period %q (day, week, month, quarter, year)", d.Period)
+	}
+	if d.Billing == "" {
+		d.Billing = BillingAdvance
+	}
+	if !d.Billing.Valid() {
+		return fmt.Errorf("unknown billing %q (advance, arrears)", d.Billing)
+	}
+	if d.Output == "" {
+		d.Output = DefaultOutput
+	}
+	if d.Invoice.Kind == 0 {
+		return fmt.Errorf("invoice is required")
+	}
+	if _, err := d.Render(Occurrence{Date: d.Start}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Occurrences returns the invoices due from the start date up to and including until.
+func (d *Definition) Occurrences(until time.Time) ([]Occurrence, error) {
+	if !d.End.IsZero() && d.End.Before(until) {
+		until = d.End
+	}
+	var out []Occurrence
+	for _, date := range d.schedule.Occurrences(d.Start, until) {
+		p, err := Period(d.Period, d.Billing, date)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Occurrence{Date: date, Period: p})
+	}
+	return out, nil
+}
+
+// Render builds the invoice for an occurrence from the definition, with the
+// placeholders filled in, the invoice date set and the billing period set unless the
+// definition gives one.
+func (d *Definition) Render(o Occurrence) (*models.InvoiceData, error) {
+	node := substitute(&d.Invoice, o.Placeholders())
+	var data models.InvoiceData
+	if err := d.profiles.DecodeYAML(node, &data); err != nil {
+		return nil, fmt.Errorf("invoice: %w", err)
+	}
+	data.Invoice.Date = o.Date
+	if data.Invoice.BillingPeriod.IsZero() {
+		data.Invoice.BillingPeriod = o.Period
+	}
+	return &data, nil
+}
+
+// OutputFile returns the output path of the invoice with the given number.
+func (d *Definition) OutputFile(o Occurrence, number string) string {
+	values := o.Placeholders()
+	values["number"] = number
+	return expand(d.Output, values)
+}
+
+// Placeholders returns the placeholder values of the occurrence.
+func (o Occurrence) Placeholders() map[string]string {
+	start := o.Period.Start
+	return map[string]string{
+		"date":         o.Date.Format("2006-01-02"),
+		"period_start": start.Format("2006-01-02"),
+		"period_end":   o.Period.End.Format("2006-01-02"),
+		"month":        start.Format("01"),
+		"month_name":   start.Format("January"),
+		"quarter":      strconv.Itoa((int(start.Month())-1)/3 + 1),
+		"year":         start.Format("2006"),
+	}
+}
+
+// expand replaces {{name}} placeholders; unknown names are left as they are.
+func expand(s string, values map[string]string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	pairs := make([]string, 0, 2*len(values))
+	for k, v := range values {
+		pairs = append(pairs, "{{"+k+"}}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// substitute returns a copy of the node tree with the placeholders expanded in all
+// scalar values. A value that consisted only of placeholders is re-resolved, so
+// "{{period_end}}" can fill a date field.
+func substitute(n *yaml.Node, values map[string]string) *yaml.Node {
+	c := *n
+	if n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "{{") {
+		c.Value = expand(n.Value, values)
+		if strings.HasPrefix(n.Value, "{{") && strings.HasSuffix(n.Value, "}}") && c.Value != n.Value {
+			c.Tag, c.Style = "", 0
+		}
+	}
+	if len(n.Content) > 0 {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = substitute(child, values)
+		}
+	}
+	return &c
+}
@@ -0,0 +1,73 @@
+package recurring
+
+import (
+	"fmt"
+	"time"
+
+	"invoiceformats/pkg/models"
+)
+
+// Unit is the length of a billing period.
+type Unit string
+
+const (
+	UnitDay     Unit = "day"
+	UnitWeek    Unit = "week"
+	UnitMonth   Unit = "month"
+	UnitQuarter Unit = "quarter"
+	UnitYear    Unit = "year"
+)
+
+// Billing selects which period an invoice covers.
+type Billing string
+
+const (
+	// BillingAdvance bills the period the invoice date falls in, e.g. a subscription
+	BillingAdvance Billing = "advance"
+	// BillingArrears bills the period before it, e.g. hours worked last month
+	BillingArrears Billing = "arrears"
+)
+
+// Valid reports whether u is a known unit.
+func (u Unit) Valid() bool {
+	switch u {
+	case UnitDay, UnitWeek, UnitMonth, UnitQuarter, UnitYear:
+		return true
+	}
+	return false
+}
+
+// Valid reports whether b is a known billing mode.
+func (b Billing) Valid() bool {
+	return b == BillingAdvance || b == BillingArrears
+}
+
+// Period returns the calendar period of the given unit that contains date, or with
+// arrears billing the one before it. Weeks run from Monday to Sunday and the end date
+// is the last day of the period, inclusive.
+func Period(unit Unit, billing Billing, date time.Time) (models.BillingPeriod, error) {
+	start := day(date)
+	var step func(t time.Time, n int) time.Time
+	switch unit {
+	case UnitDay:
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }
+	case UnitWeek:
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
+	case UnitMonth:
+		start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }
+	case UnitQuarter:
+		start = time.Date(start.Year(), start.Month()-(start.Month()-1)%3, 1, 0, 0, 0, 0, start.Location())
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 3*n, 0) }
+	case UnitYear:
+		start = time.Date(start.Year(), time.January, 1, 0, 0, 0, 0, start.Location())
+		step = func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }
+	default:
+		return models.BillingPeriod{}, fmt.Errorf("unknown period unit %q", unit)
+	}
+	if billing == BillingArrears {
+		start = step(start, -1)
+	}
+	return models.BillingPeriod{Start: start, End: step(start, 1).AddDate(0, 0, -1)}, nil
+}
@@ -0,0 +1,243 @@
+package recurring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/pkg/models"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func dates(ts []time.Time) []string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format("2006-01-02")
+	}
+	return out
+}
+
+func TestParseSchedule_RRule(t *testing.T) {
+	tests := []struct {
+		rule  string
+		start time.Time
+		want  []string
+		unit  Unit
+	}{
+		{"FREQ=MONTHLY", date(2025, 1, 31), []string{"2025-01-31", "2025-03-31", "2025-05-31"}, UnitMonth},
+		{"RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", date(2025, 1, 1), []string{"2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30", "2025-05-31"}, UnitMonth},
+		{"FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1", date(2025, 1, 1), []string{"2025-01-01", "2025-04-01"}, UnitQuarter},
+		{"FREQ=MONTHLY;BYDAY=1MO;COUNT=2", date(2025, 1, 1), []string{"2025-01-06", "2025-02-03"}, UnitMonth},
+		{"FREQ=WEEKLY;BYDAY=MO,TH;UNTIL=20250110", date(2025, 1, 1), []string{"2025-01-02", "2025-01-06", "2025-01-09"}, UnitWeek},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", date(2024, 1, 1), []string{"2024-02-29"}, UnitYear},
+		{"FREQ=DAILY;INTERVAL=10", date(2025, 5, 1), []string{"2025-05-01", "2025-05-11", "2025-05-21", "2025-05-31"}, UnitDay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			s, err := ParseSchedule(tt.rule)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, dates(s.Occurrences(tt.start, date(2025, 5, 31))))
+			assert.Equal(t, tt.unit, s.Unit())
+		})
+	}
+
+	for _, bad := range []string{"FREQ=HOURLY", "FREQ=MONTHLY;INTERVAL=0", "FREQ=MONTHLY;BYMONTHDAY=32", "FREQ=WEEKLY;BYDAY=XX", "FREQ=MONTHLY;COUNT=2;UNTIL=20250101", "INTERVAL=2;FREQ"} {
+		_, err := ParseSchedule(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseSchedule_Cron(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+		unit Unit
+	}{
+		{"0 0 1 * *", []string{"2025-01-01", "2025-02-01", "2025-03-01"}, UnitMonth},
+		{"@quarterly", []string{"2025-01-01"}, UnitQuarter},
+		{"0 9 15 */2 *", []string{"2025-01-15", "2025-03-15"}, UnitMonth},
+		{"0 0 * 2 1-2", []string{"2025-02-03", "2025-02-04", "2025-02-10", "2025-02-11", "2025-02-17", "2025-02-18", "2025-02-24", "2025-02-25"}, UnitMonth},
+		// Both day fields restricted: either matches
+		{"0 0 1 3 0", []string{"2025-03-01", "2025-03-02", "2025-03-09", "2025-03-16", "2025-03-23", "2025-03-30"}, UnitMonth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := ParseSchedule(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, dates(s.Occurrences(date(2025, 1, 1), date(2025, 3, 31))))
+			assert.Equal(t, tt.unit, s.Unit())
+		})
+	}
+
+	for _, bad := range []string{"", "0 0 1 *", "0 0 32 * *", "60 0 1 * *", "0 0 5-1 * *", "0 0 1/0 * *"} {
+		_, err := ParseSchedule(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	tests := []struct {
+		unit       Unit
+		billing    Billing
+		on         time.Time
+		start, end string
+	}{
+		{UnitMonth, BillingAdvance, date(2025, 2, 10), "2025-02-01", "2025-02-28"},
+		{UnitMonth, BillingArrears, date(2025, 3, 1), "2025-02-01", "2025-02-28"},
+		{UnitQuarter, BillingAdvance, date(2025, 5, 20), "2025-04-01", "2025-06-30"},
+		{UnitQuarter, BillingArrears, date(2025, 1, 1), "2024-10-01", "2024-12-31"},
+		{UnitWeek, BillingAdvance, date(2025, 7, 3), "2025-06-30", "2025-07-06"},
+		{UnitYear, BillingArrears, date(2025, 1, 1), "2024-01-01", "2024-12-31"},
+		{UnitDay, BillingAdvance, date(2025, 1, 1), "2025-01-01", "2025-01-01"},
+	}
+	for _, tt := range tests {
+		p, err := Period(tt.unit, tt.billing, tt.on)
+		require.NoError(t, err)
+		assert.Equal(t, tt.start, p.Start.Format("2006-01-02"), "%s %s %s", tt.unit, tt.billing, tt.on)
+		assert.Equal(t, tt.end, p.End.Format("2006-01-02"), "%s %s %s", tt.unit, tt.billing, tt.on)
+	}
+	_, err := Period("fortnight", BillingAdvance, date(2025, 1, 1))
+	assert.Error(t, err)
+}
+
+const hostingDefinition = `schedule: "FREQ=MONTHLY;BYMONTHDAY=1"
+start: 2025-01-01
+end: 2025-03-31
+billing: arrears
+series: hosting
+output: "out/{{year}}-{{month}}/{{number}}.pdf"
+invoice:
+  provider:
+    name: "TechCorp Solutions Ltd"
+  client:
+    name: "Startup Innovations Inc"
+  invoice:
+    notes: "Services {{period_start}} to {{period_end}}, billed {{date}}"
+    lines:
+      - description: "Hosting {{month_name}} {{year}} (Q{{quarter}})"
+        quantity: 1
+        unit_price: 49.90
+        tax_rate: 19
+`
+
+func TestDefinition(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hosting.yaml"), []byte(hostingDefinition), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0644))
+
+	defs, err := LoadDir(dir, nil)
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+	def := defs[0]
+	assert.Equal(t, "hosting", def.ID)
+	assert.Equal(t, UnitMonth, def.Period)
+	assert.Equal(t, BillingArrears, def.Billing)
+
+	occurrences, err := def.Occurrences(date(2025, 12, 31))
+	require.NoError(t, err)
+	require.Len(t, occurrences, 3, "end caps the occurrences")
+	o := occurrences[1]
+	assert.Equal(t, date(2025, 2, 1), o.Date)
+	assert.Equal(t, models.BillingPeriod{Start: date(2025, 1, 1), End: date(2025, 1, 31)}, o.Period)
+
+	data, err := def.Render(o)
+	require.NoError(t, err)
+	assert.Equal(t, date(2025, 2, 1), data.Invoice.Date)
+	assert.Equal(t, o.Period, data.Invoice.BillingPeriod)
+	assert.Equal(t, "Hosting January 2025 (Q1)", data.Invoice.Lines[0].Description)
+	assert.Equal(t, "Services 2025-01-01 to 2025-01-31, billed 2025-02-01", data.Invoice.Notes)
+	assert.Equal(t, "49.9", data.Invoice.Lines[0].UnitPrice.String())
+	assert.Equal(t, "out/2025-01/RE-7.pdf", def.OutputFile(o, "RE-7"))
+
+	// Rendering leaves the definition untouched
+	again, err := def.Render(occurrences[2])
+	require.NoError(t, err)
+	assert.Equal(t, "Hosting February 2025 (Q1)", again.Invoice.Lines[0].Description)
+}
+
+func TestDefinition_PlaceholderDates(t *testing.T) {
+	dir := t.TempDir()
+	def := `schedule: "@monthly"
+start: 2025-01-01
+invoice:
+  invoice:
+    due_date: "{{period_end}}"
+    billing_period:
+      start: "{{date}}"
+      end: "{{period_end}}"
+`
+	path := filepath.Join(dir, "support.yml")
+	require.NoError(t, os.WriteFile(path, []byte(def), 0644))
+	d, err := Load(path, nil)
+	require.NoError(t, err)
+	data, err := d.Render(Occurrence{Date: date(2025, 6, 1), Period: models.BillingPeriod{Start: date(2025, 6, 1), End: date(2025, 6, 30)}})
+	require.NoError(t, err)
+	assert.Equal(t, date(2025, 6, 30), data.Invoice.DueDate)
+	assert.Equal(t, date(2025, 6, 1), data.Invoice.BillingPeriod.Start)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"no-schedule": "start: 2025-01-01\ninvoice: {}\n",
+		"no-start":    "schedule: \"@monthly\"\ninvoice: {}\n",
+		"no-invoice":  "schedule: \"@monthly\"\nstart: 2025-01-01\n",
+		"billing":     "schedule: \"@monthly\"\nstart: 2025-01-01\nbilling: later\ninvoice: {}\n",
+		"period":      "schedule: \"@monthly\"\nstart: 2025-01-01\nperiod: fortnight\ninvoice: {}\n",
+		"end":         "schedule: \"@monthly\"\nstart: 2025-01-01\nend: 2024-01-01\ninvoice: {}\n",
+	} {
+		path := filepath.Join(dir, name+".yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		_, err := Load(path, nil)
+		assert.Error(t, err, name)
+	}
+
+	dup := t.TempDir()
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dup, name), []byte("id: same\nschedule: \"@monthly\"\nstart: 2025-01-01\ninvoice: {}\n"), 0644))
+	}
+	_, err := LoadDir(dup, nil)
+	assert.ErrorContains(t, err, "duplicate")
+
+	defs, err := LoadDir(filepath.Join(dir, "missing"), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, defs)
+}
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "recurring.json")
+	s, err := LoadState(path)
+	require.NoError(t, err)
+	_, ok := s.Number("hosting", date(2025, 2, 1))
+	assert.False(t, ok)
+
+	require.NoError(t, s.Mark("hosting", date(2025, 2, 1), "RE-2025-0001"))
+	s, err = LoadState(path)
+	require.NoError(t, err)
+	number, ok := s.Number("hosting", date(2025, 2, 1))
+	assert.True(t, ok)
+	assert.Equal(t, "RE-2025-0001", number)
+	_, ok = s.Number("hosting", date(2025, 3, 1))
+	assert.False(t, ok)
+
+	// Pending numbers are kept until the occurrence is marked
+	require.NoError(t, s.MarkPending("hosting", date(2025, 3, 1), "RE-2025-0002"))
+	s, err = LoadState(path)
+	require.NoError(t, err)
+	number, ok = s.PendingNumber("hosting", date(2025, 3, 1))
+	assert.True(t, ok)
+	assert.Equal(t, "RE-2025-0002", number)
+	require.NoError(t, s.Mark("hosting", date(2025, 3, 1), "RE-2025-0002"))
+	_, ok = s.PendingNumber("hosting", date(2025, 3, 1))
+	assert.False(t, ok)
+	require.NoError(t, s.MarkPending("hosting", date(2025, 4, 1), "RE-2025-0003"))
+	require.NoError(t, s.ClearPending("hosting", date(2025, 4, 1)))
+	assert.Empty(t, s.Pending)
+}
@@ -0,0 +1,452 @@
+// Package recurring generates invoices from recurring definitions on a schedule.
+package recurring
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule yields the issue dates of a recurring invoice.
+type Schedule interface {
+	// Occurrences returns the dates from start up to and including until, in order.
+	Occurrences(start, until time.Time) []time.Time
+	// Unit is the billing period the schedule implies.
+	Unit() Unit
+}
+
+// ParseSchedule parses a schedule expression. Expressions containing FREQ= are read as
+// an iCalendar RRULE (RFC 5545), with or without the "RRULE:" prefix; anything else as
+// a five-field cron expression or one of the @daily, @weekly, @monthly, @quarterly and
+// @yearly shorthands. Invoices are issued per day, so the minute and hour fields of a
+// cron expression are checked but otherwise ignored.
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	if strings.Contains(strings.ToUpper(expr), "FREQ=") {
+		return parseRRule(expr)
+	}
+	return parseCron(expr)
+}
+
+// day truncates t to midnight in its location.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// daysIn returns the number of days in the month.
+func daysIn(year int, month time.Month, loc *time.Location) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+}
+
+// cron is a day-granular cron schedule.
+type cron struct {
+	dom, month, dow []bool
+	domAny, dowAny  bool
+	unit            Unit
+}
+
+var cronShorthands = map[string]struct {
+	expr string
+	unit Unit
+}{
+	"@daily":     {"0 0 * * *", UnitDay},
+	"@weekly":    {"0 0 * * 1", UnitWeek},
+	"@monthly":   {"0 0 1 * *", UnitMonth},
+	"@quarterly": {"0 0 1 1,4,7,10 *", UnitQuarter},
+	"@yearly":    {"0 0 1 1 *", UnitYear},
+	"@annually":  {"0 0 1 1 *", UnitYear},
+}
+
+func parseCron(expr string) (*cron, error) {
+	unit := UnitMonth
+	if s, ok := cronShorthands[strings.ToLower(expr)]; ok {
+		expr, unit = s.expr, s.unit
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+	if _, err := cronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if _, err := cronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	c := &cron{unit: unit, domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	var err error
+	if c.dom, err = cronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron day of month: %w", err)
+	}
+	if c.month, err = cronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	if c.dow, err = cronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron day of week: %w", err)
+	}
+	c.dow[0] = c.dow[0] || c.dow[7]
+	return c, nil
+}
+
+// cronField parses a comma separated list of *, values, ranges and steps into a set
+// indexed by value.
+func cronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rng = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// matches follows cron semantics: when both day of month and day of week are
+// restricted, a day matching either is due.
+func (c *cron) matches(t time.Time) bool {
+	if !c.month[t.Month()] {
+		return false
+	}
+	dom, dow := c.dom[t.Day()], c.dow[t.Weekday()]
+	if !c.domAny && !c.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Occurrences implements Schedule.
+func (c *cron) Occurrences(start, until time.Time) []time.Time {
+	var out []time.Time
+	for d := day(start); !d.After(until); d = d.AddDate(0, 0, 1) {
+		if c.matches(d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Unit implements Schedule.
+func (c *cron) Unit() Unit { return c.unit }
+
+// rrule is the subset of RFC 5545 recurrence rules useful for billing: FREQ (DAILY,
+// WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY and BYDAY.
+// The start date of the definition acts as DTSTART.
+type rrule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byMonth    []time.Month
+	byMonthDay []int
+	byDay      []weekday
+}
+
+// weekday is a BYDAY entry; n is the ordinal within the month or year, 0 for every.
+type weekday struct {
+	n   int
+	day time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+func parseRRule(expr string) (*rrule, error) {
+	expr = strings.TrimPrefix(strings.ToUpper(expr), "RRULE:")
+	r := &rrule{interval: 1}
+	for _, part := range strings.Split(expr, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("rrule: invalid part %q", part)
+		}
+		key, value := kv[0], kv[1]
+		var err error
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return nil, fmt.Errorf("rrule: unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			if r.interval, err = strconv.Atoi(value); err != nil || r.interval < 1 {
+				return nil, fmt.Errorf("rrule: invalid INTERVAL %q", value)
+			}
+		case "COUNT":
+			if r.count, err = strconv.Atoi(value); err != nil || r.count < 1 {
+				return nil, fmt.Errorf("rrule: invalid COUNT %q", value)
+			}
+		case "UNTIL":
+			if r.until, err = parseRRuleDate(value); err != nil {
+				return nil, fmt.Errorf("rrule: invalid UNTIL %q", value)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				m, err := strconv.Atoi(v)
+				if err != nil || m < 1 || m > 12 {
+					return nil, fmt.Errorf("rrule: invalid BYMONTH %q", v)
+				}
+				r.byMonth = append(r.byMonth, time.Month(m))
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				d, err := strconv.Atoi(v)
+				if err != nil || d == 0 || d < -31 || d > 31 {
+					return nil, fmt.Errorf("rrule: invalid BYMONTHDAY %q", v)
+				}
+				r.byMonthDay = append(r.byMonthDay, d)
+			}
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				if len(v) < 2 {
+					return nil, fmt.Errorf("rrule: invalid BYDAY %q", v)
+				}
+				wd, ok := weekdays[v[len(v)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("rrule: invalid BYDAY %q", v)
+				}
+				n := 0
+				if prefix := v[:len(v)-2]; prefix != "" {
+					if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -5 || n > 5 {
+						return nil, fmt.Errorf("rrule: invalid BYDAY %q", v)
+					}
+				}
+				r.byDay = append(r.byDay, weekday{n: n, day: wd})
+			}
+		case "WKST", "DTSTART":
+			// Weeks start on Monday and the definition start is the DTSTART
+		default:
+			return nil, fmt.Errorf("rrule: unsupported part %s", key)
+		}
+	}
+	if r.freq == "" {
+		return nil, fmt.Errorf("rrule: FREQ is required")
+	}
+	if r.count > 0 && !r.until.IsZero() {
+		return nil, fmt.Errorf("rrule: COUNT and UNTIL are mutually exclusive")
+	}
+	return r, nil
+}
+
+func parseRRuleDate(v string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", v)
+}
+
+// Unit implements Schedule. Monthly rules every 3 or 12 months bill by quarter or year.
+func (r *rrule) Unit() Unit {
+	switch r.freq {
+	case "DAILY":
+		return UnitDay
+	case "WEEKLY":
+		return UnitWeek
+	case "YEARLY":
+		return UnitYear
+	}
+	switch r.interval {
+	case 3:
+		return UnitQuarter
+	case 12:
+		return UnitYear
+	}
+	return UnitMonth
+}
+
+// Occurrences implements Schedule. COUNT counts from the start date, so occurrences
+// before a later requested window still use up the count.
+func (r *rrule) Occurrences(start, until time.Time) []time.Time {
+	start = day(start)
+	last := until
+	if !r.until.IsZero() {
+		ruleUntil := time.Date(r.until.Year(), r.until.Month(), r.until.Day(), 0, 0, 0, 0, start.Location())
+		if ruleUntil.Before(last) {
+			last = ruleUntil
+		}
+	}
+	var out []time.Time
+	emitted := 0
+	for i := 0; ; i++ {
+		periodStart, candidates := r.period(start, i)
+		if periodStart.After(last) {
+			break
+		}
+		for _, c := range candidates {
+			if c.Before(start) || c.After(last) {
+				continue
+			}
+			if r.count > 0 && emitted >= r.count {
+				return out
+			}
+			emitted++
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// period returns the first day of the i-th interval after start and the sorted
+// candidate dates within it.
+func (r *rrule) period(start time.Time, i int) (time.Time, []time.Time) {
+	loc := start.Location()
+	var first time.Time
+	var candidates []time.Time
+	switch r.freq {
+	case "DAILY":
+		first = start.AddDate(0, 0, i*r.interval)
+		if r.matchesDay(first) {
+			candidates = append(candidates, first)
+		}
+	case "WEEKLY":
+		monday := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		first = monday.AddDate(0, 0, 7*i*r.interval)
+		days := []time.Weekday{start.Weekday()}
+		if len(r.byDay) > 0 {
+			days = days[:0]
+			for _, wd := range r.byDay {
+				days = append(days, wd.day)
+			}
+		}
+		for _, wd := range days {
+			d := first.AddDate(0, 0, (int(wd)+6)%7)
+			if r.inMonths(d.Month()) {
+				candidates = append(candidates, d)
+			}
+		}
+	case "MONTHLY":
+		first = time.Date(start.Year(), start.Month()+time.Month(i*r.interval), 1, 0, 0, 0, 0, loc)
+		if r.inMonths(first.Month()) {
+			candidates = r.monthDays(first, start.Day())
+		}
+	case "YEARLY":
+		first = time.Date(start.Year()+i*r.interval, time.January, 1, 0, 0, 0, 0, loc)
+		months := r.byMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, m := range months {
+			month := time.Date(first.Year(), m, 1, 0, 0, 0, 0, loc)
+			candidates = append(candidates, r.monthDays(month, start.Day())...)
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].Before(candidates[b]) })
+	return first, candidates
+}
+
+// monthDays expands BYMONTHDAY and BYDAY within the month starting at first. Without
+// either the start day is used; months too short for it are skipped, as RFC 5545 asks.
+func (r *rrule) monthDays(first time.Time, startDay int) []time.Time {
+	n := daysIn(first.Year(), first.Month(), first.Location())
+	var out []time.Time
+	switch {
+	case len(r.byMonthDay) > 0:
+		for _, d := range r.byMonthDay {
+			if d < 0 {
+				d = n + d + 1
+			}
+			if d >= 1 && d <= n {
+				out = append(out, first.AddDate(0, 0, d-1))
+			}
+		}
+	case len(r.byDay) > 0:
+		for _, wd := range r.byDay {
+			var matches []time.Time
+			for d := 0; d < n; d++ {
+				if t := first.AddDate(0, 0, d); t.Weekday() == wd.day {
+					matches = append(matches, t)
+				}
+			}
+			switch {
+			case wd.n == 0:
+				out = append(out, matches...)
+			case wd.n > 0 && wd.n <= len(matches):
+				out = append(out, matches[wd.n-1])
+			case wd.n < 0 && -wd.n <= len(matches):
+				out = append(out, matches[len(matches)+wd.n])
+			}
+		}
+	case startDay <= n:
+		out = append(out, first.AddDate(0, 0, startDay-1))
+	}
+	return out
+}
+
+// matchesDay filters a daily candidate by BYMONTH, BYMONTHDAY and BYDAY.
+func (r *rrule) matchesDay(t time.Time) bool {
+	if !r.inMonths(t.Month()) {
+		return false
+	}
+	if len(r.byMonthDay) > 0 {
+		n := daysIn(t.Year(), t.Month(), t.Location())
+		found := false
+		for _, d := range r.byMonthDay {
+			if d == t.Day() || d < 0 && n+d+1 == t.Day() {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.byDay) > 0 {
+		for _, wd := range r.byDay {
+			if wd.day == t.Weekday() {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (r *rrule) inMonths(m time.Month) bool {
+	if len(r.byMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.byMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package recurring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State records which occurrences have been invoiced, so a run never issues an
+// occurrence twice. An occurrence is pending from the moment its invoice number is
+// issued until the invoice is generated, so a run interrupted in between can finish it
+// with the same number. The state is kept in a JSON file that is replaced atomically on
+// each change.
+type State struct {
+	path string
+	// Issued maps a definition ID to occurrence dates (YYYY-MM-DD) and their invoice numbers
+	Issued map[string]map[string]string `json:"issued"`
+	// Pending maps a definition ID to occurrence dates and the numbers issued for invoices
+	// that have not been generated yet
+	Pending map[string]map[string]string `json:"pending,omitempty"`
+}
+
+// LoadState reads the state file at path; a missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	s := &State{path: path, Issued: make(map[string]map[string]string), Pending: make(map[string]map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if s.Issued == nil {
+		s.Issued = make(map[string]map[string]string)
+	}
+	if s.Pending == nil {
+		s.Pending = make(map[string]map[string]string)
+	}
+	return s, nil
+}
+
+// Number returns the invoice number issued for an occurrence, if any.
+func (s *State) Number(id string, date time.Time) (string, bool) {
+	number, ok := s.Issued[id][date.Format("2006-01-02")]
+	return number, ok
+}
+
+// Mark records the invoice issued for an occurrence, which is no longer pending, and
+// saves the state.
+func (s *State) Mark(id string, date time.Time, number string) error {
+	set(s.Issued, id, date, number)
+	unset(s.Pending, id, date)
+	return s.save()
+}
+
+// PendingNumber returns the number issued for an occurrence whose invoice has not been
+// generated yet, if any.
+func (s *State) PendingNumber(id string, date time.Time) (string, bool) {
+	number, ok := s.Pending[id][date.Format("2006-01-02")]
+	return number, ok
+}
+
+// MarkPending records the number issued for an occurrence before its invoice is
+// generated and saves the state.
+func (s *State) MarkPending(id string, date time.Time, number string) error {
+	set(s.Pending, id, date, number)
+	return s.save()
+}
+
+// ClearPending forgets the pending number of an occurrence, e.g. once it has been
+// handed back, and saves the state.
+func (s *State) ClearPending(id string, date time.Time) error {
+	unset(s.Pending, id, date)
+	return s.save()
+}
+
+func set(m map[string]map[string]string, id string, date time.Time, number string) {
+	if m[id] == nil {
+		m[id] = make(map[string]string)
+	}
+	m[id][date.Format("2006-01-02")] = number
+}
+
+func unset(m map[string]map[string]string, id string, date time.Time) {
+	delete(m[id], date.Format("2006-01-02"))
+	if len(m[id]) == 0 {
+		delete(m, id)
+	}
+}
+
+func (s *State) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	out, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replace %s: %w", s.path, err)
+	}
+	return nil
+}
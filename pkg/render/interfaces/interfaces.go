@@ -1,11 +1,15 @@
 package interfaces
 
-import "invoiceformats/pkg/models"
+import (
+	"context"
+
+	"invoiceformats/pkg/models"
+)
 
 // TemplateRenderer defines the interface for rendering templates.
 type TemplateRenderer interface {
-	Render(data models.InvoiceData, templatePath string) (string, error)
-	RenderOutputName(data models.InvoiceData, templatePath string) (string, error)
+	Render(ctx context.Context, data models.InvoiceData, templatePath string) (string, error)
+	RenderOutputName(ctx context.Context, data models.InvoiceData, templatePath string) (string, error)
 }
 
 // LocaleLoader defines the interface for loading locales.
@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"context"
 	"invoiceformats/pkg/models"
 	"testing"
 
@@ -9,10 +10,10 @@ import (
 
 type mockRenderer struct{}
 
-func (m *mockRenderer) Render(data models.InvoiceData, templatePath string) (string, error) {
+func (m *mockRenderer) Render(ctx context.Context, data models.InvoiceData, templatePath string) (string, error) {
 	return "rendered", nil
 }
-func (m *mockRenderer) RenderOutputName(data models.InvoiceData, templatePath string) (string, error) {
+func (m *mockRenderer) RenderOutputName(ctx context.Context, data models.InvoiceData, templatePath string) (string, error) {
 	return "output_name", nil
 }
 
@@ -24,10 +25,10 @@ func (m *mockLocaleLoader) Load(lang, customPath string) (map[string]string, err
 
 func TestTemplateRendererInterface(t *testing.T) {
 	var r TemplateRenderer = &mockRenderer{}
-	out, err := r.Render(models.InvoiceData{}, "")
+	out, err := r.Render(context.Background(), models.InvoiceData{}, "")
 	assert.NoError(t, err)
 	assert.Equal(t, "rendered", out)
-	name, err := r.RenderOutputName(models.InvoiceData{}, "")
+	name, err := r.RenderOutputName(context.Background(), models.InvoiceData{}, "")
 	assert.NoError(t, err)
 	assert.Equal(t, "output_name", name)
 }
@@ -1,12 +1,23 @@
 package render
 
 import (
+	"bytes"
+	"context"
 	"embed"
+	"encoding/json"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
 	"invoiceformats/pkg/models"
 	"invoiceformats/pkg/render/functions"
 	"invoiceformats/pkg/render/interfaces"
 	"invoiceformats/pkg/render/locale"
 	"invoiceformats/pkg/render/template"
+	"invoiceformats/pkg/tracing"
 )
 
 //go:embed templates/* locales.json
@@ -26,17 +37,47 @@ func NewLocaleLoader(embeddedData []byte) interfaces.LocaleLoader {
 	return &locale.Loader{EmbeddedData: embeddedData}
 }
 
+// Templates returns the names of the embedded invoice templates, which can be passed as
+// template path to the render functions.
+func Templates() []string {
+	paths, _ := fs.Glob(templateFS, "templates/*.html.tmpl")
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = path.Base(p)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Languages returns the languages of the embedded locales.
+func Languages() ([]string, error) {
+	data, err := templateFS.ReadFile("locales.json")
+	if err != nil {
+		return nil, err
+	}
+	var locales map[string]json.RawMessage
+	if err := json.Unmarshal(data, &locales); err != nil {
+		return nil, err
+	}
+	langs := make([]string, 0, len(locales))
+	for lang := range locales {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs, nil
+}
+
 // The following functions are for backward compatibility and simple use cases.
 // For advanced use, use the interfaces directly.
 
 // RenderHTML renders invoice data to HTML using embedded templates.
-func RenderHTML(data models.InvoiceData, templatePath string, i18nProvider interfaces.TranslatorProvider) (string, error) {
+func RenderHTML(ctx context.Context, data models.InvoiceData, templatePath string, i18nProvider interfaces.TranslatorProvider) (string, error) {
 	renderer := NewRenderer(i18nProvider)
-	return renderer.Render(data, templatePath)
+	return renderer.Render(ctx, data, templatePath)
 }
 
 // RenderHTMLWithLocale renders invoice data to HTML with custom locale and template paths.
-func RenderHTMLWithLocale(data models.InvoiceData, templatePath, localePath string, i18nProvider interfaces.TranslatorProvider, localeLoader interfaces.LocaleLoader) (string, error) {
+func RenderHTMLWithLocale(ctx context.Context, data models.InvoiceData, templatePath, localePath string, i18nProvider interfaces.TranslatorProvider, localeLoader interfaces.LocaleLoader) (string, error) {
 	lang := data.Invoice.Language
 	if lang == "" {
 		lang = "en"
@@ -56,13 +97,55 @@ func RenderHTMLWithLocale(data models.InvoiceData, templatePath, localePath stri
 		TemplateFuncs: functions.NewTemplateFuncs(tFunc),
 		I18nProvider:  i18nProvider,
 	}
-	return renderer.Render(data, templatePath)
+	return renderer.Render(ctx, data, templatePath)
 }
 
 // RenderOutputName renders the output_name block or returns a default name.
-func RenderOutputName(data models.InvoiceData, templatePath string, i18nProvider interfaces.TranslatorProvider) (string, error) {
+func RenderOutputName(ctx context.Context, data models.InvoiceData, templatePath string, i18nProvider interfaces.TranslatorProvider) (string, error) {
 	renderer := NewRenderer(i18nProvider)
-	return renderer.RenderOutputName(data, templatePath)
+	return renderer.RenderOutputName(ctx, data, templatePath)
+}
+
+// RenderNotice renders a reminder or dunning notice. templatePath is either the name of
+// an embedded template in templates/notices, such as "reminder.html.tmpl", or the path
+// of a template file.
+// Texts are looked up in the locale lang; missing keys are rendered as is.
+func RenderNotice(ctx context.Context, data any, templatePath, lang, localePath string, localeLoader interfaces.LocaleLoader) (string, error) {
+	if lang == "" {
+		lang = "en"
+	}
+	locales, err := localeLoader.Load(lang, localePath)
+	if err != nil {
+		return "", err
+	}
+	tFunc := func(key string) string {
+		if v, ok := locales[key]; ok {
+			return v
+		}
+		return key
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	_, span := tracing.Start(ctx, "template.parse", tracing.Template.String(templatePath))
+	tmpl := htmltemplate.New(filepath.Base(templatePath)).Funcs(functions.NewTemplateFuncs(tFunc))
+	if strings.ContainsAny(templatePath, `/\`) {
+		tmpl, err = tmpl.ParseFiles(templatePath)
+	} else {
+		tmpl, err = tmpl.ParseFS(templateFS, "templates/notices/"+templatePath)
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
+	}
+	_, span = tracing.Start(ctx, "template.execute", tracing.Template.String(templatePath))
+	var buf bytes.Buffer
+	err = tmpl.Execute(template.ContextWriter(ctx, &buf), data)
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
 
 // TODO [context: render.go, priority: medium, effort: 2h]: Refactor i18n package to support dependency injection for locale sources (embedded, file, remote)
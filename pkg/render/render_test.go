@@ -1,8 +1,11 @@
 package render
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -68,7 +71,7 @@ func fakeI18nProvider(lang string, locales map[string]string) func(string) strin
 
 func TestRenderHTML_Success(t *testing.T) {
 	data := sampleInvoiceData()
-	html, err := RenderHTML(data, "", fakeI18nProvider)
+	html, err := RenderHTML(context.Background(), data, "", fakeI18nProvider)
 	require.NoError(t, err)
 	assert.NotEmpty(t, html)
 	assert.Contains(t, html, "Test Provider")
@@ -80,7 +83,7 @@ func TestRenderHTML_Success(t *testing.T) {
 func TestRenderHTML_InvalidData(t *testing.T) {
 	// Provide incomplete data (missing required fields)
 	data := models.InvoiceData{}
-	html, err := RenderHTML(data, "", fakeI18nProvider)
+	html, err := RenderHTML(context.Background(), data, "", fakeI18nProvider)
 	// The template will render zero values, but should not error
 	assert.NoError(t, err)
 	assert.NotEmpty(t, html)
@@ -93,13 +96,29 @@ func TestRenderHTML_CustomTemplate(t *testing.T) {
 	tmplContent := `Custom Template: {{.Provider.Name}} - {{.Client.Name}}`
 	os.WriteFile(customPath, []byte(tmplContent), 0644)
 	defer os.Remove(customPath)
-	html, err := RenderHTML(data, customPath, fakeI18nProvider)
+	html, err := RenderHTML(context.Background(), data, customPath, fakeI18nProvider)
 	require.NoError(t, err)
 	assert.Contains(t, html, "Custom Template")
 	assert.Contains(t, html, data.Provider.Name)
 	assert.Contains(t, html, data.Client.Name)
 }
 
+func TestTemplatesAndLanguages(t *testing.T) {
+	templates := Templates()
+	assert.Contains(t, templates, "invoice.html.tmpl")
+	assert.Contains(t, templates, "modern-dark.html.tmpl")
+
+	// Embedded templates can be selected by name
+	html, err := RenderHTML(context.Background(), sampleInvoiceData(), "modern-dark.html.tmpl", fakeI18nProvider)
+	require.NoError(t, err)
+	assert.Contains(t, html, "Test Provider")
+
+	langs, err := Languages()
+	require.NoError(t, err)
+	assert.Contains(t, langs, "en")
+	assert.Contains(t, langs, "de")
+}
+
 func TestRenderHTMLWithLocale_CustomTemplateAndLocale(t *testing.T) {
 	data := sampleInvoiceData()
 	customTemplate := "test_custom.tmpl"
@@ -134,12 +153,187 @@ func TestRenderHTMLWithLocale_CustomTemplateAndLocale(t *testing.T) {
 	locMap, err := loader.Load(lang, customLocale)
 	require.NoError(t, err)
 
-	html, err := RenderHTMLWithLocale(data, customTemplate, customLocale, func(l string, _ map[string]string) func(string) string { return translator(l, locMap) }, loader)
+	html, err := RenderHTMLWithLocale(context.Background(), data, customTemplate, customLocale, func(l string, _ map[string]string) func(string) string { return translator(l, locMap) }, loader)
 	require.NoError(t, err)
 	assert.Contains(t, html, "Custom Template")
 	assert.Contains(t, html, data.Provider.Name)
 	assert.Contains(t, html, "Test Invoice")
 }
 
+func TestRenderHTML_TaxCurrencyInAllTemplates(t *testing.T) {
+	data := sampleInvoiceData()
+	data.Invoice.TaxCurrency = "EUR"
+	data.Invoice.Currency.Rate = decimal.NewFromFloat(0.8)
+	data.Invoice.CalculateTotals()
+
+	templates, err := filepath.Glob("templates/*.html.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+	for _, path := range templates {
+		html, err := RenderHTML(context.Background(), data, path, fakeI18nProvider)
+		require.NoError(t, err, path)
+		assert.Contains(t, html, "tax_accounting_currency", path)
+		assert.Contains(t, html, "32.00", path)
+		assert.Contains(t, html, "0.8 EUR", path)
+	}
+}
+
+func TestRenderHTML_WithholdingInAllTemplates(t *testing.T) {
+	data := sampleInvoiceData()
+	data.Invoice.Withholdings = []models.Withholding{{Type: models.WithholdingRitenuta, Rate: decimal.NewFromInt(20)}}
+	data.Invoice.CalculateTotals()
+	withheld := fmt.Sprintf("%.2f", data.Invoice.TotalWithholding.InexactFloat64())
+	due := fmt.Sprintf("%.2f", data.Invoice.AmountDue.InexactFloat64())
+
+	templates, err := filepath.Glob("templates/*.html.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+	for _, path := range templates {
+		html, err := RenderHTML(context.Background(), data, path, fakeI18nProvider)
+		require.NoError(t, err, path)
+		assert.Contains(t, html, "Ritenuta d&#39;acconto 20%", path)
+		assert.Contains(t, html, withheld, path)
+		assert.Contains(t, html, "amount_due", path)
+		assert.Contains(t, html, due, path)
+	}
+}
+
+func TestRenderHTML_FinalInvoiceInAllTemplates(t *testing.T) {
+	data := sampleInvoiceData()
+	data.Invoice.Type = models.InvoiceTypeFinal
+	data.Invoice.PrecedingInvoices = []models.InvoiceReference{{Number: "ADV-001", Amount: decimal.NewFromInt(10)}}
+	data.Invoice.CalculateTotals()
+	due := fmt.Sprintf("%.2f", data.Invoice.AmountDue.InexactFloat64())
+
+	templates, err := filepath.Glob("templates/*.html.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+	for _, path := range templates {
+		html, err := RenderHTML(context.Background(), data, path, fakeI18nProvider)
+		require.NoError(t, err, path)
+		assert.Contains(t, html, "final_invoice", path)
+		assert.Contains(t, html, "ADV-001", path)
+		assert.Contains(t, html, "prepaid_amount", path)
+		assert.Contains(t, html, due, path)
+	}
+}
+
+func TestRenderHTML_CreditNoteInAllTemplates(t *testing.T) {
+	data := sampleInvoiceData()
+	data.Invoice.Type = models.InvoiceTypeCreditNote
+	data.Invoice.PrecedingInvoices = []models.InvoiceReference{{Number: "INV-000", Date: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)}}
+	data.Invoice.CalculateTotals()
+
+	templates, err := filepath.Glob("templates/*.html.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+	for _, path := range templates {
+		html, err := RenderHTML(context.Background(), data, path, fakeI18nProvider)
+		require.NoError(t, err, path)
+		assert.Contains(t, html, "credit_note", path)
+		assert.Contains(t, html, "credited_invoice", path)
+		assert.Contains(t, html, "INV-000 (2025-07-01)", path)
+		assert.NotContains(t, html, "prepaid_amount", path)
+	}
+}
+
+func TestRenderHTML_BillingPeriodInAllTemplates(t *testing.T) {
+	data := sampleInvoiceData()
+	data.Invoice.BillingPeriod = models.BillingPeriod{
+		Start: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2025, 7, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	templates, err := filepath.Glob("templates/*.html.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+	for _, path := range templates {
+		html, err := RenderHTML(context.Background(), data, path, fakeI18nProvider)
+		require.NoError(t, err, path)
+		assert.Contains(t, html, "billing_period", path)
+		assert.Contains(t, html, "2025", path)
+	}
+
+	html, err := RenderHTML(context.Background(), sampleInvoiceData(), "", fakeI18nProvider)
+	require.NoError(t, err)
+	assert.NotContains(t, html, "billing_period")
+}
+
+func TestRenderHTML_QuoteInAllTemplates(t *testing.T) {
+	data := sampleInvoiceData()
+	data.Invoice.Type = models.InvoiceTypeQuote
+	data.Invoice.ValidUntil = time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC)
+
+	confirmation := sampleInvoiceData()
+	confirmation.Invoice.Type = models.InvoiceTypeOrderConfirmation
+	confirmation.Invoice.OrderReference = "Q-2025-0007"
+
+	templates, err := filepath.Glob("templates/*.html.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+	for _, path := range templates {
+		html, err := RenderHTML(context.Background(), data, path, fakeI18nProvider)
+		require.NoError(t, err, path)
+		assert.Contains(t, html, "quote_text", path)
+		assert.Contains(t, html, "valid_until", path)
+		assert.Contains(t, html, "2025", path)
+		assert.NotContains(t, html, "due_date", path)
+
+		html, err = RenderHTML(context.Background(), confirmation, path, fakeI18nProvider)
+		require.NoError(t, err, path)
+		assert.Contains(t, html, "order_confirmation_text", path)
+		assert.Contains(t, html, "Q-2025-0007", path)
+		assert.NotContains(t, html, "valid_until", path)
+	}
+
+	html, err := RenderHTML(context.Background(), sampleInvoiceData(), "", fakeI18nProvider)
+	require.NoError(t, err)
+	assert.Contains(t, html, "due_date")
+	assert.NotContains(t, html, "quote_text")
+	assert.NotContains(t, html, "order_reference")
+}
+
 // TODO [context: render_test.go, priority: medium, effort: 1h]: Add more edge case tests for RenderHTMLWithLocale (invalid locale, missing template, etc.)
 // Remove tests for embedded data type extraction, as compliance is now set from YAML, not template
+
+func TestRenderNotice_EmbeddedTemplates(t *testing.T) {
+	data := sampleInvoiceData()
+	data.Invoice.Language = "de"
+	localeData, err := os.ReadFile("locales.json")
+	require.NoError(t, err)
+	loader := &locale.Loader{EmbeddedData: localeData}
+
+	notice := map[string]any{
+		"Number":       data.Invoice.Number,
+		"Step":         map[string]string{"Name": "dunning"},
+		"Date":         time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+		"PayBy":        time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC),
+		"DueDate":      time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		"DaysOverdue":  31,
+		"Outstanding":  decimal.NewFromInt(240),
+		"Fees":         decimal.NewFromInt(5),
+		"LumpSum":      decimal.NewFromInt(40),
+		"InterestRate": decimal.RequireFromString("10.27"),
+		"Interest":     decimal.RequireFromString("2.09"),
+		"Total":        decimal.RequireFromString("287.09"),
+		"Previous":     []map[string]any{{"Name": "reminder", "Date": time.Date(2025, 7, 15, 0, 0, 0, 0, time.UTC)}},
+		"Data":         data,
+	}
+	html, err := RenderNotice(context.Background(), notice, "dunning.html.tmpl", "de", "", loader)
+	require.NoError(t, err)
+	assert.Contains(t, html, "Mahnung")
+	assert.Contains(t, html, "Verzugspauschale")
+	assert.Contains(t, html, "Zahlungserinnerung")
+	assert.Contains(t, html, "10.27%")
+	assert.Contains(t, html, "287.09")
+	assert.Contains(t, html, "Test Client")
+
+	notice["Step"] = map[string]string{"Name": "reminder"}
+	html, err = RenderNotice(context.Background(), notice, "reminder.html.tmpl", "en", "", loader)
+	require.NoError(t, err)
+	assert.Contains(t, html, "Payment Reminder")
+	assert.Contains(t, html, "August 11, 2025")
+
+	_, err = RenderNotice(context.Background(), notice, "missing.html.tmpl", "en", "", loader)
+	assert.Error(t, err)
+}
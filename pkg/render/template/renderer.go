@@ -2,11 +2,15 @@ package template
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"fmt"
 	"html/template"
 	"invoiceformats/pkg/models"
 	"invoiceformats/pkg/render/interfaces"
+	"invoiceformats/pkg/tracing"
+	"io"
+	"io/fs"
 	"path/filepath"
 	"strings"
 )
@@ -18,52 +22,37 @@ type Renderer struct {
 	I18nProvider  interfaces.TranslatorProvider
 }
 
-func (r *Renderer) Render(data models.InvoiceData, templatePath string) (string, error) {
+func (r *Renderer) Render(ctx context.Context, data models.InvoiceData, templatePath string) (string, error) {
 	lang := "en"
 	if data.Invoice.Language != "" {
 		lang = data.Invoice.Language
 	}
 	r.TemplateFuncs["t"] = r.I18nProvider(lang, nil)
 
-	var tmpl *template.Template
-	var err error
-	if templatePath != "" {
-		tmpl, err = template.New(filepath.Base(templatePath)).Funcs(r.TemplateFuncs).ParseFiles(templatePath)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		tmpl, err = template.New("invoice.html.tmpl").Funcs(r.TemplateFuncs).ParseFS(r.TemplateFS, "templates/invoice.html.tmpl")
-		if err != nil {
-			return "", err
-		}
+	tmpl, err := r.parse(ctx, templatePath)
+	if err != nil {
+		return "", err
 	}
+	_, span := tracing.Start(ctx, "template.execute", tracing.Template.String(templatePath))
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
+	err = tmpl.Execute(ContextWriter(ctx, &buf), data)
+	tracing.End(span, err)
+	if err != nil {
 		return "", err
 	}
 	return buf.String(), nil
 }
 
-func (r *Renderer) RenderOutputName(data models.InvoiceData, templatePath string) (string, error) {
+func (r *Renderer) RenderOutputName(ctx context.Context, data models.InvoiceData, templatePath string) (string, error) {
 	lang := "en"
 	if data.Invoice.Language != "" {
 		lang = data.Invoice.Language
 	}
 	r.TemplateFuncs["t"] = r.I18nProvider(lang, nil)
 
-	var tmpl *template.Template
-	var err error
-	if templatePath != "" {
-		tmpl, err = template.New(filepath.Base(templatePath)).Funcs(r.TemplateFuncs).ParseFiles(templatePath)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		tmpl, err = template.New("invoice.html.tmpl").Funcs(r.TemplateFuncs).ParseFS(r.TemplateFS, "templates/invoice.html.tmpl")
-		if err != nil {
-			return "", err
-		}
+	tmpl, err := r.parse(ctx, templatePath)
+	if err != nil {
+		return "", err
 	}
 	var buf bytes.Buffer
 	if tmpl.Lookup("output_name") != nil {
@@ -77,3 +66,41 @@ func (r *Renderer) RenderOutputName(data models.InvoiceData, templatePath string
 	}
 	return fmt.Sprintf("Invoice-%s", data.Invoice.Number), nil
 }
+
+// parse loads the template: the default invoice template if templatePath is empty, an
+// embedded template if templatePath is the bare name of one, such as
+// "modern-dark.html.tmpl", and the template file at templatePath otherwise.
+func (r *Renderer) parse(ctx context.Context, templatePath string) (tmpl *template.Template, err error) {
+	if templatePath == "" {
+		templatePath = "invoice.html.tmpl"
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	_, span := tracing.Start(ctx, "template.parse", tracing.Template.String(templatePath))
+	defer func() { tracing.End(span, err) }()
+	if !strings.ContainsAny(templatePath, `/\`) {
+		if _, err := fs.Stat(r.TemplateFS, "templates/"+templatePath); err == nil {
+			return template.New(templatePath).Funcs(r.TemplateFuncs).ParseFS(r.TemplateFS, "templates/"+templatePath)
+		}
+	}
+	return template.New(filepath.Base(templatePath)).Funcs(r.TemplateFuncs).ParseFiles(templatePath)
+}
+
+// ContextWriter returns a writer to w whose writes fail with ctx.Err() once ctx is done.
+// Templates executed into it stop at their next output when ctx ends.
+func ContextWriter(ctx context.Context, w io.Writer) io.Writer {
+	return &contextWriter{ctx: ctx, w: w}
+}
+
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c *contextWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
@@ -1,9 +1,12 @@
 package template
 
 import (
+	"context"
 	"embed"
 	"invoiceformats/pkg/models"
 	"invoiceformats/pkg/render/functions"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -25,7 +28,7 @@ func TestRenderer_Render(t *testing.T) {
 		I18nProvider:  func(lang string, locales map[string]string) func(string) string { return func(key string) string { return key } },
 	}
 	data := models.InvoiceData{Invoice: models.InvoiceDetails{Number: "INV-123"}}
-	out, err := r.Render(data, "testdata/invoice.html.tmpl")
+	out, err := r.Render(context.Background(), data, "testdata/invoice.html.tmpl")
 	assert.NoError(t, err)
 	assert.Contains(t, out, "INV-123")
 }
@@ -37,7 +40,28 @@ func TestRenderer_RenderOutputName(t *testing.T) {
 		I18nProvider:  func(lang string, locales map[string]string) func(string) string { return func(key string) string { return key } },
 	}
 	data := models.InvoiceData{Invoice: models.InvoiceDetails{Number: "INV-456"}}
-	name, err := r.RenderOutputName(data, "testdata/invoice.html.tmpl")
+	name, err := r.RenderOutputName(context.Background(), data, "testdata/invoice.html.tmpl")
 	assert.NoError(t, err)
 	assert.Contains(t, name, "INV-456")
 }
+
+func TestRenderer_RenderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	funcs := functions.NewTemplateFuncs(func(key string) string { return key })
+	funcs["cancel"] = func() string { cancel(); return "" }
+	r := &Renderer{
+		TemplateFS:    testFS,
+		TemplateFuncs: funcs,
+		I18nProvider:  func(lang string, locales map[string]string) func(string) string { return func(key string) string { return key } },
+	}
+	path := filepath.Join(t.TempDir(), "cancel.html.tmpl")
+	assert.NoError(t, os.WriteFile(path, []byte(`<p>{{.Invoice.Number}}</p>{{cancel}}<p>lines</p>`), 0644))
+
+	// Cancellation stops the template at its next output
+	_, err := r.Render(ctx, models.InvoiceData{Invoice: models.InvoiceDetails{Number: "INV-789"}}, path)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// A done context is not rendered at all
+	_, err = r.Render(ctx, models.InvoiceData{}, "testdata/invoice.html.tmpl")
+	assert.ErrorIs(t, err, context.Canceled)
+}
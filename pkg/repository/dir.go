@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"invoiceformats/pkg/models"
+)
+
+// DirRepository keeps one JSON document per invoice in a directory, named after the
+// invoice number. Documents are written to a temporary file and renamed into place.
+type DirRepository struct {
+	dir string
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewDirRepository creates a repository in dir. The directory is created on first write.
+func NewDirRepository(dir string) *DirRepository {
+	return &DirRepository{dir: dir, now: time.Now}
+}
+
+// Save implements Repository.
+func (r *DirRepository) Save(rec *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	existing, err := r.load(rec.Number)
+	switch {
+	case err == nil:
+		if existing.Status.IsIssued() {
+			return fmt.Errorf("%w: %s is %s", ErrIssued, rec.Number, existing.Status)
+		}
+		rec.CreatedAt = existing.CreatedAt
+		rec.History = existing.History
+	case errors.Is(err, ErrNotFound):
+		rec.CreatedAt = now
+		rec.History = []StatusChange{{To: rec.Status, At: now}}
+	default:
+		return err
+	}
+	rec.UpdatedAt = now
+	return r.write(rec)
+}
+
+// Get implements Repository.
+func (r *DirRepository) Get(number string) (*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.load(number)
+}
+
+// List implements Repository.
+func (r *DirRepository) List(filter Filter) ([]*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read repository %s: %w", r.dir, err)
+	}
+	var records []*Record
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		rec, err := r.read(filepath.Join(r.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if filter.Match(rec) {
+			records = append(records, rec)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Date.Equal(records[j].Date) {
+			return records[i].Date.Before(records[j].Date)
+		}
+		return records[i].Number < records[j].Number
+	})
+	return records, nil
+}
+
+// Transition implements Repository.
+func (r *DirRepository) Transition(number string, to models.InvoiceStatus, at time.Time, note string) (*Record, error) {
+	if to == models.StatusCancelled {
+		return r.Cancel(number, "", at)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.transition(number, to, at, note)
+}
+
+// Cancel implements Repository.
+func (r *DirRepository) Cancel(number, creditNote string, at time.Time) (*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.load(number)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status.IsIssued() && rec.Type.IsInvoice() {
+		if creditNote == "" {
+			return nil, fmt.Errorf("%w: %s is %s", ErrCreditNoteRequired, number, rec.Status)
+		}
+		credit, err := r.load(creditNote)
+		if err != nil {
+			return nil, fmt.Errorf("credit note %s: %w", creditNote, err)
+		}
+		if !references(credit, number) {
+			return nil, fmt.Errorf("%w: %s does not credit %s", ErrCreditNoteRequired, creditNote, number)
+		}
+	}
+	note := ""
+	if creditNote != "" {
+		note = "credit note " + creditNote
+	}
+	return r.transition(number, models.StatusCancelled, at, note, func(rec *Record) { rec.CreditNote = creditNote })
+}
+
+// RecordPayment implements Repository.
+func (r *DirRepository) RecordPayment(number string, p Payment) (*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.load(number)
+	if err != nil {
+		return nil, err
+	}
+	if !rec.IsOpen() {
+		return nil, fmt.Errorf("%w: %s is %s and cannot receive payments", ErrInvalidTransition, number, rec.Status)
+	}
+	if rec.HasPayment(p.Reference) {
+		return nil, fmt.Errorf("%w: %s on %s", ErrDuplicatePayment, p.Reference, number)
+	}
+	if p.Date.IsZero() {
+		p.Date = r.now()
+	}
+	rec.Payments = append(rec.Payments, p)
+	rec.PaidAmount = rec.PaidAmount.Add(p.Amount)
+	if rec.Outstanding().IsPositive() {
+		rec.UpdatedAt = r.now()
+		if err := r.write(rec); err != nil {
+			return nil, err
+		}
+		return rec, nil
+	}
+	note := "paid"
+	if p.Reference != "" {
+		note = "payment " + p.Reference
+	}
+	return r.transition(number, models.StatusPaid, p.Date, note, func(updated *Record) {
+		updated.Payments, updated.PaidAmount = rec.Payments, rec.PaidAmount
+	})
+}
+
+// RecordNotice implements Repository.
+func (r *DirRepository) RecordNotice(number string, n Notice) (*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.load(number)
+	if err != nil {
+		return nil, err
+	}
+	if !rec.IsOpen() {
+		return nil, fmt.Errorf("%w: %s is %s and cannot be dunned", ErrInvalidTransition, number, rec.Status)
+	}
+	if n.Date.IsZero() {
+		n.Date = r.now()
+	}
+	rec.Notices = append(rec.Notices, n)
+	rec.UpdatedAt = r.now()
+	if err := r.write(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// MarkOverdue implements Repository.
+func (r *DirRepository) MarkOverdue(now time.Time) ([]*Record, error) {
+	sent, err := r.List(Filter{Status: models.StatusSent})
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var overdue []*Record
+	for _, rec := range sent {
+		if !rec.IsOverdue(now) {
+			continue
+		}
+		updated, err := r.transition(rec.Number, models.StatusOverdue, now, "due "+rec.DueDate.Format("2006-01-02"))
+		if err != nil {
+			return overdue, err
+		}
+		overdue = append(overdue, updated)
+	}
+	return overdue, nil
+}
+
+// transition applies a status change to a stored record; callers hold the mutex.
+func (r *DirRepository) transition(number string, to models.InvoiceStatus, at time.Time, note string, update ...func(*Record)) (*Record, error) {
+	rec, err := r.load(number)
+	if err != nil {
+		return nil, err
+	}
+	if !rec.Status.CanTransitionTo(to) || !rec.Type.AllowsStatus(to) {
+		return nil, fmt.Errorf("%w: %s is %s and cannot become %s", ErrInvalidTransition, number, rec.Status, to)
+	}
+	if at.IsZero() {
+		at = r.now()
+	}
+	rec.History = append(rec.History, StatusChange{From: rec.Status, To: to, At: at, Note: note})
+	rec.Status = to
+	if rec.Data != nil {
+		rec.Data.Invoice.Status = to
+	}
+	for _, fn := range update {
+		fn(rec)
+	}
+	rec.UpdatedAt = r.now()
+	if err := r.write(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// references reports whether a credit note names the invoice as preceding invoice.
+func references(credit *Record, number string) bool {
+	if credit.Type != models.InvoiceTypeCreditNote || credit.Data == nil {
+		return false
+	}
+	for _, ref := range credit.Data.Invoice.PrecedingInvoices {
+		if ref.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *DirRepository) load(number string) (*Record, error) {
+	rec, err := r.read(r.path(number))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, number)
+	}
+	if err == nil && rec.Number != number {
+		return nil, fmt.Errorf("invoice number %s maps to the document of %s", number, rec.Number)
+	}
+	return rec, err
+}
+
+func (r *DirRepository) read(path string) (*Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &rec, nil
+}
+
+func (r *DirRepository) write(rec *Record) error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("create repository %s: %w", r.dir, err)
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := r.path(rec.Number)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// path maps an invoice number to its document, replacing characters that are not
+// safe in file names.
+func (r *DirRepository) path(number string) string {
+	name := strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+			return c
+		}
+		return '_'
+	}, number)
+	return filepath.Join(r.dir, name+".json")
+}
+
+var _ Repository = (*DirRepository)(nil)
@@ -0,0 +1,178 @@
+// Package repository records generated invoices and tracks their status.
+//
+// Every generated invoice is stored with its data, the generated PDF and the history
+// of its status changes. Status changes follow the lifecycle defined by
+// models.InvoiceStatus: draft → sent → paid, with sent invoices turning overdue after
+// their due date. An issued invoice can only be cancelled by a credit note. Quotes and
+// order confirmations are stored alongside; a sent quote is accepted or declined
+// instead of being paid.
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"invoiceformats/pkg/models"
+)
+
+var (
+	// ErrNotFound is returned for unknown invoice numbers.
+	ErrNotFound = errors.New("invoice not found")
+	// ErrInvalidTransition is returned for status changes the lifecycle does not allow.
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrIssued is returned when saving over an invoice that has left draft state.
+	ErrIssued = errors.New("invoice already issued")
+	// ErrCreditNoteRequired is returned when cancelling an issued invoice without a credit note.
+	ErrCreditNoteRequired = errors.New("issued invoices can only be cancelled by a credit note")
+	// ErrDuplicatePayment is returned when a payment reference was recorded before.
+	ErrDuplicatePayment = errors.New("payment already recorded")
+)
+
+// Record is a stored invoice.
+type Record struct {
+	Number     string               `json:"number"`
+	Type       models.InvoiceType   `json:"type,omitempty"`
+	Status     models.InvoiceStatus `json:"status"`
+	Client     string               `json:"client"`
+	Date       time.Time            `json:"date"`
+	DueDate    time.Time            `json:"due_date"`
+	Currency   string               `json:"currency"`
+	GrandTotal decimal.Decimal      `json:"grand_total"`
+	AmountDue  decimal.Decimal      `json:"amount_due"`
+	PDFFile    string               `json:"pdf_file,omitempty"`
+	CreditNote string               `json:"credit_note,omitempty"` // Credit note that cancelled the invoice
+	Payments   []Payment            `json:"payments,omitempty"`
+	PaidAmount decimal.Decimal      `json:"paid_amount"`
+	Notices    []Notice             `json:"notices,omitempty"` // Reminders and dunning notices sent
+	History    []StatusChange       `json:"history"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+	Data       *models.InvoiceData  `json:"data"`
+}
+
+// StatusChange is an entry in the status history of a record.
+type StatusChange struct {
+	From models.InvoiceStatus `json:"from,omitempty"`
+	To   models.InvoiceStatus `json:"to"`
+	At   time.Time            `json:"at"`
+	Note string               `json:"note,omitempty"`
+}
+
+// Payment is a payment received for an invoice.
+type Payment struct {
+	Date      time.Time       `json:"date"`
+	Amount    decimal.Decimal `json:"amount"`
+	Reference string          `json:"reference,omitempty"` // Bank transaction reference
+	Payer     string          `json:"payer,omitempty"`
+	IBAN      string          `json:"iban,omitempty"`
+	Note      string          `json:"note,omitempty"`
+}
+
+// Notice is a reminder or dunning notice sent for an invoice.
+type Notice struct {
+	Level    int             `json:"level"`
+	Name     string          `json:"name"`
+	Date     time.Time       `json:"date"`
+	PayBy    time.Time       `json:"pay_by"`
+	Fee      decimal.Decimal `json:"fee"`      // Fee charged by this notice
+	Interest decimal.Decimal `json:"interest"` // Default interest up to the notice date
+	Total    decimal.Decimal `json:"total"`    // Amount claimed by the notice
+	File     string          `json:"file,omitempty"`
+}
+
+// NewRecord creates a draft record for generated invoice data.
+func NewRecord(data *models.InvoiceData, pdfFile string) *Record {
+	inv := data.Invoice
+	return &Record{
+		Number:     inv.Number,
+		Type:       inv.Type,
+		Status:     models.StatusDraft,
+		Client:     data.Client.Name,
+		Date:       inv.Date,
+		DueDate:    inv.DueDate,
+		Currency:   inv.Currency.Code,
+		GrandTotal: inv.GrandTotal,
+		AmountDue:  inv.AmountDue,
+		PDFFile:    pdfFile,
+		Data:       data,
+	}
+}
+
+// Outstanding returns the amount still to be paid.
+func (r *Record) Outstanding() decimal.Decimal {
+	return r.AmountDue.Sub(r.PaidAmount)
+}
+
+// IsOpen reports whether the invoice awaits payment.
+func (r *Record) IsOpen() bool {
+	return r.Type.IsInvoice() && (r.Status == models.StatusSent || r.Status == models.StatusOverdue)
+}
+
+// HasPayment reports whether a payment with the bank reference was recorded.
+func (r *Record) HasPayment(reference string) bool {
+	for _, p := range r.Payments {
+		if reference != "" && p.Reference == reference {
+			return true
+		}
+	}
+	return false
+}
+
+// DunningLevel returns the level of the last notice sent, 0 if none.
+func (r *Record) DunningLevel() int {
+	if len(r.Notices) == 0 {
+		return 0
+	}
+	return r.Notices[len(r.Notices)-1].Level
+}
+
+// IsOverdue reports whether a sent invoice is past its due date at now.
+func (r *Record) IsOverdue(now time.Time) bool {
+	return r.Type.IsInvoice() && r.Status == models.StatusSent && !r.DueDate.IsZero() && r.DueDate.Before(now)
+}
+
+// Filter selects records in List. Zero fields match everything.
+type Filter struct {
+	Status models.InvoiceStatus
+	Type   models.InvoiceType
+	Client string // Case-insensitive substring of the client name
+}
+
+// Match reports whether the record passes the filter.
+func (f Filter) Match(r *Record) bool {
+	if f.Status != "" && r.Status != f.Status {
+		return false
+	}
+	if f.Type != "" && r.Type != f.Type {
+		return false
+	}
+	if f.Client != "" && !strings.Contains(strings.ToLower(r.Client), strings.ToLower(f.Client)) {
+		return false
+	}
+	return true
+}
+
+// Repository stores invoice records.
+type Repository interface {
+	// Save stores a new record or replaces a draft; issued invoices fail with ErrIssued.
+	Save(rec *Record) error
+	// Get returns the record for an invoice number.
+	Get(number string) (*Record, error)
+	// List returns the records matching the filter, ordered by date and number.
+	List(filter Filter) ([]*Record, error)
+	// Transition moves an invoice to a new status.
+	Transition(number string, to models.InvoiceStatus, at time.Time, note string) (*Record, error)
+	// Cancel cancels an invoice; issued invoices need the number of a stored credit
+	// note referencing them.
+	Cancel(number, creditNote string, at time.Time) (*Record, error)
+	// RecordPayment adds a payment to an open invoice and marks it paid once nothing is
+	// outstanding.
+	RecordPayment(number string, p Payment) (*Record, error)
+	// RecordNotice adds a reminder or dunning notice to an open invoice.
+	RecordNotice(number string, n Notice) (*Record, error)
+	// MarkOverdue moves sent invoices past their due date to overdue and returns them.
+	MarkOverdue(now time.Time) ([]*Record, error)
+}
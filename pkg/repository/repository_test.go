@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/pkg/models"
+)
+
+func invoice(number string, due time.Time) *models.InvoiceData {
+	return &models.InvoiceData{
+		Client: models.ClientInfo{Name: "Pixel Dynamics GmbH"},
+		Invoice: models.InvoiceDetails{
+			Number:     number,
+			Date:       due.AddDate(0, 0, -30),
+			DueDate:    due,
+			Currency:   models.Currency{Code: "EUR"},
+			GrandTotal: decimal.NewFromInt(119),
+		},
+	}
+}
+
+func creditNote(number, credits string) *models.InvoiceData {
+	data := invoice(number, time.Now())
+	data.Invoice.Type = models.InvoiceTypeCreditNote
+	data.Invoice.PrecedingInvoices = []models.InvoiceReference{{Number: credits}}
+	return data
+}
+
+func TestDirRepository_Lifecycle(t *testing.T) {
+	repo := NewDirRepository(t.TempDir())
+	require.NoError(t, repo.Save(NewRecord(invoice("RE-2025-0001", time.Now().AddDate(0, 0, 30)), "out.pdf")))
+
+	// Drafts can be regenerated
+	require.NoError(t, repo.Save(NewRecord(invoice("RE-2025-0001", time.Now().AddDate(0, 0, 30)), "out.pdf")))
+
+	_, err := repo.Transition("RE-2025-0001", models.StatusPaid, time.Time{}, "")
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+
+	rec, err := repo.Transition("RE-2025-0001", models.StatusSent, time.Time{}, "by email")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, rec.Status)
+	assert.Equal(t, models.StatusSent, rec.Data.Invoice.Status)
+
+	// Issued invoices are immutable
+	err = repo.Save(NewRecord(invoice("RE-2025-0001", time.Now()), "out.pdf"))
+	assert.True(t, errors.Is(err, ErrIssued))
+
+	rec, err = repo.Transition("RE-2025-0001", models.StatusPaid, time.Time{}, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPaid, rec.Status)
+
+	rec, err = repo.Get("RE-2025-0001")
+	require.NoError(t, err)
+	require.Len(t, rec.History, 3)
+	assert.Equal(t, models.StatusDraft, rec.History[0].To)
+	assert.Equal(t, "by email", rec.History[1].Note)
+	assert.Equal(t, models.StatusSent, rec.History[2].From)
+
+	_, err = repo.Get("RE-2025-0099")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestDirRepository_CancelRequiresCreditNote(t *testing.T) {
+	repo := NewDirRepository(t.TempDir())
+	require.NoError(t, repo.Save(NewRecord(invoice("RE-1", time.Now()), "")))
+	require.NoError(t, repo.Save(NewRecord(invoice("RE-2", time.Now()), "")))
+	require.NoError(t, repo.Save(NewRecord(creditNote("GS-1", "RE-2"), "")))
+	_, err := repo.Transition("RE-1", models.StatusSent, time.Time{}, "")
+	require.NoError(t, err)
+
+	_, err = repo.Transition("RE-1", models.StatusCancelled, time.Time{}, "")
+	assert.True(t, errors.Is(err, ErrCreditNoteRequired))
+
+	_, err = repo.Cancel("RE-1", "GS-1", time.Time{})
+	assert.True(t, errors.Is(err, ErrCreditNoteRequired), "credit note references another invoice")
+
+	require.NoError(t, repo.Save(NewRecord(creditNote("GS-2", "RE-1"), "")))
+	rec, err := repo.Cancel("RE-1", "GS-2", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, rec.Status)
+	assert.Equal(t, "GS-2", rec.CreditNote)
+
+	// Drafts were never issued and can be dropped directly
+	rec, err = repo.Transition("RE-2", models.StatusCancelled, time.Time{}, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, rec.Status)
+}
+
+func TestDirRepository_QuoteLifecycle(t *testing.T) {
+	repo := NewDirRepository(t.TempDir())
+	quote := invoice("Q-1", time.Now().AddDate(0, 0, -1))
+	quote.Invoice.Type = models.InvoiceTypeQuote
+	require.NoError(t, repo.Save(NewRecord(quote, "")))
+	rec, err := repo.Transition("Q-1", models.StatusSent, time.Time{}, "")
+	require.NoError(t, err)
+
+	// Quotes are never due for payment
+	assert.False(t, rec.IsOpen())
+	overdue, err := repo.MarkOverdue(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, overdue)
+	_, err = repo.Transition("Q-1", models.StatusPaid, time.Time{}, "")
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+	_, err = repo.RecordPayment("Q-1", Payment{Amount: decimal.NewFromInt(119)})
+	assert.Error(t, err)
+
+	rec, err = repo.Transition("Q-1", models.StatusAccepted, time.Time{}, "PO 4711")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusAccepted, rec.Status)
+
+	// Invoices cannot be accepted, and sent quotes are withdrawn without a credit note
+	require.NoError(t, repo.Save(NewRecord(invoice("RE-1", time.Now()), "")))
+	_, err = repo.Transition("RE-1", models.StatusSent, time.Time{}, "")
+	require.NoError(t, err)
+	_, err = repo.Transition("RE-1", models.StatusAccepted, time.Time{}, "")
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+
+	quote = invoice("Q-2", time.Now())
+	quote.Invoice.Type = models.InvoiceTypeQuote
+	require.NoError(t, repo.Save(NewRecord(quote, "")))
+	_, err = repo.Transition("Q-2", models.StatusSent, time.Time{}, "")
+	require.NoError(t, err)
+	rec, err = repo.Transition("Q-2", models.StatusCancelled, time.Time{}, "withdrawn")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, rec.Status)
+}
+
+func TestDirRepository_ListAndOverdue(t *testing.T) {
+	repo := NewDirRepository(t.TempDir())
+	now := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Save(NewRecord(invoice("RE/2025/2", now.AddDate(0, 0, 10)), "")))
+	require.NoError(t, repo.Save(NewRecord(invoice("RE/2025/1", now.AddDate(0, 0, -1)), "")))
+	for _, n := range []string{"RE/2025/1", "RE/2025/2"} {
+		_, err := repo.Transition(n, models.StatusSent, now, "")
+		require.NoError(t, err)
+	}
+
+	overdue, err := repo.MarkOverdue(now)
+	require.NoError(t, err)
+	require.Len(t, overdue, 1)
+	assert.Equal(t, "RE/2025/1", overdue[0].Number)
+
+	all, err := repo.List(Filter{})
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "RE/2025/1", all[0].Number)
+
+	sent, err := repo.List(Filter{Status: models.StatusSent, Client: "pixel"})
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Equal(t, "RE/2025/2", sent[0].Number)
+
+	// Overdue invoices can still be paid
+	rec, err := repo.Transition("RE/2025/1", models.StatusPaid, now, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPaid, rec.Status)
+}
+
+func TestDirRepository_RecordPayment(t *testing.T) {
+	repo := NewDirRepository(t.TempDir())
+	data := invoice("RE-1", time.Now().AddDate(0, 0, 30))
+	data.Invoice.AmountDue = decimal.NewFromInt(119)
+	require.NoError(t, repo.Save(NewRecord(data, "")))
+
+	// Drafts are not open for payment
+	_, err := repo.RecordPayment("RE-1", Payment{Amount: decimal.NewFromInt(19), Reference: "TX-1"})
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+
+	_, err = repo.Transition("RE-1", models.StatusSent, time.Time{}, "")
+	require.NoError(t, err)
+
+	rec, err := repo.RecordPayment("RE-1", Payment{Amount: decimal.NewFromInt(19), Reference: "TX-1"})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, rec.Status)
+	assert.True(t, decimal.NewFromInt(100).Equal(rec.Outstanding()))
+
+	_, err = repo.RecordPayment("RE-1", Payment{Amount: decimal.NewFromInt(19), Reference: "TX-1"})
+	assert.True(t, errors.Is(err, ErrDuplicatePayment))
+
+	rec, err = repo.RecordPayment("RE-1", Payment{Amount: decimal.NewFromInt(100), Reference: "TX-2"})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPaid, rec.Status)
+	assert.Len(t, rec.Payments, 2)
+
+	rec, err = repo.Get("RE-1")
+	require.NoError(t, err)
+	assert.True(t, rec.HasPayment("TX-2"))
+	assert.True(t, rec.Outstanding().IsZero())
+}
+
+func TestDirRepository_RecordNotice(t *testing.T) {
+	repo := NewDirRepository(t.TempDir())
+	require.NoError(t, repo.Save(NewRecord(invoice("RE-1", time.Now().AddDate(0, 0, -10)), "")))
+
+	_, err := repo.RecordNotice("RE-1", Notice{Level: 1, Name: "reminder"})
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+
+	_, err = repo.Transition("RE-1", models.StatusSent, time.Time{}, "")
+	require.NoError(t, err)
+	rec, err := repo.RecordNotice("RE-1", Notice{Level: 1, Name: "reminder", Total: decimal.NewFromInt(119), File: "r.pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rec.DunningLevel())
+	assert.False(t, rec.Notices[0].Date.IsZero())
+
+	rec, err = repo.Get("RE-1")
+	require.NoError(t, err)
+	require.Len(t, rec.Notices, 1)
+	assert.Equal(t, "r.pdf", rec.Notices[0].File)
+}
@@ -0,0 +1,330 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+)
+
+// APIKeyHeader is the header of API keys; keys may also be sent as bearer tokens.
+const APIKeyHeader = "X-API-Key"
+
+// authenticator identifies the tenant of requests by API key or HS256 JWT bearer
+// token and enforces the per-key rate limits.
+type authenticator struct {
+	tenants   map[string]*config.TenantConfig
+	keys      map[[sha256.Size]byte]*config.TenantConfig // By SHA-256 digest of the key
+	jwtSecret []byte
+	rateLimit int
+	rateBurst int
+	limiter   *rateLimiter
+	now       func() time.Time
+}
+
+// identity is an authenticated caller.
+type identity struct {
+	tenant *config.TenantConfig
+	key    string // Rate limit key: the API key digest or the token subject
+}
+
+// newAuthenticator returns the authenticator of cfg, or nil if no tenants are
+// configured and the API is open.
+func newAuthenticator(cfg config.AuthConfig) (*authenticator, error) {
+	if len(cfg.Tenants) == 0 {
+		if cfg.JWTSecret != "" || cfg.JWTSecretEnv != "" {
+			return nil, appErrs.NewConfigError("server.auth needs tenants for JWT authentication", nil)
+		}
+		return nil, nil
+	}
+	a := &authenticator{
+		tenants:   make(map[string]*config.TenantConfig),
+		keys:      make(map[[sha256.Size]byte]*config.TenantConfig),
+		rateLimit: cfg.RateLimit,
+		rateBurst: cfg.RateBurst,
+		limiter:   newRateLimiter(),
+		now:       time.Now,
+	}
+	a.jwtSecret = []byte(cfg.JWTSecret)
+	if cfg.JWTSecretEnv != "" {
+		a.jwtSecret = []byte(os.Getenv(cfg.JWTSecretEnv))
+		if len(a.jwtSecret) == 0 {
+			return nil, appErrs.NewConfigError("JWT secret variable "+cfg.JWTSecretEnv+" is not set", nil)
+		}
+	}
+
+	for i := range cfg.Tenants {
+		t := &cfg.Tenants[i]
+		if t.ID == "" || strings.ContainsAny(t.ID, `/\.`) {
+			return nil, appErrs.NewConfigError(fmt.Sprintf("invalid tenant id %q", t.ID), nil)
+		}
+		if _, dup := a.tenants[t.ID]; dup {
+			return nil, appErrs.NewConfigError("duplicate tenant "+t.ID, nil)
+		}
+		a.tenants[t.ID] = t
+
+		keys := t.APIKeys
+		if t.APIKeysEnv != "" {
+			env := os.Getenv(t.APIKeysEnv)
+			if env == "" {
+				return nil, appErrs.NewConfigError("API key variable "+t.APIKeysEnv+" is not set", nil)
+			}
+			keys = append(append([]string(nil), keys...), strings.Split(env, ",")...)
+		}
+		for _, key := range keys {
+			digest, err := keyDigest(strings.TrimSpace(key))
+			if err != nil {
+				return nil, appErrs.NewConfigError("invalid API key of tenant "+t.ID, err)
+			}
+			if other, dup := a.keys[digest]; dup && other != t {
+				return nil, appErrs.NewConfigError("API key shared by tenants "+other.ID+" and "+t.ID, nil)
+			}
+			a.keys[digest] = t
+		}
+	}
+	return a, nil
+}
+
+// keyDigest returns the SHA-256 digest of a configured key, which is the key in plain
+// text or its digest as "sha256:<hex>".
+func keyDigest(key string) ([sha256.Size]byte, error) {
+	var digest [sha256.Size]byte
+	if hexDigest, ok := strings.CutPrefix(key, "sha256:"); ok {
+		raw, err := hex.DecodeString(hexDigest)
+		if err != nil || len(raw) != sha256.Size {
+			return digest, errors.New("sha256 digest must be 64 hex digits")
+		}
+		copy(digest[:], raw)
+		return digest, nil
+	}
+	if key == "" {
+		return digest, errors.New("empty key")
+	}
+	return sha256.Sum256([]byte(key)), nil
+}
+
+// identify authenticates the credentials of a request: an API key in X-API-Key or a
+// bearer token, which is either an API key or a JWT.
+func (a *authenticator) identify(authorization, apiKey string) (*identity, error) {
+	token := apiKey
+	if token == "" {
+		scheme, value, _ := strings.Cut(authorization, " ")
+		if !strings.EqualFold(scheme, "Bearer") || value == "" {
+			return nil, appErrs.NewUnauthorizedError("missing API key or bearer token", nil)
+		}
+		token = strings.TrimSpace(value)
+	}
+	if len(a.jwtSecret) > 0 && strings.Count(token, ".") == 2 {
+		return a.verifyJWT(token)
+	}
+	digest := sha256.Sum256([]byte(token))
+	t, ok := a.keys[digest]
+	if !ok {
+		return nil, appErrs.NewUnauthorizedError("invalid API key", nil)
+	}
+	return &identity{tenant: t, key: "key:" + hex.EncodeToString(digest[:8])}, nil
+}
+
+// jwtClaims are the claims of bearer tokens. The tenant claim names the tenant; the
+// subject, if any, gets its own rate limit within the tenant.
+type jwtClaims struct {
+	Tenant    string `json:"tenant"`
+	Subject   string `json:"sub"`
+	ExpiresAt *int64 `json:"exp"`
+	NotBefore *int64 `json:"nbf"`
+}
+
+// verifyJWT checks an HS256 token and its exp and nbf claims; tokens must expire.
+func (a *authenticator) verifyJWT(token string) (*identity, error) {
+	parts := strings.Split(token, ".")
+	invalid := func(msg string, cause error) (*identity, error) {
+		return nil, appErrs.NewUnauthorizedError("invalid bearer token: "+msg, cause)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return invalid("malformed header", err)
+	}
+	if header.Alg != "HS256" {
+		return invalid("algorithm must be HS256", nil)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return invalid("malformed signature", err)
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return invalid("signature mismatch", nil)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return invalid("malformed claims", err)
+	}
+	now := a.now().Unix()
+	switch {
+	case claims.ExpiresAt == nil:
+		return invalid("exp claim is required", nil)
+	case now >= *claims.ExpiresAt:
+		return invalid("token expired", nil)
+	case claims.NotBefore != nil && now < *claims.NotBefore:
+		return invalid("token not valid yet", nil)
+	}
+	t, ok := a.tenants[claims.Tenant]
+	if !ok {
+		return invalid(fmt.Sprintf("unknown tenant %q", claims.Tenant), nil)
+	}
+	return &identity{tenant: t, key: "jwt:" + t.ID + ":" + claims.Subject}, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// allow takes a request from the rate limit of the caller and, if it is exhausted,
+// returns an error and the time until the next request is allowed.
+func (a *authenticator) allow(id *identity) (time.Duration, error) {
+	limit := a.rateLimit
+	if id.tenant.RateLimit > 0 {
+		limit = id.tenant.RateLimit
+	}
+	if limit <= 0 {
+		return 0, nil
+	}
+	burst := a.rateBurst
+	if burst <= 0 {
+		burst = limit
+	}
+	if wait := a.limiter.take(id.key, limit, burst, a.now()); wait > 0 {
+		return wait, appErrs.NewRateLimitedError(fmt.Sprintf("rate limit of %d requests per minute exceeded", limit))
+	}
+	return 0, nil
+}
+
+// rateLimiter keeps a token bucket per key.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// maxBuckets bounds the number of buckets kept; full buckets are dropped beyond it.
+const maxBuckets = 10000
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket)}
+}
+
+// take takes a token from the bucket of key, which holds up to burst tokens and gains
+// perMinute tokens a minute. It returns 0 on success, or the time until a token is
+// available.
+func (l *rateLimiter) take(key string, perMinute, burst int, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rate := float64(perMinute) / time.Minute.Seconds()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.prune(now, rate, burst)
+		}
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// prune drops the buckets that have refilled; a new bucket would be the same.
+func (l *rateLimiter) prune(now time.Time, rate float64, burst int) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= float64(burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type tenantKey struct{}
+
+func withTenant(ctx context.Context, t *config.TenantConfig) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// TenantID returns the ID of the tenant a request was authenticated for, or "" if the
+// API is open.
+func TenantID(ctx context.Context) string {
+	if t, ok := ctx.Value(tenantKey{}).(*config.TenantConfig); ok {
+		return t.ID
+	}
+	return ""
+}
+
+// publicPaths are served without authentication: the API description, the probes and
+// the metrics.
+var publicPaths = map[string]bool{
+	"/v1/openapi.json": true,
+	"/healthz":         true,
+	"/readyz":          true,
+	"/metrics":         true,
+}
+
+// authenticate is the middleware that identifies the tenant of requests, applies the
+// rate limit and the tenant's request size limit and passes the tenant on in the
+// request context. The publicPaths are exempt.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, err := s.auth.identify(r.Header.Get("Authorization"), r.Header.Get(APIKeyHeader))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="invoicegen"`)
+			s.writeError(w, err)
+			return
+		}
+		if rec, ok := w.(*statusRecorder); ok {
+			rec.tenant = id.tenant.ID
+		}
+		if wait, err := s.auth.allow(id); err != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			s.writeError(w, err)
+			return
+		}
+		if id.tenant.MaxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, id.tenant.MaxBodyBytes)
+		}
+		next.ServeHTTP(w, r.WithContext(withTenant(r.Context(), id.tenant)))
+	})
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/jobs"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/server/invoicepb"
+	"invoiceformats/pkg/service"
+)
+
+// ChunkSize is the size of the chunks streamed by GeneratePDF.
+const ChunkSize = 64 << 10
+
+// ErrorDomain is the domain of the ErrorInfo details of gRPC errors.
+const ErrorDomain = "invoicegen"
+
+// NewGRPCServer creates the gRPC server of the API. It shares the invoice service, its
+// serialization, authentication and tracing of the REST endpoints; credentials are
+// sent as authorization or x-api-key metadata, trace context as traceparent.
+func (s *Server) NewGRPCServer() *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(MaxBodyBytes),
+		grpc.ChainUnaryInterceptor(traceUnary),
+		grpc.ChainStreamInterceptor(traceStream),
+	}
+	if s.auth != nil {
+		opts = append(opts, grpc.ChainUnaryInterceptor(s.authenticateUnary), grpc.ChainStreamInterceptor(s.authenticateStream))
+	}
+	gs := grpc.NewServer(opts...)
+	invoicepb.RegisterInvoiceServiceServer(gs, &grpcService{server: s})
+	return gs
+}
+
+// identifyCall authenticates a gRPC call and applies the rate limit like authenticate.
+func (s *Server) identifyCall(ctx context.Context) (*identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	id, err := s.auth.identify(first("authorization"), first(strings.ToLower(APIKeyHeader)))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if _, err := s.auth.allow(id); err != nil {
+		return nil, grpcError(err)
+	}
+	return id, nil
+}
+
+// checkSize applies the request size limit of a tenant to a request message.
+func checkSize(id *identity, req interface{}) error {
+	msg, ok := req.(proto.Message)
+	if ok && id.tenant.MaxBodyBytes > 0 && int64(proto.Size(msg)) > id.tenant.MaxBodyBytes {
+		return status.Errorf(codes.ResourceExhausted, "request larger than %d bytes", id.tenant.MaxBodyBytes)
+	}
+	return nil
+}
+
+func (s *Server) authenticateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id, err := s.identifyCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSize(id, req); err != nil {
+		return nil, err
+	}
+	return handler(withTenant(ctx, id.tenant), req)
+}
+
+func (s *Server) authenticateStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id, err := s.identifyCall(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &tenantStream{ServerStream: stream, id: id})
+}
+
+// tenantStream passes the tenant of a stream on in its context.
+type tenantStream struct {
+	grpc.ServerStream
+	id *identity
+}
+
+func (t *tenantStream) Context() context.Context {
+	return withTenant(t.ServerStream.Context(), t.id.tenant)
+}
+
+func (t *tenantStream) RecvMsg(m interface{}) error {
+	if err := t.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return checkSize(t.id, m)
+}
+
+// grpcService implements invoicepb.InvoiceServiceServer on top of the REST handlers'
+// request preparation. Invoices are converted through their JSON, which the messages
+// mirror, so profile references and catalog SKUs work as in invoice files.
+type grpcService struct {
+	invoicepb.UnimplementedInvoiceServiceServer
+	server *Server
+}
+
+func (g *grpcService) Validate(ctx context.Context, req *invoicepb.GenerateRequest) (*invoicepb.ValidateResponse, error) {
+	s := g.server
+	data, opts, err := g.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	opts.ValidateOnly = true
+	if err := s.serviceFor(TenantID(ctx)).GenerateInvoice(ctx, data, opts); err != nil {
+		return nil, grpcError(err)
+	}
+	invoice, err := toProto(data)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &invoicepb.ValidateResponse{Valid: true, Invoice: invoice}, nil
+}
+
+func (g *grpcService) RenderHTML(ctx context.Context, req *invoicepb.GenerateRequest) (*invoicepb.RenderHTMLResponse, error) {
+	s := g.server
+	data, opts, err := g.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	html, err := s.serviceFor(TenantID(ctx)).PreviewHTML(ctx, data, opts)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &invoicepb.RenderHTMLResponse{Html: html, InvoiceNumber: data.Invoice.Number}, nil
+}
+
+func (g *grpcService) GeneratePDF(req *invoicepb.GenerateRequest, stream grpc.ServerStreamingServer[invoicepb.FileChunk]) error {
+	s := g.server
+	tenant := TenantID(stream.Context())
+	data, opts, err := g.prepare(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	opts.EnableZUGFeRD = data.EmbeddedData == models.EmbeddedDataZUGFeRD
+	pdfData, err := s.serviceFor(tenant).GeneratePDF(stream.Context(), data, opts, s.outputDirFor(tenant))
+	if err != nil {
+		return grpcError(err)
+	}
+
+	first := &invoicepb.FileChunk{ContentType: "application/pdf", InvoiceNumber: data.Invoice.Number, Size: int64(len(pdfData))}
+	for off := 0; off == 0 || off < len(pdfData); off += ChunkSize {
+		chunk := &invoicepb.FileChunk{}
+		if off == 0 {
+			chunk = first
+		}
+		chunk.Data = pdfData[off:min(off+ChunkSize, len(pdfData))]
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *grpcService) GenerateXML(ctx context.Context, req *invoicepb.GenerateRequest) (*invoicepb.File, error) {
+	s := g.server
+	data, opts, err := g.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	xmlData, err := s.serviceFor(TenantID(ctx)).GenerateXML(ctx, data, opts)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &invoicepb.File{ContentType: "application/xml", InvoiceNumber: data.Invoice.Number, Data: xmlData}, nil
+}
+
+// ExtractFromPDF is not supported yet: generated PDFs do not carry the e-invoice XML
+// until the ZUGFeRD embedder writes PDF/A-3 attachments.
+func (g *grpcService) ExtractFromPDF(ctx context.Context, req *invoicepb.ExtractFromPDFRequest) (*invoicepb.InvoiceData, error) {
+	return nil, status.Error(codes.Unimplemented, "extracting invoices from PDFs is not supported yet")
+}
+
+// prepare decodes the invoice and options of a request like readRequest and prepare
+// do for REST requests.
+func (g *grpcService) prepare(ctx context.Context, req *invoicepb.GenerateRequest) (*models.InvoiceData, *service.GenerateOptions, error) {
+	if req.GetInvoice() == nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "invoice is required")
+	}
+	if req.Template != "" && !isTemplate(req.Template) {
+		return nil, nil, grpcError(appErrs.NewValidationError(fmt.Sprintf("unknown template %q", req.Template), nil))
+	}
+	raw, err := (protojson.MarshalOptions{UseProtoNames: true}).Marshal(req.Invoice)
+	if err != nil {
+		return nil, nil, grpcError(appErrs.NewValidationError("invalid invoice", err))
+	}
+	data, opts, err := g.server.prepare(jobs.Request{
+		Tenant:   TenantID(ctx),
+		Invoice:  raw,
+		Template: req.Template,
+		Lang:     req.Lang,
+		Currency: req.Currency,
+		Series:   req.Series,
+	})
+	if err != nil {
+		return nil, nil, grpcError(err)
+	}
+	return data, opts, nil
+}
+
+// toProto converts invoice data to its message.
+func toProto(data *models.InvoiceData) (*invoicepb.InvoiceData, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	msg := &invoicepb.InvoiceData{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, msg); err != nil {
+		return nil, appErrs.NewAppError(appErrs.ErrUnknown, "failed to convert invoice", err)
+	}
+	return msg, nil
+}
+
+// grpcError converts an error to a gRPC status with the code matching its HTTP status
+// and an ErrorInfo detail naming the AppError code.
+func grpcError(err error) error {
+	code := codes.Internal
+	switch StatusCode(err) {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	case StatusClientClosedRequest:
+		code = codes.Canceled
+	}
+	info := &errdetails.ErrorInfo{Reason: string(appErrs.ErrUnknown), Domain: ErrorDomain}
+	msg := err.Error()
+	var appErr *appErrs.AppError
+	if errors.As(err, &appErr) {
+		info.Reason, msg = string(appErr.Code), appErr.Message
+		if appErr.Cause != nil {
+			msg = fmt.Sprintf("%s: %v", msg, appErr.Cause)
+		}
+	}
+	st, detailErr := status.New(code, msg).WithDetails(info)
+	if detailErr != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
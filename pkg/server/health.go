@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"invoiceformats/pkg/metrics"
+	"invoiceformats/pkg/pdf"
+)
+
+// ReadyCheckInterval is how long /readyz reuses the result of a browser check, so
+// frequent probes do not open a browser tab each.
+const ReadyCheckInterval = 10 * time.Second
+
+// ReadyCheckTimeout bounds a browser check.
+const ReadyCheckTimeout = 10 * time.Second
+
+// HungAfter is how long a PDF may be printed past its deadline before /healthz reports
+// the browser as hung; printing is cancelled at the deadline.
+const HungAfter = pdf.RenderTimeout
+
+// HealthResponse is the body of /healthz and /readyz.
+type HealthResponse struct {
+	Status string            `json:"status"` // ok, ready or the failure
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// readiness caches the result of the browser check.
+type readiness struct {
+	check   func(context.Context) error
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+func (r *readiness) browser(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.checked.IsZero() && time.Since(r.checked) < ReadyCheckInterval {
+		return r.err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ReadyCheckTimeout)
+	defer cancel()
+	r.err = r.check(ctx)
+	r.checked = time.Now()
+	if r.err != nil {
+		metrics.BrowserUp.Set(0)
+	} else {
+		metrics.BrowserUp.Set(1)
+	}
+	return r.err
+}
+
+// handleHealthz is the liveness probe. It fails while a PDF has been printing for
+// longer than HungAfter past its deadline, since only a restart recovers a hung browser.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if overdue := pdf.Overdue(); overdue > HungAfter {
+		writeJSON(w, http.StatusServiceUnavailable, HealthResponse{
+			Status: "unhealthy",
+			Checks: map[string]string{"pdf": "printing for " + pdf.Busy().Round(time.Second).String()},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// handleReadyz is the readiness probe. It loads a blank page in the shared browser, at
+// most every ReadyCheckInterval, and fails if Chrome is unavailable.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := s.ready.browser(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, HealthResponse{
+			Status: "unavailable",
+			Checks: map[string]string{"browser": err.Error()},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ready", Checks: map[string]string{"browser": "ok"}})
+}
@@ -0,0 +1,5 @@
+// Package invoicepb holds the protobuf messages and gRPC stubs of the invoicegen gRPC
+// API, generated from invoicegen.proto.
+package invoicepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative invoicegen.proto
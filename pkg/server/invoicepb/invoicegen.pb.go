@@ -0,0 +1,1836 @@
+// gRPC API of invoicegen. The messages mirror the invoice model and its JSON: field
+// names are those of invoice files, amounts are decimal strings such as "120.50" and
+// enum-like fields take the values of the JSON Schema (`invoicegen schema`).
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: invoicegen.proto
+
+package invoicepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// GenerateRequest is an invoice with the options of the invoice endpoints.
+type GenerateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Invoice       *InvoiceData           `protobuf:"bytes,1,opt,name=invoice,proto3" json:"invoice,omitempty"`
+	Template      string                 `protobuf:"bytes,2,opt,name=template,proto3" json:"template,omitempty"` // Embedded template name
+	Lang          string                 `protobuf:"bytes,3,opt,name=lang,proto3" json:"lang,omitempty"`
+	Currency      string                 `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"` // Currency to convert the invoice to
+	Series        string                 `protobuf:"bytes,5,opt,name=series,proto3" json:"series,omitempty"`     // Number series
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateRequest) Reset() {
+	*x = GenerateRequest{}
+	mi := &file_invoicegen_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRequest) ProtoMessage() {}
+
+func (x *GenerateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRequest) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GenerateRequest) GetInvoice() *InvoiceData {
+	if x != nil {
+		return x.Invoice
+	}
+	return nil
+}
+
+func (x *GenerateRequest) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetSeries() string {
+	if x != nil {
+		return x.Series
+	}
+	return ""
+}
+
+type ValidateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Valid         bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	Invoice       *InvoiceData           `protobuf:"bytes,2,opt,name=invoice,proto3" json:"invoice,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateResponse) Reset() {
+	*x = ValidateResponse{}
+	mi := &file_invoicegen_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateResponse) ProtoMessage() {}
+
+func (x *ValidateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateResponse.ProtoReflect.Descriptor instead.
+func (*ValidateResponse) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ValidateResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *ValidateResponse) GetInvoice() *InvoiceData {
+	if x != nil {
+		return x.Invoice
+	}
+	return nil
+}
+
+type RenderHTMLResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Html          string                 `protobuf:"bytes,1,opt,name=html,proto3" json:"html,omitempty"`
+	InvoiceNumber string                 `protobuf:"bytes,2,opt,name=invoice_number,json=invoiceNumber,proto3" json:"invoice_number,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenderHTMLResponse) Reset() {
+	*x = RenderHTMLResponse{}
+	mi := &file_invoicegen_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenderHTMLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenderHTMLResponse) ProtoMessage() {}
+
+func (x *RenderHTMLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenderHTMLResponse.ProtoReflect.Descriptor instead.
+func (*RenderHTMLResponse) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RenderHTMLResponse) GetHtml() string {
+	if x != nil {
+		return x.Html
+	}
+	return ""
+}
+
+func (x *RenderHTMLResponse) GetInvoiceNumber() string {
+	if x != nil {
+		return x.InvoiceNumber
+	}
+	return ""
+}
+
+type File struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentType   string                 `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	InvoiceNumber string                 `protobuf:"bytes,2,opt,name=invoice_number,json=invoiceNumber,proto3" json:"invoice_number,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *File) Reset() {
+	*x = File{}
+	mi := &file_invoicegen_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *File) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*File) ProtoMessage() {}
+
+func (x *File) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use File.ProtoReflect.Descriptor instead.
+func (*File) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *File) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *File) GetInvoiceNumber() string {
+	if x != nil {
+		return x.InvoiceNumber
+	}
+	return ""
+}
+
+func (x *File) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// FileChunk is a part of a streamed file. Only the first chunk sets content_type,
+// invoice_number and size.
+type FileChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentType   string                 `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	InvoiceNumber string                 `protobuf:"bytes,2,opt,name=invoice_number,json=invoiceNumber,proto3" json:"invoice_number,omitempty"`
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileChunk) Reset() {
+	*x = FileChunk{}
+	mi := &file_invoicegen_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunk) ProtoMessage() {}
+
+func (x *FileChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FileChunk) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *FileChunk) GetInvoiceNumber() string {
+	if x != nil {
+		return x.InvoiceNumber
+	}
+	return ""
+}
+
+func (x *FileChunk) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type ExtractFromPDFRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pdf           []byte                 `protobuf:"bytes,1,opt,name=pdf,proto3" json:"pdf,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtractFromPDFRequest) Reset() {
+	*x = ExtractFromPDFRequest{}
+	mi := &file_invoicegen_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtractFromPDFRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtractFromPDFRequest) ProtoMessage() {}
+
+func (x *ExtractFromPDFRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtractFromPDFRequest.ProtoReflect.Descriptor instead.
+func (*ExtractFromPDFRequest) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ExtractFromPDFRequest) GetPdf() []byte {
+	if x != nil {
+		return x.Pdf
+	}
+	return nil
+}
+
+type InvoiceData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      *CompanyInfo           `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Client        *ClientInfo            `protobuf:"bytes,2,opt,name=client,proto3" json:"client,omitempty"`
+	Invoice       *InvoiceDetails        `protobuf:"bytes,3,opt,name=invoice,proto3" json:"invoice,omitempty"`
+	EmbeddedData  string                 `protobuf:"bytes,4,opt,name=embedded_data,json=embeddedData,proto3" json:"embedded_data,omitempty"` // none or zugferd
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InvoiceData) Reset() {
+	*x = InvoiceData{}
+	mi := &file_invoicegen_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvoiceData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvoiceData) ProtoMessage() {}
+
+func (x *InvoiceData) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvoiceData.ProtoReflect.Descriptor instead.
+func (*InvoiceData) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *InvoiceData) GetProvider() *CompanyInfo {
+	if x != nil {
+		return x.Provider
+	}
+	return nil
+}
+
+func (x *InvoiceData) GetClient() *ClientInfo {
+	if x != nil {
+		return x.Client
+	}
+	return nil
+}
+
+func (x *InvoiceData) GetInvoice() *InvoiceDetails {
+	if x != nil {
+		return x.Invoice
+	}
+	return nil
+}
+
+func (x *InvoiceData) GetEmbeddedData() string {
+	if x != nil {
+		return x.EmbeddedData
+	}
+	return ""
+}
+
+type Currency struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Symbol        string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Rate          string                 `protobuf:"bytes,3,opt,name=rate,proto3" json:"rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Currency) Reset() {
+	*x = Currency{}
+	mi := &file_invoicegen_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Currency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Currency) ProtoMessage() {}
+
+func (x *Currency) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Currency.ProtoReflect.Descriptor instead.
+func (*Currency) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Currency) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Currency) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Currency) GetRate() string {
+	if x != nil {
+		return x.Rate
+	}
+	return ""
+}
+
+type Address struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Street        string                 `protobuf:"bytes,1,opt,name=street,proto3" json:"street,omitempty"`
+	City          string                 `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
+	PostalCode    string                 `protobuf:"bytes,3,opt,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	Country       string                 `protobuf:"bytes,5,opt,name=country,proto3" json:"country,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Address) Reset() {
+	*x = Address{}
+	mi := &file_invoicegen_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Address) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Address) ProtoMessage() {}
+
+func (x *Address) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Address.ProtoReflect.Descriptor instead.
+func (*Address) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Address) GetStreet() string {
+	if x != nil {
+		return x.Street
+	}
+	return ""
+}
+
+func (x *Address) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Address) GetPostalCode() string {
+	if x != nil {
+		return x.PostalCode
+	}
+	return ""
+}
+
+func (x *Address) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Address) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+type CompanyInfo struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name      string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Address   *Address               `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	VatId     string                 `protobuf:"bytes,4,opt,name=vat_id,json=vatId,proto3" json:"vat_id,omitempty"`
+	Email     string                 `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	Phone     string                 `protobuf:"bytes,6,opt,name=phone,proto3" json:"phone,omitempty"`
+	Website   string                 `protobuf:"bytes,7,opt,name=website,proto3" json:"website,omitempty"`
+	Iban      string                 `protobuf:"bytes,8,opt,name=iban,proto3" json:"iban,omitempty"`
+	Swift     string                 `protobuf:"bytes,9,opt,name=swift,proto3" json:"swift,omitempty"`
+	TaxNumber string                 `protobuf:"bytes,10,opt,name=tax_number,json=taxNumber,proto3" json:"tax_number,omitempty"`
+	Logo      string                 `protobuf:"bytes,11,opt,name=logo,proto3" json:"logo,omitempty"`
+	// ID of a provider profile; the other fields override it
+	Profile       string `protobuf:"bytes,100,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompanyInfo) Reset() {
+	*x = CompanyInfo{}
+	mi := &file_invoicegen_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompanyInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompanyInfo) ProtoMessage() {}
+
+func (x *CompanyInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompanyInfo.ProtoReflect.Descriptor instead.
+func (*CompanyInfo) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CompanyInfo) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetAddress() *Address {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *CompanyInfo) GetVatId() string {
+	if x != nil {
+		return x.VatId
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetWebsite() string {
+	if x != nil {
+		return x.Website
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetIban() string {
+	if x != nil {
+		return x.Iban
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetSwift() string {
+	if x != nil {
+		return x.Swift
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetTaxNumber() string {
+	if x != nil {
+		return x.TaxNumber
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetLogo() string {
+	if x != nil {
+		return x.Logo
+	}
+	return ""
+}
+
+func (x *CompanyInfo) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+type ClientInfo struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name     string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Address  *Address               `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Email    string                 `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
+	Phone    string                 `protobuf:"bytes,5,opt,name=phone,proto3" json:"phone,omitempty"`
+	VatId    string                 `protobuf:"bytes,6,opt,name=vat_id,json=vatId,proto3" json:"vat_id,omitempty"`
+	Business bool                   `protobuf:"varint,7,opt,name=business,proto3" json:"business,omitempty"`
+	Iban     string                 `protobuf:"bytes,8,opt,name=iban,proto3" json:"iban,omitempty"`
+	// ID of a client profile; the other fields override it
+	Profile       string `protobuf:"bytes,100,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientInfo) Reset() {
+	*x = ClientInfo{}
+	mi := &file_invoicegen_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientInfo) ProtoMessage() {}
+
+func (x *ClientInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientInfo.ProtoReflect.Descriptor instead.
+func (*ClientInfo) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ClientInfo) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetAddress() *Address {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *ClientInfo) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetVatId() string {
+	if x != nil {
+		return x.VatId
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetBusiness() bool {
+	if x != nil {
+		return x.Business
+	}
+	return false
+}
+
+func (x *ClientInfo) GetIban() string {
+	if x != nil {
+		return x.Iban
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+type InvoiceLine struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Id                 string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Description        string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Quantity           string                 `protobuf:"bytes,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	UnitPrice          string                 `protobuf:"bytes,4,opt,name=unit_price,json=unitPrice,proto3" json:"unit_price,omitempty"`
+	Total              string                 `protobuf:"bytes,5,opt,name=total,proto3" json:"total,omitempty"`
+	TaxRate            string                 `protobuf:"bytes,6,opt,name=tax_rate,json=taxRate,proto3" json:"tax_rate,omitempty"`
+	TaxAmount          string                 `protobuf:"bytes,7,opt,name=tax_amount,json=taxAmount,proto3" json:"tax_amount,omitempty"`
+	Discount           string                 `protobuf:"bytes,8,opt,name=discount,proto3" json:"discount,omitempty"`
+	Period             string                 `protobuf:"bytes,9,opt,name=period,proto3" json:"period,omitempty"`
+	ProductType        string                 `protobuf:"bytes,10,opt,name=product_type,json=productType,proto3" json:"product_type,omitempty"`
+	TaxClass           string                 `protobuf:"bytes,11,opt,name=tax_class,json=taxClass,proto3" json:"tax_class,omitempty"`
+	TaxCategory        string                 `protobuf:"bytes,12,opt,name=tax_category,json=taxCategory,proto3" json:"tax_category,omitempty"`
+	TaxExemptionReason string                 `protobuf:"bytes,13,opt,name=tax_exemption_reason,json=taxExemptionReason,proto3" json:"tax_exemption_reason,omitempty"`
+	TaxExemptionCode   string                 `protobuf:"bytes,14,opt,name=tax_exemption_code,json=taxExemptionCode,proto3" json:"tax_exemption_code,omitempty"`
+	Sku                string                 `protobuf:"bytes,15,opt,name=sku,proto3" json:"sku,omitempty"` // Catalog item the line is filled in from
+	Unit               string                 `protobuf:"bytes,16,opt,name=unit,proto3" json:"unit,omitempty"`
+	SellerItemId       string                 `protobuf:"bytes,17,opt,name=seller_item_id,json=sellerItemId,proto3" json:"seller_item_id,omitempty"`
+	BuyerItemId        string                 `protobuf:"bytes,18,opt,name=buyer_item_id,json=buyerItemId,proto3" json:"buyer_item_id,omitempty"`
+	StandardItemId     string                 `protobuf:"bytes,19,opt,name=standard_item_id,json=standardItemId,proto3" json:"standard_item_id,omitempty"`
+	StandardItemScheme string                 `protobuf:"bytes,20,opt,name=standard_item_scheme,json=standardItemScheme,proto3" json:"standard_item_scheme,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *InvoiceLine) Reset() {
+	*x = InvoiceLine{}
+	mi := &file_invoicegen_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvoiceLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvoiceLine) ProtoMessage() {}
+
+func (x *InvoiceLine) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvoiceLine.ProtoReflect.Descriptor instead.
+func (*InvoiceLine) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *InvoiceLine) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetQuantity() string {
+	if x != nil {
+		return x.Quantity
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetUnitPrice() string {
+	if x != nil {
+		return x.UnitPrice
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetTotal() string {
+	if x != nil {
+		return x.Total
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetTaxRate() string {
+	if x != nil {
+		return x.TaxRate
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetTaxAmount() string {
+	if x != nil {
+		return x.TaxAmount
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetDiscount() string {
+	if x != nil {
+		return x.Discount
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetProductType() string {
+	if x != nil {
+		return x.ProductType
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetTaxClass() string {
+	if x != nil {
+		return x.TaxClass
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetTaxCategory() string {
+	if x != nil {
+		return x.TaxCategory
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetTaxExemptionReason() string {
+	if x != nil {
+		return x.TaxExemptionReason
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetTaxExemptionCode() string {
+	if x != nil {
+		return x.TaxExemptionCode
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetSellerItemId() string {
+	if x != nil {
+		return x.SellerItemId
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetBuyerItemId() string {
+	if x != nil {
+		return x.BuyerItemId
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetStandardItemId() string {
+	if x != nil {
+		return x.StandardItemId
+	}
+	return ""
+}
+
+func (x *InvoiceLine) GetStandardItemScheme() string {
+	if x != nil {
+		return x.StandardItemScheme
+	}
+	return ""
+}
+
+type PaymentTerms struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DueDays       int32                  `protobuf:"varint,1,opt,name=due_days,json=dueDays,proto3" json:"due_days,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaymentTerms) Reset() {
+	*x = PaymentTerms{}
+	mi := &file_invoicegen_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaymentTerms) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentTerms) ProtoMessage() {}
+
+func (x *PaymentTerms) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentTerms.ProtoReflect.Descriptor instead.
+func (*PaymentTerms) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PaymentTerms) GetDueDays() int32 {
+	if x != nil {
+		return x.DueDays
+	}
+	return 0
+}
+
+func (x *PaymentTerms) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type InvoiceReference struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Number        string                 `protobuf:"bytes,1,opt,name=number,proto3" json:"number,omitempty"`
+	Date          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`
+	Amount        string                 `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InvoiceReference) Reset() {
+	*x = InvoiceReference{}
+	mi := &file_invoicegen_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvoiceReference) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvoiceReference) ProtoMessage() {}
+
+func (x *InvoiceReference) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvoiceReference.ProtoReflect.Descriptor instead.
+func (*InvoiceReference) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *InvoiceReference) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *InvoiceReference) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+func (x *InvoiceReference) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+type BillingPeriod struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BillingPeriod) Reset() {
+	*x = BillingPeriod{}
+	mi := &file_invoicegen_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BillingPeriod) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BillingPeriod) ProtoMessage() {}
+
+func (x *BillingPeriod) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BillingPeriod.ProtoReflect.Descriptor instead.
+func (*BillingPeriod) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *BillingPeriod) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *BillingPeriod) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+type Withholding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Rate          string                 `protobuf:"bytes,3,opt,name=rate,proto3" json:"rate,omitempty"`
+	Base          string                 `protobuf:"bytes,4,opt,name=base,proto3" json:"base,omitempty"`
+	BasisAmount   string                 `protobuf:"bytes,5,opt,name=basis_amount,json=basisAmount,proto3" json:"basis_amount,omitempty"`
+	Amount        string                 `protobuf:"bytes,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Withholding) Reset() {
+	*x = Withholding{}
+	mi := &file_invoicegen_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Withholding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Withholding) ProtoMessage() {}
+
+func (x *Withholding) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Withholding.ProtoReflect.Descriptor instead.
+func (*Withholding) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Withholding) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Withholding) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Withholding) GetRate() string {
+	if x != nil {
+		return x.Rate
+	}
+	return ""
+}
+
+func (x *Withholding) GetBase() string {
+	if x != nil {
+		return x.Base
+	}
+	return ""
+}
+
+func (x *Withholding) GetBasisAmount() string {
+	if x != nil {
+		return x.BasisAmount
+	}
+	return ""
+}
+
+func (x *Withholding) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+type InvoiceDetails struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Id                 string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Number             string                 `protobuf:"bytes,2,opt,name=number,proto3" json:"number,omitempty"`
+	Date               *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=date,proto3" json:"date,omitempty"`
+	DueDate            *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=due_date,json=dueDate,proto3" json:"due_date,omitempty"`
+	Status             string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Type               string                 `protobuf:"bytes,6,opt,name=type,proto3" json:"type,omitempty"`
+	Currency           *Currency              `protobuf:"bytes,7,opt,name=currency,proto3" json:"currency,omitempty"`
+	Lines              []*InvoiceLine         `protobuf:"bytes,8,rep,name=lines,proto3" json:"lines,omitempty"`
+	PaymentTerms       *PaymentTerms          `protobuf:"bytes,9,opt,name=payment_terms,json=paymentTerms,proto3" json:"payment_terms,omitempty"`
+	Notes              string                 `protobuf:"bytes,10,opt,name=notes,proto3" json:"notes,omitempty"`
+	Language           string                 `protobuf:"bytes,11,opt,name=language,proto3" json:"language,omitempty"`
+	Subtotal           string                 `protobuf:"bytes,12,opt,name=subtotal,proto3" json:"subtotal,omitempty"`
+	TotalTax           string                 `protobuf:"bytes,13,opt,name=total_tax,json=totalTax,proto3" json:"total_tax,omitempty"`
+	TotalDiscount      string                 `protobuf:"bytes,14,opt,name=total_discount,json=totalDiscount,proto3" json:"total_discount,omitempty"`
+	GrandTotal         string                 `protobuf:"bytes,15,opt,name=grand_total,json=grandTotal,proto3" json:"grand_total,omitempty"`
+	CreatedAt          *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt          *timestamppb.Timestamp `protobuf:"bytes,17,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	LegalFields        map[string]string      `protobuf:"bytes,18,rep,name=legal_fields,json=legalFields,proto3" json:"legal_fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Recurrence         string                 `protobuf:"bytes,19,opt,name=recurrence,proto3" json:"recurrence,omitempty"`
+	VatExemptionType   string                 `protobuf:"bytes,20,opt,name=vat_exemption_type,json=vatExemptionType,proto3" json:"vat_exemption_type,omitempty"`
+	VatExemptionReason string                 `protobuf:"bytes,21,opt,name=vat_exemption_reason,json=vatExemptionReason,proto3" json:"vat_exemption_reason,omitempty"`
+	TariffType         string                 `protobuf:"bytes,22,opt,name=tariff_type,json=tariffType,proto3" json:"tariff_type,omitempty"`
+	AdditionalTariffs  string                 `protobuf:"bytes,23,opt,name=additional_tariffs,json=additionalTariffs,proto3" json:"additional_tariffs,omitempty"`
+	TaxCurrency        string                 `protobuf:"bytes,24,opt,name=tax_currency,json=taxCurrency,proto3" json:"tax_currency,omitempty"`
+	TotalTaxAccounting string                 `protobuf:"bytes,25,opt,name=total_tax_accounting,json=totalTaxAccounting,proto3" json:"total_tax_accounting,omitempty"`
+	Withholdings       []*Withholding         `protobuf:"bytes,26,rep,name=withholdings,proto3" json:"withholdings,omitempty"`
+	TotalWithholding   string                 `protobuf:"bytes,27,opt,name=total_withholding,json=totalWithholding,proto3" json:"total_withholding,omitempty"`
+	AmountDue          string                 `protobuf:"bytes,28,opt,name=amount_due,json=amountDue,proto3" json:"amount_due,omitempty"`
+	PrecedingInvoices  []*InvoiceReference    `protobuf:"bytes,29,rep,name=preceding_invoices,json=precedingInvoices,proto3" json:"preceding_invoices,omitempty"`
+	PrepaidAmount      string                 `protobuf:"bytes,30,opt,name=prepaid_amount,json=prepaidAmount,proto3" json:"prepaid_amount,omitempty"`
+	BillingPeriod      *BillingPeriod         `protobuf:"bytes,31,opt,name=billing_period,json=billingPeriod,proto3" json:"billing_period,omitempty"`
+	OrderReference     string                 `protobuf:"bytes,32,opt,name=order_reference,json=orderReference,proto3" json:"order_reference,omitempty"`
+	ValidUntil         *timestamppb.Timestamp `protobuf:"bytes,33,opt,name=valid_until,json=validUntil,proto3" json:"valid_until,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *InvoiceDetails) Reset() {
+	*x = InvoiceDetails{}
+	mi := &file_invoicegen_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvoiceDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvoiceDetails) ProtoMessage() {}
+
+func (x *InvoiceDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_invoicegen_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvoiceDetails.ProtoReflect.Descriptor instead.
+func (*InvoiceDetails) Descriptor() ([]byte, []int) {
+	return file_invoicegen_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *InvoiceDetails) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetDueDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DueDate
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetCurrency() *Currency {
+	if x != nil {
+		return x.Currency
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetLines() []*InvoiceLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetPaymentTerms() *PaymentTerms {
+	if x != nil {
+		return x.PaymentTerms
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetSubtotal() string {
+	if x != nil {
+		return x.Subtotal
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetTotalTax() string {
+	if x != nil {
+		return x.TotalTax
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetTotalDiscount() string {
+	if x != nil {
+		return x.TotalDiscount
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetGrandTotal() string {
+	if x != nil {
+		return x.GrandTotal
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetLegalFields() map[string]string {
+	if x != nil {
+		return x.LegalFields
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetRecurrence() string {
+	if x != nil {
+		return x.Recurrence
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetVatExemptionType() string {
+	if x != nil {
+		return x.VatExemptionType
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetVatExemptionReason() string {
+	if x != nil {
+		return x.VatExemptionReason
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetTariffType() string {
+	if x != nil {
+		return x.TariffType
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetAdditionalTariffs() string {
+	if x != nil {
+		return x.AdditionalTariffs
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetTaxCurrency() string {
+	if x != nil {
+		return x.TaxCurrency
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetTotalTaxAccounting() string {
+	if x != nil {
+		return x.TotalTaxAccounting
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetWithholdings() []*Withholding {
+	if x != nil {
+		return x.Withholdings
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetTotalWithholding() string {
+	if x != nil {
+		return x.TotalWithholding
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetAmountDue() string {
+	if x != nil {
+		return x.AmountDue
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetPrecedingInvoices() []*InvoiceReference {
+	if x != nil {
+		return x.PrecedingInvoices
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetPrepaidAmount() string {
+	if x != nil {
+		return x.PrepaidAmount
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetBillingPeriod() *BillingPeriod {
+	if x != nil {
+		return x.BillingPeriod
+	}
+	return nil
+}
+
+func (x *InvoiceDetails) GetOrderReference() string {
+	if x != nil {
+		return x.OrderReference
+	}
+	return ""
+}
+
+func (x *InvoiceDetails) GetValidUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ValidUntil
+	}
+	return nil
+}
+
+var File_invoicegen_proto protoreflect.FileDescriptor
+
+const file_invoicegen_proto_rawDesc = "" +
+	"\n" +
+	"\x10invoicegen.proto\x12\rinvoicegen.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xab\x01\n" +
+	"\x0fGenerateRequest\x124\n" +
+	"\ainvoice\x18\x01 \x01(\v2\x1a.invoicegen.v1.InvoiceDataR\ainvoice\x12\x1a\n" +
+	"\btemplate\x18\x02 \x01(\tR\btemplate\x12\x12\n" +
+	"\x04lang\x18\x03 \x01(\tR\x04lang\x12\x1a\n" +
+	"\bcurrency\x18\x04 \x01(\tR\bcurrency\x12\x16\n" +
+	"\x06series\x18\x05 \x01(\tR\x06series\"^\n" +
+	"\x10ValidateResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid\x124\n" +
+	"\ainvoice\x18\x02 \x01(\v2\x1a.invoicegen.v1.InvoiceDataR\ainvoice\"O\n" +
+	"\x12RenderHTMLResponse\x12\x12\n" +
+	"\x04html\x18\x01 \x01(\tR\x04html\x12%\n" +
+	"\x0einvoice_number\x18\x02 \x01(\tR\rinvoiceNumber\"d\n" +
+	"\x04File\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12%\n" +
+	"\x0einvoice_number\x18\x02 \x01(\tR\rinvoiceNumber\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"}\n" +
+	"\tFileChunk\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12%\n" +
+	"\x0einvoice_number\x18\x02 \x01(\tR\rinvoiceNumber\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\")\n" +
+	"\x15ExtractFromPDFRequest\x12\x10\n" +
+	"\x03pdf\x18\x01 \x01(\fR\x03pdf\"\xd6\x01\n" +
+	"\vInvoiceData\x126\n" +
+	"\bprovider\x18\x01 \x01(\v2\x1a.invoicegen.v1.CompanyInfoR\bprovider\x121\n" +
+	"\x06client\x18\x02 \x01(\v2\x19.invoicegen.v1.ClientInfoR\x06client\x127\n" +
+	"\ainvoice\x18\x03 \x01(\v2\x1d.invoicegen.v1.InvoiceDetailsR\ainvoice\x12#\n" +
+	"\rembedded_data\x18\x04 \x01(\tR\fembeddedData\"J\n" +
+	"\bCurrency\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04rate\x18\x03 \x01(\tR\x04rate\"\x86\x01\n" +
+	"\aAddress\x12\x16\n" +
+	"\x06street\x18\x01 \x01(\tR\x06street\x12\x12\n" +
+	"\x04city\x18\x02 \x01(\tR\x04city\x12\x1f\n" +
+	"\vpostal_code\x18\x03 \x01(\tR\n" +
+	"postalCode\x12\x14\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12\x18\n" +
+	"\acountry\x18\x05 \x01(\tR\acountry\"\xb7\x02\n" +
+	"\vCompanyInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x120\n" +
+	"\aaddress\x18\x03 \x01(\v2\x16.invoicegen.v1.AddressR\aaddress\x12\x15\n" +
+	"\x06vat_id\x18\x04 \x01(\tR\x05vatId\x12\x14\n" +
+	"\x05email\x18\x05 \x01(\tR\x05email\x12\x14\n" +
+	"\x05phone\x18\x06 \x01(\tR\x05phone\x12\x18\n" +
+	"\awebsite\x18\a \x01(\tR\awebsite\x12\x12\n" +
+	"\x04iban\x18\b \x01(\tR\x04iban\x12\x14\n" +
+	"\x05swift\x18\t \x01(\tR\x05swift\x12\x1d\n" +
+	"\n" +
+	"tax_number\x18\n" +
+	" \x01(\tR\ttaxNumber\x12\x12\n" +
+	"\x04logo\x18\v \x01(\tR\x04logo\x12\x18\n" +
+	"\aprofile\x18d \x01(\tR\aprofile\"\xef\x01\n" +
+	"\n" +
+	"ClientInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x120\n" +
+	"\aaddress\x18\x03 \x01(\v2\x16.invoicegen.v1.AddressR\aaddress\x12\x14\n" +
+	"\x05email\x18\x04 \x01(\tR\x05email\x12\x14\n" +
+	"\x05phone\x18\x05 \x01(\tR\x05phone\x12\x15\n" +
+	"\x06vat_id\x18\x06 \x01(\tR\x05vatId\x12\x1a\n" +
+	"\bbusiness\x18\a \x01(\bR\bbusiness\x12\x12\n" +
+	"\x04iban\x18\b \x01(\tR\x04iban\x12\x18\n" +
+	"\aprofile\x18d \x01(\tR\aprofile\"\x8d\x05\n" +
+	"\vInvoiceLine\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\tR\bquantity\x12\x1d\n" +
+	"\n" +
+	"unit_price\x18\x04 \x01(\tR\tunitPrice\x12\x14\n" +
+	"\x05total\x18\x05 \x01(\tR\x05total\x12\x19\n" +
+	"\btax_rate\x18\x06 \x01(\tR\ataxRate\x12\x1d\n" +
+	"\n" +
+	"tax_amount\x18\a \x01(\tR\ttaxAmount\x12\x1a\n" +
+	"\bdiscount\x18\b \x01(\tR\bdiscount\x12\x16\n" +
+	"\x06period\x18\t \x01(\tR\x06period\x12!\n" +
+	"\fproduct_type\x18\n" +
+	" \x01(\tR\vproductType\x12\x1b\n" +
+	"\ttax_class\x18\v \x01(\tR\btaxClass\x12!\n" +
+	"\ftax_category\x18\f \x01(\tR\vtaxCategory\x120\n" +
+	"\x14tax_exemption_reason\x18\r \x01(\tR\x12taxExemptionReason\x12,\n" +
+	"\x12tax_exemption_code\x18\x0e \x01(\tR\x10taxExemptionCode\x12\x10\n" +
+	"\x03sku\x18\x0f \x01(\tR\x03sku\x12\x12\n" +
+	"\x04unit\x18\x10 \x01(\tR\x04unit\x12$\n" +
+	"\x0eseller_item_id\x18\x11 \x01(\tR\fsellerItemId\x12\"\n" +
+	"\rbuyer_item_id\x18\x12 \x01(\tR\vbuyerItemId\x12(\n" +
+	"\x10standard_item_id\x18\x13 \x01(\tR\x0estandardItemId\x120\n" +
+	"\x14standard_item_scheme\x18\x14 \x01(\tR\x12standardItemScheme\"K\n" +
+	"\fPaymentTerms\x12\x19\n" +
+	"\bdue_days\x18\x01 \x01(\x05R\adueDays\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\"r\n" +
+	"\x10InvoiceReference\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\tR\x06number\x12.\n" +
+	"\x04date\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04date\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\tR\x06amount\"o\n" +
+	"\rBillingPeriod\x120\n" +
+	"\x05start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\"\xa6\x01\n" +
+	"\vWithholding\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04rate\x18\x03 \x01(\tR\x04rate\x12\x12\n" +
+	"\x04base\x18\x04 \x01(\tR\x04base\x12!\n" +
+	"\fbasis_amount\x18\x05 \x01(\tR\vbasisAmount\x12\x16\n" +
+	"\x06amount\x18\x06 \x01(\tR\x06amount\"\x83\f\n" +
+	"\x0eInvoiceDetails\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06number\x18\x02 \x01(\tR\x06number\x12.\n" +
+	"\x04date\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04date\x125\n" +
+	"\bdue_date\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\adueDate\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x12\n" +
+	"\x04type\x18\x06 \x01(\tR\x04type\x123\n" +
+	"\bcurrency\x18\a \x01(\v2\x17.invoicegen.v1.CurrencyR\bcurrency\x120\n" +
+	"\x05lines\x18\b \x03(\v2\x1a.invoicegen.v1.InvoiceLineR\x05lines\x12@\n" +
+	"\rpayment_terms\x18\t \x01(\v2\x1b.invoicegen.v1.PaymentTermsR\fpaymentTerms\x12\x14\n" +
+	"\x05notes\x18\n" +
+	" \x01(\tR\x05notes\x12\x1a\n" +
+	"\blanguage\x18\v \x01(\tR\blanguage\x12\x1a\n" +
+	"\bsubtotal\x18\f \x01(\tR\bsubtotal\x12\x1b\n" +
+	"\ttotal_tax\x18\r \x01(\tR\btotalTax\x12%\n" +
+	"\x0etotal_discount\x18\x0e \x01(\tR\rtotalDiscount\x12\x1f\n" +
+	"\vgrand_total\x18\x0f \x01(\tR\n" +
+	"grandTotal\x129\n" +
+	"\n" +
+	"created_at\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x11 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12Q\n" +
+	"\flegal_fields\x18\x12 \x03(\v2..invoicegen.v1.InvoiceDetails.LegalFieldsEntryR\vlegalFields\x12\x1e\n" +
+	"\n" +
+	"recurrence\x18\x13 \x01(\tR\n" +
+	"recurrence\x12,\n" +
+	"\x12vat_exemption_type\x18\x14 \x01(\tR\x10vatExemptionType\x120\n" +
+	"\x14vat_exemption_reason\x18\x15 \x01(\tR\x12vatExemptionReason\x12\x1f\n" +
+	"\vtariff_type\x18\x16 \x01(\tR\n" +
+	"tariffType\x12-\n" +
+	"\x12additional_tariffs\x18\x17 \x01(\tR\x11additionalTariffs\x12!\n" +
+	"\ftax_currency\x18\x18 \x01(\tR\vtaxCurrency\x120\n" +
+	"\x14total_tax_accounting\x18\x19 \x01(\tR\x12totalTaxAccounting\x12>\n" +
+	"\fwithholdings\x18\x1a \x03(\v2\x1a.invoicegen.v1.WithholdingR\fwithholdings\x12+\n" +
+	"\x11total_withholding\x18\x1b \x01(\tR\x10totalWithholding\x12\x1d\n" +
+	"\n" +
+	"amount_due\x18\x1c \x01(\tR\tamountDue\x12N\n" +
+	"\x12preceding_invoices\x18\x1d \x03(\v2\x1f.invoicegen.v1.InvoiceReferenceR\x11precedingInvoices\x12%\n" +
+	"\x0eprepaid_amount\x18\x1e \x01(\tR\rprepaidAmount\x12C\n" +
+	"\x0ebilling_period\x18\x1f \x01(\v2\x1c.invoicegen.v1.BillingPeriodR\rbillingPeriod\x12'\n" +
+	"\x0forder_reference\x18  \x01(\tR\x0eorderReference\x12;\n" +
+	"\vvalid_until\x18! \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"validUntil\x1a>\n" +
+	"\x10LegalFieldsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\x91\x03\n" +
+	"\x0eInvoiceService\x12K\n" +
+	"\bValidate\x12\x1e.invoicegen.v1.GenerateRequest\x1a\x1f.invoicegen.v1.ValidateResponse\x12O\n" +
+	"\n" +
+	"RenderHTML\x12\x1e.invoicegen.v1.GenerateRequest\x1a!.invoicegen.v1.RenderHTMLResponse\x12I\n" +
+	"\vGeneratePDF\x12\x1e.invoicegen.v1.GenerateRequest\x1a\x18.invoicegen.v1.FileChunk0\x01\x12B\n" +
+	"\vGenerateXML\x12\x1e.invoicegen.v1.GenerateRequest\x1a\x13.invoicegen.v1.File\x12R\n" +
+	"\x0eExtractFromPDF\x12$.invoicegen.v1.ExtractFromPDFRequest\x1a\x1a.invoicegen.v1.InvoiceDataB:\n" +
+	"\x11com.invoicegen.v1P\x01Z#invoiceformats/pkg/server/invoicepbb\x06proto3"
+
+var (
+	file_invoicegen_proto_rawDescOnce sync.Once
+	file_invoicegen_proto_rawDescData []byte
+)
+
+func file_invoicegen_proto_rawDescGZIP() []byte {
+	file_invoicegen_proto_rawDescOnce.Do(func() {
+		file_invoicegen_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_invoicegen_proto_rawDesc), len(file_invoicegen_proto_rawDesc)))
+	})
+	return file_invoicegen_proto_rawDescData
+}
+
+var file_invoicegen_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_invoicegen_proto_goTypes = []any{
+	(*GenerateRequest)(nil),       // 0: invoicegen.v1.GenerateRequest
+	(*ValidateResponse)(nil),      // 1: invoicegen.v1.ValidateResponse
+	(*RenderHTMLResponse)(nil),    // 2: invoicegen.v1.RenderHTMLResponse
+	(*File)(nil),                  // 3: invoicegen.v1.File
+	(*FileChunk)(nil),             // 4: invoicegen.v1.FileChunk
+	(*ExtractFromPDFRequest)(nil), // 5: invoicegen.v1.ExtractFromPDFRequest
+	(*InvoiceData)(nil),           // 6: invoicegen.v1.InvoiceData
+	(*Currency)(nil),              // 7: invoicegen.v1.Currency
+	(*Address)(nil),               // 8: invoicegen.v1.Address
+	(*CompanyInfo)(nil),           // 9: invoicegen.v1.CompanyInfo
+	(*ClientInfo)(nil),            // 10: invoicegen.v1.ClientInfo
+	(*InvoiceLine)(nil),           // 11: invoicegen.v1.InvoiceLine
+	(*PaymentTerms)(nil),          // 12: invoicegen.v1.PaymentTerms
+	(*InvoiceReference)(nil),      // 13: invoicegen.v1.InvoiceReference
+	(*BillingPeriod)(nil),         // 14: invoicegen.v1.BillingPeriod
+	(*Withholding)(nil),           // 15: invoicegen.v1.Withholding
+	(*InvoiceDetails)(nil),        // 16: invoicegen.v1.InvoiceDetails
+	nil,                           // 17: invoicegen.v1.InvoiceDetails.LegalFieldsEntry
+	(*timestamppb.Timestamp)(nil), // 18: google.protobuf.Timestamp
+}
+var file_invoicegen_proto_depIdxs = []int32{
+	6,  // 0: invoicegen.v1.GenerateRequest.invoice:type_name -> invoicegen.v1.InvoiceData
+	6,  // 1: invoicegen.v1.ValidateResponse.invoice:type_name -> invoicegen.v1.InvoiceData
+	9,  // 2: invoicegen.v1.InvoiceData.provider:type_name -> invoicegen.v1.CompanyInfo
+	10, // 3: invoicegen.v1.InvoiceData.client:type_name -> invoicegen.v1.ClientInfo
+	16, // 4: invoicegen.v1.InvoiceData.invoice:type_name -> invoicegen.v1.InvoiceDetails
+	8,  // 5: invoicegen.v1.CompanyInfo.address:type_name -> invoicegen.v1.Address
+	8,  // 6: invoicegen.v1.ClientInfo.address:type_name -> invoicegen.v1.Address
+	18, // 7: invoicegen.v1.InvoiceReference.date:type_name -> google.protobuf.Timestamp
+	18, // 8: invoicegen.v1.BillingPeriod.start:type_name -> google.protobuf.Timestamp
+	18, // 9: invoicegen.v1.BillingPeriod.end:type_name -> google.protobuf.Timestamp
+	18, // 10: invoicegen.v1.InvoiceDetails.date:type_name -> google.protobuf.Timestamp
+	18, // 11: invoicegen.v1.InvoiceDetails.due_date:type_name -> google.protobuf.Timestamp
+	7,  // 12: invoicegen.v1.InvoiceDetails.currency:type_name -> invoicegen.v1.Currency
+	11, // 13: invoicegen.v1.InvoiceDetails.lines:type_name -> invoicegen.v1.InvoiceLine
+	12, // 14: invoicegen.v1.InvoiceDetails.payment_terms:type_name -> invoicegen.v1.PaymentTerms
+	18, // 15: invoicegen.v1.InvoiceDetails.created_at:type_name -> google.protobuf.Timestamp
+	18, // 16: invoicegen.v1.InvoiceDetails.updated_at:type_name -> google.protobuf.Timestamp
+	17, // 17: invoicegen.v1.InvoiceDetails.legal_fields:type_name -> invoicegen.v1.InvoiceDetails.LegalFieldsEntry
+	15, // 18: invoicegen.v1.InvoiceDetails.withholdings:type_name -> invoicegen.v1.Withholding
+	13, // 19: invoicegen.v1.InvoiceDetails.preceding_invoices:type_name -> invoicegen.v1.InvoiceReference
+	14, // 20: invoicegen.v1.InvoiceDetails.billing_period:type_name -> invoicegen.v1.BillingPeriod
+	18, // 21: invoicegen.v1.InvoiceDetails.valid_until:type_name -> google.protobuf.Timestamp
+	0,  // 22: invoicegen.v1.InvoiceService.Validate:input_type -> invoicegen.v1.GenerateRequest
+	0,  // 23: invoicegen.v1.InvoiceService.RenderHTML:input_type -> invoicegen.v1.GenerateRequest
+	0,  // 24: invoicegen.v1.InvoiceService.GeneratePDF:input_type -> invoicegen.v1.GenerateRequest
+	0,  // 25: invoicegen.v1.InvoiceService.GenerateXML:input_type -> invoicegen.v1.GenerateRequest
+	5,  // 26: invoicegen.v1.InvoiceService.ExtractFromPDF:input_type -> invoicegen.v1.ExtractFromPDFRequest
+	1,  // 27: invoicegen.v1.InvoiceService.Validate:output_type -> invoicegen.v1.ValidateResponse
+	2,  // 28: invoicegen.v1.InvoiceService.RenderHTML:output_type -> invoicegen.v1.RenderHTMLResponse
+	4,  // 29: invoicegen.v1.InvoiceService.GeneratePDF:output_type -> invoicegen.v1.FileChunk
+	3,  // 30: invoicegen.v1.InvoiceService.GenerateXML:output_type -> invoicegen.v1.File
+	6,  // 31: invoicegen.v1.InvoiceService.ExtractFromPDF:output_type -> invoicegen.v1.InvoiceData
+	27, // [27:32] is the sub-list for method output_type
+	22, // [22:27] is the sub-list for method input_type
+	22, // [22:22] is the sub-list for extension type_name
+	22, // [22:22] is the sub-list for extension extendee
+	0,  // [0:22] is the sub-list for field type_name
+}
+
+func init() { file_invoicegen_proto_init() }
+func file_invoicegen_proto_init() {
+	if File_invoicegen_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_invoicegen_proto_rawDesc), len(file_invoicegen_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_invoicegen_proto_goTypes,
+		DependencyIndexes: file_invoicegen_proto_depIdxs,
+		MessageInfos:      file_invoicegen_proto_msgTypes,
+	}.Build()
+	File_invoicegen_proto = out.File
+	file_invoicegen_proto_goTypes = nil
+	file_invoicegen_proto_depIdxs = nil
+}
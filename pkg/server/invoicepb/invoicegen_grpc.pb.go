@@ -0,0 +1,303 @@
+// gRPC API of invoicegen. The messages mirror the invoice model and its JSON: field
+// names are those of invoice files, amounts are decimal strings such as "120.50" and
+// enum-like fields take the values of the JSON Schema (`invoicegen schema`).
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: invoicegen.proto
+
+package invoicepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	InvoiceService_Validate_FullMethodName       = "/invoicegen.v1.InvoiceService/Validate"
+	InvoiceService_RenderHTML_FullMethodName     = "/invoicegen.v1.InvoiceService/RenderHTML"
+	InvoiceService_GeneratePDF_FullMethodName    = "/invoicegen.v1.InvoiceService/GeneratePDF"
+	InvoiceService_GenerateXML_FullMethodName    = "/invoicegen.v1.InvoiceService/GenerateXML"
+	InvoiceService_ExtractFromPDF_FullMethodName = "/invoicegen.v1.InvoiceService/ExtractFromPDF"
+)
+
+// InvoiceServiceClient is the client API for InvoiceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// InvoiceService validates invoices, renders HTML previews and generates PDFs and
+// e-invoice XML, like the REST endpoints under /v1/invoices. Errors carry an ErrorInfo
+// detail whose reason is the error code, e.g. VALIDATION_FAILED.
+type InvoiceServiceClient interface {
+	// Validate validates the invoice and returns it with defaults, tax rules and totals
+	// applied. Sequential numbers are only previewed.
+	Validate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
+	// RenderHTML renders the HTML preview of the invoice.
+	RenderHTML(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*RenderHTMLResponse, error)
+	// GeneratePDF generates the PDF and issues the invoice number. The PDF is streamed
+	// in chunks; the first chunk carries the metadata.
+	GeneratePDF(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error)
+	// GenerateXML generates the ZUGFeRD/Factur-X XML of the invoice.
+	GenerateXML(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*File, error)
+	// ExtractFromPDF reads the invoice back from the e-invoice XML embedded in a PDF.
+	ExtractFromPDF(ctx context.Context, in *ExtractFromPDFRequest, opts ...grpc.CallOption) (*InvoiceData, error)
+}
+
+type invoiceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInvoiceServiceClient(cc grpc.ClientConnInterface) InvoiceServiceClient {
+	return &invoiceServiceClient{cc}
+}
+
+func (c *invoiceServiceClient) Validate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateResponse)
+	err := c.cc.Invoke(ctx, InvoiceService_Validate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *invoiceServiceClient) RenderHTML(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*RenderHTMLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenderHTMLResponse)
+	err := c.cc.Invoke(ctx, InvoiceService_RenderHTML_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *invoiceServiceClient) GeneratePDF(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &InvoiceService_ServiceDesc.Streams[0], InvoiceService_GeneratePDF_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GenerateRequest, FileChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InvoiceService_GeneratePDFClient = grpc.ServerStreamingClient[FileChunk]
+
+func (c *invoiceServiceClient) GenerateXML(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*File, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(File)
+	err := c.cc.Invoke(ctx, InvoiceService_GenerateXML_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *invoiceServiceClient) ExtractFromPDF(ctx context.Context, in *ExtractFromPDFRequest, opts ...grpc.CallOption) (*InvoiceData, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InvoiceData)
+	err := c.cc.Invoke(ctx, InvoiceService_ExtractFromPDF_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InvoiceServiceServer is the server API for InvoiceService service.
+// All implementations must embed UnimplementedInvoiceServiceServer
+// for forward compatibility.
+//
+// InvoiceService validates invoices, renders HTML previews and generates PDFs and
+// e-invoice XML, like the REST endpoints under /v1/invoices. Errors carry an ErrorInfo
+// detail whose reason is the error code, e.g. VALIDATION_FAILED.
+type InvoiceServiceServer interface {
+	// Validate validates the invoice and returns it with defaults, tax rules and totals
+	// applied. Sequential numbers are only previewed.
+	Validate(context.Context, *GenerateRequest) (*ValidateResponse, error)
+	// RenderHTML renders the HTML preview of the invoice.
+	RenderHTML(context.Context, *GenerateRequest) (*RenderHTMLResponse, error)
+	// GeneratePDF generates the PDF and issues the invoice number. The PDF is streamed
+	// in chunks; the first chunk carries the metadata.
+	GeneratePDF(*GenerateRequest, grpc.ServerStreamingServer[FileChunk]) error
+	// GenerateXML generates the ZUGFeRD/Factur-X XML of the invoice.
+	GenerateXML(context.Context, *GenerateRequest) (*File, error)
+	// ExtractFromPDF reads the invoice back from the e-invoice XML embedded in a PDF.
+	ExtractFromPDF(context.Context, *ExtractFromPDFRequest) (*InvoiceData, error)
+	mustEmbedUnimplementedInvoiceServiceServer()
+}
+
+// UnimplementedInvoiceServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedInvoiceServiceServer struct{}
+
+func (UnimplementedInvoiceServiceServer) Validate(context.Context, *GenerateRequest) (*ValidateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Validate not implemented")
+}
+func (UnimplementedInvoiceServiceServer) RenderHTML(context.Context, *GenerateRequest) (*RenderHTMLResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RenderHTML not implemented")
+}
+func (UnimplementedInvoiceServiceServer) GeneratePDF(*GenerateRequest, grpc.ServerStreamingServer[FileChunk]) error {
+	return status.Error(codes.Unimplemented, "method GeneratePDF not implemented")
+}
+func (UnimplementedInvoiceServiceServer) GenerateXML(context.Context, *GenerateRequest) (*File, error) {
+	return nil, status.Error(codes.Unimplemented, "method GenerateXML not implemented")
+}
+func (UnimplementedInvoiceServiceServer) ExtractFromPDF(context.Context, *ExtractFromPDFRequest) (*InvoiceData, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExtractFromPDF not implemented")
+}
+func (UnimplementedInvoiceServiceServer) mustEmbedUnimplementedInvoiceServiceServer() {}
+func (UnimplementedInvoiceServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeInvoiceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InvoiceServiceServer will
+// result in compilation errors.
+type UnsafeInvoiceServiceServer interface {
+	mustEmbedUnimplementedInvoiceServiceServer()
+}
+
+func RegisterInvoiceServiceServer(s grpc.ServiceRegistrar, srv InvoiceServiceServer) {
+	// If the following call panics, it indicates UnimplementedInvoiceServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&InvoiceService_ServiceDesc, srv)
+}
+
+func _InvoiceService_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InvoiceServiceServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InvoiceService_Validate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InvoiceServiceServer).Validate(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InvoiceService_RenderHTML_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InvoiceServiceServer).RenderHTML(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InvoiceService_RenderHTML_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InvoiceServiceServer).RenderHTML(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InvoiceService_GeneratePDF_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GenerateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InvoiceServiceServer).GeneratePDF(m, &grpc.GenericServerStream[GenerateRequest, FileChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InvoiceService_GeneratePDFServer = grpc.ServerStreamingServer[FileChunk]
+
+func _InvoiceService_GenerateXML_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InvoiceServiceServer).GenerateXML(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InvoiceService_GenerateXML_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InvoiceServiceServer).GenerateXML(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InvoiceService_ExtractFromPDF_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtractFromPDFRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InvoiceServiceServer).ExtractFromPDF(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InvoiceService_ExtractFromPDF_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InvoiceServiceServer).ExtractFromPDF(ctx, req.(*ExtractFromPDFRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// InvoiceService_ServiceDesc is the grpc.ServiceDesc for InvoiceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InvoiceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "invoicegen.v1.InvoiceService",
+	HandlerType: (*InvoiceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Validate",
+			Handler:    _InvoiceService_Validate_Handler,
+		},
+		{
+			MethodName: "RenderHTML",
+			Handler:    _InvoiceService_RenderHTML_Handler,
+		},
+		{
+			MethodName: "GenerateXML",
+			Handler:    _InvoiceService_GenerateXML_Handler,
+		},
+		{
+			MethodName: "ExtractFromPDF",
+			Handler:    _InvoiceService_ExtractFromPDF_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GeneratePDF",
+			Handler:       _InvoiceService_GeneratePDF_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "invoicegen.proto",
+}
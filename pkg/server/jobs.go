@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/jobs"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/tracing"
+)
+
+// handleSubmitJob queues the generation of the invoice in the body. The query takes the
+// options of the invoice endpoints plus kind (pdf, xml or html; default pdf) and
+// callback_url, which is posted the job once it has finished.
+func (s *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.readRequest(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	req.Kind = jobs.Kind(q.Get("kind"))
+	if req.Kind == "" {
+		req.Kind = jobs.KindPDF
+	}
+	if !req.Kind.IsValid() {
+		s.writeStatus(w, http.StatusBadRequest, appErrs.NewValidationError("kind must be pdf, xml or html", nil))
+		return
+	}
+	if cb := q.Get("callback_url"); cb != "" {
+		u, err := url.Parse(cb)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			s.writeStatus(w, http.StatusBadRequest, appErrs.NewValidationError("callback_url must be an http or https URL", err))
+			return
+		}
+		req.CallbackURL = cb
+	}
+
+	// Reject unreadable invoices now; everything else is reported by the job
+	_, _, err := s.prepare(req)
+	if err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+
+	job, err := s.jobs.Submit(req)
+	if err != nil {
+		s.writeError(w, appErrs.NewAppError(appErrs.ErrUnknown, "failed to queue job", err))
+		return
+	}
+	w.Header().Set("Location", "/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.getJob(r)
+	if err != nil {
+		s.writeError(w, jobError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleJobArtifact(w http.ResponseWriter, r *http.Request) {
+	job, err := s.getJob(r)
+	if err != nil {
+		s.writeError(w, jobError(err))
+		return
+	}
+	if job.Status != jobs.StatusSucceeded {
+		s.writeError(w, appErrs.NewJobStateError("job is "+string(job.Status)+", no artifact available", nil))
+		return
+	}
+	data, err := os.ReadFile(job.Artifact)
+	if err != nil {
+		s.writeError(w, appErrs.NewAppError(appErrs.ErrUnknown, "failed to read job artifact", err))
+		return
+	}
+	name := job.ID + "." + string(job.Request.Kind)
+	if job.InvoiceNumber != "" {
+		name = job.InvoiceNumber + "." + string(job.Request.Kind)
+	}
+	writeFile(w, job.ContentType, name, job.InvoiceNumber, data)
+}
+
+// handleCancelJob cancels a job. Queued jobs are cancelled at once (200); running jobs
+// once the current attempt stops (202).
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.getJob(r)
+	if err == nil {
+		job, err = s.jobs.Cancel(job.ID)
+	}
+	if err != nil {
+		s.writeError(w, jobError(err))
+		return
+	}
+	status := http.StatusOK
+	if job.Status == jobs.StatusRunning {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, job)
+}
+
+// getJob returns the job of the request path. Jobs of other tenants are not found.
+func (s *Server) getJob(r *http.Request) (*jobs.Job, error) {
+	job, err := s.jobs.Get(r.PathValue("id"))
+	if err != nil {
+		return nil, err
+	}
+	if job.Request.Tenant != TenantID(r.Context()) {
+		return nil, jobs.ErrNotFound
+	}
+	return job, nil
+}
+
+// jobError maps errors of the job queue to AppErrors.
+func jobError(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		return appErrs.NewJobNotFoundError("job not found", err)
+	case errors.Is(err, jobs.ErrFinished):
+		return appErrs.NewJobStateError("job already finished", err)
+	default:
+		return err
+	}
+}
+
+// runJob generates the artifact of a job into the job store. Errors that retrying
+// cannot fix, such as invalid invoices, are permanent.
+//
+// The number a PDF job issues is checkpointed in the job before the invoice is
+// generated. A job that was running when the server stopped is run again with that
+// number, or returns the artifact it already wrote, so it never issues a second one.
+func (s *Server) runJob(ctx context.Context, job *jobs.Job) (_ *jobs.Result, err error) {
+	ctx, span := tracing.Start(ctx, "job.run", attribute.String("job.id", job.ID), attribute.String("job.kind", string(job.Request.Kind)))
+	defer func() { tracing.End(span, err) }()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tenant := job.Request.Tenant
+	if tenant != "" && (s.auth == nil || s.auth.tenants[tenant] == nil) {
+		return nil, jobs.Permanent(appErrs.NewUnauthorizedError("tenant "+tenant+" is no longer configured", nil))
+	}
+	data, opts, err := s.prepare(job.Request)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	svc := s.serviceFor(tenant)
+	var content []byte
+	result := &jobs.Result{File: filepath.Join(s.cfg.JobsDir, job.ID+"."+string(job.Request.Kind))}
+	switch job.Request.Kind {
+	case jobs.KindPDF:
+		result.ContentType = "application/pdf"
+		if job.InvoiceNumber != "" {
+			// Issued by an interrupted run
+			if _, err := os.Stat(result.File); err == nil {
+				result.InvoiceNumber = job.InvoiceNumber
+				return result, nil
+			}
+			data.Invoice.Number = job.InvoiceNumber
+		}
+		issued := false
+		opts.NumberIssued = func(number string) error {
+			issued = true
+			job.InvoiceNumber = number
+			return s.jobs.Checkpoint(job)
+		}
+		opts.EnableZUGFeRD = data.EmbeddedData == models.EmbeddedDataZUGFeRD
+		content, err = svc.GeneratePDF(ctx, data, opts, s.outputDirFor(tenant))
+		if err != nil && issued {
+			// The number was handed back
+			job.InvoiceNumber = ""
+		}
+	case jobs.KindXML:
+		content, err = svc.GenerateXML(ctx, data, opts)
+		result.ContentType = "application/xml"
+	case jobs.KindHTML:
+		var html string
+		html, err = svc.PreviewHTML(ctx, data, opts)
+		content = []byte(html)
+		result.ContentType = "text/html; charset=utf-8"
+	}
+	if err != nil {
+		if StatusCode(err) < http.StatusInternalServerError {
+			err = jobs.Permanent(err)
+		}
+		return nil, err
+	}
+
+	result.InvoiceNumber = data.Invoice.Number
+	// Written in one step, so a resumed run never returns a partial artifact
+	tmp := result.File + ".tmp"
+	err = os.WriteFile(tmp, content, 0644)
+	if err == nil {
+		err = os.Rename(tmp, result.File)
+	}
+	if err != nil {
+		// A retry generates the invoice again with the checkpointed number
+		return nil, appErrs.NewAppError(appErrs.ErrUnknown, "failed to write job artifact", err)
+	}
+	return result, nil
+}
+
+// errorCode returns the AppError code of an error, if it has one.
+func errorCode(err error) string {
+	var appErr *appErrs.AppError
+	if errors.As(err, &appErr) {
+		return string(appErr.Code)
+	}
+	return ""
+}
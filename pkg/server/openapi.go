@@ -0,0 +1,183 @@
+package server
+
+import (
+	"net/http"
+
+	"invoiceformats/internal/config"
+	"invoiceformats/pkg/jobs"
+	"invoiceformats/pkg/jsonschema"
+	"invoiceformats/pkg/models"
+)
+
+// OpenAPIVersion is the OpenAPI version of the API description.
+const OpenAPIVersion = "3.1.0"
+
+// OpenAPI returns the OpenAPI description of the API as served with cfg; the job
+// endpoints are included if jobs are enabled and security schemes if tenants are. Its schemas are generated from the same
+// types as the invoice JSON Schema of `invoicegen schema`.
+func OpenAPI(cfg config.ServerConfig) map[string]interface{} {
+	g := jsonschema.NewGenerator()
+	g.RefPrefix = "#/components/schemas/"
+	g.For(models.InvoiceData{})
+	g.AddInvoiceInputRules()
+	g.For(ErrorResponse{})
+	g.For(ValidationResult{})
+	g.For(HealthResponse{})
+	if cfg.JobsDir != "" {
+		g.For(jobs.Job{})
+	}
+
+	invoice := ref("InvoiceData")
+	options := []map[string]interface{}{
+		queryParam("template", "Embedded template, see /v1/templates"),
+		queryParam("lang", "Language of the invoice, see /v1/locales"),
+		queryParam("currency", "Currency to convert the invoice to"),
+		queryParam("series", "Numbering series of the invoice number"),
+	}
+	paths := map[string]interface{}{
+		"/v1/templates": map[string]interface{}{"get": operation("listTemplates", "List the embedded templates", nil, nil,
+			jsonResponse("Template names", listSchema("templates")))},
+		"/v1/locales": map[string]interface{}{"get": operation("listLocales", "List the supported languages", nil, nil,
+			jsonResponse("Language codes", listSchema("locales")))},
+		"/v1/openapi.json": map[string]interface{}{"get": operation("getOpenAPI", "Get this API description", nil, nil,
+			jsonResponse("OpenAPI document", map[string]interface{}{"type": "object"}))},
+		"/v1/invoices/validate": map[string]interface{}{"post": operation("validateInvoice",
+			"Validate an invoice and return it with defaults, tax rules and totals applied", options, invoice,
+			jsonResponse("Valid invoice", ref("ValidationResult")))},
+		"/v1/invoices/html": map[string]interface{}{"post": operation("renderInvoiceHTML", "Render the HTML preview of an invoice",
+			options, invoice, fileResponse("HTML preview", "text/html"))},
+		"/v1/invoices/pdf": map[string]interface{}{"post": operation("generateInvoicePDF",
+			"Generate the PDF of an invoice, with embedded ZUGFeRD XML if requested", options, invoice,
+			fileResponse("PDF document", "application/pdf"))},
+		"/v1/invoices/xml": map[string]interface{}{"post": operation("generateInvoiceXML", "Generate the e-invoice XML of an invoice",
+			options, invoice, fileResponse("E-invoice XML", "application/xml"))},
+		"/healthz": map[string]interface{}{"get": withResponse(operation("getHealth", "Liveness probe; fails while the browser hangs",
+			nil, nil, jsonResponse("Healthy", ref("HealthResponse"))), "503", jsonResponse("Browser hangs", ref("HealthResponse")))},
+		"/readyz": map[string]interface{}{"get": withResponse(operation("getReadiness", "Readiness probe; checks that the headless browser starts",
+			nil, nil, jsonResponse("Ready", ref("HealthResponse"))), "503", jsonResponse("Browser unavailable", ref("HealthResponse")))},
+		"/metrics": map[string]interface{}{"get": operation("getMetrics", "Get the Prometheus metrics", nil, nil, map[string]interface{}{
+			"description": "Metrics in the Prometheus text format",
+			"content":     map[string]interface{}{"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}},
+		})},
+	}
+	if cfg.JobsDir != "" {
+		id := pathParam("id", "Job ID")
+		submit := append([]map[string]interface{}{
+			{"name": "kind", "in": "query", "description": "Artifact to generate",
+				"schema": map[string]interface{}{"type": "string", "enum": []string{"pdf", "xml", "html"}, "default": "pdf"}},
+			queryParam("callback_url", "HTTP(S) URL posted the job once it has finished"),
+		}, options...)
+		accepted := jsonResponse("Job queued", ref("Job"))
+		accepted["headers"] = map[string]interface{}{"Location": map[string]interface{}{
+			"description": "URL of the job", "schema": map[string]interface{}{"type": "string"}}}
+		paths["/v1/jobs"] = map[string]interface{}{"post": withResponse(
+			operation("submitJob", "Queue the generation of an invoice", submit, invoice, nil), "202", accepted)}
+		paths["/v1/jobs/{id}"] = map[string]interface{}{
+			"get": operation("getJob", "Get the state of a job", []map[string]interface{}{id}, nil,
+				jsonResponse("Job", ref("Job"))),
+			"delete": withResponse(operation("cancelJob", "Cancel a job", []map[string]interface{}{id}, nil,
+				jsonResponse("Job cancelled", ref("Job"))), "202", jsonResponse("Job cancelled once the running attempt stops", ref("Job"))),
+		}
+		paths["/v1/jobs/{id}/artifact"] = map[string]interface{}{"get": operation("getJobArtifact",
+			"Download the artifact of a succeeded job", []map[string]interface{}{id}, nil,
+			fileResponse("Generated file", "application/octet-stream"))}
+	}
+
+	schemas := make(map[string]interface{}, len(g.Defs))
+	for name, def := range g.Defs {
+		schemas[name] = def
+	}
+	components := map[string]interface{}{"schemas": schemas}
+	doc := map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":       "invoicegen API",
+			"description": "Validates invoices, renders HTML previews and generates PDFs and e-invoice XML.",
+			"version":     "v1",
+		},
+		"jsonSchemaDialect": jsonschema.Draft,
+		"paths":             paths,
+		"components":        components,
+	}
+	if len(cfg.Auth.Tenants) > 0 {
+		components["securitySchemes"] = map[string]interface{}{
+			"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": APIKeyHeader},
+			"bearer": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "API key or HS256 JWT with tenant and exp claims"},
+		}
+		doc["security"] = []map[string]interface{}{{"apiKey": []string{}}, {"bearer": []string{}}}
+		for path := range publicPaths {
+			paths[path].(map[string]interface{})["get"].(map[string]interface{})["security"] = []map[string]interface{}{}
+		}
+	}
+	return doc
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, OpenAPI(s.cfg))
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func listSchema(key string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{key: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}},
+	}
+}
+
+func queryParam(name, description string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "in": "query", "description": description, "schema": map[string]interface{}{"type": "string"}}
+}
+
+func pathParam(name, description string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "in": "path", "required": true, "description": description, "schema": map[string]interface{}{"type": "string"}}
+}
+
+func jsonResponse(description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+	}
+}
+
+// fileResponse describes a generated file; its invoice number is in X-Invoice-Number.
+func fileResponse(description, contentType string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"headers": map[string]interface{}{"X-Invoice-Number": map[string]interface{}{
+			"description": "Number of the invoice", "schema": map[string]interface{}{"type": "string"}}},
+		"content": map[string]interface{}{contentType: map[string]interface{}{
+			"schema": map[string]interface{}{"type": "string", "format": "binary"}}},
+	}
+}
+
+// operation describes an endpoint that answers with ok on success and an
+// ErrorResponse on failure. A nil ok leaves the success response to the caller.
+func operation(id, summary string, params []map[string]interface{}, body, ok map[string]interface{}) map[string]interface{} {
+	errorResponse := jsonResponse("Error", ref("ErrorResponse"))
+	op := map[string]interface{}{
+		"operationId": id,
+		"summary":     summary,
+		"responses":   map[string]interface{}{"default": errorResponse},
+	}
+	if ok != nil {
+		op["responses"].(map[string]interface{})["200"] = ok
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if body != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": body}},
+		}
+	}
+	return op
+}
+
+func withResponse(op map[string]interface{}, status string, response map[string]interface{}) map[string]interface{} {
+	op["responses"].(map[string]interface{})[status] = response
+	return op
+}
@@ -0,0 +1,437 @@
+// Package server provides the HTTP API of invoicegen. It validates invoices, renders
+// HTML previews and generates PDFs and e-invoice XML from invoice JSON, using the same
+// invoice service as the CLI.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/jobs"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/metrics"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/pdf"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/service"
+)
+
+// MaxBodyBytes limits the size of request bodies.
+const MaxBodyBytes = 10 << 20
+
+// StatusClientClosedRequest is the status of requests whose client went away before the
+// invoice was generated. The client never sees it, but it is logged.
+const StatusClientClosedRequest = 499
+
+// Server serves the HTTP API.
+type Server struct {
+	cfg     config.ServerConfig
+	service *service.InvoiceService
+	logger  logging.Logger
+	mux     *http.ServeMux
+	jobs    *jobs.Queue    // Nil if jobs are disabled
+	auth    *authenticator // Nil if the API is open
+	ready   *readiness
+}
+
+// New creates the API server for the invoice service. With tenants configured in
+// cfg.Auth, requests must authenticate and use the service of their tenant.
+func New(cfg config.ServerConfig, svc *service.InvoiceService, logger logging.Logger) (*Server, error) {
+	auth, err := newAuthenticator(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cfg: cfg, service: svc, logger: logger, mux: http.NewServeMux(), auth: auth, ready: &readiness{check: pdf.CheckBrowser}}
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	s.mux.Handle("GET /metrics", metrics.Handler())
+	s.mux.HandleFunc("GET /v1/templates", s.handleTemplates)
+	s.mux.HandleFunc("GET /v1/locales", s.handleLocales)
+	s.mux.HandleFunc("GET /v1/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("POST /v1/invoices/validate", s.handleValidate)
+	s.mux.HandleFunc("POST /v1/invoices/html", s.handleHTML)
+	s.mux.HandleFunc("POST /v1/invoices/pdf", s.handlePDF)
+	s.mux.HandleFunc("POST /v1/invoices/xml", s.handleXML)
+	if cfg.JobsDir != "" {
+		s.jobs = jobs.NewQueue(jobs.NewStore(cfg.JobsDir), s.runJob, jobs.Options{
+			Workers:     cfg.JobWorkers,
+			MaxAttempts: cfg.JobMaxAttempts,
+			RetryDelay:  cfg.JobRetryDelay,
+			ErrorCode:   errorCode,
+		}, logger)
+		s.mux.HandleFunc("POST /v1/jobs", s.handleSubmitJob)
+		s.mux.HandleFunc("GET /v1/jobs/{id}", s.handleGetJob)
+		s.mux.HandleFunc("GET /v1/jobs/{id}/artifact", s.handleJobArtifact)
+		s.mux.HandleFunc("DELETE /v1/jobs/{id}", s.handleCancelJob)
+	}
+	return s, nil
+}
+
+// Handler returns the HTTP handler of the API, with authentication if tenants are
+// configured, CORS headers if enabled, and request logging and tracing.
+func (s *Server) Handler() http.Handler {
+	h := s.authenticate(s.mux)
+	if s.cfg.EnableCORS {
+		h = cors(h)
+	}
+	return s.logRequests(traceRequests(h))
+}
+
+// ListenAndServe serves the API on the configured host and port, and the gRPC API on
+// the gRPC port if set, until ctx is done, then shuts down gracefully, letting running
+// requests finish. Queued jobs run in the background meanwhile; unfinished jobs resume
+// on the next start. Without tenants the API is open, so it is only served on a
+// loopback address.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if s.auth == nil && !isLoopback(s.cfg.Host) {
+		return appErrs.NewConfigError(fmt.Sprintf("refusing to serve the API without authentication on %q; configure server.auth.tenants or listen on localhost", s.cfg.Host), nil)
+	}
+	if s.jobs != nil {
+		jobsCtx, stopJobs := context.WithCancel(ctx)
+		defer s.jobs.Wait()
+		defer stopJobs()
+		if err := s.jobs.Start(jobsCtx); err != nil {
+			return appErrs.NewConfigError("failed to start job queue", err)
+		}
+	}
+	srv := &http.Server{
+		Addr:         net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port)),
+		Handler:      s.Handler(),
+		ReadTimeout:  s.cfg.ReadTimeout,
+		WriteTimeout: s.cfg.WriteTimeout,
+	}
+	errc := make(chan error, 1)
+	go func() {
+		s.logger.Info("API server listening", &logging.LogFields{URL: "http://" + srv.Addr})
+		errc <- srv.ListenAndServe()
+	}()
+
+	grpcErrc := make(chan error, 1)
+	if s.cfg.GRPCPort != 0 {
+		lis, err := net.Listen("tcp", net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.GRPCPort)))
+		if err != nil {
+			srv.Close()
+			return err
+		}
+		gs := s.NewGRPCServer()
+		defer gs.GracefulStop()
+		go func() {
+			s.logger.Info("gRPC server listening", &logging.LogFields{URL: lis.Addr().String()})
+			grpcErrc <- gs.Serve(lis)
+		}()
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case err := <-grpcErrc:
+		srv.Close()
+		return err
+	case <-ctx.Done():
+	}
+	s.logger.Info("API server shutting down", nil)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{"templates": render.Templates()})
+}
+
+func (s *Server) handleLocales(w http.ResponseWriter, r *http.Request) {
+	langs, err := render.Languages()
+	if err != nil {
+		s.writeError(w, appErrs.NewConfigError("failed to read locales", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"locales": langs})
+}
+
+// ValidationResult is the response of the validate endpoint.
+type ValidationResult struct {
+	Valid   bool                `json:"valid"`
+	Invoice *models.InvoiceData `json:"invoice"` // With defaults, tax rules and totals applied
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	data, opts, ok := s.readInvoice(w, r)
+	if !ok {
+		return
+	}
+	opts.ValidateOnly = true
+	err := s.serviceFor(TenantID(r.Context())).GenerateInvoice(r.Context(), data, opts)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ValidationResult{Valid: true, Invoice: data})
+}
+
+func (s *Server) handleHTML(w http.ResponseWriter, r *http.Request) {
+	data, opts, ok := s.readInvoice(w, r)
+	if !ok {
+		return
+	}
+	html, err := s.serviceFor(TenantID(r.Context())).PreviewHTML(r.Context(), data, opts)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, html)
+}
+
+func (s *Server) handlePDF(w http.ResponseWriter, r *http.Request) {
+	data, opts, ok := s.readInvoice(w, r)
+	if !ok {
+		return
+	}
+	opts.EnableZUGFeRD = data.EmbeddedData == models.EmbeddedDataZUGFeRD
+	tenant := TenantID(r.Context())
+	pdfData, err := s.serviceFor(tenant).GeneratePDF(r.Context(), data, opts, s.outputDirFor(tenant))
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	writeFile(w, "application/pdf", data.Invoice.Number+".pdf", data.Invoice.Number, pdfData)
+}
+
+func (s *Server) handleXML(w http.ResponseWriter, r *http.Request) {
+	data, opts, ok := s.readInvoice(w, r)
+	if !ok {
+		return
+	}
+	xmlData, err := s.serviceFor(TenantID(r.Context())).GenerateXML(r.Context(), data, opts)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	writeFile(w, "application/xml", data.Invoice.Number+".xml", data.Invoice.Number, xmlData)
+}
+
+// readInvoice decodes the invoice JSON of the request body and the generation options
+// of the query: template (an embedded template name, see /v1/templates), lang,
+// currency and series.
+func (s *Server) readInvoice(w http.ResponseWriter, r *http.Request) (*models.InvoiceData, *service.GenerateOptions, bool) {
+	req, ok := s.readRequest(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	data, opts, err := s.prepare(req)
+	if err != nil {
+		s.writeDecodeError(w, err)
+		return nil, nil, false
+	}
+	return data, opts, true
+}
+
+// readRequest reads the invoice JSON of the request body and the generation options
+// of the query without decoding the invoice.
+func (s *Server) readRequest(w http.ResponseWriter, r *http.Request) (jobs.Request, bool) {
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		s.writeStatus(w, http.StatusRequestEntityTooLarge, appErrs.NewValidationError("request body too large", err))
+		return jobs.Request{}, false
+	}
+	q := r.URL.Query()
+	req := jobs.Request{
+		Tenant:   TenantID(r.Context()),
+		Invoice:  raw,
+		Template: q.Get("template"),
+		Lang:     q.Get("lang"),
+		Currency: q.Get("currency"),
+		Series:   q.Get("series"),
+	}
+	if req.Template != "" && !isTemplate(req.Template) {
+		s.writeStatus(w, http.StatusBadRequest, appErrs.NewValidationError(fmt.Sprintf("unknown template %q", req.Template), nil))
+		return jobs.Request{}, false
+	}
+	return req, true
+}
+
+// prepare decodes the invoice of a request with the profiles and catalog of its tenant
+// and its generation options.
+func (s *Server) prepare(req jobs.Request) (*models.InvoiceData, *service.GenerateOptions, error) {
+	data, err := s.serviceFor(req.Tenant).DecodeInvoiceJSON(req.Invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.Lang != "" {
+		data.Invoice.Language = req.Lang
+	}
+	opts := &service.GenerateOptions{
+		Template: req.Template,
+		Currency: req.Currency,
+		Series:   req.Series,
+	}
+	return data, opts, nil
+}
+
+// serviceFor returns the invoice service of a tenant.
+func (s *Server) serviceFor(tenant string) *service.InvoiceService {
+	if tenant == "" {
+		return s.service
+	}
+	return s.service.ForTenant(*s.auth.tenants[tenant])
+}
+
+// outputDirFor returns the directory where the generated PDFs of a tenant are kept.
+func (s *Server) outputDirFor(tenant string) string {
+	if tenant == "" || s.cfg.OutputDir == "" {
+		return s.cfg.OutputDir
+	}
+	return filepath.Join(s.auth.tenants[tenant].TenantDir(), "pdf")
+}
+
+// writeDecodeError reports an invoice that cannot be decoded as a bad request.
+func (s *Server) writeDecodeError(w http.ResponseWriter, err error) {
+	var appErr *appErrs.AppError
+	if errors.As(err, &appErr) && appErr.Code == appErrs.ErrValidationFailed {
+		s.writeStatus(w, http.StatusBadRequest, err)
+	} else {
+		s.writeError(w, err)
+	}
+}
+
+// isLoopback reports whether host only accepts connections from this machine. An
+// empty host listens on all interfaces.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isTemplate reports whether name is an embedded template. Template files are not
+// served, so clients cannot read files of the server.
+func isTemplate(name string) bool {
+	for _, t := range render.Templates() {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorResponse is the body of error responses.
+type ErrorResponse struct {
+	Code    appErrs.ErrorCode `json:"code"`
+	Message string            `json:"message"`
+	Cause   string            `json:"cause,omitempty"`
+}
+
+// StatusCode maps an error to the HTTP status of its AppError code.
+func StatusCode(err error) int {
+	var appErr *appErrs.AppError
+	if !errors.As(err, &appErr) {
+		return http.StatusInternalServerError
+	}
+	switch appErr.Code {
+	case appErrs.ErrValidationFailed, appErrs.ErrCurrencyUnsupported, appErrs.ErrExchangeRate:
+		return http.StatusUnprocessableEntity
+	case appErrs.ErrInvoiceNotFound, appErrs.ErrJobNotFound:
+		return http.StatusNotFound
+	case appErrs.ErrStatusTransition, appErrs.ErrNumbering, appErrs.ErrJobState:
+		return http.StatusConflict
+	case appErrs.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case appErrs.ErrRateLimited:
+		return http.StatusTooManyRequests
+	case appErrs.ErrTimeout:
+		return http.StatusGatewayTimeout
+	case appErrs.ErrCanceled:
+		return StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	s.writeStatus(w, StatusCode(err), err)
+}
+
+func (s *Server) writeStatus(w http.ResponseWriter, status int, err error) {
+	resp := ErrorResponse{Code: appErrs.ErrUnknown, Message: err.Error()}
+	var appErr *appErrs.AppError
+	if errors.As(err, &appErr) {
+		resp.Code, resp.Message = appErr.Code, appErr.Message
+		if appErr.Cause != nil {
+			resp.Cause = appErr.Cause.Error()
+		}
+	}
+	if status >= http.StatusInternalServerError {
+		s.logger.Error("API request failed", &logging.LogFields{Error: err.Error(), Status: strconv.Itoa(status)})
+	}
+	writeJSON(w, status, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeFile(w http.ResponseWriter, contentType, name, number string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("X-Invoice-Number", number)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// cors allows cross-origin requests from any origin and answers preflight requests.
+func cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition, X-Invoice-Number")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder captures the status code of a response for logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	tenant string // Set by authenticate
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		s.logger.Info("API request", &logging.LogFields{URL: r.Method + " " + r.URL.Path, Status: strconv.Itoa(rec.status), Tenant: rec.tenant})
+	})
+}
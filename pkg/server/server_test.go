@@ -0,0 +1,736 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"invoiceformats/internal/config"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/jobs"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/render/locale"
+	"invoiceformats/pkg/repository"
+	"invoiceformats/pkg/server/invoicepb"
+	"invoiceformats/pkg/service"
+	"invoiceformats/testutils"
+)
+
+func newTestServer(t *testing.T) (*Server, *service.InvoiceService) {
+	t.Helper()
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "sequential",
+			NumberPattern:     "RE-{YYYY}-{SEQ:4}",
+			NumberingFile:     t.TempDir() + "/numbering.json",
+			RepositoryDir:     t.TempDir(),
+			DefaultTaxRate:    19.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+		Server:   config.ServerConfig{EnableCORS: true},
+	}
+	logger := &testutils.TestLogger{}
+	localeData, err := os.ReadFile("../render/locales.json")
+	require.NoError(t, err)
+	svc := service.NewInvoiceService(cfg, logger, &locale.Loader{EmbeddedData: localeData})
+	s, err := New(cfg.Server, svc, logger)
+	require.NoError(t, err)
+	return s, svc
+}
+
+func sampleJSON(t *testing.T, svc *service.InvoiceService) string {
+	t.Helper()
+	data := svc.CreateSampleInvoice()
+	data.Invoice.Number = ""
+	data.Invoice.Date = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func do(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func doWith(s *Server, method, target, body, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(header, value)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestTemplatesAndLocales(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := do(s, http.MethodGet, "/v1/templates", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var templates map[string][]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &templates))
+	assert.Contains(t, templates["templates"], "invoice.html.tmpl")
+
+	rec = do(s, http.MethodGet, "/v1/locales", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var locales map[string][]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &locales))
+	assert.Contains(t, locales["locales"], "en")
+
+	rec = do(s, http.MethodPost, "/v1/templates", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestOpenAPI(t *testing.T) {
+	s, _ := newTestServer(t)
+	rec := do(s, http.MethodGet, "/v1/openapi.json", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc struct {
+		OpenAPI    string                     `json:"openapi"`
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, OpenAPIVersion, doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/v1/invoices/pdf")
+	assert.NotContains(t, doc.Paths, "/v1/jobs", "jobs are disabled")
+	for _, name := range []string{"InvoiceData", "InvoiceLine", "ErrorResponse", "ValidationResult"} {
+		assert.Contains(t, doc.Components.Schemas, name)
+	}
+	assert.NotContains(t, rec.Body.String(), "#/$defs/", "references point into the components")
+
+	withJobs := OpenAPI(config.ServerConfig{JobsDir: t.TempDir()})
+	assert.Contains(t, withJobs["paths"], "/v1/jobs/{id}/artifact")
+}
+
+func TestValidate(t *testing.T) {
+	s, svc := newTestServer(t)
+
+	rec := do(s, http.MethodPost, "/v1/invoices/validate", sampleJSON(t, svc))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var result ValidationResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.Valid)
+	assert.Equal(t, "RE-2025-0001", result.Invoice.Invoice.Number, "the number is only previewed")
+	assert.True(t, result.Invoice.Invoice.GrandTotal.IsPositive())
+
+	// Validating again previews the same number
+	rec = do(s, http.MethodPost, "/v1/invoices/validate", sampleJSON(t, svc))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "RE-2025-0001", result.Invoice.Invoice.Number)
+}
+
+func TestRequestErrors(t *testing.T) {
+	s, svc := newTestServer(t)
+
+	rec := do(s, http.MethodPost, "/v1/invoices/validate", "{not json")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, appErrs.ErrValidationFailed, decodeError(t, rec).Code)
+
+	rec = do(s, http.MethodPost, "/v1/invoices/html?template=../../etc/passwd", sampleJSON(t, svc))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, decodeError(t, rec).Message, "unknown template")
+
+	rec = do(s, http.MethodPost, "/v1/invoices/validate", `{"invoice": {"number": "RE-1"}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, appErrs.ErrValidationFailed, decodeError(t, rec).Code)
+
+	rec = do(s, http.MethodPost, "/v1/invoices/validate", `{"provider": "unknown"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(s, http.MethodPost, "/v1/invoices/validate", `{"x": "`+strings.Repeat("a", MaxBodyBytes)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestHTMLAndXML(t *testing.T) {
+	s, svc := newTestServer(t)
+
+	rec := do(s, http.MethodPost, "/v1/invoices/html?lang=de", sampleJSON(t, svc))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "RE-2025-0001")
+
+	rec = do(s, http.MethodPost, "/v1/invoices/xml", sampleJSON(t, svc))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "RE-2025-0001", rec.Header().Get("X-Invoice-Number"))
+	assert.Contains(t, rec.Body.String(), "CrossIndustryInvoice")
+}
+
+func TestCORS(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/invoices/pdf", nil)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Methods"), "POST")
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, http.StatusUnprocessableEntity, StatusCode(appErrs.NewValidationError("invalid", nil)))
+	assert.Equal(t, http.StatusNotFound, StatusCode(appErrs.NewAppError(appErrs.ErrInvoiceNotFound, "missing", nil)))
+	assert.Equal(t, http.StatusConflict, StatusCode(appErrs.NewAppError(appErrs.ErrNumbering, "exhausted", nil)))
+	assert.Equal(t, http.StatusInternalServerError, StatusCode(appErrs.NewPDFGenerationError("failed", nil)))
+	assert.Equal(t, http.StatusInternalServerError, StatusCode(errors.New("plain")))
+	assert.Equal(t, http.StatusGatewayTimeout, StatusCode(appErrs.FromContext("printing", context.DeadlineExceeded)))
+	assert.Equal(t, StatusClientClosedRequest, StatusCode(appErrs.FromContext("printing", context.Canceled)))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(grpcError(appErrs.NewTimeoutError("timed out", nil))))
+	assert.Equal(t, codes.Canceled, status.Code(grpcError(appErrs.NewCanceledError("canceled", nil))))
+}
+
+func TestCanceledRequest(t *testing.T) {
+	s, svc := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/v1/invoices/html", strings.NewReader(sampleJSON(t, svc))).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, StatusClientClosedRequest, rec.Code)
+	assert.Equal(t, appErrs.ErrCanceled, decodeError(t, rec).Code)
+}
+
+func TestJobs(t *testing.T) {
+	s, svc := newTestServer(t)
+	s.cfg.JobsDir = t.TempDir()
+	s, err := New(s.cfg, svc, s.logger)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.jobs.Wait()
+	}()
+	require.NoError(t, s.jobs.Start(ctx))
+
+	rec := do(s, http.MethodPost, "/v1/jobs?kind=xml", sampleJSON(t, svc))
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var job jobs.Job
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	assert.Equal(t, "/v1/jobs/"+job.ID, rec.Header().Get("Location"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !job.Status.IsFinished() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		rec = do(s, http.MethodGet, "/v1/jobs/"+job.ID, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	}
+	require.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+
+	rec = do(s, http.MethodGet, "/v1/jobs/"+job.ID+"/artifact", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "RE-2025-0001", rec.Header().Get("X-Invoice-Number"))
+	assert.Contains(t, rec.Body.String(), "CrossIndustryInvoice")
+
+	rec = do(s, http.MethodDelete, "/v1/jobs/"+job.ID, "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, appErrs.ErrJobState, decodeError(t, rec).Code)
+
+	// Invalid invoices are accepted and fail without retries
+	rec = do(s, http.MethodPost, "/v1/jobs?kind=html", `{"invoice": {"number": "RE-1"}}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	for !job.Status.IsFinished() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		rec = do(s, http.MethodGet, "/v1/jobs/"+job.ID, "")
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	}
+	assert.Equal(t, jobs.StatusFailed, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, string(appErrs.ErrValidationFailed), job.ErrorCode)
+	rec = do(s, http.MethodGet, "/v1/jobs/"+job.ID+"/artifact", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = do(s, http.MethodGet, "/v1/jobs/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, appErrs.ErrJobNotFound, decodeError(t, rec).Code)
+	rec = do(s, http.MethodPost, "/v1/jobs?kind=docx", sampleJSON(t, svc))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = do(s, http.MethodPost, "/v1/jobs?callback_url=file:///etc/passwd", sampleJSON(t, svc))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = do(s, http.MethodPost, "/v1/jobs", "{not json")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGRPC(t *testing.T) {
+	s, svc := newTestServer(t)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	gs := s.NewGRPCServer()
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := invoicepb.NewInvoiceServiceClient(conn)
+	ctx := context.Background()
+
+	invoice := &invoicepb.InvoiceData{}
+	require.NoError(t, protojson.Unmarshal([]byte(sampleJSON(t, svc)), invoice))
+
+	validated, err := client.Validate(ctx, &invoicepb.GenerateRequest{Invoice: invoice})
+	require.NoError(t, err)
+	assert.True(t, validated.Valid)
+	assert.Equal(t, "RE-2025-0001", validated.Invoice.Invoice.Number)
+	assert.NotEmpty(t, validated.Invoice.Invoice.GrandTotal)
+	assert.Equal(t, int64(1740787200), validated.Invoice.Invoice.Date.Seconds)
+
+	html, err := client.RenderHTML(ctx, &invoicepb.GenerateRequest{Invoice: invoice, Lang: "de"})
+	require.NoError(t, err)
+	assert.Contains(t, html.Html, "RE-2025-0001")
+
+	xml, err := client.GenerateXML(ctx, &invoicepb.GenerateRequest{Invoice: invoice})
+	require.NoError(t, err)
+	assert.Equal(t, "RE-2025-0001", xml.InvoiceNumber)
+	assert.Contains(t, string(xml.Data), "CrossIndustryInvoice")
+
+	// Errors carry the AppError code
+	invalid := &invoicepb.InvoiceData{Invoice: &invoicepb.InvoiceDetails{Number: "RE-1"}}
+	_, err = client.Validate(ctx, &invoicepb.GenerateRequest{Invoice: invalid})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	assert.Equal(t, string(appErrs.ErrValidationFailed), st.Details()[0].(*errdetails.ErrorInfo).Reason)
+
+	_, err = client.RenderHTML(ctx, &invoicepb.GenerateRequest{Invoice: invoice, Template: "../../etc/passwd"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.ExtractFromPDF(ctx, &invoicepb.ExtractFromPDFRequest{Pdf: []byte("%PDF-1.7")})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func signJWT(secret string, claims map[string]interface{}) string {
+	segment := func(v interface{}) string {
+		raw, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	unsigned := segment(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newAuthServer(t *testing.T) (*Server, *service.InvoiceService) {
+	t.Helper()
+	s, svc := newTestServer(t)
+	globexDigest := sha256.Sum256([]byte("globex-key"))
+	s.cfg.JobsDir = t.TempDir()
+	s.cfg.Auth = config.AuthConfig{
+		JWTSecret: "secret",
+		RateLimit: 100,
+		Tenants: []config.TenantConfig{
+			{ID: "acme", APIKeys: []string{"acme-key"}, Dir: t.TempDir()},
+			{ID: "globex", APIKeys: []string{"sha256:" + hex.EncodeToString(globexDigest[:])}, Dir: t.TempDir()},
+			{ID: "limited", APIKeys: []string{"limited-key"}, Dir: t.TempDir(), RateLimit: 1, MaxBodyBytes: 64},
+		},
+	}
+	s, err := New(s.cfg, svc, s.logger)
+	require.NoError(t, err)
+	return s, svc
+}
+
+func TestAuth(t *testing.T) {
+	s, svc := newAuthServer(t)
+	invoice := sampleJSON(t, svc)
+
+	rec := do(s, http.MethodPost, "/v1/invoices/validate", invoice)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, appErrs.ErrUnauthorized, decodeError(t, rec).Code)
+	assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+	rec = doWith(s, http.MethodPost, "/v1/invoices/validate", invoice, APIKeyHeader, "wrong-key")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The API description is public and names the security schemes
+	rec = do(s, http.MethodGet, "/v1/openapi.json", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "securitySchemes")
+
+	// Tenants have their own numbering
+	acmeCounters := filepath.Join(s.cfg.Auth.Tenants[0].Dir, "numbering.json")
+	require.NoError(t, os.WriteFile(acmeCounters, []byte(`{"counters": {"default/2025": 41}}`), 0644))
+	rec = doWith(s, http.MethodPost, "/v1/invoices/xml", invoice, APIKeyHeader, "acme-key")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "RE-2025-0042", rec.Header().Get("X-Invoice-Number"))
+	rec = doWith(s, http.MethodPost, "/v1/invoices/xml", invoice, "Authorization", "Bearer globex-key")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "RE-2025-0001", rec.Header().Get("X-Invoice-Number"))
+
+	// Jobs are only visible to their tenant
+	rec = doWith(s, http.MethodPost, "/v1/jobs?kind=xml", invoice, APIKeyHeader, "acme-key")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var job jobs.Job
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	assert.Equal(t, "acme", job.Request.Tenant)
+	rec = doWith(s, http.MethodGet, "/v1/jobs/"+job.ID, "", APIKeyHeader, "acme-key")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doWith(s, http.MethodGet, "/v1/jobs/"+job.ID, "", APIKeyHeader, "globex-key")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doWith(s, http.MethodDelete, "/v1/jobs/"+job.ID, "", APIKeyHeader, "globex-key")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Request size and rate limits of the tenant
+	rec = doWith(s, http.MethodPost, "/v1/invoices/validate", invoice, APIKeyHeader, "limited-key")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	rec = doWith(s, http.MethodPost, "/v1/invoices/validate", invoice, APIKeyHeader, "limited-key")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, appErrs.ErrRateLimited, decodeError(t, rec).Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+}
+
+func TestAuthJWT(t *testing.T) {
+	s, svc := newAuthServer(t)
+	invoice := sampleJSON(t, svc)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	token := signJWT("secret", map[string]interface{}{"tenant": "globex", "sub": "billing", "exp": exp})
+	rec := doWith(s, http.MethodPost, "/v1/invoices/validate", invoice, "Authorization", "Bearer "+token)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	for name, token := range map[string]string{
+		"expired":        signJWT("secret", map[string]interface{}{"tenant": "globex", "exp": time.Now().Add(-time.Minute).Unix()}),
+		"no exp":         signJWT("secret", map[string]interface{}{"tenant": "globex"}),
+		"not yet valid":  signJWT("secret", map[string]interface{}{"tenant": "globex", "exp": exp, "nbf": exp - 60}),
+		"unknown tenant": signJWT("secret", map[string]interface{}{"tenant": "initech", "exp": exp}),
+		"wrong secret":   signJWT("other", map[string]interface{}{"tenant": "globex", "exp": exp}),
+	} {
+		rec := doWith(s, http.MethodPost, "/v1/invoices/validate", invoice, "Authorization", "Bearer "+token)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, name)
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	a, err := newAuthenticator(config.AuthConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	for name, cfg := range map[string]config.AuthConfig{
+		"secret without tenants": {JWTSecret: "secret"},
+		"duplicate tenant":       {Tenants: []config.TenantConfig{{ID: "a"}, {ID: "a"}}},
+		"invalid tenant id":      {Tenants: []config.TenantConfig{{ID: "../a"}}},
+		"shared key":             {Tenants: []config.TenantConfig{{ID: "a", APIKeys: []string{"k"}}, {ID: "b", APIKeys: []string{"k"}}}},
+		"invalid digest":         {Tenants: []config.TenantConfig{{ID: "a", APIKeys: []string{"sha256:abc"}}}},
+		"unset key variable":     {Tenants: []config.TenantConfig{{ID: "a", APIKeysEnv: "INVOICEGEN_TEST_UNSET_KEYS"}}},
+	} {
+		_, err := newAuthenticator(cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestConcurrentPDFNumbering(t *testing.T) {
+	s, svc := newTestServer(t)
+	data := svc.CreateSampleInvoice()
+	data.Invoice.Number = ""
+	data.Invoice.Date = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	data.EmbeddedData = "" // The fake PDFs cannot carry XML
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	invoice := string(raw)
+	var prints, printing, overlapping atomic.Int32
+	svc.SetPrinter(func(ctx context.Context, html, outputFile string, logger logging.Logger) error {
+		if printing.Add(1) > 1 {
+			overlapping.Store(1)
+		}
+		defer printing.Add(-1)
+		time.Sleep(20 * time.Millisecond)
+		if prints.Add(1) == 2 {
+			return errors.New("browser tab crashed")
+		}
+		return os.WriteFile(outputFile, []byte("%PDF-1.7"), 0644)
+	})
+
+	const requests = 6
+	numbers := make(chan string, requests)
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := do(s, http.MethodPost, "/v1/invoices/pdf", invoice)
+			if rec.Code == http.StatusOK {
+				numbers <- rec.Header().Get("X-Invoice-Number")
+			}
+		}()
+	}
+	wg.Wait()
+	close(numbers)
+	assert.Equal(t, int32(1), overlapping.Load(), "PDFs are printed at the same time")
+
+	// The failed invoice hands its number back, or records it as cancelled if later
+	// numbers were issued meanwhile, so the sequence has no gap
+	issued := make(map[string]bool)
+	for n := range numbers {
+		issued[n] = true
+	}
+	require.Len(t, issued, requests-1)
+	cancelled, err := svc.ListInvoices(repository.Filter{Status: models.StatusCancelled})
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(cancelled), 1)
+	for _, rec := range cancelled {
+		issued[rec.Number] = true
+	}
+	for i := 1; i <= len(issued); i++ {
+		assert.True(t, issued[fmt.Sprintf("RE-2025-%04d", i)], i)
+	}
+}
+
+func TestJobs_ResumedPDFJobKeepsNumber(t *testing.T) {
+	s, svc := newTestServer(t)
+	s.cfg.JobsDir = t.TempDir()
+	s, err := New(s.cfg, svc, s.logger)
+	require.NoError(t, err)
+	data := svc.CreateSampleInvoice()
+	data.Invoice.Number = ""
+	data.Invoice.Date = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	data.EmbeddedData = "" // The fake PDFs cannot carry XML
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	store := jobs.NewStore(s.cfg.JobsDir)
+	var prints atomic.Int32
+	svc.SetPrinter(func(ctx context.Context, html, outputFile string, logger logging.Logger) error {
+		prints.Add(1)
+		// The number is checkpointed before the invoice is printed
+		list, err := store.List()
+		if err != nil {
+			return err
+		}
+		for _, job := range list {
+			if job.Status == jobs.StatusRunning && job.InvoiceNumber == "" {
+				return errors.New("job " + job.ID + " runs without a checkpointed number")
+			}
+		}
+		return os.WriteFile(outputFile, []byte("%PDF-1.7"), 0644)
+	})
+
+	// A run interrupted by a crash after issuing RE-2025-0001, and one that had
+	// already written its artifact
+	rec := do(s, http.MethodPost, "/v1/invoices/pdf", string(raw))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "RE-2025-0001", rec.Header().Get("X-Invoice-Number"))
+	now := time.Now()
+	crashed := &jobs.Job{ID: "crashed", Status: jobs.StatusRunning, Request: jobs.Request{Kind: jobs.KindPDF, Invoice: raw},
+		Attempts: 1, MaxAttempts: 3, InvoiceNumber: "RE-2025-0001", CreatedAt: now, UpdatedAt: now}
+	written := &jobs.Job{ID: "written", Status: jobs.StatusRunning, Request: jobs.Request{Kind: jobs.KindPDF, Invoice: raw},
+		Attempts: 1, MaxAttempts: 3, InvoiceNumber: "RE-2025-0007", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, store.Save(crashed))
+	require.NoError(t, store.Save(written))
+	require.NoError(t, os.WriteFile(filepath.Join(s.cfg.JobsDir, "written.pdf"), []byte("%PDF-1.7 written"), 0644))
+	prints.Store(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.jobs.Wait()
+	}()
+	require.NoError(t, s.jobs.Start(ctx))
+	fresh, err := s.jobs.Submit(jobs.Request{Kind: jobs.KindPDF, Invoice: raw})
+	require.NoError(t, err)
+
+	finished := func(id string) *jobs.Job {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			job, err := s.jobs.Get(id)
+			require.NoError(t, err)
+			if job.Status.IsFinished() || time.Now().After(deadline) {
+				return job
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	job := finished("crashed")
+	require.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+	assert.Equal(t, "RE-2025-0001", job.InvoiceNumber)
+	job = finished("written")
+	require.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+	assert.Equal(t, "RE-2025-0007", job.InvoiceNumber)
+	rec = do(s, http.MethodGet, "/v1/jobs/written/artifact", "")
+	assert.Equal(t, "%PDF-1.7 written", rec.Body.String())
+	job = finished(fresh.ID)
+	require.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+	assert.Equal(t, "RE-2025-0002", job.InvoiceNumber)
+	assert.Equal(t, int32(2), prints.Load(), "the written artifact is not printed again")
+}
+
+func TestListenWithoutAuth(t *testing.T) {
+	s, _ := newTestServer(t)
+	for _, host := range []string{"", "0.0.0.0", "192.0.2.1", "::"} {
+		s.cfg.Host = host
+		err := s.ListenAndServe(context.Background())
+		var appErr *appErrs.AppError
+		require.ErrorAs(t, err, &appErr, host)
+		assert.Equal(t, appErrs.ErrConfigInvalid, appErr.Code, host)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		assert.True(t, isLoopback(host), host)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.Zero(t, l.take("k", 60, 2, now))
+	assert.Zero(t, l.take("k", 60, 2, now))
+	assert.Equal(t, time.Second, l.take("k", 60, 2, now))
+	assert.Zero(t, l.take("other", 60, 2, now))
+	assert.Zero(t, l.take("k", 60, 2, now.Add(time.Second)))
+}
+
+func TestGRPCAuth(t *testing.T) {
+	s, svc := newAuthServer(t)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	gs := s.NewGRPCServer()
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := invoicepb.NewInvoiceServiceClient(conn)
+	invoice := &invoicepb.InvoiceData{}
+	require.NoError(t, protojson.Unmarshal([]byte(sampleJSON(t, svc)), invoice))
+	req := &invoicepb.GenerateRequest{Invoice: invoice}
+
+	_, err = client.Validate(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "acme-key")
+	validated, err := client.Validate(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, validated.Valid)
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer limited-key")
+	_, err = client.Validate(ctx, req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	stream, err := client.GeneratePDF(ctx, req)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestHealth(t *testing.T) {
+	s, svc := newAuthServer(t)
+	s.ready.check = func(context.Context) error { return nil }
+
+	rec := do(s, http.MethodGet, "/healthz", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, rec.Body.String())
+	rec = do(s, http.MethodGet, "/readyz", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"ready"`)
+
+	// Check results are reused for ReadyCheckInterval
+	s.ready.check = func(context.Context) error { return errors.New("chrome not found") }
+	rec = do(s, http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	s.ready.checked = time.Time{}
+	rec = do(s, http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "chrome not found")
+
+	rec = doWith(s, http.MethodPost, "/v1/invoices/xml", sampleJSON(t, svc), APIKeyHeader, "acme-key")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = do(s, http.MethodGet, "/metrics", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `invoicegen_generations_total{format="xml",outcome="success"`)
+	assert.Contains(t, rec.Body.String(), "invoicegen_browser_up 0")
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	s, svc := newTestServer(t)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	rec := doWith(s, http.MethodPost, "/v1/invoices/xml", sampleJSON(t, svc), "traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	require.Equal(t, http.StatusOK, rec.Code)
+	do(s, http.MethodGet, "/healthz", "")
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String(), span.Name())
+		spans[span.Name()] = span
+	}
+	require.Contains(t, spans, "POST /v1/invoices/xml")
+	require.Contains(t, spans, "invoice.generate")
+	assert.NotContains(t, spans, "GET /healthz")
+	request := spans["POST /v1/invoices/xml"]
+	assert.Equal(t, "00f067aa0ba902b7", request.Parent().SpanID().String())
+	assert.Equal(t, request.SpanContext().SpanID(), spans["invoice.generate"].Parent().SpanID())
+
+	// gRPC calls continue the trace of their traceparent metadata
+	recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	gs := s.NewGRPCServer()
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	var invoice invoicepb.InvoiceData
+	require.NoError(t, protojson.Unmarshal([]byte(sampleJSON(t, svc)), &invoice))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	_, err = invoicepb.NewInvoiceServiceClient(conn).GenerateXML(ctx, &invoicepb.GenerateRequest{Invoice: &invoice})
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String(), span.Name())
+		names = append(names, span.Name())
+	}
+	assert.Contains(t, names, invoicepb.InvoiceService_GenerateXML_FullMethodName)
+	assert.Contains(t, names, "xml.build")
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"invoiceformats/pkg/archive"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/pdf"
+	"invoiceformats/pkg/repository"
+)
+
+// getArchive returns the invoice archive, or nil if archiving is disabled. With
+// sequential numbering, the archive checks the number series for gaps.
+func (s *InvoiceService) getArchive() *archive.Archive {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.archive == nil && s.config.Invoice.ArchiveDir != "" {
+		s.archive = archive.New(s.config.Invoice.ArchiveDir)
+		if s.sequentialNumbering() {
+			s.archive.NumberSeries = s.numberPatterns()
+		}
+	}
+	return s.archive
+}
+
+// archiveInvoice stores the final PDF, the XML embedded in it and the source data of
+// an invoice that is about to be issued. The XML is extracted from the PDF, so the
+// archive holds exactly what the customer receives.
+func (s *InvoiceService) archiveInvoice(arch *archive.Archive, rec *repository.Record) error {
+	if rec.Data == nil {
+		return appErrs.NewArchiveError("invoice "+rec.Number+" has no recorded data", nil)
+	}
+	pdfData, err := os.ReadFile(rec.PDFFile)
+	if err != nil {
+		return appErrs.NewArchiveError("PDF of invoice "+rec.Number+" not found, regenerate the draft", err)
+	}
+	files := []archive.File{{Name: "invoice.pdf", Data: pdfData}}
+	xmlData, err := (&pdf.ZugferdEmbedder{}).ExtractXML(pdfData)
+	switch {
+	case err == nil:
+		files = append(files, archive.File{Name: "invoice.xml", Data: xmlData})
+	case rec.Data.EmbeddedData == models.EmbeddedDataZUGFeRD:
+		return appErrs.NewArchiveError("PDF of invoice "+rec.Number+" carries no embedded XML, regenerate the draft", err)
+	}
+	sourceData, err := json.MarshalIndent(rec.Data, "", "  ")
+	if err != nil {
+		return appErrs.NewArchiveError("failed to encode invoice data", err)
+	}
+	files = append(files, archive.File{Name: "data.json", Data: sourceData})
+
+	entry, err := arch.Store(rec.Number, files)
+	if errors.Is(err, archive.ErrAlreadyArchived) {
+		s.logger.Warn("Invoice already archived", &logging.LogFields{InvoiceNum: rec.Number, Error: err.Error()})
+		return nil
+	}
+	if err != nil {
+		return appErrs.NewArchiveError("failed to archive invoice "+rec.Number, err)
+	}
+	s.logger.Info("Invoice archived", &logging.LogFields{InvoiceNum: rec.Number, Status: "retain until " + entry.RetainUntil.Format("2006-01-02")})
+	return nil
+}
+
+// VerifyArchive checks the archive for tampering and deleted invoices. Number gaps
+// explained by drafts that were never issued are not reported; issued invoices in the
+// repository that are missing from the archive are. knownHead is an optional head hash
+// from an earlier verification.
+func (s *InvoiceService) VerifyArchive(knownHead string) (*archive.Report, error) {
+	arch := s.getArchive()
+	if arch == nil {
+		return nil, appErrs.NewConfigError("invoice archive is disabled (archive_dir is empty)", nil)
+	}
+	report, err := arch.Verify(knownHead)
+	if err != nil {
+		return nil, appErrs.NewArchiveError("failed to read archive", err)
+	}
+	repo := s.getRepository()
+	if repo == nil {
+		return report, nil
+	}
+
+	problems := report.Problems[:0]
+	for _, p := range report.Problems {
+		if p.Kind == archive.ProblemNumberGap {
+			if rec, err := repo.Get(p.Number); err == nil && !wasIssued(rec) {
+				continue
+			}
+		}
+		problems = append(problems, p)
+	}
+	report.Problems = problems
+
+	entries, err := arch.Entries()
+	if err != nil {
+		return nil, appErrs.NewArchiveError("failed to read archive", err)
+	}
+	archived := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		archived[e.Number] = true
+	}
+	records, err := repo.List(repository.Filter{})
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	for _, rec := range records {
+		if wasIssued(rec) && !archived[rec.Number] {
+			report.Problems = append(report.Problems, archive.Problem{Kind: archive.ProblemNotArchived, Number: rec.Number, Detail: "issued invoice missing from the archive"})
+		}
+	}
+	return report, nil
+}
+
+// wasIssued reports whether an invoice was ever sent
+func wasIssued(rec *repository.Record) bool {
+	for _, h := range rec.History {
+		if h.To == models.StatusSent {
+			return true
+		}
+	}
+	return false
+}
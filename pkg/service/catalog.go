@@ -0,0 +1,33 @@
+package service
+
+import (
+	"invoiceformats/pkg/catalog"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/models"
+)
+
+// Catalog returns the product and service catalog, loaded and validated on first use
+func (s *InvoiceService) Catalog() (*catalog.Catalog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.catalog == nil {
+		c, err := catalog.Load(s.config.Invoice.CatalogFile)
+		if err != nil {
+			return nil, appErrs.NewConfigError("invalid catalog", err)
+		}
+		s.catalog = c
+	}
+	return s.catalog, nil
+}
+
+// applyCatalog fills in the lines that reference a catalog item by SKU
+func (s *InvoiceService) applyCatalog(data *models.InvoiceData) error {
+	c, err := s.Catalog()
+	if err != nil {
+		return err
+	}
+	if err := c.Apply(&data.Invoice); err != nil {
+		return appErrs.NewValidationError("invalid catalog reference", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+)
+
+// CreditNoteOptions controls how a credit note is derived from an invoice
+type CreditNoteOptions struct {
+	Number  string    // Credit note number; issued on generation with sequential numbering, otherwise the invoice number with a "-CN" suffix
+	Date    time.Time // Issue date; defaults to today
+	Lines   []int     // 1-based invoice lines to credit; all lines if empty
+	Percent float64   // Share of the selected lines to credit, e.g. 10 for a price reduction; 100 if zero
+	Reason  string    // Reason for the credit, shown in the notes
+}
+
+// CreateCreditNote derives a credit note (type code 381) from an issued invoice. The
+// credit note references the invoice (BG-3) and repeats the credited lines with their
+// tax treatment; amounts stay positive as required by EN 16931.
+func (s *InvoiceService) CreateCreditNote(original *models.InvoiceData, opts CreditNoteOptions) (*models.InvoiceData, error) {
+	inv := original.Invoice
+	if inv.IsCreditNote() {
+		return nil, appErrs.NewValidationError(fmt.Sprintf("%s is already a credit note", inv.Number), nil)
+	}
+	percent := decimal.NewFromInt(100)
+	if opts.Percent != 0 {
+		percent = decimal.NewFromFloat(opts.Percent)
+	}
+	if !percent.IsPositive() || percent.GreaterThan(decimal.NewFromInt(100)) {
+		return nil, appErrs.NewValidationError("credit percentage must be between 0 and 100", nil)
+	}
+
+	selected := opts.Lines
+	if len(selected) == 0 {
+		for i := range inv.Lines {
+			selected = append(selected, i+1)
+		}
+	}
+	lines := make([]models.InvoiceLine, 0, len(selected))
+	for _, n := range selected {
+		if n < 1 || n > len(inv.Lines) {
+			return nil, appErrs.NewValidationError(fmt.Sprintf("invoice %s has no line %d", inv.Number, n), nil)
+		}
+		line := inv.Lines[n-1]
+		line.ID = uuid.Nil
+		line.UnitPrice = line.UnitPrice.Mul(percent).Div(decimal.NewFromInt(100)).Round(2)
+		lines = append(lines, line)
+	}
+
+	number := opts.Number
+	if number == "" && !s.sequentialNumbering() {
+		number = inv.Number + "-CN"
+	}
+	date := opts.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	credit := &models.InvoiceData{
+		Provider:     original.Provider,
+		Client:       original.Client,
+		EmbeddedData: original.EmbeddedData,
+		Invoice: models.InvoiceDetails{
+			Number:             number,
+			Type:               models.InvoiceTypeCreditNote,
+			Date:               date,
+			DueDate:            date.AddDate(0, 0, inv.PaymentTerms.DueDays),
+			Status:             models.StatusDraft,
+			Currency:           inv.Currency,
+			Lines:              lines,
+			PaymentTerms:       inv.PaymentTerms,
+			Notes:              opts.Reason,
+			Language:           inv.Language,
+			LegalFields:        inv.LegalFields,
+			VATExemptionType:   inv.VATExemptionType,
+			VATExemptionReason: inv.VATExemptionReason,
+			TaxCurrency:        inv.TaxCurrency,
+			PrecedingInvoices:  []models.InvoiceReference{{Number: inv.Number, Date: inv.Date}},
+		},
+	}
+	for _, w := range inv.Withholdings {
+		w.Base = w.Base.Mul(percent).Div(decimal.NewFromInt(100)).Round(2)
+		credit.Invoice.Withholdings = append(credit.Invoice.Withholdings, w)
+	}
+	if !credit.Invoice.DueDate.After(date) {
+		credit.Invoice.DueDate = date.AddDate(0, 0, s.config.Invoice.DefaultDueDays)
+	}
+	credit.Invoice.CalculateTotals()
+
+	s.logger.Info("Created credit note", &logging.LogFields{InvoiceNum: number, Status: "credits " + inv.Number, Lines: len(lines)})
+	return credit, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"invoiceformats/pkg/dunning"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/render"
+	"invoiceformats/pkg/repository"
+)
+
+// DunningOptions holds options for a dunning run
+type DunningOptions struct {
+	Date      time.Time // Reference date; defaults to now
+	OutputDir string    // Directory for the notice PDFs; defaults to config.Dunning.OutputDir
+	Locale    string    // Path to custom locale file
+	DryRun    bool      // Only determine the notices due
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// dunningPolicy builds the escalation policy from the configuration
+func (s *InvoiceService) dunningPolicy() (dunning.Policy, error) {
+	cfg := s.config.Dunning
+	policy := dunning.Policy{BaseRate: decimal.NewFromFloat(cfg.BaseRate), Levels: dunning.DefaultLevels()}
+	if len(cfg.Levels) > 0 {
+		policy.Levels = make([]dunning.Level, len(cfg.Levels))
+		for i, l := range cfg.Levels {
+			policy.Levels[i] = dunning.Level{
+				Name:              l.Name,
+				DaysAfterDue:      l.DaysAfterDue,
+				DaysAfterPrevious: l.DaysAfterPrevious,
+				PaymentDays:       l.PaymentDays,
+				Fee:               decimal.NewFromFloat(l.Fee),
+				Interest:          l.Interest,
+				Template:          l.Template,
+			}
+			if policy.Levels[i].Template == "" {
+				policy.Levels[i].Template = "dunning.html.tmpl"
+				if i == 0 {
+					policy.Levels[i].Template = "reminder.html.tmpl"
+				}
+			}
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return policy, appErrs.NewConfigError("invalid dunning configuration", err)
+	}
+	return policy, nil
+}
+
+// RunDunning marks invoices past their due date as overdue and issues the reminders
+// and dunning notices that are due. Each notice is rendered to a PDF in the invoice
+// language and recorded with the invoice. With DryRun the notices are only returned.
+// When ctx is done, the run stops and returns the notices issued so far.
+func (s *InvoiceService) RunDunning(ctx context.Context, opts *DunningOptions) ([]dunning.Notice, error) {
+	repo, err := s.requireRepository()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.dunningPolicy()
+	if err != nil {
+		return nil, err
+	}
+	now := opts.Date
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if !opts.DryRun {
+		if err := s.markOverdue(repo, now); err != nil {
+			return nil, err
+		}
+	}
+	records, err := repo.List(repository.Filter{})
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	notices := dunning.Plan(records, now, policy)
+	s.logger.Info("Dunning run", &logging.LogFields{Status: fmt.Sprintf("%d notices due", len(notices))})
+	if opts.DryRun {
+		return notices, nil
+	}
+
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = s.config.Dunning.OutputDir
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, appErrs.NewPDFGenerationError("failed to create dunning output directory", err)
+	}
+	for i, n := range notices {
+		file := filepath.Join(outputDir, fmt.Sprintf("%s-%d-%s.pdf", unsafeFileChars.ReplaceAllString(n.Number, "_"), n.Level, n.Step.Name))
+		if err := s.renderNotice(ctx, n, file, opts.Locale); err != nil {
+			return notices[:i], err
+		}
+		if _, err := repo.RecordNotice(n.Number, n.Record(file)); err != nil {
+			return notices[:i], repositoryError(err)
+		}
+		s.logger.Info("Dunning notice issued", &logging.LogFields{InvoiceNum: n.Number, File: file, Status: fmt.Sprintf("level %d %s, %s %s", n.Level, n.Step.Name, n.Total.StringFixed(2), n.Currency)})
+	}
+	return notices, nil
+}
+
+// renderNotice renders a notice in the invoice language and prints it to file
+func (s *InvoiceService) renderNotice(ctx context.Context, n dunning.Notice, file, localePath string) (err error) {
+	defer func() {
+		if err != nil {
+			err = appErrs.FromContext("dunning notice for "+n.Number, err)
+		}
+	}()
+	if n.Data == nil {
+		return appErrs.NewPDFGenerationError("invoice "+n.Number+" has no recorded data", nil)
+	}
+	html, err := render.RenderNotice(ctx, n, n.Step.Template, n.Data.Invoice.Language, localePath, s.localeLoader)
+	if err != nil {
+		s.logger.Error("Notice rendering failed", &logging.LogFields{Error: err.Error(), InvoiceNum: n.Number})
+		return appErrs.NewPDFGenerationError("failed to render "+n.Step.Name+" for "+n.Number, err)
+	}
+	if err := s.printer(ctx, html, file, s.logger); err != nil {
+		s.logger.Error("PDF generation failed", &logging.LogFields{Error: err.Error(), InvoiceNum: n.Number})
+		return appErrs.NewPDFGenerationError("failed to generate PDF", err)
+	}
+	return nil
+}
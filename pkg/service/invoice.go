@@ -2,31 +2,47 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
 
 	"invoiceformats/internal/config"
+	"invoiceformats/pkg/archive"
+	"invoiceformats/pkg/catalog"
 	"invoiceformats/pkg/compliance"
 	"invoiceformats/pkg/di"
 	appErrs "invoiceformats/pkg/errors"
-	"invoiceformats/pkg/i18n"
+	"invoiceformats/pkg/exchange"
 	interfacesPDF "invoiceformats/pkg/interfaces"
 	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/metrics"
 	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/numbering"
 	"invoiceformats/pkg/pdf"
+	"invoiceformats/pkg/profile"
 	"invoiceformats/pkg/render"
 	"invoiceformats/pkg/render/interfaces"
+	"invoiceformats/pkg/repository"
+	"invoiceformats/pkg/tax"
+	"invoiceformats/pkg/tracing"
 	"invoiceformats/pkg/validation"
+	"invoiceformats/pkg/webhook"
 	"invoiceformats/pkg/xml"
 )
 
-// InvoiceService handles invoice generation and related operations
+// InvoiceService handles invoice generation and related operations. It is safe for
+// concurrent use: invoices are rendered and printed at the same time, only issuing and
+// handing back sequential numbers is serialized.
 // Inject ZUGFeRDInvoiceXMLBuilder for DI
 
 type InvoiceService struct {
@@ -34,8 +50,22 @@ type InvoiceService struct {
 	logger      logging.Logger
 	validator   *validation.Validator
 	localeLoader interfaces.LocaleLoader
+	taxEngine   *tax.Engine
+	numberer    *numbering.Numberer // Created on first use, see getNumberer
+	repository  repository.Repository // Created on first use, see getRepository
+	archive     *archive.Archive      // Created on first use, see getArchive
+	profiles    *profile.Registry     // Loaded on first use, see Profiles
+	catalog     *catalog.Catalog      // Loaded on first use, see Catalog
+	webhooks    *webhook.Dispatcher   // Created on first use, see Webhooks
+	tenants     map[string]*InvoiceService // Created on first use, see ForTenant
+	printer     PrintFunc                  // Prints PDFs; see SetPrinter
+	mu          sync.Mutex                 // Guards the state created on first use
+	numberMu    sync.Mutex                 // Held while a sequential number is issued or handed back
 }
 
+// PrintFunc prints HTML to a PDF file, as pdf.GeneratePDFChromedp does.
+type PrintFunc func(ctx context.Context, html, outputFile string, logger logging.Logger) error
+
 // NewInvoiceService creates a new invoice service instance
 func NewInvoiceService(cfg *config.AppConfig, logger logging.Logger, loader interfaces.LocaleLoader) *InvoiceService {
 	return &InvoiceService{
@@ -43,6 +73,19 @@ func NewInvoiceService(cfg *config.AppConfig, logger logging.Logger, loader inte
 		logger:      logger,
 		validator:   validation.NewValidator(),
 		localeLoader: loader,
+		taxEngine:   di.ProvideTaxEngine(),
+		printer:     pdf.GeneratePDFChromedp,
+	}
+}
+
+// SetPrinter replaces the headless Chrome printer of the service and its tenants, e.g.
+// with another PDF backend. Set it before the service is used.
+func (s *InvoiceService) SetPrinter(print PrintFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.printer = print
+	for _, svc := range s.tenants {
+		svc.printer = print
 	}
 }
 
@@ -58,10 +101,71 @@ type GenerateOptions struct {
 	ValidateOnly   bool
 	EnableZUGFeRD  bool
 	EmbeddedDataProvider interfacesPDF.PDFEmbeddedDataProvider
+	ExchangeRates  exchange.RateSource // Optional; falls back to config.Invoice.ExchangeRatesFile
+	Series         string              // Number series for sequential numbering; default series if empty
+	// NumberIssued is called with a sequential number once it is issued, before the
+	// invoice is generated; an error stops the generation and hands the number back
+	NumberIssued   func(number string) error
+}
+
+// GenerateInvoice creates an invoice PDF from the provided data. Generation stops when
+// ctx is done, with an ErrTimeout or ErrCanceled error; a number issued for the
+// invoice is handed back then.
+func (s *InvoiceService) GenerateInvoice(ctx context.Context, data *models.InvoiceData, opts *GenerateOptions) (err error) {
+	format := "pdf"
+	if opts.ValidateOnly || opts.DryRun {
+		format = "validation"
+	}
+	ctx, span := startGeneration(ctx, data, opts, format)
+	defer func() { tracing.End(span, err) }()
+	err = contextError(ctx, s.generateInvoice(ctx, data, opts))
+	recordGeneration(opts, format, err)
+	return err
+}
+
+// contextError returns err as an ErrTimeout or ErrCanceled error if ctx ended the
+// generation. Steps that were cut short, such as the pdfcpu process killed at the
+// deadline, do not always report the end of ctx, so any error after ctx is done is
+// taken to be caused by it.
+func contextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		err = fmt.Errorf("%w: %w", ctxErr, err)
+	}
+	return appErrs.FromContext("invoice generation", err)
+}
+
+// startGeneration starts the span of a generation; format is validation, html, xml or
+// pdf. The steps of the generation are its children.
+func startGeneration(ctx context.Context, data *models.InvoiceData, opts *GenerateOptions, format string) (context.Context, trace.Span) {
+	attrs := append(tracing.InvoiceAttributes(data), tracing.Template.String(metrics.Template(opts.Template)), tracing.Format.String(format))
+	return tracing.Start(ctx, "invoice.generate", attrs...)
 }
 
-// GenerateInvoice creates an invoice PDF from the provided data
-func (s *InvoiceService) GenerateInvoice(data *models.InvoiceData, opts *GenerateOptions) error {
+// recordGeneration counts a generation in the metrics; format is validation, html, xml
+// or pdf.
+func recordGeneration(opts *GenerateOptions, format string, err error) {
+	metrics.Generations.WithLabelValues(metrics.Template(opts.Template), format, metrics.Outcome(err)).Inc()
+}
+
+// generateInvoice implements GenerateInvoice without counting the generation, for
+// the methods that generate other formats and count those.
+func (s *InvoiceService) generateInvoice(ctx context.Context, data *models.InvoiceData, opts *GenerateOptions) (err error) {
+	// Invoices rejected before rendering are counted by error code. Preparing the
+	// invoice, from the defaults to its validation, is traced as one step.
+	_, prepare := tracing.Start(ctx, "invoice.prepare")
+	validated := false
+	defer func() {
+		if !validated {
+			tracing.End(prepare, err)
+			if err != nil {
+				metrics.ValidationErrors.WithLabelValues(metrics.Outcome(err)).Inc()
+			}
+		}
+	}()
+
 	s.logger.Info("Starting invoice generation", &logging.LogFields{
 		InvoiceNum: data.Invoice.Number,
 		File: opts.OutputFile,
@@ -75,6 +179,39 @@ func (s *InvoiceService) GenerateInvoice(data *models.InvoiceData, opts *Generat
 
 	// Apply service defaults FIRST
 	s.applyDefaults(data, opts)
+	if err := s.applyCatalog(data); err != nil {
+		s.logger.Error("Catalog lookup failed", &logging.LogFields{Error: err.Error(), InvoiceNum: data.Invoice.Number})
+		return err
+	}
+	if err := s.applyExchangeRate(data, opts); err != nil {
+		s.logger.Error("Exchange rate lookup failed", &logging.LogFields{Error: err.Error(), Currency: data.Invoice.Currency.Code})
+		return err
+	}
+	if err := s.applyTaxRules(data, opts); err != nil {
+		s.logger.Error("Tax rule evaluation failed", &logging.LogFields{Error: err.Error(), InvoiceNum: data.Invoice.Number})
+		return err
+	}
+
+	// Issue the next sequential number; it is handed back if generation fails below
+	alloc, err := s.assignInvoiceNumber(data, opts)
+	if err != nil {
+		s.logger.Error("Invoice numbering failed", &logging.LogFields{Error: err.Error()})
+		return err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(tracing.InvoiceNumber.String(data.Invoice.Number))
+	if alloc != nil {
+		defer func() {
+			if err != nil {
+				err = s.releaseOnFailure(data, alloc, err)
+			}
+		}()
+	}
+	if !opts.ValidateOnly && !opts.DryRun {
+		if err := s.checkNotIssued(data); err != nil {
+			s.logger.Error("Invoice already issued", &logging.LogFields{Error: err.Error(), InvoiceNum: data.Invoice.Number})
+			return err
+		}
+	}
 
 	// Debug: Log currency after applying defaults
 	s.logger.Debug("Currency after applying defaults", &logging.LogFields{
@@ -87,52 +224,20 @@ func (s *InvoiceService) GenerateInvoice(data *models.InvoiceData, opts *Generat
 		s.logger.Error("Invoice validation failed", &logging.LogFields{Error: err.Error()})
 		return appErrs.NewValidationError("validation failed", err)
 	}
+	validated = true
+	prepare.End()
 
 	if opts.ValidateOnly {
 		s.logger.Info("Validation successful, skipping generation (validate-only mode)", nil)
 		return nil
 	}
-
-	// Calculate totals
-	data.Invoice.CalculateTotals()
-
-	// Auto-set recurrence if all lines have the same period or only one line with period
-	periodSet := make(map[string]struct{})
-	for _, line := range data.Invoice.Lines {
-		if line.Period != "" {
-			periodSet[line.Period] = struct{}{}
-		}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	if len(periodSet) == 1 && len(data.Invoice.Lines) > 0 {
-		for p := range periodSet {
-			data.Invoice.Recurrence = p
-		}
-	}
-
-	// Update service to use new RenderHTML signature and set EmbeddedData
-	// Render HTML and extract embedded data type from template
-
-	// Prepare locale loader and translator provider
-	// localeLoader := &locale.Loader{} // REMOVE
-	translator := func(lang string, loc map[string]string) func(string) string {
-		return func(key string) string {
-			if v, ok := loc[key]; ok {
-				return v
-			}
-			return key
-		}
-	}
-
-	lang := data.Invoice.Language
-	if lang == "" {
-		lang = "en"
-	}
-	locMap, _ := s.localeLoader.Load(lang, opts.Locale)
 
-	html, err := render.RenderHTMLWithLocale(*data, opts.Template, opts.Locale, func(l string, _ map[string]string) func(string) string { return translator(l, locMap) }, s.localeLoader)
+	html, err := s.renderInvoiceHTML(ctx, data, opts)
 	if err != nil {
-		s.logger.Error("HTML rendering failed", &logging.LogFields{Error: err.Error()})
-		return appErrs.NewPDFGenerationError("failed to render HTML", err)
+		return err
 	}
 
 	if opts.DryRun {
@@ -151,23 +256,31 @@ func (s *InvoiceService) GenerateInvoice(data *models.InvoiceData, opts *Generat
 	}
 
 	// Generate PDF
-	if err := pdf.GeneratePDFChromedp(html, opts.OutputFile, s.logger); err != nil {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.printer(ctx, html, opts.OutputFile, s.logger); err != nil {
 		s.logger.Error("PDF generation failed", &logging.LogFields{Error: err.Error()})
 		return appErrs.NewPDFGenerationError("failed to generate PDF", err)
 	}
 
 	// ZUGFeRD compliance: generate and embed XML if requested
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if opts.EnableZUGFeRD {
 		s.logger.Info("ZUGFeRD embedding requested", &logging.LogFields{File: opts.OutputFile})
 		if opts.EmbeddedDataProvider != nil {
 			s.logger.Info("Generating embedded data using provider", &logging.LogFields{File: opts.OutputFile})
-			filePath, desc, err := opts.EmbeddedDataProvider.Generate(*data, opts)
+			filePath, desc, err := opts.EmbeddedDataProvider.Generate(ctx, *data, opts)
 			if err != nil {
 				s.logger.Error("Embedded data generation failed", &logging.LogFields{Error: err.Error(), File: opts.OutputFile})
 				return appErrs.NewPDFGenerationError("failed to generate embedded data", err)
 			}
 			s.logger.Info("Embedded data generated", &logging.LogFields{File: filePath, Status: desc})
+			_, span := tracing.Start(ctx, "pdf.embed")
 			err = compliance.EmbedZUGFeRDXML(opts.OutputFile, filePath, desc)
+			tracing.End(span, err)
 			if err != nil {
 				s.logger.Error("Failed to embed data in PDF", &logging.LogFields{Error: err.Error(), File: opts.OutputFile})
 				return appErrs.NewPDFGenerationError("failed to embed data in PDF", err)
@@ -182,7 +295,9 @@ func (s *InvoiceService) GenerateInvoice(data *models.InvoiceData, opts *Generat
 				s.logger.Error("Failed to read embedded XML for validation", &logging.LogFields{Error: err.Error(), File: filePath})
 				return appErrs.NewPDFGenerationError("failed to read embedded XML for validation", err)
 			}
+			_, span = tracing.Start(ctx, "xml.validate")
 			err = xml.ValidateXMLWithSchema(xmlBytes, xsdPath)
+			tracing.End(span, err)
 			if err != nil {
 				s.logger.Error("Embedded XML failed XSD validation", &logging.LogFields{Error: err.Error(), File: filePath})
 				return appErrs.NewPDFGenerationError("embedded XML failed XSD validation", err)
@@ -191,8 +306,10 @@ func (s *InvoiceService) GenerateInvoice(data *models.InvoiceData, opts *Generat
 
 			// 2. Validate PDF/A-3u compliance using pdfcpu CLI
 			s.logger.Info("Validating PDF/A-3u compliance using pdfcpu", &logging.LogFields{File: opts.OutputFile})
-			pdfcpuCmd := exec.Command("pdfcpu", "validate", opts.OutputFile)
+			_, span = tracing.Start(ctx, "pdf.validate")
+			pdfcpuCmd := exec.CommandContext(ctx, "pdfcpu", "validate", opts.OutputFile)
 			output, err := pdfcpuCmd.CombinedOutput()
+			tracing.End(span, err)
 			if err != nil {
 				s.logger.Error("PDF/A-3u compliance validation failed", &logging.LogFields{Error: string(output), File: opts.OutputFile})
 				return appErrs.NewPDFGenerationError("PDF/A-3u compliance validation failed", fmt.Errorf("%s", string(output)))
@@ -203,12 +320,20 @@ func (s *InvoiceService) GenerateInvoice(data *models.InvoiceData, opts *Generat
 		}
 	}
 
+	if err := s.recordInvoice(data, opts); err != nil {
+		return err
+	}
+	s.notify(webhook.EventGenerated, repository.NewRecord(data, opts.OutputFile))
+
 	s.logger.Info("Invoice generated successfully", &logging.LogFields{File: opts.OutputFile})
 	return nil
 }
 
 // ValidateInvoiceData validates invoice data without generating
 func (s *InvoiceService) ValidateInvoiceData(data *models.InvoiceData) error {
+	if err := s.applyCatalog(data); err != nil {
+		return err
+	}
 	return s.validator.ValidateInvoiceData(data)
 }
 
@@ -222,7 +347,12 @@ func (s *InvoiceService) GenerateInvoiceNumber() string {
 	case "date":
 		return fmt.Sprintf("%s%s", s.config.Invoice.NumberPrefix, now.Format("2006-01-02"))
 	case "sequential":
-		// TODO: Implement sequential numbering with persistent storage
+		// Only a preview; the number is issued when the invoice is generated
+		number, err := s.previewInvoiceNumber()
+		if err == nil {
+			return number
+		}
+		s.logger.Warn("Failed to read invoice number sequence", &logging.LogFields{Error: err.Error()})
 		return fmt.Sprintf("%s%s-001", s.config.Invoice.NumberPrefix, now.Format("2006-01"))
 	default:
 		return fmt.Sprintf("%s%s-001", s.config.Invoice.NumberPrefix, now.Format("2006-01"))
@@ -330,7 +460,9 @@ func (s *InvoiceService) CreateSampleInvoice() *models.InvoiceData {
 }
 
 // GenerateInvoicesMultiLang generates invoices in multiple languages using renderer's output_name logic for filenames.
-func (s *InvoiceService) GenerateInvoicesMultiLang(data *models.InvoiceData, opts *GenerateOptions, languages []string) map[string]error {
+// The PDFs of the languages are printed at the same time, up to the parallelism of the
+// browser pool.
+func (s *InvoiceService) GenerateInvoicesMultiLang(ctx context.Context, data *models.InvoiceData, opts *GenerateOptions, languages []string) map[string]error {
 	results := make(map[string]error)
 	if len(languages) == 0 {
 		languages = []string{"en"}
@@ -347,13 +479,20 @@ func (s *InvoiceService) GenerateInvoicesMultiLang(data *models.InvoiceData, opt
 
 	renderer := render.NewRenderer(i18nProvider)
 
+	// The languages are rendered one after another and printed at the same time
+	type printJob struct{ lang, html, file string }
+	var jobs []printJob
 	for _, lang := range languages {
+		if err := ctx.Err(); err != nil {
+			results[lang] = contextError(ctx, err)
+			continue
+		}
 		invoiceCopy := *data
 		invoiceCopy.Invoice.Language = lang
 
 		locMap, _ := s.localeLoader.Load(lang, opts.Locale)
 
-		outputName, err := renderer.RenderOutputName(invoiceCopy, opts.Template)
+		outputName, err := renderer.RenderOutputName(ctx, invoiceCopy, opts.Template)
 		if err != nil || outputName == "" {
 			outputName = fmt.Sprintf("Invoice-%s-%s.pdf", invoiceCopy.Invoice.Number, lang)
 		}
@@ -362,7 +501,7 @@ func (s *InvoiceService) GenerateInvoicesMultiLang(data *models.InvoiceData, opt
 			outputFile += ".pdf"
 		}
 
-		html, err := render.RenderHTMLWithLocale(invoiceCopy, opts.Template, opts.Locale, func(l string, _ map[string]string) func(string) string { return i18nProvider(l, locMap) }, s.localeLoader)
+		html, err := render.RenderHTMLWithLocale(ctx, invoiceCopy, opts.Template, opts.Locale, func(l string, _ map[string]string) func(string) string { return i18nProvider(l, locMap) }, s.localeLoader)
 		if err != nil {
 			s.logger.Error("HTML rendering failed", &logging.LogFields{Error: err.Error()})
 			results[lang] = err
@@ -384,36 +523,108 @@ func (s *InvoiceService) GenerateInvoicesMultiLang(data *models.InvoiceData, opt
 			}
 		}
 
-		if err := pdf.GeneratePDFChromedp(html, outputFile, s.logger); err != nil {
-			s.logger.Error("PDF generation failed", &logging.LogFields{Error: err.Error(), File: outputFile})
-			results[lang] = err
-			continue
-		}
+		jobs = append(jobs, printJob{lang: lang, html: html, file: outputFile})
+	}
 
-		s.logger.Info("Invoice generated successfully", &logging.LogFields{File: outputFile})
-		results[lang] = nil
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.printer(ctx, job.html, job.file, s.logger)
+			if err != nil {
+				s.logger.Error("PDF generation failed", &logging.LogFields{Error: err.Error(), File: job.file})
+				err = contextError(ctx, err)
+			} else {
+				s.logger.Info("Invoice generated successfully", &logging.LogFields{File: job.file})
+			}
+			mu.Lock()
+			results[job.lang] = err
+			mu.Unlock()
+		}()
 	}
+	wg.Wait()
 	return results
 }
 
+// renderInvoiceHTML calculates the totals of a prepared invoice and renders its HTML
+func (s *InvoiceService) renderInvoiceHTML(ctx context.Context, data *models.InvoiceData, opts *GenerateOptions) (html string, err error) {
+	ctx, span := tracing.Start(ctx, "invoice.render")
+	defer func() { tracing.End(span, err) }()
+
+	// Calculate totals
+	data.Invoice.CalculateTotals()
+
+	// Auto-set recurrence if all lines have the same period or only one line with period
+	periodSet := make(map[string]struct{})
+	for _, line := range data.Invoice.Lines {
+		if line.Period != "" {
+			periodSet[line.Period] = struct{}{}
+		}
+	}
+	if len(periodSet) == 1 && len(data.Invoice.Lines) > 0 {
+		for p := range periodSet {
+			data.Invoice.Recurrence = p
+		}
+	}
+
+	// Update service to use new RenderHTML signature and set EmbeddedData
+	// Render HTML and extract embedded data type from template
+
+	// Prepare locale loader and translator provider
+	// localeLoader := &locale.Loader{} // REMOVE
+	translator := func(lang string, loc map[string]string) func(string) string {
+		return func(key string) string {
+			if v, ok := loc[key]; ok {
+				return v
+			}
+			return key
+		}
+	}
+
+	lang := data.Invoice.Language
+	if lang == "" {
+		lang = "en"
+	}
+	locMap, _ := s.localeLoader.Load(lang, opts.Locale)
+
+	start := time.Now()
+	defer func() {
+		metrics.RenderDuration.WithLabelValues(metrics.Template(opts.Template)).Observe(time.Since(start).Seconds())
+	}()
+
+	html, err = render.RenderHTMLWithLocale(ctx, *data, opts.Template, opts.Locale, func(l string, _ map[string]string) func(string) string { return translator(l, locMap) }, s.localeLoader)
+	if err != nil {
+		s.logger.Error("HTML rendering failed", &logging.LogFields{Error: err.Error()})
+		return "", appErrs.NewPDFGenerationError("failed to render HTML", err)
+	}
+	return html, nil
+}
+
 // applyDefaults applies service configuration defaults to invoice data and options
 func (s *InvoiceService) applyDefaults(data *models.InvoiceData, opts *GenerateOptions) {
+	// Apply VAT accounting currency default
+	if data.Invoice.TaxCurrency == "" {
+		data.Invoice.TaxCurrency = s.config.Invoice.TaxCurrency
+	}
+
 	// Apply currency default - only if currency is not already set
 	if opts.Currency != "" {
 		// Override with command-line specified currency
 		data.Invoice.Currency.Code = opts.Currency
 		data.Invoice.Currency.Symbol = getCurrencySymbol(opts.Currency)
-		if data.Invoice.Currency.Rate.IsZero() {
-			data.Invoice.Currency.Rate = decimal.NewFromInt(1)
-		}
 	} else if data.Invoice.Currency.Code == "" {
 		// Set default currency if none is specified
 		data.Invoice.Currency = models.Currency{
 			Code:   s.config.Invoice.DefaultCurrency,
 			Symbol: getCurrencySymbol(s.config.Invoice.DefaultCurrency),
-			Rate:   decimal.NewFromInt(1),
 		}
 	}
+	// A missing rate only matters for foreign-currency invoices, which get it from applyExchangeRate
+	if data.Invoice.Currency.Rate.IsZero() && !data.Invoice.HasTaxCurrency() {
+		data.Invoice.Currency.Rate = decimal.NewFromInt(1)
+	}
 	// If currency is already set (like in sample invoice), leave it as is
 
 	// Apply template default
@@ -421,13 +632,8 @@ func (s *InvoiceService) applyDefaults(data *models.InvoiceData, opts *GenerateO
 		opts.Template = ""
 	}
 
-	// Generate invoice number if not provided
-	if data.Invoice.Number == "" {
-		data.Invoice.Number = s.GenerateInvoiceNumber()
-	}
-
 	// Set due date if not provided
-	if data.Invoice.DueDate.IsZero() {
+	if data.Invoice.DueDate.IsZero() && data.Invoice.Type.IsInvoice() {
 		dueDate := time.Now().AddDate(0, 0, s.config.Invoice.DefaultDueDays)
 		data.Invoice.DueDate = dueDate
 	}
@@ -437,43 +643,121 @@ func (s *InvoiceService) applyDefaults(data *models.InvoiceData, opts *GenerateO
 		data.Invoice.Date = time.Now()
 	}
 
-	// Apply default tax rate to lines that don't have one
-	if opts.TaxRate != nil {
-		defaultTaxRate := *opts.TaxRate
-		for i := range data.Invoice.Lines {
-			if data.Invoice.Lines[i].TaxRate.IsZero() {
-				data.Invoice.Lines[i].TaxRate = decimal.NewFromFloat(defaultTaxRate)
+	// Quotes are binding for the configured number of days
+	if data.Invoice.Type == models.InvoiceTypeQuote && data.Invoice.ValidUntil.IsZero() && s.config.Invoice.QuoteValidDays > 0 {
+		data.Invoice.ValidUntil = data.Invoice.Date.AddDate(0, 0, s.config.Invoice.QuoteValidDays)
+	}
+
+	// Generate invoice number if not provided; sequential numbers are issued by assignInvoiceNumber
+	if data.Invoice.Number == "" && !s.sequentialNumbering() {
+		data.Invoice.Number = s.GenerateInvoiceNumber()
+	}
+
+	// In service layer, select provider based on data.EmbeddedData (from YAML).
+	// Quotes and order confirmations keep it for the invoice but are not e-invoices.
+	if !data.Invoice.Type.IsInvoice() {
+		opts.EnableZUGFeRD = false
+		opts.EmbeddedDataProvider = nil
+	} else if data.EmbeddedData == models.EmbeddedDataZUGFeRD {
+		opts.EmbeddedDataProvider = di.ProvidePDFEmbeddedDataProvider()
+	} else {
+		opts.EmbeddedDataProvider = nil
+	}
+}
+
+// applyExchangeRate fills in the rate into the VAT accounting currency for the invoice date
+// when the invoice is issued in a foreign currency and does not state a rate itself.
+func (s *InvoiceService) applyExchangeRate(data *models.InvoiceData, opts *GenerateOptions) error {
+	inv := &data.Invoice
+	if !inv.HasTaxCurrency() || !inv.Currency.Rate.IsZero() {
+		return nil
+	}
+
+	rates := opts.ExchangeRates
+	if rates == nil && s.config.Invoice.ExchangeRatesFile != "" {
+		table, err := exchange.LoadFile(s.config.Invoice.ExchangeRatesFile)
+		if err != nil {
+			return appErrs.NewExchangeRateError("failed to load exchange rates", err)
+		}
+		rates = table
+	}
+	if rates == nil {
+		return appErrs.NewExchangeRateError(fmt.Sprintf("no %s/%s rate on invoice and no exchange rate source configured", inv.Currency.Code, inv.TaxCurrency), nil)
+	}
+
+	rate, err := rates.Rate(inv.Currency.Code, inv.TaxCurrency, inv.Date)
+	if err != nil {
+		return appErrs.NewExchangeRateError(fmt.Sprintf("no %s/%s rate for %s", inv.Currency.Code, inv.TaxCurrency, inv.Date.Format("2006-01-02")), err)
+	}
+	inv.Currency.Rate = rate
+	s.logger.Info("Applied exchange rate", &logging.LogFields{Currency: inv.Currency.Code + "/" + inv.TaxCurrency, Status: rate.String()})
+	return nil
+}
+
+// applyTaxRules decides rates and categories for lines that don't state them. The tax
+// engine handles sellers with a registered rule set, and an explicit --tax-rate replaces
+// the rate of the lines of the standard tax class it rates as standard; reduced lines
+// keep their reduced rate. Other sellers fall back to a flat default rate.
+func (s *InvoiceService) applyTaxRules(data *models.InvoiceData, opts *GenerateOptions) error {
+	if s.taxEngine != nil {
+		// Standard-class lines without a rate, whose standard rate --tax-rate replaces
+		var unrated []int
+		for i, line := range data.Invoice.Lines {
+			standardClass := line.TaxClass == "" || line.TaxClass == models.TaxClassStandard
+			if line.TaxRate.IsZero() && line.TaxCategory == "" && standardClass {
+				unrated = append(unrated, i)
 			}
 		}
-	} else {
-		for i := range data.Invoice.Lines {
-			if data.Invoice.Lines[i].TaxRate.IsZero() {
-				data.Invoice.Lines[i].TaxRate = decimal.NewFromFloat(s.config.Invoice.DefaultTaxRate)
+		notes, err := s.taxEngine.Apply(data, s.noteTranslator(data, opts))
+		if err == nil {
+			if len(notes) > 0 && data.Invoice.VATExemptionReason == "" {
+				data.Invoice.VATExemptionReason = strings.Join(notes, "\n")
 			}
+			if opts.TaxRate != nil {
+				for _, i := range unrated {
+					line := &data.Invoice.Lines[i]
+					if line.TaxCategory != models.TaxCategoryStandard {
+						continue
+					}
+					line.TaxRate = decimal.NewFromFloat(*opts.TaxRate)
+					if line.TaxRate.IsZero() {
+						line.TaxCategory = models.TaxCategoryZero
+					}
+				}
+			}
+			return nil
+		}
+		if !errors.Is(err, tax.ErrNoRuleSet) {
+			return appErrs.NewValidationError("failed to apply tax rules", err)
 		}
+		s.logger.Debug("No tax rule set for seller, using default tax rate", &logging.LogFields{Provider: data.Provider.Name, Status: data.Provider.Address.Country})
 	}
 
-	// Apply custom tax rule if defined for invoice language/locale
-	if rule := i18n.GetTaxRule(data.Invoice.Language); rule != nil {
-		// Convert invoice data to map for rule
-		invoiceMap := map[string]interface{}{
-			"lines": data.Invoice.Lines,
-			"subtotal": data.Invoice.Subtotal,
-			"total_tax": data.Invoice.TotalTax,
-			"currency": data.Invoice.Currency.Code,
-			"country": data.Provider.Address.Country,
-			// Add more fields as needed
+	defaultTaxRate := s.config.Invoice.DefaultTaxRate
+	if opts.TaxRate != nil {
+		defaultTaxRate = *opts.TaxRate
+	}
+	for i := range data.Invoice.Lines {
+		if data.Invoice.Lines[i].TaxRate.IsZero() && data.Invoice.Lines[i].TaxCategory == "" {
+			data.Invoice.Lines[i].TaxRate = decimal.NewFromFloat(defaultTaxRate)
 		}
-		customTax := rule(invoiceMap)
-		data.Invoice.TotalTax = decimal.NewFromFloat(customTax)
-		// TODO: Log and validate custom tax application
 	}
+	return nil
+}
 
-	// In service layer, select provider based on data.EmbeddedData (from YAML)
-	if data.EmbeddedData == models.EmbeddedDataZUGFeRD {
-		opts.EmbeddedDataProvider = di.ProvidePDFEmbeddedDataProvider()
-	} else {
-		opts.EmbeddedDataProvider = nil
+// noteTranslator returns a lookup for tax notes in the invoice language. Keys without a
+// translation are returned unchanged.
+func (s *InvoiceService) noteTranslator(data *models.InvoiceData, opts *GenerateOptions) func(string) string {
+	lang := data.Invoice.Language
+	if lang == "" {
+		lang = "en"
+	}
+	locMap, _ := s.localeLoader.Load(lang, opts.Locale)
+	return func(key string) string {
+		if v, ok := locMap[key]; ok {
+			return v
+		}
+		return key
 	}
 }
 
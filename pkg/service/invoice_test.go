@@ -1,21 +1,53 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"invoiceformats/internal/config"
+	"invoiceformats/pkg/archive"
+	"invoiceformats/pkg/bank"
 	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/exchange"
+	invoiceloader "invoiceformats/pkg/loader"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/metrics"
 	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/numbering"
+	"invoiceformats/pkg/pdf"
+	"invoiceformats/pkg/recurring"
 	"invoiceformats/pkg/render"
 	"invoiceformats/pkg/render/locale"
+	"invoiceformats/pkg/repository"
+	"invoiceformats/pkg/tracing"
+	"invoiceformats/pkg/webhook"
 	"invoiceformats/testutils"
 )
 
+// newTestService returns a service for cfg that reads the locales of the render package.
+func newTestService(t *testing.T, cfg *config.AppConfig) *InvoiceService {
+	t.Helper()
+	localeData, err := os.ReadFile("../render/locales.json")
+	require.NoError(t, err)
+	return NewInvoiceService(cfg, &testutils.TestLogger{}, &locale.Loader{EmbeddedData: localeData})
+}
+
 func TestGenerateInvoice_ValidationError(t *testing.T) {
 	cfg := &config.AppConfig{
 		Invoice: config.InvoiceConfig{
@@ -27,16 +59,13 @@ func TestGenerateInvoice_ValidationError(t *testing.T) {
 		},
 		Template: config.TemplateConfig{Theme: "modern"},
 	}
-	logger := &testutils.TestLogger{}
-	localeData, _ := os.ReadFile("../render/locales.json")
-	loader := &locale.Loader{EmbeddedData: localeData}
-	service := NewInvoiceService(cfg, logger, loader)
+	service := newTestService(t, cfg)
 
 	// Missing required fields (invalid invoice)
 	data := &models.InvoiceData{}
 	opts := &GenerateOptions{OutputFile: "test.pdf"}
 
-	err := service.GenerateInvoice(data, opts)
+	err := service.GenerateInvoice(context.Background(), data, opts)
 	assert.Error(t, err)
 	appErr, ok := err.(*appErrs.AppError)
 	assert.True(t, ok)
@@ -54,17 +83,14 @@ func TestGenerateInvoice_PDFGenerationError(t *testing.T) {
 		},
 		Template: config.TemplateConfig{Theme: "modern"},
 	}
-	logger := &testutils.TestLogger{}
-	localeData, _ := os.ReadFile("../render/locales.json")
-	loader := &locale.Loader{EmbeddedData: localeData}
-	service := NewInvoiceService(cfg, logger, loader)
+	service := newTestService(t, cfg)
 
 	// Valid invoice, but inject a broken render function
 	data := service.CreateSampleInvoice()
 	opts := &GenerateOptions{OutputFile: "/invalid/path/test.pdf"}
 
 	// Patch pdf.GeneratePDFChromedp to simulate error (not possible here, so just check error on bad path)
-	err := service.GenerateInvoice(data, opts)
+	err := service.GenerateInvoice(context.Background(), data, opts)
 	assert.Error(t, err)
 	appErr, ok := err.(*appErrs.AppError)
 	assert.True(t, ok)
@@ -82,10 +108,7 @@ func TestCreateSampleInvoice_Valid(t *testing.T) {
 		},
 		Template: config.TemplateConfig{Theme: "modern"},
 	}
-	logger := &testutils.TestLogger{}
-	localeData, _ := os.ReadFile("../render/locales.json")
-	loader := &locale.Loader{EmbeddedData: localeData}
-	service := NewInvoiceService(cfg, logger, loader)
+	service := newTestService(t, cfg)
 
 	invoice := service.CreateSampleInvoice()
 	assert.NotNil(t, invoice)
@@ -106,10 +129,7 @@ func TestGenerateInvoiceNumber(t *testing.T) {
 		},
 		Template: config.TemplateConfig{Theme: "modern"},
 	}
-	logger := &testutils.TestLogger{}
-	localeData, _ := os.ReadFile("../render/locales.json")
-	loader := &locale.Loader{EmbeddedData: localeData}
-	service := NewInvoiceService(cfg, logger, loader)
+	service := newTestService(t, cfg)
 
 	num := service.GenerateInvoiceNumber()
 	assert.Contains(t, num, "INV-")
@@ -139,7 +159,7 @@ func TestGenerateInvoice_ZUGFeRDEmbedding(t *testing.T) {
 			lang = "en"
 		}
 		locMap, _ := loader.Load(lang, opts.Locale)
-		_, err := render.RenderHTMLWithLocale(*data, opts.Template, opts.Locale, func(l string, _ map[string]string) func(string) string { return translator(l, locMap) }, loader)
+		_, err := render.RenderHTMLWithLocale(context.Background(), *data, opts.Template, opts.Locale, func(l string, _ map[string]string) func(string) string { return translator(l, locMap) }, loader)
 		if err != nil {
 			return err
 		}
@@ -203,10 +223,7 @@ func TestGenerateInvoicesMultiLang(t *testing.T) {
 		},
 		Template: config.TemplateConfig{Theme: "modern"},
 	}
-	logger := &testutils.TestLogger{}
-	localeData, _ := os.ReadFile("../render/locales.json")
-	loader := &locale.Loader{EmbeddedData: localeData}
-	service := NewInvoiceService(cfg, logger, loader)
+	service := newTestService(t, cfg)
 
 	invoice := service.CreateSampleInvoice()
 	invoice.Invoice.Lines = invoice.Invoice.Lines[:1] // Keep it simple
@@ -231,7 +248,7 @@ func TestGenerateInvoicesMultiLang(t *testing.T) {
 	_ = localeData // just to avoid unused warning if not used
 
 	languages := []string{"en", "de", "fr"}
-	results := service.GenerateInvoicesMultiLang(invoice, opts, languages)
+	results := service.GenerateInvoicesMultiLang(context.Background(), invoice, opts, languages)
 
 	for _, lang := range languages {
 		err := results[lang]
@@ -239,11 +256,944 @@ func TestGenerateInvoicesMultiLang(t *testing.T) {
 	}
 
 	// Test fallback to default language if empty
-	results = service.GenerateInvoicesMultiLang(invoice, opts, []string{})
+	results = service.GenerateInvoicesMultiLang(context.Background(), invoice, opts, []string{})
 	assert.NoError(t, results["en"])
 
 	// Test for a truly missing locale
 	missingLang := "xx"
-	results = service.GenerateInvoicesMultiLang(invoice, opts, []string{missingLang})
+	results = service.GenerateInvoicesMultiLang(context.Background(), invoice, opts, []string{missingLang})
 	assert.Error(t, results[missingLang], "should error for missing locale for language %s", missingLang)
+
+	// The languages are printed at the same time
+	var printing, most atomic.Int32
+	printed := make(chan string, len(languages))
+	service.SetPrinter(func(ctx context.Context, html, outputFile string, logger logging.Logger) error {
+		n := printing.Add(1)
+		defer printing.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		printed <- outputFile
+		return nil
+	})
+	results = service.GenerateInvoicesMultiLang(context.Background(), invoice, &GenerateOptions{}, languages)
+	for _, lang := range languages {
+		assert.NoError(t, results[lang], lang)
+	}
+	assert.Len(t, printed, len(languages))
+	assert.Greater(t, most.Load(), int32(1))
+}
+
+func TestGenerateInvoice_ForeignCurrencyUsesRateSource(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    19.0,
+			TaxCurrency:       "EUR",
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	invoiceDate := time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC)
+	rates := exchange.NewTable("EUR", map[time.Time]map[string]decimal.Decimal{
+		invoiceDate: {"USD": decimal.NewFromFloat(1.25)},
+	})
+
+	data := service.CreateSampleInvoice()
+	data.EmbeddedData = models.EmbeddedDataNone
+	data.Invoice.Date = invoiceDate
+	data.Invoice.DueDate = invoiceDate.AddDate(0, 0, 30)
+	data.Invoice.Currency = models.Currency{Code: "USD", Symbol: "$"}
+	data.Invoice.Lines = data.Invoice.Lines[:1] // 40 x 125.00 at 19%
+	data.Invoice.CalculateTotals()
+
+	err := service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true, ExchangeRates: rates})
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromFloat(0.8).Equal(data.Invoice.Currency.Rate), "got %s", data.Invoice.Currency.Rate)
+
+	data.Invoice.CalculateTotals()
+	assert.True(t, decimal.NewFromInt(950).Equal(data.Invoice.TotalTax))
+	assert.True(t, decimal.NewFromInt(760).Equal(data.Invoice.TotalTaxAccounting), "got %s", data.Invoice.TotalTaxAccounting)
+}
+
+func TestGenerateInvoice_ForeignCurrencyWithoutRateSource(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    19.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	data := service.CreateSampleInvoice()
+	data.Invoice.Currency = models.Currency{Code: "CHF", Symbol: "CHF"}
+	data.Invoice.TaxCurrency = "EUR"
+
+	err := service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true})
+	assert.Error(t, err)
+	appErr, ok := err.(*appErrs.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, appErrs.ErrExchangeRate, appErr.Code)
+}
+
+func TestGenerateInvoice_TaxEngineDecidesRates(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    10.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	data := &models.InvoiceData{
+		Provider: models.CompanyInfo{
+			Name:    "Glowing Pixels UG (haftungsbeschränkt)",
+			Address: models.Address{Street: "Coppistr. 12", City: "Berlin", Country: "Germany", PostalCode: "10365"},
+			Email:   "info@glowing-pixels.com",
+		},
+		Client: models.ClientInfo{
+			Name:    "Pixel Dynamics GmbH",
+			Address: models.Address{Street: "Hauptstr. 45", City: "München", Country: "Germany", PostalCode: "80331"},
+			Email:   "kontakt@pixeldynamics.de",
+		},
+		Invoice: models.InvoiceDetails{
+			Number:   "RE-2025-008",
+			Date:     time.Now(),
+			DueDate:  time.Now().AddDate(0, 0, 30),
+			Currency: models.Currency{Code: "EUR", Symbol: "€"},
+			Language: "de",
+			Lines: []models.InvoiceLine{
+				{Description: "Web design", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromFloat(100.0)},
+				{Description: "Handbook", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromFloat(20.0), TaxClass: models.TaxClassReduced},
+			},
+		},
+	}
+
+	err := service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true})
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(19).Equal(data.Invoice.Lines[0].TaxRate), "got %s", data.Invoice.Lines[0].TaxRate)
+	assert.True(t, decimal.NewFromInt(7).Equal(data.Invoice.Lines[1].TaxRate), "got %s", data.Invoice.Lines[1].TaxRate)
+
+	// --tax-rate replaces the standard rate only; the handbook keeps its reduced rate
+	for i := range data.Invoice.Lines {
+		data.Invoice.Lines[i].TaxRate, data.Invoice.Lines[i].TaxCategory = decimal.Zero, ""
+	}
+	data.Invoice.GrandTotal = decimal.Zero
+	rate := 16.0
+	err = service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true, TaxRate: &rate})
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(16).Equal(data.Invoice.Lines[0].TaxRate), "got %s", data.Invoice.Lines[0].TaxRate)
+	assert.True(t, decimal.NewFromInt(7).Equal(data.Invoice.Lines[1].TaxRate), "got %s", data.Invoice.Lines[1].TaxRate)
+
+	// Kleinunternehmer: no VAT and the note in the invoice language
+	data.Invoice.Lines[0].TaxRate, data.Invoice.Lines[0].TaxCategory = decimal.Zero, ""
+	data.Invoice.Lines[1].TaxRate, data.Invoice.Lines[1].TaxCategory = decimal.Zero, ""
+	data.Invoice.VATExemptionType = models.VATExemptionSmallBusiness
+	data.Invoice.GrandTotal = decimal.Zero
+
+	err = service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true})
+	assert.NoError(t, err)
+	assert.True(t, data.Invoice.TotalTax.IsZero())
+	assert.Contains(t, data.Invoice.VATExemptionReason, "§ 19 UStG")
+}
+
+func TestGenerateInvoice_SampleReverseCharge(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    19.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+	logger := &testutils.TestLogger{}
+
+	data, err := invoiceloader.LoadInvoiceData("../../invoices/glpx-reverse-charge.yaml", logger)
+	assert.NoError(t, err)
+
+	err = service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true})
+	assert.NoError(t, err)
+	assert.True(t, data.Invoice.TotalTax.IsZero())
+	for _, line := range data.Invoice.Lines {
+		assert.Equal(t, models.TaxCategoryReverseCharge, line.TaxCategory)
+		assert.Equal(t, "VATEX-EU-AE", line.TaxExemptionCode)
+	}
+	assert.Equal(t, "Reverse-Charge-Verfahren angewendet.", data.Invoice.VATExemptionReason)
+
+	// A domestic invoice stating an exemption reason keeps its lines free of VAT
+	data, err = invoiceloader.LoadInvoiceData("../../invoices/glpx-zugferd.yaml", logger)
+	require.NoError(t, err)
+	require.NoError(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true}))
+	assert.True(t, data.Invoice.TotalTax.IsZero())
+	for _, line := range data.Invoice.Lines {
+		assert.Equal(t, models.TaxCategoryExempt, line.TaxCategory)
+		assert.Equal(t, "Reverse charge procedure.", line.TaxExemptionReason)
+	}
+
+	// --tax-rate replaces the standard rate only
+	data, err = invoiceloader.LoadInvoiceData("../../invoices/glpx-reverse-charge.yaml", logger)
+	require.NoError(t, err)
+	rate := 16.0
+	require.NoError(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true, TaxRate: &rate}))
+	assert.True(t, data.Invoice.TotalTax.IsZero())
+	assert.Equal(t, models.TaxCategoryReverseCharge, data.Invoice.Lines[0].TaxCategory)
+
+	data, err = invoiceloader.LoadInvoiceData("../../invoices/glpx-reverse-charge.yaml", logger)
+	require.NoError(t, err)
+	data.Client = service.CreateSampleInvoice().Client
+	data.Client.Address.Country = "Germany"
+	data.Client.VATID = ""
+	require.NoError(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true, TaxRate: &rate}))
+	assert.Equal(t, models.TaxCategoryStandard, data.Invoice.Lines[0].TaxCategory)
+	assert.True(t, decimal.NewFromInt(16).Equal(data.Invoice.Lines[0].TaxRate), "got %s", data.Invoice.Lines[0].TaxRate)
+}
+
+func TestCreateCreditNote(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    10.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	original := service.CreateSampleInvoice()
+	original.Invoice.CalculateTotals()
+
+	full, err := service.CreateCreditNote(original, CreditNoteOptions{Reason: "Cancelled"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.InvoiceTypeCreditNote, full.Invoice.Type)
+	assert.Equal(t, "381", full.Invoice.Type.TypeCode())
+	assert.Equal(t, original.Invoice.Number+"-CN", full.Invoice.Number)
+	assert.Equal(t, []models.InvoiceReference{{Number: original.Invoice.Number, Date: original.Invoice.Date}}, full.Invoice.PrecedingInvoices)
+	assert.True(t, original.Invoice.GrandTotal.Equal(full.Invoice.GrandTotal), "got %s", full.Invoice.GrandTotal)
+	assert.NoError(t, service.ValidateInvoiceData(full))
+
+	partial, err := service.CreateCreditNote(original, CreditNoteOptions{Number: "CN-1", Lines: []int{1}, Percent: 10})
+	assert.NoError(t, err)
+	assert.Len(t, partial.Invoice.Lines, 1)
+	want := original.Invoice.Lines[0].UnitPrice.Div(decimal.NewFromInt(10)).Round(2)
+	assert.True(t, want.Equal(partial.Invoice.Lines[0].UnitPrice), "got %s", partial.Invoice.Lines[0].UnitPrice)
+
+	_, err = service.CreateCreditNote(original, CreditNoteOptions{Lines: []int{99}})
+	assert.Error(t, err)
+	_, err = service.CreateCreditNote(full, CreditNoteOptions{})
+	assert.Error(t, err)
+}
+
+func TestGenerateInvoice_SequentialNumbering(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "sequential",
+			NumberPattern:     "RE-{YYYY}-{SEQ:4}",
+			NumberingFile:     t.TempDir() + "/numbering.json",
+			DefaultTaxRate:    19.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	newInvoice := func() *models.InvoiceData {
+		data := service.CreateSampleInvoice()
+		data.Invoice.Number = ""
+		data.Invoice.Date = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		return data
+	}
+
+	// Validate-only runs preview the number without consuming it
+	for i := 0; i < 2; i++ {
+		data := newInvoice()
+		assert.NoError(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true}))
+		assert.Equal(t, "RE-2025-0001", data.Invoice.Number)
+	}
+
+	// A failed generation hands its number back
+	data := newInvoice()
+	err := service.GenerateInvoice(context.Background(), data, &GenerateOptions{Template: "does-not-exist.html.tmpl", OutputFile: t.TempDir() + "/out.pdf"})
+	assert.Error(t, err)
+	assert.Equal(t, "RE-2025-0001", data.Invoice.Number)
+
+	data = newInvoice()
+	assert.NoError(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true}))
+	assert.Equal(t, "RE-2025-0001", data.Invoice.Number)
+
+	// Unknown series are rejected
+	err = service.GenerateInvoice(context.Background(), newInvoice(), &GenerateOptions{Series: "export"})
+	appErr, ok := err.(*appErrs.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, appErrs.ErrNumbering, appErr.Code)
+}
+
+func TestGenerateInvoice_NumberTakenDuringGeneration(t *testing.T) {
+	numberingFile := t.TempDir() + "/numbering.json"
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "sequential",
+			NumberPattern:     "RE-{YYYY}-{SEQ:4}",
+			NumberingFile:     numberingFile,
+			DefaultTaxRate:    19.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	newInvoice := func() *models.InvoiceData {
+		data := service.CreateSampleInvoice()
+		data.Invoice.Number = ""
+		data.Invoice.Date = date
+		return data
+	}
+	// Another process issues the following number while the PDF is printed, which then fails
+	other, err := numbering.NewNumberer(numbering.NewFileStore(numberingFile), map[string]numbering.Pattern{numbering.DefaultSeries: "RE-{YYYY}-{SEQ:4}"})
+	require.NoError(t, err)
+	service.SetPrinter(func(ctx context.Context, html, outputFile string, logger logging.Logger) error {
+		_, err := other.Next(numbering.DefaultSeries, date)
+		require.NoError(t, err)
+		return assert.AnError
+	})
+
+	// Without a repository the number cannot be accounted for
+	err = service.GenerateInvoice(context.Background(), newInvoice(), &GenerateOptions{OutputFile: t.TempDir() + "/out.pdf"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Contains(t, err.Error(), "RE-2025-0001 could not be released")
+
+	// With one, it is recorded as a cancelled invoice
+	cfg.Invoice.RepositoryDir = t.TempDir()
+	service = newTestService(t, cfg)
+	service.SetPrinter(func(ctx context.Context, html, outputFile string, logger logging.Logger) error {
+		_, err := other.Next(numbering.DefaultSeries, date)
+		require.NoError(t, err)
+		return assert.AnError
+	})
+	err = service.GenerateInvoice(context.Background(), newInvoice(), &GenerateOptions{OutputFile: t.TempDir() + "/out.pdf"})
+	appErr, ok := err.(*appErrs.AppError)
+	require.True(t, ok)
+	assert.Equal(t, appErrs.ErrPDFGeneration, appErr.Code)
+
+	record, err := service.getRepository().Get("RE-2025-0003")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, record.Status)
+
+	service.SetPrinter(func(ctx context.Context, html, outputFile string, logger logging.Logger) error { return nil })
+	data := newInvoice()
+	require.NoError(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{OutputFile: t.TempDir() + "/out.pdf"}))
+	assert.Equal(t, "RE-2025-0005", data.Invoice.Number)
+}
+
+func TestInvoiceRepository_StatusLifecycle(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    19.0,
+			RepositoryDir:     t.TempDir(),
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	data := service.CreateSampleInvoice()
+	data.Invoice.Number = "RE-2025-0100"
+	data.Invoice.CalculateTotals()
+	assert.NoError(t, service.recordInvoice(data, &GenerateOptions{OutputFile: "invoices/pdf/RE-2025-0100.pdf"}))
+
+	_, err := service.MarkPaid("RE-2025-0100", time.Time{}, "")
+	appErr, ok := err.(*appErrs.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, appErrs.ErrStatusTransition, appErr.Code)
+
+	rec, err := service.MarkSent("RE-2025-0100", time.Time{}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusSent, rec.Status)
+
+	// A sent invoice can no longer be regenerated
+	again := service.CreateSampleInvoice()
+	again.Invoice.Number = "RE-2025-0100"
+	err = service.GenerateInvoice(context.Background(), again, &GenerateOptions{OutputFile: t.TempDir() + "/out.pdf"})
+	appErr, ok = err.(*appErrs.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, appErrs.ErrValidationFailed, appErr.Code)
+
+	records, err := service.ListInvoices(repository.Filter{Status: models.StatusSent})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	_, err = service.GetInvoice("RE-2025-0999")
+	appErr, ok = err.(*appErrs.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, appErrs.ErrInvoiceNotFound, appErr.Code)
+}
+
+func TestMarkSent_ArchivesInvoice(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "sequential",
+			NumberPrefix:      "RE-",
+			NumberingFile:     dir + "/numbering.json",
+			DefaultTaxRate:    19.0,
+			RepositoryDir:     dir + "/invoices",
+			ArchiveDir:        dir + "/archive",
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	for _, number := range []string{"RE-2025-0001", "RE-2025-0002", "RE-2025-0003", "RE-2025-0004"} {
+		pdfFile := dir + "/" + number + ".pdf"
+		pdfData, err := (&pdf.ZugferdEmbedder{}).EmbedXML([]byte("%PDF-1.7 "+number), []byte("<rsm:CrossIndustryInvoice>"+number+" as issued</rsm:CrossIndustryInvoice>"), "invoice")
+		require.NoError(t, err)
+		if number == "RE-2025-0004" {
+			pdfData = []byte("%PDF-1.7 " + number)
+		}
+		assert.NoError(t, os.WriteFile(pdfFile, pdfData, 0644))
+		data := service.CreateSampleInvoice()
+		data.Invoice.Number = number
+		data.EmbeddedData = models.EmbeddedDataZUGFeRD
+		data.Invoice.CalculateTotals()
+		assert.NoError(t, service.recordInvoice(data, &GenerateOptions{OutputFile: pdfFile}))
+	}
+
+	// Only sent invoices are archived; the unsent draft does not count as a gap
+	_, err := service.MarkSent("RE-2025-0001", time.Time{}, "")
+	assert.NoError(t, err)
+	_, err = service.MarkSent("RE-2025-0003", time.Time{}, "")
+	assert.NoError(t, err)
+
+	// The archived XML is the one embedded in the PDF
+	xmlData, err := service.getArchive().Open("RE-2025-0001", "invoice.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, "<rsm:CrossIndustryInvoice>RE-2025-0001 as issued</rsm:CrossIndustryInvoice>", string(xmlData))
+
+	// A ZUGFeRD invoice whose PDF lost its XML is not sent
+	_, err = service.MarkSent("RE-2025-0004", time.Time{}, "")
+	assert.ErrorContains(t, err, "carries no embedded XML")
+
+	report, err := service.VerifyArchive("")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Entries)
+	assert.True(t, report.OK(), "%v", report.Problems)
+
+	// The archive alone sees RE-2025-0002 as a gap, which the draft explains
+	raw, err := service.getArchive().Verify("")
+	assert.NoError(t, err)
+	assert.Equal(t, []archive.Problem{{Kind: archive.ProblemNumberGap, Number: "RE-2025-0002", Detail: "number missing from the archive"}}, raw.Problems)
+
+	// An issued invoice whose archive entry is gone is reported
+	assert.NoError(t, os.Remove(dir+"/archive/chain.jsonl"))
+	assert.NoError(t, os.RemoveAll(dir+"/archive/documents"))
+	report, err = service.VerifyArchive("")
+	assert.NoError(t, err)
+	assert.Len(t, report.Problems, 2)
+	assert.Equal(t, archive.ProblemNotArchived, report.Problems[0].Kind)
+}
+
+func TestReconcileStatement(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    19.0,
+			RepositoryDir:     t.TempDir(),
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	data := service.CreateSampleInvoice()
+	data.Invoice.Number = "RE-2025-0200"
+	data.Invoice.CalculateTotals()
+	assert.NoError(t, service.recordInvoice(data, &GenerateOptions{OutputFile: "invoices/pdf/RE-2025-0200.pdf"}))
+	_, err := service.MarkSent("RE-2025-0200", time.Time{}, "")
+	assert.NoError(t, err)
+
+	half := data.Invoice.AmountDue.Div(decimal.NewFromInt(2)).Round(2)
+	txs := []bank.Transaction{
+		{ID: "TX-1", Date: time.Now(), Amount: half, Currency: "EUR", Credit: true, RemittanceInfo: "RE 2025 0200 part 1"},
+		{ID: "TX-2", Date: time.Now(), Amount: data.Invoice.AmountDue.Sub(half), Currency: "EUR", Credit: true, RemittanceInfo: "RE-2025-0200 rest"},
+	}
+
+	// A dry run records nothing
+	result, err := service.ReconcileStatement(txs, true)
+	assert.NoError(t, err)
+	assert.Len(t, result.Matched, 2)
+	rec, err := service.GetInvoice("RE-2025-0200")
+	assert.NoError(t, err)
+	assert.Empty(t, rec.Payments)
+
+	_, err = service.ReconcileStatement(txs[:1], false)
+	assert.NoError(t, err)
+	rec, _ = service.GetInvoice("RE-2025-0200")
+	assert.Equal(t, models.StatusSent, rec.Status)
+
+	// Importing the same statement again only records the new transaction
+	result, err = service.ReconcileStatement(txs, false)
+	assert.NoError(t, err)
+	assert.Len(t, result.Matched, 1)
+	assert.Len(t, result.Skipped, 1)
+	rec, _ = service.GetInvoice("RE-2025-0200")
+	assert.Equal(t, models.StatusPaid, rec.Status)
+	assert.Len(t, rec.Payments, 2)
+}
+
+func TestRunDunning(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    19.0,
+			RepositoryDir:     t.TempDir(),
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+		Dunning:  config.DunningConfig{BaseRate: 1.27},
+	}
+	service := newTestService(t, cfg)
+
+	data := service.CreateSampleInvoice()
+	data.Invoice.Number = "RE-2025-0300"
+	data.Invoice.CalculateTotals()
+	assert.NoError(t, service.recordInvoice(data, &GenerateOptions{OutputFile: "invoices/pdf/RE-2025-0300.pdf"}))
+	_, err := service.MarkSent("RE-2025-0300", time.Time{}, "")
+	assert.NoError(t, err)
+
+	notices, err := service.RunDunning(context.Background(), &DunningOptions{Date: data.Invoice.DueDate.AddDate(0, 0, 30), DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, notices, 1)
+	assert.Equal(t, "reminder", notices[0].Step.Name)
+	assert.Equal(t, 30, notices[0].DaysOverdue)
+
+	// A dry run neither records the notice nor changes the status
+	rec, err := service.GetInvoice("RE-2025-0300")
+	assert.NoError(t, err)
+	assert.Empty(t, rec.Notices)
+
+	cfg.Dunning.Levels = []config.DunningLevelConfig{{Name: "reminder", DaysAfterDue: -1}}
+	_, err = service.RunDunning(context.Background(), &DunningOptions{DryRun: true})
+	appErr, ok := err.(*appErrs.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, appErrs.ErrConfigInvalid, appErr.Code)
+}
+
+func TestRunRecurring(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:    "EUR",
+			DefaultDueDays:     14,
+			NumberingStrategy:  "sequential",
+			NumberPattern:      "RE-{YYYY}-{SEQ:4}",
+			NumberingFile:      dir + "/numbering.json",
+			DefaultTaxRate:     19.0,
+			RecurringDir:       dir + "/recurring",
+			RecurringStateFile: dir + "/recurring.json",
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	definition := `schedule: "0 0 1 * *"
+start: 2025-01-01
+billing: arrears
+output: "` + dir + `/pdf/{{number}}.pdf"
+invoice:
+  provider:
+    name: "TechCorp Solutions Ltd"
+    email: "billing@techcorp.com"
+    address: {street: "Innovation Drive 1", city: "Berlin", postal_code: "10115", country: "DE"}
+  client:
+    name: "Startup Innovations Inc"
+    email: "accounts@startupinc.com"
+    address: {street: "Startup Boulevard 4", city: "Munich", postal_code: "80331", country: "DE"}
+  invoice:
+    lines:
+      - description: "Support {{month_name}} {{year}}"
+        quantity: 1
+        unit_price: 500
+        tax_rate: 19
+`
+	assert.NoError(t, os.MkdirAll(cfg.Invoice.RecurringDir, 0755))
+	assert.NoError(t, os.WriteFile(cfg.Invoice.RecurringDir+"/support.yaml", []byte(definition), 0644))
+
+	runs, err := service.RunRecurring(context.Background(), &RecurringOptions{Until: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, runs, 3)
+	assert.Equal(t, "support", runs[1].Definition)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), runs[1].Date)
+	assert.Equal(t, time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), runs[1].Period.End)
+	assert.Equal(t, "RE-2025-0001", runs[1].Number, "dry runs only preview numbers")
+	assert.Equal(t, dir+"/pdf/RE-2025-0001.pdf", runs[1].File)
+
+	// Occurrences already issued are skipped
+	state, err := recurring.LoadState(cfg.Invoice.RecurringStateFile)
+	assert.NoError(t, err)
+	assert.NoError(t, state.Mark("support", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "RE-2025-0001"))
+	runs, err = service.RunRecurring(context.Background(), &RecurringOptions{Until: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+
+	_, err = service.RunRecurring(context.Background(), &RecurringOptions{IDs: []string{"hosting"}, DryRun: true})
+	appErr, ok := err.(*appErrs.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, appErrs.ErrValidationFailed, appErr.Code)
+
+	// A run interrupted after issuing RE-2025-0001 for February finishes it with that number
+	cfg.Invoice.RepositoryDir = dir + "/repository"
+	var prints int
+	service.SetPrinter(func(ctx context.Context, html, outputFile string, logger logging.Logger) error {
+		prints++
+		return os.WriteFile(outputFile, []byte("%PDF-1.7"), 0644)
+	})
+	feb, mar := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, os.WriteFile(cfg.Invoice.NumberingFile, []byte(`{"counters": {"default/2025": 1}}`), 0644))
+	state, err = recurring.LoadState(cfg.Invoice.RecurringStateFile)
+	require.NoError(t, err)
+	require.NoError(t, state.MarkPending("support", feb, "RE-2025-0001"))
+	runs, err = service.RunRecurring(context.Background(), &RecurringOptions{Until: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "RE-2025-0001", runs[0].Number)
+	assert.Equal(t, "RE-2025-0002", runs[1].Number)
+	state, err = recurring.LoadState(cfg.Invoice.RecurringStateFile)
+	require.NoError(t, err)
+	number, _ := state.Number("support", feb)
+	assert.Equal(t, "RE-2025-0001", number)
+	_, pending := state.PendingNumber("support", feb)
+	assert.False(t, pending)
+
+	// An invoice generated before the run was interrupted is only marked as done
+	delete(state.Issued["support"], "2025-03-01")
+	require.NoError(t, state.MarkPending("support", mar, "RE-2025-0002"))
+	runs, err = service.RunRecurring(context.Background(), &RecurringOptions{Until: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "RE-2025-0002", runs[0].Number)
+	assert.Equal(t, dir+"/pdf/RE-2025-0002.pdf", runs[0].File)
+	assert.Equal(t, 2, prints, "the March invoice is not generated again")
+	numberer, err := service.getNumberer()
+	require.NoError(t, err)
+	next, err := numberer.Peek(numbering.DefaultSeries, mar)
+	require.NoError(t, err)
+	assert.Equal(t, "RE-2025-0003", next, "no number is issued twice")
+}
+
+func TestConvertQuote(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "sequential",
+			NumberPattern:     "RE-{YYYY}-{SEQ:4}",
+			NumberingFile:     t.TempDir() + "/numbering.json",
+			DefaultTaxRate:    19.0,
+			QuoteValidDays:    14,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	quote := service.CreateSampleInvoice()
+	quote.Invoice.Number = ""
+	quote.Invoice.Type = models.InvoiceTypeQuote
+	quote.Invoice.Date = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	quote.Invoice.DueDate = time.Time{}
+	assert.NoError(t, service.GenerateInvoice(context.Background(), quote, &GenerateOptions{ValidateOnly: true}))
+	assert.Equal(t, "Q-2025-0001", quote.Invoice.Number, "quotes are numbered in their own series")
+	assert.Equal(t, time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), quote.Invoice.ValidUntil)
+	assert.True(t, quote.Invoice.DueDate.IsZero())
+
+	invoice, err := service.ConvertQuote(quote, ConvertQuoteOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, models.InvoiceType(""), invoice.Invoice.Type)
+	assert.Equal(t, "Q-2025-0001", invoice.Invoice.OrderReference)
+	assert.Equal(t, models.StatusDraft, invoice.Invoice.Status)
+	assert.Empty(t, invoice.Invoice.Number)
+	assert.True(t, invoice.Invoice.ValidUntil.IsZero())
+	assert.Len(t, invoice.Invoice.Lines, len(quote.Invoice.Lines))
+	assert.Equal(t, quote.Invoice.Lines[0].Description, invoice.Invoice.Lines[0].Description)
+
+	invoice.Invoice.Date = time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, service.GenerateInvoice(context.Background(), invoice, &GenerateOptions{ValidateOnly: true}))
+	assert.Equal(t, "RE-2025-0001", invoice.Invoice.Number)
+	assert.True(t, invoice.Invoice.DueDate.After(invoice.Invoice.Date))
+
+	confirmation, err := service.ConvertQuote(quote, ConvertQuoteOptions{Type: models.InvoiceTypeOrderConfirmation})
+	assert.NoError(t, err)
+	confirmation.Invoice.Number = "OC-7"
+	_, err = service.ConvertQuote(confirmation, ConvertQuoteOptions{Type: models.InvoiceTypeOrderConfirmation})
+	assert.Error(t, err)
+	fromConfirmation, err := service.ConvertQuote(confirmation, ConvertQuoteOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "OC-7", fromConfirmation.Invoice.OrderReference)
+
+	_, err = service.ConvertQuote(invoice, ConvertQuoteOptions{})
+	appErr, ok := err.(*appErrs.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, appErrs.ErrValidationFailed, appErr.Code)
+	_, err = service.ConvertQuote(quote, ConvertQuoteOptions{Type: models.InvoiceTypeCreditNote})
+	assert.Error(t, err)
+}
+
+func TestGenerateInvoice_CatalogItems(t *testing.T) {
+	dir := t.TempDir()
+	catalogFile := dir + "/catalog.yaml"
+	assert.NoError(t, os.WriteFile(catalogFile, []byte(`items:
+  - sku: CONS-H
+    description: "Consulting"
+    descriptions:
+      de: "Beratung"
+    unit: HUR
+    price: 120
+    product_type: services
+`), 0644))
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency: "EUR",
+			DefaultDueDays:  30,
+			DefaultTaxRate:  19.0,
+			CatalogFile:     catalogFile,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	data := service.CreateSampleInvoice()
+	data.Invoice.Language = "de"
+	data.Invoice.Lines = []models.InvoiceLine{{SKU: "CONS-H", Quantity: decimal.NewFromInt(8)}}
+	data.Invoice.GrandTotal = decimal.Zero // As in a data file, totals are calculated
+	assert.NoError(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true}))
+	line := data.Invoice.Lines[0]
+	assert.Equal(t, "Beratung", line.Description)
+	assert.Equal(t, "HUR", line.Unit)
+	assert.Equal(t, "CONS-H", line.SellerItemID)
+	assert.True(t, decimal.NewFromInt(960).Equal(data.Invoice.Subtotal), "got %s", data.Invoice.Subtotal)
+	assert.False(t, line.TaxRate.IsZero(), "tax rules apply to catalog items")
+
+	data.Invoice.Lines = []models.InvoiceLine{{SKU: "UNKNOWN", Quantity: decimal.NewFromInt(1)}}
+	assert.Error(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true}))
+}
+
+func TestWebhooks_LifecycleEvents(t *testing.T) {
+	var events []webhook.Event
+	stand := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, webhook.Verify("s3cret", r.Header.Get(webhook.SignatureHeader), body, time.Minute))
+		var evt webhook.Event
+		assert.NoError(t, json.Unmarshal(body, &evt))
+		events = append(events, evt)
+	}))
+	defer stand.Close()
+
+	dir := t.TempDir()
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "date",
+			NumberPrefix:      "INV-",
+			DefaultTaxRate:    19.0,
+			RepositoryDir:     dir + "/invoices",
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+		Webhooks: config.WebhooksConfig{
+			Endpoints: []config.WebhookEndpointConfig{
+				{URL: stand.URL, Events: []string{"invoice.sent", "invoice.paid", "invoice.overdue"}, Secret: "s3cret"},
+			},
+			LogFile: dir + "/webhooks.jsonl",
+		},
+	}
+	service := newTestService(t, cfg)
+
+	for _, number := range []string{"RE-2025-0200", "RE-2025-0201"} {
+		data := service.CreateSampleInvoice()
+		data.Invoice.Number = number
+		data.Invoice.DueDate = time.Now().AddDate(0, 0, -1)
+		data.Invoice.CalculateTotals()
+		assert.NoError(t, service.recordInvoice(data, &GenerateOptions{OutputFile: "invoices/pdf/" + number + ".pdf"}))
+		_, err := service.MarkSent(number, time.Time{}, "")
+		assert.NoError(t, err)
+	}
+	_, err := service.MarkPaid("RE-2025-0200", time.Time{}, "")
+	assert.NoError(t, err)
+	_, err = service.ListInvoices(repository.Filter{})
+	assert.NoError(t, err)
+
+	var got []string
+	for _, evt := range events {
+		got = append(got, string(evt.Type)+" "+evt.Invoice.Number)
+	}
+	assert.Equal(t, []string{
+		"invoice.sent RE-2025-0200",
+		"invoice.sent RE-2025-0201",
+		"invoice.paid RE-2025-0200",
+		"invoice.overdue RE-2025-0201",
+	}, got)
+
+	log, err := webhook.ReadLog(dir + "/webhooks.jsonl")
+	assert.NoError(t, err)
+	assert.Len(t, log, 4)
+
+	cfg.Webhooks.Endpoints[0].SecretEnv = "INVOICEGEN_TEST_UNSET_SECRET"
+	_, err = newTestService(t, cfg).Webhooks()
+	assert.Error(t, err)
+}
+
+func TestGenerationMetrics(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency: "EUR",
+			DefaultDueDays:  30,
+			DefaultTaxRate:  19.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	xmlOK := metrics.Generations.WithLabelValues("default", "xml", metrics.OutcomeSuccess)
+	htmlOK := metrics.Generations.WithLabelValues("default", "html", metrics.OutcomeSuccess)
+	invalid := metrics.Generations.WithLabelValues("default", "validation", string(appErrs.ErrValidationFailed))
+	rejected := metrics.ValidationErrors.WithLabelValues(string(appErrs.ErrValidationFailed))
+	before := []float64{testutil.ToFloat64(xmlOK), testutil.ToFloat64(htmlOK), testutil.ToFloat64(invalid), testutil.ToFloat64(rejected)}
+
+	_, err := service.GenerateXML(context.Background(), service.CreateSampleInvoice(), &GenerateOptions{})
+	assert.NoError(t, err)
+	_, err = service.PreviewHTML(context.Background(), service.CreateSampleInvoice(), &GenerateOptions{})
+	assert.NoError(t, err)
+	data := service.CreateSampleInvoice()
+	data.Invoice.Lines = nil
+	assert.Error(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true}))
+
+	assert.Equal(t, before[0]+1, testutil.ToFloat64(xmlOK))
+	assert.Equal(t, before[1]+1, testutil.ToFloat64(htmlOK))
+	assert.Equal(t, before[2]+1, testutil.ToFloat64(invalid))
+	assert.Equal(t, before[3]+1, testutil.ToFloat64(rejected))
+}
+
+func TestGenerationSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency: "EUR",
+			DefaultDueDays:  30,
+			DefaultTaxRate:  19.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	_, err := service.GenerateXML(ctx, service.CreateSampleInvoice(), &GenerateOptions{})
+	require.NoError(t, err)
+	parent.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"invoice.generate", "invoice.prepare", "xml.build"} {
+		require.Contains(t, spans, name)
+	}
+	generate := spans["invoice.generate"]
+	assert.Equal(t, parent.SpanContext().SpanID(), generate.Parent().SpanID())
+	assert.Equal(t, generate.SpanContext().SpanID(), spans["invoice.prepare"].Parent().SpanID())
+	assert.Equal(t, generate.SpanContext().SpanID(), spans["xml.build"].Parent().SpanID())
+	assert.Contains(t, generate.Attributes(), tracing.Format.String("xml"))
+	assert.Contains(t, generate.Attributes(), tracing.Template.String("default"))
+
+	// Rejected invoices fail the preparation
+	recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	data := service.CreateSampleInvoice()
+	data.Invoice.Lines = nil
+	require.Error(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true}))
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, codes.Error, span.Status().Code, span.Name())
+	}
+}
+
+func TestGenerateInvoice_Context(t *testing.T) {
+	cfg := &config.AppConfig{
+		Invoice: config.InvoiceConfig{
+			DefaultCurrency:   "EUR",
+			DefaultDueDays:    30,
+			NumberingStrategy: "sequential",
+			NumberPattern:     "RE-{YYYY}-{SEQ:4}",
+			NumberingFile:     t.TempDir() + "/numbering.json",
+			DefaultTaxRate:    19.0,
+		},
+		Template: config.TemplateConfig{Theme: "modern"},
+	}
+	service := newTestService(t, cfg)
+	newInvoice := func() *models.InvoiceData {
+		data := service.CreateSampleInvoice()
+		data.Invoice.Number = ""
+		data.Invoice.Date = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		return data
+	}
+	timeouts := metrics.Generations.WithLabelValues("default", "pdf", string(appErrs.ErrTimeout))
+	before := testutil.ToFloat64(timeouts)
+
+	// A deadline that has passed stops the generation with a timeout and hands the
+	// number back
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err := service.GeneratePDF(ctx, newInvoice(), &GenerateOptions{}, t.TempDir())
+	var appErr *appErrs.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, appErrs.ErrTimeout, appErr.Code)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, before+1, testutil.ToFloat64(timeouts))
+
+	data := newInvoice()
+	require.NoError(t, service.GenerateInvoice(context.Background(), data, &GenerateOptions{ValidateOnly: true}))
+	assert.Equal(t, "RE-2025-0001", data.Invoice.Number)
+
+	// Cancellation ends previews and multi-language runs
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = service.PreviewHTML(ctx, newInvoice(), &GenerateOptions{})
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, appErrs.ErrCanceled, appErr.Code)
+	results := service.GenerateInvoicesMultiLang(ctx, newInvoice(), &GenerateOptions{DryRun: true}, []string{"en", "de"})
+	for _, lang := range []string{"en", "de"} {
+		assert.ErrorIs(t, results[lang], context.Canceled, lang)
+	}
+	_, err = service.RunRecurring(ctx, &RecurringOptions{DryRun: true})
+	assert.NoError(t, err) // Nothing due without definitions
 }
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/numbering"
+	"invoiceformats/pkg/repository"
+)
+
+// creditNoteSeries is used for credit notes without an explicit series when configured
+const creditNoteSeries = "credit_note"
+
+// documentSeries are the series of quotes and order confirmations, which must not take
+// numbers from the invoice sequence; the patterns apply unless number_series sets them
+var documentSeries = map[models.InvoiceType]numbering.Pattern{
+	models.InvoiceTypeQuote:             "Q-{YYYY}-{SEQ:4}",
+	models.InvoiceTypeOrderConfirmation: "OC-{YYYY}-{SEQ:4}",
+}
+
+// sequentialNumbering reports whether numbers come from the persistent sequence
+func (s *InvoiceService) sequentialNumbering() bool {
+	return s.config.Invoice.NumberingStrategy == "sequential"
+}
+
+// getNumberer returns the numberer for the configured series, creating it on first use
+func (s *InvoiceService) getNumberer() (*numbering.Numberer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.numberer != nil {
+		return s.numberer, nil
+	}
+	n, err := numbering.NewNumberer(numbering.NewFileStore(s.config.Invoice.NumberingFile), s.numberPatterns())
+	if err != nil {
+		return nil, appErrs.NewConfigError("invalid invoice numbering configuration", err)
+	}
+	s.numberer = n
+	return n, nil
+}
+
+// numberPatterns returns the patterns of all number series by series name
+func (s *InvoiceService) numberPatterns() map[string]numbering.Pattern {
+	cfg := s.config.Invoice
+	pattern := cfg.NumberPattern
+	if pattern == "" {
+		pattern = cfg.NumberPrefix + "{YYYY}-{SEQ:4}"
+	}
+	series := map[string]numbering.Pattern{numbering.DefaultSeries: numbering.Pattern(pattern)}
+	for docType, p := range documentSeries {
+		series[string(docType)] = p
+	}
+	for name, p := range cfg.NumberSeries {
+		series[name] = numbering.Pattern(p)
+	}
+	return series
+}
+
+// numberSeries picks the series for an invoice: the requested one, the series of quotes
+// and order confirmations, or the credit note series for credit notes if one is configured
+func (s *InvoiceService) numberSeries(data *models.InvoiceData, opts *GenerateOptions) string {
+	if opts.Series != "" {
+		return opts.Series
+	}
+	if _, ok := documentSeries[data.Invoice.Type]; ok {
+		return string(data.Invoice.Type)
+	}
+	if _, ok := s.config.Invoice.NumberSeries[creditNoteSeries]; ok && data.Invoice.IsCreditNote() {
+		return creditNoteSeries
+	}
+	return numbering.DefaultSeries
+}
+
+// assignInvoiceNumber gives an invoice without a number the next number of its series.
+// Validate-only and dry runs only preview the number, so they never consume one. An
+// issued number is passed to opts.NumberIssued, if set. The returned allocation is nil
+// when no number was issued.
+func (s *InvoiceService) assignInvoiceNumber(data *models.InvoiceData, opts *GenerateOptions) (*numbering.Allocation, error) {
+	if data.Invoice.Number != "" || !s.sequentialNumbering() {
+		return nil, nil
+	}
+	n, err := s.getNumberer()
+	if err != nil {
+		return nil, err
+	}
+	series := s.numberSeries(data, opts)
+	if opts.ValidateOnly || opts.DryRun {
+		number, err := n.Peek(series, data.Invoice.Date)
+		if err != nil {
+			return nil, appErrs.NewNumberingError("failed to read invoice number sequence", err)
+		}
+		data.Invoice.Number = number
+		return nil, nil
+	}
+	s.numberMu.Lock()
+	defer s.numberMu.Unlock()
+	alloc, err := n.Next(series, data.Invoice.Date)
+	if err != nil {
+		return nil, appErrs.NewNumberingError("failed to allocate invoice number", err)
+	}
+	data.Invoice.Number = alloc.Number
+	s.logger.Info("Assigned invoice number", &logging.LogFields{InvoiceNum: alloc.Number, Status: "series " + alloc.Series})
+	if opts.NumberIssued != nil {
+		if err := opts.NumberIssued(alloc.Number); err != nil {
+			return nil, s.releaseOnFailure(data, &alloc, appErrs.NewNumberingError("failed to record invoice number", err))
+		}
+	}
+	return &alloc, nil
+}
+
+// releaseInvoiceNumber hands back the number of an invoice that failed to generate
+// with cause. If another process issued later numbers meanwhile, the number can no
+// longer be handed back; it is recorded as a cancelled invoice in the repository
+// instead, so the sequence still accounts for it. The error reports a number that
+// could be neither released nor recorded and so leaves a gap.
+func (s *InvoiceService) releaseInvoiceNumber(data *models.InvoiceData, alloc *numbering.Allocation, cause error) error {
+	s.numberMu.Lock()
+	defer s.numberMu.Unlock()
+	err := s.numberer.Release(*alloc)
+	if err == nil {
+		s.logger.Info("Released invoice number", &logging.LogFields{InvoiceNum: alloc.Number})
+		return nil
+	}
+	if errors.Is(err, numbering.ErrNotLast) {
+		voidErr := s.voidInvoiceNumber(data, cause)
+		if voidErr == nil {
+			s.logger.Warn("Invoice number could not be released, recorded as cancelled", &logging.LogFields{InvoiceNum: alloc.Number, Error: err.Error()})
+			return nil
+		}
+		err = errors.Join(err, voidErr)
+	}
+	s.logger.Error("Invoice number leaves a gap in the sequence", &logging.LogFields{InvoiceNum: alloc.Number, Error: err.Error()})
+	return appErrs.NewNumberingError(fmt.Sprintf("invoice number %s could not be released and leaves a gap in the sequence", alloc.Number), err)
+}
+
+// voidInvoiceNumber records an invoice that failed to generate as cancelled, keeping
+// its number accounted for.
+func (s *InvoiceService) voidInvoiceNumber(data *models.InvoiceData, cause error) error {
+	repo, err := s.requireRepository()
+	if err != nil {
+		return err
+	}
+	if err := repo.Save(repository.NewRecord(data, "")); err != nil {
+		return err
+	}
+	note := "number voided, generation failed"
+	if cause != nil {
+		note += ": " + cause.Error()
+	}
+	_, err = repo.Transition(data.Invoice.Number, models.StatusCancelled, time.Now(), note)
+	return err
+}
+
+// releaseOnFailure releases the number of a failed generation, adding the error of
+// the release, if any, to err.
+func (s *InvoiceService) releaseOnFailure(data *models.InvoiceData, alloc *numbering.Allocation, err error) error {
+	if relErr := s.releaseInvoiceNumber(data, alloc, err); relErr != nil {
+		return errors.Join(err, relErr)
+	}
+	return err
+}
+
+// previewInvoiceNumber returns the next number of the default series for today
+func (s *InvoiceService) previewInvoiceNumber() (string, error) {
+	n, err := s.getNumberer()
+	if err != nil {
+		return "", err
+	}
+	return n.Peek(numbering.DefaultSeries, time.Now())
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"invoiceformats/pkg/di"
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/tracing"
+)
+
+// DecodeInvoiceJSON decodes invoice data from JSON, resolving provider and client
+// references to the configured profiles
+func (s *InvoiceService) DecodeInvoiceJSON(raw []byte) (*models.InvoiceData, error) {
+	profiles, err := s.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	var data models.InvoiceData
+	if err := profiles.DecodeJSON(raw, &data); err != nil {
+		return nil, appErrs.NewValidationError("invalid invoice JSON", err)
+	}
+	return &data, nil
+}
+
+// PreviewHTML validates the invoice like GenerateInvoice with ValidateOnly and returns
+// the rendered HTML. No number is issued; a sequential number is only previewed.
+func (s *InvoiceService) PreviewHTML(ctx context.Context, data *models.InvoiceData, opts *GenerateOptions) (html string, err error) {
+	ctx, span := startGeneration(ctx, data, opts, "html")
+	defer func() {
+		err = contextError(ctx, err)
+		tracing.End(span, err)
+		recordGeneration(opts, "html", err)
+	}()
+	check := *opts
+	check.ValidateOnly = true
+	if err := s.generateInvoice(ctx, data, &check); err != nil {
+		return "", err
+	}
+	return s.renderInvoiceHTML(ctx, data, &check)
+}
+
+// GenerateXML validates the invoice like GenerateInvoice with ValidateOnly and returns
+// its ZUGFeRD/Factur-X XML. No number is issued; a sequential number is only previewed.
+func (s *InvoiceService) GenerateXML(ctx context.Context, data *models.InvoiceData, opts *GenerateOptions) (xmlData []byte, err error) {
+	ctx, span := startGeneration(ctx, data, opts, "xml")
+	defer func() {
+		err = contextError(ctx, err)
+		tracing.End(span, err)
+		recordGeneration(opts, "xml", err)
+	}()
+	if !data.Invoice.Type.IsInvoice() {
+		return nil, appErrs.NewValidationError("only invoices have e-invoice XML", nil)
+	}
+	check := *opts
+	check.ValidateOnly = true
+	if err := s.generateInvoice(ctx, data, &check); err != nil {
+		return nil, err
+	}
+	data.Invoice.CalculateTotals()
+	_, build := tracing.Start(ctx, "xml.build")
+	xmlData, err = di.ProvideZUGFeRDInvoiceXMLBuilder().BuildXML(*data)
+	tracing.End(build, err)
+	if err != nil {
+		return nil, appErrs.NewPDFGenerationError("failed to build XML", err)
+	}
+	return xmlData, nil
+}
+
+// GeneratePDF generates the invoice like GenerateInvoice and returns the PDF. The PDF is
+// kept as <number>.pdf in outputDir, where the invoice record refers to it; without an
+// outputDir it is only returned. Sequential numbers are issued as usual.
+func (s *InvoiceService) GeneratePDF(ctx context.Context, data *models.InvoiceData, opts *GenerateOptions, outputDir string) (pdfData []byte, err error) {
+	ctx, span := startGeneration(ctx, data, opts, "pdf")
+	defer func() {
+		err = contextError(ctx, err)
+		tracing.End(span, err)
+		recordGeneration(opts, "pdf", err)
+	}()
+	out := *opts
+	out.IncludeHTML = false
+	out.DryRun = false
+	out.ValidateOnly = false
+
+	if outputDir == "" {
+		dir, err := os.MkdirTemp("", "invoicegen-*")
+		if err != nil {
+			return nil, appErrs.NewPDFGenerationError("failed to create output directory", err)
+		}
+		defer os.RemoveAll(dir)
+		out.OutputFile = filepath.Join(dir, "invoice.pdf")
+		if err := s.generateInvoice(ctx, data, &out); err != nil {
+			return nil, err
+		}
+	} else {
+		// The number is issued first because it names the file
+		alloc, err := s.assignInvoiceNumber(data, &out)
+		if err != nil {
+			return nil, err
+		}
+		if data.Invoice.Number == "" {
+			data.Invoice.Number = s.GenerateInvoiceNumber()
+		}
+		out.OutputFile = filepath.Join(outputDir, unsafeFileChars.ReplaceAllString(data.Invoice.Number, "_")+".pdf")
+		err = os.MkdirAll(outputDir, 0755)
+		if err != nil {
+			err = appErrs.NewPDFGenerationError("failed to create output directory", err)
+		} else {
+			err = s.generateInvoice(ctx, data, &out)
+		}
+		if err != nil {
+			if alloc != nil {
+				err = s.releaseOnFailure(data, alloc, err)
+			}
+			return nil, err
+		}
+	}
+
+	pdfData, err = os.ReadFile(out.OutputFile)
+	if err != nil {
+		return nil, appErrs.NewPDFGenerationError("failed to read generated PDF", err)
+	}
+	return pdfData, nil
+}
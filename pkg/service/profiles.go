@@ -0,0 +1,32 @@
+package service
+
+import (
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/loader"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/profile"
+)
+
+// Profiles returns the provider and client profiles, loaded and validated on first use
+func (s *InvoiceService) Profiles() (*profile.Registry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.profiles == nil {
+		profiles, err := profile.Load(s.config.Invoice.ProfilesDir)
+		if err != nil {
+			return nil, appErrs.NewConfigError("invalid provider or client profile", err)
+		}
+		s.profiles = profiles
+	}
+	return s.profiles, nil
+}
+
+// LoadInvoiceData loads invoice data from a YAML or JSON file, resolving provider and
+// client references to the configured profiles
+func (s *InvoiceService) LoadInvoiceData(filename string) (*models.InvoiceData, error) {
+	profiles, err := s.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadInvoiceDataWithProfiles(filename, profiles, s.logger)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/repository"
+)
+
+// ConvertQuoteOptions controls how a quote is turned into an invoice or order confirmation
+type ConvertQuoteOptions struct {
+	Type   models.InvoiceType // Target document type; a regular invoice if empty
+	Number string             // Number of the new document; issued on generation if empty
+	Date   time.Time          // Date of the new document; set on generation if zero
+}
+
+// ConvertQuote derives a draft invoice or order confirmation from an accepted quote, or a
+// draft invoice from an order confirmation. Lines, parties and terms are kept and the
+// source document is referenced as the sales order (BT-14); dates, number and totals are
+// left to the generation of the new document.
+func (s *InvoiceService) ConvertQuote(source *models.InvoiceData, opts ConvertQuoteOptions) (*models.InvoiceData, error) {
+	inv := source.Invoice
+	if inv.Type.IsInvoice() {
+		return nil, appErrs.NewValidationError(fmt.Sprintf("%s is not a quote or order confirmation", inv.Number), nil)
+	}
+	if inv.Number == "" {
+		return nil, appErrs.NewValidationError("the quote has no number to reference", nil)
+	}
+	switch opts.Type {
+	case "", models.InvoiceTypeAdvance, models.InvoiceTypePartial, models.InvoiceTypeFinal:
+	case models.InvoiceTypeOrderConfirmation:
+		if inv.Type != models.InvoiceTypeQuote {
+			return nil, appErrs.NewValidationError("only quotes can be confirmed as orders", nil)
+		}
+	default:
+		return nil, appErrs.NewValidationError(fmt.Sprintf("cannot convert a %s into a %s", inv.Type, opts.Type), nil)
+	}
+
+	lines := make([]models.InvoiceLine, len(inv.Lines))
+	for i, line := range inv.Lines {
+		line.ID = uuid.Nil
+		lines[i] = line
+	}
+	converted := &models.InvoiceData{
+		Provider:     source.Provider,
+		Client:       source.Client,
+		EmbeddedData: source.EmbeddedData,
+		Invoice: models.InvoiceDetails{
+			Number:             opts.Number,
+			Type:               opts.Type,
+			Date:               opts.Date,
+			Status:             models.StatusDraft,
+			Currency:           inv.Currency,
+			Lines:              lines,
+			PaymentTerms:       inv.PaymentTerms,
+			Notes:              inv.Notes,
+			Language:           inv.Language,
+			LegalFields:        inv.LegalFields,
+			VATExemptionType:   inv.VATExemptionType,
+			VATExemptionReason: inv.VATExemptionReason,
+			TariffType:         inv.TariffType,
+			AdditionalTariffs:  inv.AdditionalTariffs,
+			TaxCurrency:        inv.TaxCurrency,
+			Withholdings:       inv.Withholdings,
+			BillingPeriod:      inv.BillingPeriod,
+			OrderReference:     inv.Number,
+		},
+	}
+	if inv.HasTaxCurrency() {
+		// The rate is looked up again for the invoice date
+		converted.Invoice.Currency.Rate = decimal.Zero
+	}
+
+	s.logger.Info("Converted quote", &logging.LogFields{InvoiceNum: inv.Number, Status: "to " + converted.Invoice.Type.TitleKey(), Lines: len(lines)})
+	return converted, nil
+}
+
+// MarkAccepted records that the client accepted a sent quote
+func (s *InvoiceService) MarkAccepted(number string, at time.Time, note string) (*repository.Record, error) {
+	return s.setStatus(number, models.StatusAccepted, at, note)
+}
+
+// MarkDeclined records that the client declined a sent quote
+func (s *InvoiceService) MarkDeclined(number string, at time.Time, note string) (*repository.Record, error) {
+	return s.setStatus(number, models.StatusDeclined, at, note)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"invoiceformats/pkg/bank"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/reconcile"
+	"invoiceformats/pkg/repository"
+	"invoiceformats/pkg/webhook"
+)
+
+// ReconcileStatement matches bank transactions to open invoices and records the matched
+// payments; fully paid invoices move to paid. With dryRun nothing is recorded.
+func (s *InvoiceService) ReconcileStatement(txs []bank.Transaction, dryRun bool) (*reconcile.Result, error) {
+	repo, err := s.requireRepository()
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := s.markOverdue(repo, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	records, err := repo.List(repository.Filter{})
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	result := reconcile.Reconcile(txs, records)
+	s.logger.Info("Bank statement reconciled", &logging.LogFields{
+		Status: fmt.Sprintf("matched %d, review %d, unmatched %d", len(result.Matched), len(result.Review), len(result.Unmatched)),
+	})
+	if dryRun {
+		return result, nil
+	}
+
+	for _, m := range result.Matched {
+		for _, a := range m.Allocations {
+			rec, err := repo.RecordPayment(a.Number, repository.Payment{
+				Date:      m.Transaction.Date,
+				Amount:    a.Amount,
+				Reference: m.Transaction.ID,
+				Payer:     m.Transaction.Name,
+				IBAN:      m.Transaction.IBAN,
+				Note:      string(m.Rule),
+			})
+			if err != nil {
+				return result, repositoryError(err)
+			}
+			s.logger.Info("Payment recorded", &logging.LogFields{InvoiceNum: rec.Number, Status: fmt.Sprintf("%s %s, %s", a.Amount.StringFixed(2), rec.Currency, rec.Status)})
+			if rec.Status == models.StatusPaid {
+				s.notify(webhook.EventPaid, rec)
+			}
+		}
+	}
+	return result, nil
+}
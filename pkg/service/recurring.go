@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/recurring"
+	"invoiceformats/pkg/repository"
+)
+
+// RecurringOptions holds options for a recurring invoice run
+type RecurringOptions struct {
+	Until  time.Time // Generate occurrences up to and including this date; defaults to today
+	IDs    []string  // Only these definitions; all if empty
+	DryRun bool      // Only determine the invoices due
+}
+
+// RecurringRun is an invoice issued, or due in a dry run, for a recurring definition
+type RecurringRun struct {
+	Definition string               `json:"definition"`
+	Date       time.Time            `json:"date"`
+	Period     models.BillingPeriod `json:"period"`
+	Number     string               `json:"number"`
+	File       string               `json:"file"`
+}
+
+// RecurringDefinitions loads the recurring invoice definitions from the configured directory
+func (s *InvoiceService) RecurringDefinitions() ([]*recurring.Definition, error) {
+	profiles, err := s.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	defs, err := recurring.LoadDir(s.config.Invoice.RecurringDir, profiles)
+	if err != nil {
+		return nil, appErrs.NewValidationError("invalid recurring invoice definition", err)
+	}
+	return defs, nil
+}
+
+// RunRecurring generates every invoice of the recurring definitions that is due up to
+// opts.Until and has not been generated yet. Each invoice gets the invoice date of its
+// occurrence, the billing period it covers and the next number of its series. The
+// number is recorded in the state file as pending before the invoice is generated and
+// the occurrence as done once it is, so runs can be repeated safely: a run interrupted
+// in between is finished with the pending number. The run stops at the first failure,
+// or when ctx is done, and returns what was issued.
+func (s *InvoiceService) RunRecurring(ctx context.Context, opts *RecurringOptions) ([]RecurringRun, error) {
+	defs, err := s.RecurringDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.IDs) > 0 {
+		defs, err = selectDefinitions(defs, opts.IDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	state, err := recurring.LoadState(s.config.Invoice.RecurringStateFile)
+	if err != nil {
+		return nil, appErrs.NewAppError(appErrs.ErrUnknown, "failed to read recurring invoice state", err)
+	}
+	until := opts.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	var runs []RecurringRun
+	for _, def := range defs {
+		occurrences, err := def.Occurrences(until)
+		if err != nil {
+			return runs, appErrs.NewValidationError("invalid recurring invoice "+def.ID, err)
+		}
+		for _, o := range occurrences {
+			if _, done := state.Number(def.ID, o.Date); done {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return runs, appErrs.FromContext("recurring run", err)
+			}
+			run, err := s.generateOccurrence(ctx, def, o, state, opts.DryRun)
+			if err != nil {
+				return runs, err
+			}
+			if !opts.DryRun {
+				if err := state.Mark(def.ID, o.Date, run.Number); err != nil {
+					s.logger.Error("Failed to record recurring invoice", &logging.LogFields{Error: err.Error(), InvoiceNum: run.Number})
+					return runs, appErrs.NewAppError(appErrs.ErrUnknown, "failed to record recurring invoice "+run.Number, err)
+				}
+				s.logger.Info("Recurring invoice issued", &logging.LogFields{InvoiceNum: run.Number, File: run.File, Status: def.ID + " " + o.Date.Format("2006-01-02")})
+			}
+			runs = append(runs, run)
+		}
+	}
+	s.logger.Info("Recurring run", &logging.LogFields{Status: fmt.Sprintf("%d invoices due", len(runs))})
+	return runs, nil
+}
+
+// generateOccurrence issues the invoice of one occurrence. The number is assigned
+// before generation because the output path may contain it, and recorded as pending in
+// state. A pending number of an interrupted run is used again, unless its invoice was
+// generated before the interruption.
+func (s *InvoiceService) generateOccurrence(ctx context.Context, def *recurring.Definition, o recurring.Occurrence, state *recurring.State, dryRun bool) (RecurringRun, error) {
+	run := RecurringRun{Definition: def.ID, Date: o.Date, Period: o.Period}
+	data, err := def.Render(o)
+	if err != nil {
+		return run, appErrs.NewValidationError("invalid recurring invoice "+def.ID, err)
+	}
+	if data.Invoice.DueDate.IsZero() {
+		data.Invoice.DueDate = o.Date.AddDate(0, 0, s.config.Invoice.DefaultDueDays)
+	}
+	opts := &GenerateOptions{
+		Template:      def.Template,
+		DryRun:        dryRun,
+		Series:        def.Series,
+		EnableZUGFeRD: data.EmbeddedData == models.EmbeddedDataZUGFeRD,
+	}
+	if number, ok := state.PendingNumber(def.ID, o.Date); ok {
+		rec, err := s.pendingRecord(number)
+		if err != nil {
+			return run, err
+		}
+		switch {
+		case rec == nil:
+			data.Invoice.Number = number
+		case rec.Status != models.StatusCancelled && rec.Date.Format("2006-01-02") == o.Date.Format("2006-01-02"):
+			s.logger.Info("Recurring invoice was generated by an interrupted run", &logging.LogFields{InvoiceNum: number, File: rec.PDFFile})
+			run.Number, run.File = number, rec.PDFFile
+			return run, nil
+		default:
+			// Voided, or taken by another invoice after it was handed back: issue a new one
+		}
+	}
+	if !dryRun {
+		opts.NumberIssued = func(number string) error {
+			return state.MarkPending(def.ID, o.Date, number)
+		}
+	}
+
+	alloc, err := s.assignInvoiceNumber(data, opts)
+	if err != nil {
+		return run, err
+	}
+	// fail hands back a number issued by this run once it is no longer pending; if that
+	// cannot be recorded, the number stays issued and pending for the next run
+	fail := func(err error) (RecurringRun, error) {
+		if alloc == nil {
+			return run, err
+		}
+		if clearErr := state.ClearPending(def.ID, o.Date); clearErr != nil {
+			return run, errors.Join(err, appErrs.NewAppError(appErrs.ErrUnknown, "failed to record recurring invoice state", clearErr))
+		}
+		return run, s.releaseOnFailure(data, alloc, err)
+	}
+	if data.Invoice.Number == "" {
+		data.Invoice.Number = s.GenerateInvoiceNumber()
+	}
+	run.Number = data.Invoice.Number
+	run.File = def.OutputFile(o, unsafeFileChars.ReplaceAllString(run.Number, "_"))
+	opts.OutputFile = run.File
+
+	if !dryRun {
+		if err := os.MkdirAll(filepath.Dir(run.File), 0755); err != nil {
+			return fail(appErrs.NewPDFGenerationError("failed to create output directory", err))
+		}
+	}
+	if err := s.GenerateInvoice(ctx, data, opts); err != nil {
+		return fail(err)
+	}
+	return run, nil
+}
+
+// pendingRecord returns the recorded invoice of a pending number, or nil if there is
+// none or recording is disabled.
+func (s *InvoiceService) pendingRecord(number string) (*repository.Record, error) {
+	repo := s.getRepository()
+	if repo == nil {
+		return nil, nil
+	}
+	rec, err := repo.Get(number)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	return rec, nil
+}
+
+// selectDefinitions returns the definitions with the given IDs
+func selectDefinitions(defs []*recurring.Definition, ids []string) ([]*recurring.Definition, error) {
+	byID := make(map[string]*recurring.Definition, len(defs))
+	for _, d := range defs {
+		byID[d.ID] = d
+	}
+	selected := make([]*recurring.Definition, 0, len(ids))
+	for _, id := range ids {
+		d, ok := byID[id]
+		if !ok {
+			return nil, appErrs.NewValidationError("unknown recurring invoice "+id, nil)
+		}
+		selected = append(selected, d)
+	}
+	return selected, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/repository"
+)
+
+// getRepository returns the invoice repository, or nil if recording is disabled
+func (s *InvoiceService) getRepository() repository.Repository {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.repository == nil && s.config.Invoice.RepositoryDir != "" {
+		s.repository = repository.NewDirRepository(s.config.Invoice.RepositoryDir)
+	}
+	return s.repository
+}
+
+// requireRepository returns the invoice repository or an error if it is disabled
+func (s *InvoiceService) requireRepository() (repository.Repository, error) {
+	repo := s.getRepository()
+	if repo == nil {
+		return nil, appErrs.NewConfigError("invoice repository is disabled (repository_dir is empty)", nil)
+	}
+	return repo, nil
+}
+
+// checkNotIssued rejects regenerating an invoice that has already left draft state
+func (s *InvoiceService) checkNotIssued(data *models.InvoiceData) error {
+	repo := s.getRepository()
+	if repo == nil {
+		return nil
+	}
+	rec, err := repo.Get(data.Invoice.Number)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return repositoryError(err)
+	}
+	if rec.Status.IsIssued() {
+		return appErrs.NewValidationError("invoice "+rec.Number+" is already "+string(rec.Status)+"; issue a credit note instead of changing it", repository.ErrIssued)
+	}
+	return nil
+}
+
+// recordInvoice stores a generated invoice as draft
+func (s *InvoiceService) recordInvoice(data *models.InvoiceData, opts *GenerateOptions) error {
+	repo := s.getRepository()
+	if repo == nil {
+		return nil
+	}
+	data.Invoice.Status = models.StatusDraft
+	if err := repo.Save(repository.NewRecord(data, opts.OutputFile)); err != nil {
+		s.logger.Error("Failed to record invoice", &logging.LogFields{Error: err.Error(), InvoiceNum: data.Invoice.Number})
+		return repositoryError(err)
+	}
+	s.logger.Info("Invoice recorded", &logging.LogFields{InvoiceNum: data.Invoice.Number, Status: string(models.StatusDraft)})
+	return nil
+}
+
+// ListInvoices returns the recorded invoices matching the filter. Sent invoices past
+// their due date are marked overdue first.
+func (s *InvoiceService) ListInvoices(filter repository.Filter) ([]*repository.Record, error) {
+	repo, err := s.requireRepository()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.markOverdue(repo, time.Now()); err != nil {
+		return nil, err
+	}
+	records, err := repo.List(filter)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	return records, nil
+}
+
+// GetInvoice returns a recorded invoice
+func (s *InvoiceService) GetInvoice(number string) (*repository.Record, error) {
+	repo, err := s.requireRepository()
+	if err != nil {
+		return nil, err
+	}
+	rec, err := repo.Get(number)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	return rec, nil
+}
+
+// MarkSent records that an invoice was sent to the client. Sending issues the invoice,
+// so a draft is archived first.
+func (s *InvoiceService) MarkSent(number string, at time.Time, note string) (*repository.Record, error) {
+	if arch := s.getArchive(); arch != nil {
+		rec, err := s.GetInvoice(number)
+		if err != nil {
+			return nil, err
+		}
+		if rec.Status == models.StatusDraft {
+			if err := s.archiveInvoice(arch, rec); err != nil {
+				s.logger.Error("Archiving failed, invoice not sent", &logging.LogFields{Error: err.Error(), InvoiceNum: number})
+				return nil, err
+			}
+		}
+	}
+	return s.setStatus(number, models.StatusSent, at, note)
+}
+
+// MarkPaid records that an invoice was paid
+func (s *InvoiceService) MarkPaid(number string, at time.Time, note string) (*repository.Record, error) {
+	return s.setStatus(number, models.StatusPaid, at, note)
+}
+
+// CancelInvoice cancels a recorded invoice. Issued invoices need a recorded credit note
+// that references them.
+func (s *InvoiceService) CancelInvoice(number, creditNote string, at time.Time) (*repository.Record, error) {
+	repo, err := s.requireRepository()
+	if err != nil {
+		return nil, err
+	}
+	rec, err := repo.Cancel(number, creditNote, at)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	s.logger.Info("Invoice cancelled", &logging.LogFields{InvoiceNum: number, Status: "credit note " + creditNote})
+	s.notifyStatus(rec)
+	return rec, nil
+}
+
+func (s *InvoiceService) setStatus(number string, to models.InvoiceStatus, at time.Time, note string) (*repository.Record, error) {
+	repo, err := s.requireRepository()
+	if err != nil {
+		return nil, err
+	}
+	rec, err := repo.Transition(number, to, at, note)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	s.logger.Info("Invoice status changed", &logging.LogFields{InvoiceNum: number, Status: string(to)})
+	s.notifyStatus(rec)
+	return rec, nil
+}
+
+// repositoryError maps repository errors to application errors
+func repositoryError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return appErrs.NewAppError(appErrs.ErrInvoiceNotFound, "invoice not found", err)
+	case errors.Is(err, repository.ErrInvalidTransition), errors.Is(err, repository.ErrCreditNoteRequired):
+		return appErrs.NewStatusTransitionError("status change not allowed", err)
+	case errors.Is(err, repository.ErrIssued):
+		return appErrs.NewValidationError("invoice already issued", err)
+	case errors.Is(err, repository.ErrDuplicatePayment):
+		return appErrs.NewValidationError("payment already recorded", err)
+	}
+	return appErrs.NewAppError(appErrs.ErrUnknown, "invoice repository error", err)
+}
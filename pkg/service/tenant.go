@@ -0,0 +1,24 @@
+package service
+
+import (
+	"invoiceformats/internal/config"
+)
+
+// ForTenant returns the invoice service of a tenant. It works on the tenant's copy of
+// the configuration (see config.AppConfig.ForTenant), so profiles, catalog, numbering
+// and stored invoices are those of the tenant. Services are created on first use and
+// shared by later calls.
+func (s *InvoiceService) ForTenant(t config.TenantConfig) *InvoiceService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if svc, ok := s.tenants[t.ID]; ok {
+		return svc
+	}
+	if s.tenants == nil {
+		s.tenants = make(map[string]*InvoiceService)
+	}
+	svc := NewInvoiceService(s.config.ForTenant(t), s.logger, s.localeLoader)
+	svc.printer = s.printer
+	s.tenants[t.ID] = svc
+	return svc
+}
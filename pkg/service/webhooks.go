@@ -0,0 +1,116 @@
+package service
+
+import (
+	"os"
+	"time"
+
+	appErrs "invoiceformats/pkg/errors"
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/repository"
+	"invoiceformats/pkg/webhook"
+)
+
+// Webhooks returns the webhook dispatcher, or nil if no endpoints are configured
+func (s *InvoiceService) Webhooks() (*webhook.Dispatcher, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.webhooks != nil || len(s.config.Webhooks.Endpoints) == 0 {
+		return s.webhooks, nil
+	}
+	cfg := s.config.Webhooks
+	endpoints := make([]webhook.Endpoint, len(cfg.Endpoints))
+	for i, ep := range cfg.Endpoints {
+		endpoints[i] = webhook.Endpoint{URL: ep.URL, Secret: ep.Secret}
+		if ep.SecretEnv != "" {
+			endpoints[i].Secret = os.Getenv(ep.SecretEnv)
+			if endpoints[i].Secret == "" {
+				return nil, appErrs.NewConfigError("webhook secret variable "+ep.SecretEnv+" is not set", nil)
+			}
+		}
+		for _, e := range ep.Events {
+			endpoints[i].Events = append(endpoints[i].Events, webhook.EventType(e))
+		}
+	}
+	d, err := webhook.NewDispatcher(endpoints, webhook.Options{
+		MaxAttempts: cfg.MaxAttempts,
+		RetryDelay:  cfg.RetryDelay,
+		Timeout:     cfg.Timeout,
+		LogFile:     cfg.LogFile,
+	}, s.logger)
+	if err != nil {
+		return nil, appErrs.NewConfigError("invalid webhook configuration", err)
+	}
+	s.webhooks = d
+	return d, nil
+}
+
+// notify delivers a lifecycle event of an invoice. Delivery failures are logged and
+// recorded in the delivery log; they do not fail the operation that caused the event.
+func (s *InvoiceService) notify(t webhook.EventType, rec *repository.Record) {
+	d, err := s.Webhooks()
+	if err != nil {
+		s.logger.Error("Webhooks disabled", &logging.LogFields{Error: err.Error()})
+		return
+	}
+	if d == nil {
+		return
+	}
+	if _, err := d.Dispatch(webhook.NewEvent(t, rec)); err != nil {
+		s.logger.Error("Webhook not delivered", &logging.LogFields{InvoiceNum: rec.Number, Status: string(t), Error: err.Error()})
+	}
+}
+
+// notifyStatus delivers the event of a status change
+func (s *InvoiceService) notifyStatus(rec *repository.Record) {
+	if t, ok := webhook.StatusEvent(rec.Status); ok {
+		s.notify(t, rec)
+	}
+}
+
+// markOverdue moves sent invoices past their due date to overdue and notifies the
+// webhooks of each
+func (s *InvoiceService) markOverdue(repo repository.Repository, now time.Time) error {
+	overdue, err := repo.MarkOverdue(now)
+	for _, rec := range overdue {
+		s.logger.Info("Invoice overdue", &logging.LogFields{InvoiceNum: rec.Number, Status: string(rec.Status)})
+		s.notify(webhook.EventOverdue, rec)
+	}
+	if err != nil {
+		return repositoryError(err)
+	}
+	return nil
+}
+
+// TestWebhooks sends a sample event of the given type to the endpoints that subscribe
+// to it and returns the last attempt per endpoint
+func (s *InvoiceService) TestWebhooks(t webhook.EventType) ([]webhook.Delivery, error) {
+	if t == webhook.EventAll || !t.IsValid() {
+		return nil, appErrs.NewValidationError("unknown webhook event "+string(t), nil)
+	}
+	d, err := s.Webhooks()
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, appErrs.NewConfigError("no webhook endpoints configured", nil)
+	}
+	data := s.CreateSampleInvoice()
+	data.Invoice.CalculateTotals()
+	deliveries, err := d.Dispatch(webhook.NewEvent(t, repository.NewRecord(data, "")))
+	if err != nil {
+		return deliveries, appErrs.NewAppError(appErrs.ErrUnknown, "webhook not delivered", err)
+	}
+	return deliveries, nil
+}
+
+// WebhookDeliveries returns the webhook delivery log, oldest first
+func (s *InvoiceService) WebhookDeliveries() ([]webhook.Delivery, error) {
+	if s.config.Webhooks.LogFile == "" {
+		return nil, appErrs.NewConfigError("webhook delivery log is disabled (webhooks.log_file is empty)", nil)
+	}
+	deliveries, err := webhook.ReadLog(s.config.Webhooks.LogFile)
+	if err != nil {
+		return nil, appErrs.NewConfigError("failed to read webhook delivery log", err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,72 @@
+package tax
+
+import "strings"
+
+// countryNames maps common English and native country names to ISO 3166-1 alpha-2 codes.
+// Invoice files use free-text country names, so lookups are case-insensitive.
+var countryNames = map[string]string{
+	"austria": "AT", "österreich": "AT",
+	"belgium": "BE", "belgië": "BE", "belgique": "BE",
+	"bulgaria": "BG",
+	"croatia":  "HR", "hrvatska": "HR",
+	"cyprus":         "CY",
+	"czech republic": "CZ", "czechia": "CZ",
+	"denmark": "DK", "danmark": "DK",
+	"estonia": "EE",
+	"finland": "FI", "suomi": "FI",
+	"france":  "FR",
+	"germany": "DE", "deutschland": "DE",
+	"greece":  "GR",
+	"hungary": "HU",
+	"ireland": "IE",
+	"italy":   "IT", "italia": "IT",
+	"latvia":      "LV",
+	"lithuania":   "LT",
+	"luxembourg":  "LU",
+	"malta":       "MT",
+	"netherlands": "NL", "the netherlands": "NL", "nederland": "NL", "holland": "NL",
+	"poland": "PL", "polska": "PL",
+	"portugal": "PT",
+	"romania":  "RO",
+	"slovakia": "SK",
+	"slovenia": "SI",
+	"spain":    "ES", "españa": "ES",
+	"sweden": "SE", "sverige": "SE",
+	"united kingdom": "GB", "uk": "GB", "great britain": "GB", "england": "GB",
+	"switzerland": "CH", "schweiz": "CH", "suisse": "CH", "svizzera": "CH",
+	"liechtenstein": "LI",
+	"norway":        "NO", "norge": "NO",
+	"united states": "US", "united states of america": "US", "usa": "US",
+	"canada":               "CA",
+	"united arab emirates": "AE", "uae": "AE",
+	"japan":     "JP",
+	"china":     "CN",
+	"india":     "IN",
+	"australia": "AU",
+	"turkey":    "TR", "türkiye": "TR",
+	"russia":  "RU",
+	"ukraine": "UA",
+}
+
+// euMembers lists the EU VAT area by ISO code.
+var euMembers = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "HR": true, "CY": true, "CZ": true, "DK": true,
+	"EE": true, "FI": true, "FR": true, "DE": true, "GR": true, "HU": true, "IE": true,
+	"IT": true, "LV": true, "LT": true, "LU": true, "MT": true, "NL": true, "PL": true,
+	"PT": true, "RO": true, "SK": true, "SI": true, "ES": true, "SE": true,
+}
+
+// CountryCode normalizes a country name or code to its ISO 3166-1 alpha-2 code.
+// Unknown names are returned upper-cased so two-letter codes pass through unchanged.
+func CountryCode(country string) string {
+	c := strings.TrimSpace(country)
+	if code, ok := countryNames[strings.ToLower(c)]; ok {
+		return code
+	}
+	return strings.ToUpper(c)
+}
+
+// IsEU reports whether the country (name or code) belongs to the EU VAT area.
+func IsEU(country string) bool {
+	return euMembers[CountryCode(country)]
+}
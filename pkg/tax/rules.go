@@ -0,0 +1,140 @@
+package tax
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"invoiceformats/pkg/models"
+)
+
+// VATRules is a rule set for a value added tax jurisdiction with a standard rate and
+// up to two reduced rates.
+type VATRules struct {
+	Code          string
+	Standard      decimal.Decimal
+	Reduced       decimal.Decimal
+	SecondReduced decimal.Decimal // Zero if the jurisdiction has only one reduced rate
+}
+
+// Built-in VAT rule sets.
+var (
+	Germany       = &VATRules{Code: "DE", Standard: decimal.NewFromInt(19), Reduced: decimal.NewFromInt(7)}
+	Austria       = &VATRules{Code: "AT", Standard: decimal.NewFromInt(20), Reduced: decimal.NewFromInt(10), SecondReduced: decimal.NewFromInt(13)}
+	France        = &VATRules{Code: "FR", Standard: decimal.NewFromInt(20), Reduced: decimal.RequireFromString("5.5"), SecondReduced: decimal.NewFromInt(10)}
+	Netherlands   = &VATRules{Code: "NL", Standard: decimal.NewFromInt(21), Reduced: decimal.NewFromInt(9)}
+	UnitedKingdom = &VATRules{Code: "GB", Standard: decimal.NewFromInt(20), Reduced: decimal.NewFromInt(5)}
+	Switzerland   = &VATRules{Code: "CH", Standard: decimal.RequireFromString("8.1"), Reduced: decimal.RequireFromString("2.6"), SecondReduced: decimal.RequireFromString("3.8")}
+)
+
+// Country implements RuleSet.
+func (r *VATRules) Country() string { return r.Code }
+
+// Decide implements RuleSet. Domestic supplies and cross-border supplies to consumers
+// are taxed at the seller's rate for the line's tax class. Cross-border supplies are
+// otherwise zero-rated:
+//
+//   - goods to a business in another EU member state: intra-community supply (K)
+//   - services to a business in another EU member state: reverse charge (AE)
+//
+// Both require the VAT IDs of seller and buyer; without them the supply is taxed like a
+// domestic one.
+//   - goods leaving the seller's VAT area: export (G)
+//   - services to a business outside the seller's VAT area: not subject to VAT (O)
+func (r *VATRules) Decide(ctx Context, item Item) (Decision, error) {
+	if ctx.BuyerCountry == "" || ctx.BuyerCountry == r.Code || item.TaxClass == models.TaxClassExempt {
+		return r.domestic(item), nil
+	}
+	goods := item.ProductType == models.ProductGoods
+	if euMembers[r.Code] && euMembers[ctx.BuyerCountry] {
+		switch {
+		case ctx.SellerVATID == "" || ctx.BuyerVATID == "":
+			return r.domestic(item), nil
+		case goods:
+			return Decision{Category: models.TaxCategoryIntraCommunity, ExemptionCode: "VATEX-EU-IC", NoteKey: "vat_exemption_intra_community"}, nil
+		default:
+			return Decision{Category: models.TaxCategoryReverseCharge, ExemptionCode: "VATEX-EU-AE", NoteKey: "vat_exemption_reverse_charge"}, nil
+		}
+	}
+	switch {
+	case goods:
+		return Decision{Category: models.TaxCategoryExport, ExemptionCode: "VATEX-EU-G", NoteKey: "vat_exemption_export"}, nil
+	case ctx.B2B:
+		return Decision{Category: models.TaxCategoryOutOfScope, ExemptionCode: "VATEX-EU-O", NoteKey: "vat_exemption_non_eu"}, nil
+	}
+	return r.domestic(item), nil
+}
+
+// domestic returns the seller-country treatment for a tax class.
+func (r *VATRules) domestic(item Item) Decision {
+	switch item.TaxClass {
+	case models.TaxClassReduced:
+		return Decision{Rate: r.Reduced, Category: models.TaxCategoryStandard}
+	case models.TaxClassSecondReduced:
+		if r.SecondReduced.IsZero() {
+			return Decision{Rate: r.Reduced, Category: models.TaxCategoryStandard}
+		}
+		return Decision{Rate: r.SecondReduced, Category: models.TaxCategoryStandard}
+	case models.TaxClassZero:
+		return Decision{Category: models.TaxCategoryZero}
+	case models.TaxClassExempt:
+		return Decision{Category: models.TaxCategoryExempt, NoteKey: "vat_exemption_other"}
+	default:
+		return Decision{Rate: r.Standard, Category: models.TaxCategoryStandard}
+	}
+}
+
+// SalesTaxRules is a destination-based sales tax rule set. Tangible goods are taxed at
+// the buyer state's rate; services and digital products, and any sale to a buyer
+// outside the country, are not subject to sales tax.
+type SalesTaxRules struct {
+	Code       string
+	StateRates map[string]decimal.Decimal // Keyed by postal abbreviation
+}
+
+// UnitedStates holds the statewide base sales tax rates. Local rates are not included.
+var UnitedStates = &SalesTaxRules{
+	Code: "US",
+	StateRates: map[string]decimal.Decimal{
+		"AL": decimal.NewFromInt(4), "AZ": decimal.RequireFromString("5.6"), "AR": decimal.RequireFromString("6.5"),
+		"CA": decimal.RequireFromString("7.25"), "CO": decimal.RequireFromString("2.9"), "CT": decimal.RequireFromString("6.35"),
+		"FL": decimal.NewFromInt(6), "GA": decimal.NewFromInt(4), "HI": decimal.NewFromInt(4),
+		"ID": decimal.NewFromInt(6), "IL": decimal.RequireFromString("6.25"), "IN": decimal.NewFromInt(7),
+		"IA": decimal.NewFromInt(6), "KS": decimal.RequireFromString("6.5"), "KY": decimal.NewFromInt(6),
+		"LA": decimal.RequireFromString("4.45"), "ME": decimal.RequireFromString("5.5"), "MD": decimal.NewFromInt(6),
+		"MA": decimal.RequireFromString("6.25"), "MI": decimal.NewFromInt(6), "MN": decimal.RequireFromString("6.875"),
+		"MS": decimal.NewFromInt(7), "MO": decimal.RequireFromString("4.225"), "NE": decimal.RequireFromString("5.5"),
+		"NV": decimal.RequireFromString("6.85"), "NJ": decimal.RequireFromString("6.625"), "NM": decimal.RequireFromString("4.875"),
+		"NY": decimal.NewFromInt(4), "NC": decimal.RequireFromString("4.75"), "ND": decimal.NewFromInt(5),
+		"OH": decimal.RequireFromString("5.75"), "OK": decimal.RequireFromString("4.5"), "PA": decimal.NewFromInt(6),
+		"RI": decimal.NewFromInt(7), "SC": decimal.NewFromInt(6), "SD": decimal.RequireFromString("4.2"),
+		"TN": decimal.NewFromInt(7), "TX": decimal.RequireFromString("6.25"), "UT": decimal.RequireFromString("6.1"),
+		"VT": decimal.NewFromInt(6), "VA": decimal.RequireFromString("5.3"), "WA": decimal.RequireFromString("6.5"),
+		"WV": decimal.NewFromInt(6), "WI": decimal.NewFromInt(5), "WY": decimal.NewFromInt(4),
+		"DC": decimal.NewFromInt(6),
+	},
+}
+
+// Country implements RuleSet.
+func (r *SalesTaxRules) Country() string { return r.Code }
+
+// Decide implements RuleSet.
+func (r *SalesTaxRules) Decide(ctx Context, item Item) (Decision, error) {
+	if ctx.BuyerCountry != r.Code {
+		return Decision{Category: models.TaxCategoryOutOfScope}, nil
+	}
+	if item.ProductType == models.ProductServices || item.ProductType == models.ProductDigital || item.TaxClass == models.TaxClassExempt {
+		return Decision{Category: models.TaxCategoryOutOfScope}, nil
+	}
+	rate, ok := r.StateRates[strings.ToUpper(strings.TrimSpace(ctx.BuyerState))]
+	if !ok || rate.IsZero() {
+		// States without a statewide sales tax (AK, DE, MT, NH, OR) or unknown regions
+		return Decision{Category: models.TaxCategoryOutOfScope}, nil
+	}
+	return Decision{Rate: rate, Category: models.TaxCategoryStandard}, nil
+}
+
+var (
+	_ RuleSet = (*VATRules)(nil)
+	_ RuleSet = (*SalesTaxRules)(nil)
+)
@@ -0,0 +1,220 @@
+// Package tax decides VAT and sales tax treatment for invoice lines.
+//
+// Tax rules are grouped into rule sets per seller jurisdiction and registered with an
+// Engine. The engine derives the transaction context (seller and buyer country, B2B
+// status, product type) from the invoice and asks the seller's rule set for a rate,
+// an EN 16931 category and, where required, an exemption note.
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"invoiceformats/pkg/models"
+)
+
+var (
+	// ErrNoRuleSet is returned when no rule set is registered for the seller's country.
+	ErrNoRuleSet = errors.New("no tax rule set registered")
+	// ErrRateConflict is returned when a line states a tax rate its category forbids.
+	ErrRateConflict = errors.New("tax rate conflicts with tax category")
+)
+
+// Context describes the parties of a taxable transaction.
+type Context struct {
+	SellerCountry string // ISO 3166-1 alpha-2
+	SellerVATID   string
+	BuyerCountry  string // ISO 3166-1 alpha-2
+	BuyerState    string // Region for sub-national taxes (US sales tax)
+	BuyerVATID    string
+	B2B           bool
+	Date          time.Time
+	Exemption     models.VATExemptionType // Invoice-level exemption chosen by the user
+}
+
+// Item describes what a line supplies.
+type Item struct {
+	ProductType models.ProductType
+	TaxClass    models.TaxClass
+}
+
+// Decision is the tax treatment for one line.
+type Decision struct {
+	Rate          decimal.Decimal
+	Category      models.TaxCategory
+	ExemptionCode string // VATEX code (BT-121) for exempt and zero-rated categories
+	NoteKey       string // Locale key of the note required on the invoice, if any
+}
+
+// zeroRated reports whether the line must carry no tax: the decision is a zero rating
+// or an exemption the invoice has to state. Sales tax decisions that only find no tax
+// due in the rule set's tables are not, so explicit rates for local taxes still apply.
+func (d Decision) zeroRated() bool {
+	return d.Category == models.TaxCategoryZero || d.NoteKey != ""
+}
+
+// RuleSet decides taxes for supplies made by sellers established in one country.
+type RuleSet interface {
+	// Country returns the ISO code of the seller jurisdiction.
+	Country() string
+	// Decide returns the treatment for an item in the given context.
+	Decide(ctx Context, item Item) (Decision, error)
+}
+
+// Engine dispatches tax decisions to the rule set of the seller's country.
+type Engine struct {
+	rules map[string]RuleSet
+}
+
+// NewEngine creates an engine with the given rule sets registered.
+func NewEngine(sets ...RuleSet) *Engine {
+	e := &Engine{rules: make(map[string]RuleSet)}
+	for _, rs := range sets {
+		e.Register(rs)
+	}
+	return e
+}
+
+// DefaultEngine returns an engine with all built-in rule sets registered.
+func DefaultEngine() *Engine {
+	return NewEngine(Germany, Austria, France, Netherlands, UnitedKingdom, Switzerland, UnitedStates)
+}
+
+// Register adds or replaces the rule set for its country.
+func (e *Engine) Register(rs RuleSet) {
+	e.rules[rs.Country()] = rs
+}
+
+// Countries returns the seller countries with a registered rule set.
+func (e *Engine) Countries() []string {
+	codes := make([]string, 0, len(e.rules))
+	for code := range e.rules {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Supports reports whether a rule set is registered for the seller country.
+func (e *Engine) Supports(sellerCountry string) bool {
+	_, ok := e.rules[CountryCode(sellerCountry)]
+	return ok
+}
+
+// Decide returns the tax treatment for an item. An explicit invoice-level exemption
+// takes precedence over the seller's rule set.
+func (e *Engine) Decide(ctx Context, item Item) (Decision, error) {
+	if d, ok := exemptionDecision(ctx.Exemption); ok {
+		return d, nil
+	}
+	rs, ok := e.rules[ctx.SellerCountry]
+	if !ok {
+		return Decision{}, fmt.Errorf("%w for seller country %q", ErrNoRuleSet, ctx.SellerCountry)
+	}
+	return rs.Decide(ctx, item)
+}
+
+// NewContext derives the transaction context from invoice data.
+func NewContext(data *models.InvoiceData) Context {
+	return Context{
+		SellerCountry: CountryCode(data.Provider.Address.Country),
+		SellerVATID:   data.Provider.VATID,
+		BuyerCountry:  CountryCode(data.Client.Address.Country),
+		BuyerState:    data.Client.Address.State,
+		BuyerVATID:    data.Client.VATID,
+		B2B:           data.Client.IsBusiness(),
+		Date:          data.Invoice.Date,
+		Exemption:     data.Invoice.VATExemptionType,
+	}
+}
+
+// Apply decides the tax treatment of every line that does not state a category and
+// updates the line's rate, category and exemption code. The category is derived from
+// the parties first and the rate follows from it: an explicit non-zero rate is kept
+// for standard-rated lines, and rejected for lines that must carry no tax. A line
+// without a rate is exempt if the invoice states a free-text exemption reason, and
+// otherwise gets the rate of the rule set. Lines with an explicit category are kept as
+// given, unless their rate conflicts with it. Notes are passed through translate,
+// which may be nil, and set as the exemption reason of lines that have none. It
+// returns the notes the invoice must carry, in line order and without duplicates.
+func (e *Engine) Apply(data *models.InvoiceData, translate func(key string) string) ([]string, error) {
+	ctx := NewContext(data)
+	var notes []string
+	seen := make(map[string]bool)
+	for i := range data.Invoice.Lines {
+		line := &data.Invoice.Lines[i]
+		if line.TaxCategory != "" {
+			if line.TaxCategory != models.TaxCategoryStandard && !line.TaxRate.IsZero() {
+				return nil, fmt.Errorf("line %d (%s): %w: tax rate %s with category %s", i+1, line.Description, ErrRateConflict, line.TaxRate, line.TaxCategory)
+			}
+			continue
+		}
+		d, err := e.Decide(ctx, Item{ProductType: line.ProductType, TaxClass: line.TaxClass})
+		if err != nil {
+			return nil, fmt.Errorf("line %d (%s): %w", i+1, line.Description, err)
+		}
+		switch {
+		case !line.TaxRate.IsZero() && d.zeroRated():
+			return nil, fmt.Errorf("line %d (%s): %w: tax rate %s with category %s", i+1, line.Description, ErrRateConflict, line.TaxRate, d.Category)
+		case !line.TaxRate.IsZero():
+			// Explicit rate of a taxed supply, e.g. including local taxes
+			line.TaxCategory = models.TaxCategoryStandard
+			continue
+		case d.Category == models.TaxCategoryStandard && data.Invoice.VATExemptionReason != "":
+			line.TaxCategory = models.TaxCategoryExempt
+			if line.TaxExemptionReason == "" {
+				line.TaxExemptionReason = data.Invoice.VATExemptionReason
+			}
+			continue
+		}
+		line.TaxRate = d.Rate
+		line.TaxCategory = d.Category
+		if line.TaxExemptionCode == "" {
+			line.TaxExemptionCode = d.ExemptionCode
+		}
+		if d.NoteKey == "" {
+			continue
+		}
+		note := d.NoteKey
+		if translate != nil {
+			note = translate(d.NoteKey)
+		}
+		if line.TaxExemptionReason == "" {
+			line.TaxExemptionReason = note
+		}
+		if !seen[note] {
+			seen[note] = true
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+// exemptionDecision maps an invoice-level exemption type to a decision.
+func exemptionDecision(t models.VATExemptionType) (Decision, bool) {
+	switch t {
+	case models.VATExemptionSmallBusiness:
+		return Decision{Category: models.TaxCategoryExempt, NoteKey: "vat_exemption_small_business"}, true
+	case models.VATExemptionReverseCharge:
+		return Decision{Category: models.TaxCategoryReverseCharge, ExemptionCode: "VATEX-EU-AE", NoteKey: "vat_exemption_reverse_charge"}, true
+	case models.VATExemptionIntraCommunity:
+		return Decision{Category: models.TaxCategoryIntraCommunity, ExemptionCode: "VATEX-EU-IC", NoteKey: "vat_exemption_intra_community"}, true
+	case models.VATExemptionExport:
+		return Decision{Category: models.TaxCategoryExport, ExemptionCode: "VATEX-EU-G", NoteKey: "vat_exemption_export"}, true
+	case models.VATExemptionNonEU:
+		return Decision{Category: models.TaxCategoryOutOfScope, ExemptionCode: "VATEX-EU-O", NoteKey: "vat_exemption_non_eu"}, true
+	case models.VATExemptionEducation:
+		return Decision{Category: models.TaxCategoryExempt, NoteKey: "vat_exemption_education"}, true
+	case models.VATExemptionMedical:
+		return Decision{Category: models.TaxCategoryExempt, NoteKey: "vat_exemption_medical"}, true
+	case models.VATExemptionFinancial:
+		return Decision{Category: models.TaxCategoryExempt, NoteKey: "vat_exemption_financial"}, true
+	case models.VATExemptionOther:
+		return Decision{Category: models.TaxCategoryExempt, NoteKey: "vat_exemption_other"}, true
+	}
+	return Decision{}, false
+}
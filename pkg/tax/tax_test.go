@@ -0,0 +1,237 @@
+package tax
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/pkg/models"
+)
+
+func invoice(seller, buyer string, lines ...models.InvoiceLine) *models.InvoiceData {
+	return &models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: seller}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: buyer}},
+		Invoice:  models.InvoiceDetails{Lines: lines},
+	}
+}
+
+func TestEngine_GermanRatesByTaxClass(t *testing.T) {
+	data := invoice("Germany", "Germany",
+		models.InvoiceLine{Description: "Consulting"},
+		models.InvoiceLine{Description: "Books", TaxClass: models.TaxClassReduced},
+		models.InvoiceLine{Description: "Rent", TaxClass: models.TaxClassExempt},
+	)
+
+	notes, err := DefaultEngine().Apply(data, nil)
+	require.NoError(t, err)
+
+	lines := data.Invoice.Lines
+	assert.True(t, decimal.NewFromInt(19).Equal(lines[0].TaxRate), "got %s", lines[0].TaxRate)
+	assert.Equal(t, models.TaxCategoryStandard, lines[0].TaxCategory)
+	assert.True(t, decimal.NewFromInt(7).Equal(lines[1].TaxRate), "got %s", lines[1].TaxRate)
+	assert.True(t, lines[2].TaxRate.IsZero())
+	assert.Equal(t, models.TaxCategoryExempt, lines[2].TaxCategory)
+	assert.Equal(t, []string{"vat_exemption_other"}, notes)
+}
+
+func TestEngine_SecondReducedFallsBackToReduced(t *testing.T) {
+	d, err := DefaultEngine().Decide(Context{SellerCountry: "DE"}, Item{TaxClass: models.TaxClassSecondReduced})
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(7).Equal(d.Rate), "got %s", d.Rate)
+
+	d, err = DefaultEngine().Decide(Context{SellerCountry: "FR"}, Item{TaxClass: models.TaxClassSecondReduced})
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(10).Equal(d.Rate), "got %s", d.Rate)
+}
+
+func TestEngine_ExemptionOverridesRuleSet(t *testing.T) {
+	data := invoice("DE", "DE", models.InvoiceLine{Description: "Design"}, models.InvoiceLine{Description: "Hosting"})
+	data.Invoice.VATExemptionType = models.VATExemptionSmallBusiness
+
+	notes, err := DefaultEngine().Apply(data, nil)
+	require.NoError(t, err)
+	for _, line := range data.Invoice.Lines {
+		assert.True(t, line.TaxRate.IsZero())
+		assert.Equal(t, models.TaxCategoryExempt, line.TaxCategory)
+	}
+	assert.Equal(t, []string{"vat_exemption_small_business"}, notes)
+}
+
+func TestEngine_USSalesTax(t *testing.T) {
+	engine := DefaultEngine()
+	ctx := Context{SellerCountry: "US", BuyerCountry: "US", BuyerState: "ca"}
+
+	d, err := engine.Decide(ctx, Item{ProductType: models.ProductGoods})
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("7.25").Equal(d.Rate), "got %s", d.Rate)
+	assert.Equal(t, models.TaxCategoryStandard, d.Category)
+
+	d, err = engine.Decide(ctx, Item{ProductType: models.ProductServices})
+	require.NoError(t, err)
+	assert.True(t, d.Rate.IsZero())
+	assert.Equal(t, models.TaxCategoryOutOfScope, d.Category)
+
+	ctx.BuyerState = "OR"
+	d, err = engine.Decide(ctx, Item{ProductType: models.ProductGoods})
+	require.NoError(t, err)
+	assert.Equal(t, models.TaxCategoryOutOfScope, d.Category)
+
+	d, err = engine.Decide(Context{SellerCountry: "US", BuyerCountry: "DE"}, Item{ProductType: models.ProductGoods})
+	require.NoError(t, err)
+	assert.Equal(t, models.TaxCategoryOutOfScope, d.Category)
+}
+
+func TestEngine_CrossBorderSupplies(t *testing.T) {
+	engine := DefaultEngine()
+	tests := []struct {
+		name     string
+		ctx      Context
+		item     Item
+		category models.TaxCategory
+		code     string
+		note     string
+	}{
+		{"EU B2B services", Context{SellerCountry: "DE", SellerVATID: "DE123456789", BuyerCountry: "AT", BuyerVATID: "ATU12345678", B2B: true}, Item{ProductType: models.ProductServices}, models.TaxCategoryReverseCharge, "VATEX-EU-AE", "vat_exemption_reverse_charge"},
+		{"EU B2B goods", Context{SellerCountry: "DE", SellerVATID: "DE123456789", BuyerCountry: "FR", BuyerVATID: "FR12345678901", B2B: true}, Item{ProductType: models.ProductGoods}, models.TaxCategoryIntraCommunity, "VATEX-EU-IC", "vat_exemption_intra_community"},
+		{"export of goods", Context{SellerCountry: "DE", BuyerCountry: "AE"}, Item{ProductType: models.ProductGoods}, models.TaxCategoryExport, "VATEX-EU-G", "vat_exemption_export"},
+		{"non-EU B2B services", Context{SellerCountry: "NL", BuyerCountry: "US", B2B: true}, Item{ProductType: models.ProductServices}, models.TaxCategoryOutOfScope, "VATEX-EU-O", "vat_exemption_non_eu"},
+		{"EU B2C services", Context{SellerCountry: "DE", BuyerCountry: "AT"}, Item{ProductType: models.ProductServices}, models.TaxCategoryStandard, "", ""},
+		{"EU goods without VAT ID", Context{SellerCountry: "DE", BuyerCountry: "AT", B2B: true}, Item{ProductType: models.ProductGoods}, models.TaxCategoryStandard, "", ""},
+		{"EU services to a business without VAT ID", Context{SellerCountry: "DE", SellerVATID: "DE123456789", BuyerCountry: "AT", B2B: true}, Item{ProductType: models.ProductServices}, models.TaxCategoryStandard, "", ""},
+		{"EU services without seller VAT ID", Context{SellerCountry: "DE", BuyerCountry: "AT", BuyerVATID: "ATU12345678", B2B: true}, Item{ProductType: models.ProductServices}, models.TaxCategoryStandard, "", ""},
+		{"UK export to EU", Context{SellerCountry: "GB", BuyerCountry: "DE"}, Item{ProductType: models.ProductGoods}, models.TaxCategoryExport, "VATEX-EU-G", "vat_exemption_export"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := engine.Decide(tt.ctx, tt.item)
+			require.NoError(t, err)
+			assert.Equal(t, tt.category, d.Category)
+			assert.Equal(t, tt.code, d.ExemptionCode)
+			assert.Equal(t, tt.note, d.NoteKey)
+			if tt.category != models.TaxCategoryStandard {
+				assert.True(t, d.Rate.IsZero())
+			}
+		})
+	}
+}
+
+func TestEngine_ApplyReverseChargeNote(t *testing.T) {
+	data := invoice("Germany", "Austria",
+		models.InvoiceLine{Description: "Web design", TaxRate: decimal.Zero},
+		models.InvoiceLine{Description: "Hosting"},
+	)
+	data.Provider.VATID = "DE123456789"
+	data.Client.VATID = "ATU12345678"
+
+	translate := func(key string) string { return "note:" + key }
+	notes, err := DefaultEngine().Apply(data, translate)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"note:vat_exemption_reverse_charge"}, notes)
+	for _, line := range data.Invoice.Lines {
+		assert.Equal(t, models.TaxCategoryReverseCharge, line.TaxCategory)
+		assert.Equal(t, "VATEX-EU-AE", line.TaxExemptionCode)
+		assert.Equal(t, "note:vat_exemption_reverse_charge", line.TaxExemptionReason)
+	}
+}
+
+func TestEngine_BusinessWithoutVATID(t *testing.T) {
+	// Reverse charge needs the buyer's VAT ID; without it the service is taxed at home
+	data := invoice("Germany", "Austria", models.InvoiceLine{Description: "Web design"})
+	data.Provider.VATID = "DE123456789"
+	data.Client.Business = true
+
+	notes, err := DefaultEngine().Apply(data, nil)
+	require.NoError(t, err)
+	assert.Empty(t, notes)
+	assert.Equal(t, models.TaxCategoryStandard, data.Invoice.Lines[0].TaxCategory)
+	assert.True(t, data.Invoice.Lines[0].TaxRate.Equal(decimal.NewFromInt(19)))
+	assert.Empty(t, data.Invoice.Lines[0].TaxExemptionCode)
+}
+
+func TestEngine_UnknownSellerCountry(t *testing.T) {
+	engine := DefaultEngine()
+	assert.False(t, engine.Supports("Japan"))
+
+	_, err := engine.Apply(invoice("Japan", "Japan", models.InvoiceLine{Description: "Service"}), nil)
+	assert.True(t, errors.Is(err, ErrNoRuleSet))
+}
+
+func TestEngine_KeepsExplicitRates(t *testing.T) {
+	data := invoice("DE", "DE",
+		models.InvoiceLine{Description: "Custom", TaxRate: decimal.NewFromInt(16)},
+		models.InvoiceLine{Description: "Zero rated", TaxCategory: models.TaxCategoryZero},
+	)
+
+	_, err := DefaultEngine().Apply(data, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(16).Equal(data.Invoice.Lines[0].TaxRate))
+	assert.Equal(t, models.TaxCategoryStandard, data.Invoice.Lines[0].TaxCategory)
+	assert.True(t, data.Invoice.Lines[1].TaxRate.IsZero())
+	assert.Equal(t, models.TaxCategoryZero, data.Invoice.Lines[1].TaxCategory)
+}
+
+func TestEngine_CategoryComesFirst(t *testing.T) {
+	// An explicit rate does not hide a cross-border supply
+	data := invoice("DE", "FR", models.InvoiceLine{Description: "Parts", ProductType: models.ProductGoods})
+	data.Provider.VATID = "DE123456789"
+	data.Client.VATID = "FR12345678901"
+	_, err := DefaultEngine().Apply(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.TaxCategoryIntraCommunity, data.Invoice.Lines[0].TaxCategory)
+
+	data.Invoice.Lines[0] = models.InvoiceLine{Description: "Parts", ProductType: models.ProductGoods, TaxRate: decimal.NewFromInt(19)}
+	_, err = DefaultEngine().Apply(data, nil)
+	assert.ErrorIs(t, err, ErrRateConflict)
+
+	data = invoice("DE", "AE", models.InvoiceLine{Description: "Machine", ProductType: models.ProductGoods, TaxRate: decimal.NewFromInt(19)})
+	_, err = DefaultEngine().Apply(data, nil)
+	assert.ErrorIs(t, err, ErrRateConflict)
+
+	data = invoice("DE", "DE", models.InvoiceLine{Description: "Zero", TaxCategory: models.TaxCategoryZero, TaxRate: decimal.NewFromInt(7)})
+	_, err = DefaultEngine().Apply(data, nil)
+	assert.ErrorIs(t, err, ErrRateConflict)
+
+	// Local sales taxes the rule set does not know may be stated
+	data = invoice("US", "US", models.InvoiceLine{Description: "Goods", ProductType: models.ProductGoods, TaxRate: decimal.RequireFromString("8.5")})
+	_, err = DefaultEngine().Apply(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.TaxCategoryStandard, data.Invoice.Lines[0].TaxCategory)
+}
+
+func TestEngine_ExemptionReasonWithoutRate(t *testing.T) {
+	data := invoice("DE", "DE",
+		models.InvoiceLine{Description: "Consulting"},
+		models.InvoiceLine{Description: "Licence", TaxRate: decimal.NewFromInt(7)},
+	)
+	data.Invoice.VATExemptionReason = "Steuerfrei nach § 4 UStG"
+
+	notes, err := DefaultEngine().Apply(data, nil)
+	require.NoError(t, err)
+	assert.Empty(t, notes)
+	assert.True(t, data.Invoice.Lines[0].TaxRate.IsZero())
+	assert.Equal(t, models.TaxCategoryExempt, data.Invoice.Lines[0].TaxCategory)
+	assert.Equal(t, "Steuerfrei nach § 4 UStG", data.Invoice.Lines[0].TaxExemptionReason)
+	assert.Equal(t, models.TaxCategoryStandard, data.Invoice.Lines[1].TaxCategory)
+}
+
+func TestEngine_RegisterCustomRuleSet(t *testing.T) {
+	engine := NewEngine(&VATRules{Code: "BE", Standard: decimal.NewFromInt(21), Reduced: decimal.NewFromInt(6)})
+	assert.Equal(t, []string{"BE"}, engine.Countries())
+	assert.True(t, engine.Supports("Belgium"))
+
+	d, err := engine.Decide(Context{SellerCountry: "BE"}, Item{})
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(21).Equal(d.Rate))
+}
+
+func TestCountryCode(t *testing.T) {
+	assert.Equal(t, "DE", CountryCode("Deutschland"))
+	assert.Equal(t, "GB", CountryCode(" United Kingdom "))
+	assert.Equal(t, "FR", CountryCode("fr"))
+	assert.True(t, IsEU("Austria"))
+	assert.False(t, IsEU("Switzerland"))
+}
@@ -0,0 +1,166 @@
+// Package tracing provides the OpenTelemetry tracing of invoice generation.
+//
+// The invoice service, the renderer, the PDF generator and the embedded data providers
+// start spans with Start, so a slow generation shows whether the time went into
+// template parsing, Chrome, XML building, XSD validation or embedding. Spans go to the
+// global tracer provider, which Setup configures; without Setup they are dropped.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"invoiceformats/internal/config"
+	"invoiceformats/pkg/models"
+)
+
+// TracerName is the instrumentation name of the spans.
+const TracerName = "invoiceformats"
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Span attribute keys
+const (
+	InvoiceNumber   = attribute.Key("invoice.number")
+	InvoiceType     = attribute.Key("invoice.type")
+	InvoiceLanguage = attribute.Key("invoice.language")
+	InvoiceCurrency = attribute.Key("invoice.currency")
+	InvoiceLines    = attribute.Key("invoice.lines")
+	Template        = attribute.Key("invoice.template")
+	Format          = attribute.Key("invoice.format")
+)
+
+// Stdout is where the stdout exporter writes; tests may replace it.
+var Stdout io.Writer = os.Stdout
+
+// Start starts a span of the invoice generation as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends a span, recording err as its error status if it is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// InvoiceAttributes returns the span attributes describing an invoice. The number is
+// only set once it is known.
+func InvoiceAttributes(data *models.InvoiceData) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		InvoiceType.String(string(data.Invoice.Type)),
+		InvoiceLanguage.String(data.Invoice.Language),
+		InvoiceCurrency.String(data.Invoice.Currency.Code),
+		InvoiceLines.Int(len(data.Invoice.Lines)),
+	}
+	if data.Invoice.Number != "" {
+		attrs = append(attrs, InvoiceNumber.String(data.Invoice.Number))
+	}
+	return attrs
+}
+
+// Setup installs the tracer provider and W3C trace context propagation configured by
+// cfg and returns the function that flushes and stops it. Settings left empty fall
+// back to the standard OTEL_* environment variables; without an exporter, tracing
+// stays disabled and the returned function does nothing.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	exporter := cfg.Exporter
+	if exporter == "" {
+		exporter = os.Getenv("OTEL_TRACES_EXPORTER")
+	}
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	switch exporter {
+	case "", ExporterNone:
+		return noop, nil
+	case ExporterStdout, "console":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(Stdout))
+	case ExporterOTLP:
+		exp, err = newOTLPExporter(ctx, cfg)
+	default:
+		return noop, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return noop, fmt.Errorf("create %s trace exporter: %w", exporter, err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "invoicegen"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return noop, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// newOTLPExporter creates the OTLP exporter of cfg.Protocol. Endpoints with a scheme
+// are used as URLs, others as host:port.
+func newOTLPExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	protocol := cfg.Protocol
+	for _, env := range []string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if protocol == "" {
+			protocol = os.Getenv(env)
+		}
+	}
+	isURL := strings.Contains(cfg.Endpoint, "://")
+	switch protocol {
+	case "grpc":
+		var opts []otlptracegrpc.Option
+		if isURL {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		} else if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case "", "http/protobuf":
+		var opts []otlptracehttp.Option
+		if isURL {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		} else if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q", protocol)
+	}
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"invoiceformats/internal/config"
+	"invoiceformats/pkg/models"
+)
+
+func TestSetupStdout(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	var buf bytes.Buffer
+	Stdout = &buf
+	t.Cleanup(func() { Stdout = nil })
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: ExporterStdout, SampleRatio: 1, ServiceName: "test"})
+	require.NoError(t, err)
+	_, span := Start(context.Background(), "invoice.generate", InvoiceNumber.String("RE-1"))
+	End(span, nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, buf.String(), `"Name":"invoice.generate"`)
+	assert.Contains(t, buf.String(), "RE-1")
+	assert.Contains(t, buf.String(), `"Value":"test"`)
+}
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	shutdown, err := Setup(context.Background(), config.TracingConfig{})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), config.TracingConfig{Exporter: "jaeger"})
+	assert.ErrorContains(t, err, `unknown trace exporter "jaeger"`)
+
+	_, err = Setup(context.Background(), config.TracingConfig{Exporter: ExporterOTLP, Protocol: "thrift"})
+	assert.ErrorContains(t, err, `unknown OTLP protocol "thrift"`)
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(TracerName)
+
+	_, span := tracer.Start(context.Background(), "ok")
+	End(span, nil)
+	_, span = tracer.Start(context.Background(), "failed")
+	End(span, errors.New("boom"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boom", spans[1].Status().Description)
+	require.Len(t, spans[1].Events(), 1)
+	assert.Equal(t, "exception", spans[1].Events()[0].Name)
+}
+
+func TestInvoiceAttributes(t *testing.T) {
+	data := &models.InvoiceData{Invoice: models.InvoiceDetails{
+		Language: "de",
+		Currency: models.Currency{Code: "EUR"},
+		Lines:    []models.InvoiceLine{{}, {}},
+	}}
+	attrs := InvoiceAttributes(data)
+	assert.Contains(t, attrs, InvoiceLines.Int(2))
+	assert.Contains(t, attrs, InvoiceCurrency.String("EUR"))
+	assert.NotContains(t, attrs, InvoiceNumber.String(""))
+
+	data.Invoice.Number = "RE-2025-0001"
+	assert.Contains(t, InvoiceAttributes(data), InvoiceNumber.String("RE-2025-0001"))
+}
@@ -64,7 +64,7 @@ func (v *Validator) ValidateInvoiceData(data *models.InvoiceData) error {
 	if data.Invoice.Date.IsZero() {
 		data.Invoice.Date = time.Now()
 	}
-	if data.Invoice.DueDate.IsZero() {
+	if data.Invoice.DueDate.IsZero() && data.Invoice.Type.IsInvoice() {
 		if data.Invoice.PaymentTerms.DueDays > 0 {
 			data.Invoice.DueDate = data.Invoice.Date.AddDate(0, 0, data.Invoice.PaymentTerms.DueDays)
 		} else {
@@ -123,9 +123,15 @@ func (v *Validator) ValidateStruct(s interface{}) error {
 
 // validateBusinessRules performs custom business logic validation
 func (v *Validator) validateBusinessRules(data *models.InvoiceData) error {
-    // Validate dates
-    if !data.Invoice.DueDate.After(data.Invoice.Date) {
-        return fmt.Errorf("due date must be after invoice date")
+    // Validate dates; quotes and order confirmations are not due for payment
+    if data.Invoice.Type.IsInvoice() {
+        if !data.Invoice.DueDate.After(data.Invoice.Date) {
+            return fmt.Errorf("due date must be after invoice date")
+        }
+    } else {
+        if !data.Invoice.ValidUntil.IsZero() && data.Invoice.ValidUntil.Before(data.Invoice.Date) {
+            return fmt.Errorf("valid until date must not be before the %s date", data.Invoice.Type)
+        }
     }
     
     // Validate invoice lines
@@ -133,6 +139,19 @@ func (v *Validator) validateBusinessRules(data *models.InvoiceData) error {
         return fmt.Errorf("invoice must have at least one line item")
     }
     
+    // Validate withholdings
+    for i, w := range data.Invoice.Withholdings {
+        if w.Type == "" {
+            return fmt.Errorf("withholding %d: type is required", i+1)
+        }
+        if !w.Rate.IsPositive() || w.Rate.GreaterThan(decimal.NewFromInt(100)) {
+            return fmt.Errorf("withholding %d: rate must be between 0 and 100", i+1)
+        }
+        if w.Base.IsNegative() {
+            return fmt.Errorf("withholding %d: base must not be negative", i+1)
+        }
+    }
+    
     // Validate totals (this also serves as a sanity check)
     originalTotal := data.Invoice.GrandTotal
     data.Invoice.CalculateTotals()
@@ -140,6 +159,33 @@ func (v *Validator) validateBusinessRules(data *models.InvoiceData) error {
         return fmt.Errorf("invoice totals are inconsistent")
     }
     
+    // Validate preceding invoices and prepayments
+    for i, ref := range data.Invoice.PrecedingInvoices {
+        if ref.Number == "" {
+            return fmt.Errorf("preceding invoice %d: number is required", i+1)
+        }
+        if ref.Amount.IsNegative() {
+            return fmt.Errorf("preceding invoice %s: amount must not be negative", ref.Number)
+        }
+    }
+    if data.Invoice.Type == models.InvoiceTypeFinal && len(data.Invoice.PrecedingInvoices) == 0 {
+        return fmt.Errorf("final invoice must reference the preceding invoices")
+    }
+    if data.Invoice.IsCreditNote() {
+        if len(data.Invoice.PrecedingInvoices) == 0 {
+            return fmt.Errorf("credit note must reference the credited invoice")
+        }
+        if data.Invoice.HasPrepayment() {
+            return fmt.Errorf("credit note cannot deduct prepayments")
+        }
+        if data.Invoice.GrandTotal.IsNegative() {
+            return fmt.Errorf("credit note amounts must be positive")
+        }
+    }
+    if data.Invoice.PrepaidAmount.GreaterThan(data.Invoice.GrandTotal) {
+        return fmt.Errorf("prepaid amount exceeds the invoice total")
+    }
+    
     return nil
 }
 
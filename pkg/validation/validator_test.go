@@ -60,3 +60,85 @@ func TestValidator_ValidateInvoiceData_Invalid(t *testing.T) {
 	err := v.ValidateInvoiceData(&invoice)
 	assert.Error(t, err)
 }
+
+func TestValidator_ValidateInvoiceData_FinalInvoice(t *testing.T) {
+	v := NewValidator()
+	invoice := models.InvoiceData{
+		Provider: models.CompanyInfo{
+			ID:      uuid.New(),
+			Name:    "Provider",
+			Email:   "provider@example.com",
+			Address: models.Address{Street: "123", City: "City", Country: "DE"},
+		},
+		Client: models.ClientInfo{
+			ID:      uuid.New(),
+			Name:    "Client",
+			Email:   "client@example.com",
+			Address: models.Address{Street: "456", City: "Town", Country: "DE"},
+		},
+		Invoice: models.InvoiceDetails{
+			ID:       uuid.New(),
+			Number:   "INV-003",
+			Type:     models.InvoiceTypeFinal,
+			Date:     time.Now(),
+			DueDate:  time.Now().AddDate(0, 0, 30),
+			Currency: models.Currency{Code: "EUR", Symbol: "€", Rate: decimal.NewFromInt(1)},
+			Lines: []models.InvoiceLine{{
+				ID:          uuid.New(),
+				Description: "Project",
+				Quantity:    decimal.NewFromInt(1),
+				UnitPrice:   decimal.NewFromFloat(1000),
+				TaxRate:     decimal.NewFromFloat(19),
+			}},
+		},
+	}
+	invoice.Invoice.CalculateTotals()
+	assert.Error(t, v.ValidateInvoiceData(&invoice), "final invoice without preceding invoices")
+
+	invoice.Invoice.PrecedingInvoices = []models.InvoiceReference{{Number: "INV-001", Amount: decimal.NewFromInt(2000)}}
+	assert.Error(t, v.ValidateInvoiceData(&invoice), "prepaid amount above total")
+
+	invoice.Invoice.PrecedingInvoices[0].Amount = decimal.NewFromInt(595)
+	assert.NoError(t, v.ValidateInvoiceData(&invoice))
+	assert.True(t, decimal.NewFromInt(595).Equal(invoice.Invoice.AmountDue), "got %s", invoice.Invoice.AmountDue)
+}
+
+func TestValidator_ValidateInvoiceData_Quote(t *testing.T) {
+	v := NewValidator()
+	date := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	invoice := models.InvoiceData{
+		Provider: models.CompanyInfo{
+			ID:      uuid.New(),
+			Name:    "Provider",
+			Email:   "provider@example.com",
+			Address: models.Address{Street: "123", City: "City", Country: "DE"},
+		},
+		Client: models.ClientInfo{
+			ID:      uuid.New(),
+			Name:    "Client",
+			Email:   "client@example.com",
+			Address: models.Address{Street: "456", City: "Town", Country: "DE"},
+		},
+		Invoice: models.InvoiceDetails{
+			ID:         uuid.New(),
+			Number:     "Q-2025-0001",
+			Type:       models.InvoiceTypeQuote,
+			Date:       date,
+			ValidUntil: date.AddDate(0, 0, 30),
+			Currency:   models.Currency{Code: "EUR", Symbol: "€", Rate: decimal.NewFromInt(1)},
+			Lines: []models.InvoiceLine{{
+				ID:          uuid.New(),
+				Description: "Project",
+				Quantity:    decimal.NewFromInt(1),
+				UnitPrice:   decimal.NewFromFloat(1000),
+				TaxRate:     decimal.NewFromFloat(19),
+			}},
+		},
+	}
+	invoice.Invoice.CalculateTotals()
+	assert.NoError(t, v.ValidateInvoiceData(&invoice), "quotes have no due date")
+	assert.True(t, invoice.Invoice.DueDate.IsZero())
+
+	invoice.Invoice.ValidUntil = date.AddDate(0, 0, -1)
+	assert.Error(t, v.ValidateInvoiceData(&invoice), "valid until before the quote date")
+}
@@ -0,0 +1,375 @@
+// Package webhook delivers invoice lifecycle events to HTTP endpoints.
+//
+// Each endpoint subscribes to event types and may have a secret. Events are posted as
+// JSON; with a secret the body is signed with HMAC-SHA256 in the Invoicegen-Signature
+// header as "t=<unix time>,v1=<hex signature of "<unix time>.<body>">", which
+// receivers check with Verify. Failed deliveries are retried with exponential backoff,
+// and every attempt is appended to the delivery log.
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"invoiceformats/pkg/logging"
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/repository"
+)
+
+// Headers of a delivery.
+const (
+	SignatureHeader = "Invoicegen-Signature"
+	EventHeader     = "Invoicegen-Event"
+	DeliveryHeader  = "Invoicegen-Delivery" // Event ID, the same for all attempts
+)
+
+// ErrSignature is returned by Verify for missing, invalid or expired signatures.
+var ErrSignature = errors.New("invalid webhook signature")
+
+// EventType names a lifecycle event.
+type EventType string
+
+const (
+	EventGenerated EventType = "invoice.generated"
+	EventSent      EventType = "invoice.sent"
+	EventPaid      EventType = "invoice.paid"
+	EventOverdue   EventType = "invoice.overdue"
+	EventCancelled EventType = "invoice.cancelled"
+	EventAccepted  EventType = "invoice.accepted" // Quotes
+	EventDeclined  EventType = "invoice.declined" // Quotes
+	// EventAll subscribes an endpoint to all events.
+	EventAll EventType = "*"
+)
+
+// EventTypes lists the event types.
+var EventTypes = []EventType{EventGenerated, EventSent, EventPaid, EventOverdue, EventCancelled, EventAccepted, EventDeclined}
+
+// IsValid reports whether t is an event type or EventAll.
+func (t EventType) IsValid() bool {
+	if t == EventAll {
+		return true
+	}
+	for _, et := range EventTypes {
+		if t == et {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusEvent returns the event of a status change; drafts have none.
+func StatusEvent(status models.InvoiceStatus) (EventType, bool) {
+	t := EventType("invoice." + string(status))
+	return t, status != models.StatusDraft && t.IsValid()
+}
+
+// Invoice is the invoice summary of an event.
+type Invoice struct {
+	Number     string               `json:"number"`
+	Type       models.InvoiceType   `json:"type,omitempty"`
+	Status     models.InvoiceStatus `json:"status"`
+	Client     string               `json:"client"`
+	Date       time.Time            `json:"date"`
+	DueDate    time.Time            `json:"due_date"`
+	Currency   string               `json:"currency"`
+	GrandTotal decimal.Decimal      `json:"grand_total"`
+	AmountDue  decimal.Decimal      `json:"amount_due"`
+	PaidAmount decimal.Decimal      `json:"paid_amount"`
+}
+
+// Event is the payload of a delivery.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Invoice   Invoice   `json:"invoice"`
+}
+
+// NewEvent creates an event for an invoice record.
+func NewEvent(t EventType, rec *repository.Record) Event {
+	return Event{
+		ID:        uuid.NewString(),
+		Type:      t,
+		CreatedAt: time.Now().UTC(),
+		Invoice: Invoice{
+			Number:     rec.Number,
+			Type:       rec.Type,
+			Status:     rec.Status,
+			Client:     rec.Client,
+			Date:       rec.Date,
+			DueDate:    rec.DueDate,
+			Currency:   rec.Currency,
+			GrandTotal: rec.GrandTotal,
+			AmountDue:  rec.AmountDue,
+			PaidAmount: rec.PaidAmount,
+		},
+	}
+}
+
+// Endpoint receives the events it subscribes to.
+type Endpoint struct {
+	URL    string
+	Events []EventType // Subscribed events; EventAll for all
+	Secret string      // Signs deliveries if set
+}
+
+// Accepts reports whether the endpoint subscribes to the event type.
+func (e Endpoint) Accepts(t EventType) bool {
+	for _, et := range e.Events {
+		if et == t || et == EventAll {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is an attempt to deliver an event, as kept in the delivery log.
+type Delivery struct {
+	EventID    string    `json:"event_id"`
+	Event      EventType `json:"event"`
+	Invoice    string    `json:"invoice"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	At         time.Time `json:"at"`
+	DurationMS int64     `json:"duration_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Delivered  bool      `json:"delivered"`
+}
+
+// Options configure a Dispatcher.
+type Options struct {
+	MaxAttempts int           // Attempts per endpoint and event; default 4
+	RetryDelay  time.Duration // Delay before the first retry, doubled for each further one; default 1s
+	Timeout     time.Duration // Timeout of one attempt; default 10s
+	LogFile     string        // Delivery log (JSON lines); empty keeps none
+}
+
+// Dispatcher delivers events to the subscribed endpoints.
+type Dispatcher struct {
+	endpoints []Endpoint
+	opts      Options
+	logger    logging.Logger
+	client    *http.Client
+	sleep     func(time.Duration)
+	mu        sync.Mutex // Serializes writes to the delivery log
+}
+
+// NewDispatcher creates a dispatcher for the endpoints. Endpoints need an http or https
+// URL and known event types.
+func NewDispatcher(endpoints []Endpoint, opts Options, logger logging.Logger) (*Dispatcher, error) {
+	for i, ep := range endpoints {
+		if !strings.HasPrefix(ep.URL, "http://") && !strings.HasPrefix(ep.URL, "https://") {
+			return nil, fmt.Errorf("endpoint %d: url %q must be http or https", i+1, ep.URL)
+		}
+		if len(ep.Events) == 0 {
+			return nil, fmt.Errorf("endpoint %s: no events", ep.URL)
+		}
+		for _, t := range ep.Events {
+			if !t.IsValid() {
+				return nil, fmt.Errorf("endpoint %s: unknown event %q", ep.URL, t)
+			}
+		}
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 4
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &Dispatcher{
+		endpoints: endpoints,
+		opts:      opts,
+		logger:    logger,
+		client:    &http.Client{Timeout: opts.Timeout},
+		sleep:     time.Sleep,
+	}, nil
+}
+
+// Endpoints returns the configured endpoints.
+func (d *Dispatcher) Endpoints() []Endpoint {
+	return d.endpoints
+}
+
+// Dispatch delivers the event to every endpoint that subscribes to it, retrying failed
+// deliveries, and returns the last attempt per endpoint. The error joins the failures of
+// endpoints the event could not be delivered to.
+func (d *Dispatcher) Dispatch(evt Event) ([]Delivery, error) {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return nil, err
+	}
+	var results []Delivery
+	var errs []error
+	for _, ep := range d.endpoints {
+		if !ep.Accepts(evt.Type) {
+			continue
+		}
+		var del Delivery
+		for attempt := 1; attempt <= d.opts.MaxAttempts; attempt++ {
+			if attempt > 1 {
+				d.sleep(d.opts.RetryDelay << (attempt - 2))
+			}
+			del = d.deliver(ep, evt, body, attempt)
+			d.record(del)
+			if del.Delivered {
+				break
+			}
+			d.logger.Warn("Webhook delivery failed", &logging.LogFields{URL: ep.URL, InvoiceNum: evt.Invoice.Number, Status: string(evt.Type) + ", attempt " + strconv.Itoa(attempt), Error: del.Error})
+		}
+		if !del.Delivered {
+			errs = append(errs, fmt.Errorf("%s to %s: %s", evt.Type, ep.URL, del.Error))
+		}
+		results = append(results, del)
+	}
+	return results, errors.Join(errs...)
+}
+
+// deliver posts the event once.
+func (d *Dispatcher) deliver(ep Endpoint, evt Event, body []byte, attempt int) Delivery {
+	start := time.Now()
+	del := Delivery{EventID: evt.ID, Event: evt.Type, Invoice: evt.Invoice.Number, URL: ep.URL, Attempt: attempt, At: start.UTC()}
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		del.Error = err.Error()
+		return del
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "invoicegen-webhook")
+	req.Header.Set(EventHeader, string(evt.Type))
+	req.Header.Set(DeliveryHeader, evt.ID)
+	if ep.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(ep.Secret, start, body))
+	}
+	resp, err := d.client.Do(req)
+	del.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		del.Error = err.Error()
+		return del
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	del.StatusCode = resp.StatusCode
+	del.Delivered = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !del.Delivered {
+		del.Error = resp.Status
+	}
+	return del
+}
+
+// record appends a delivery to the delivery log.
+func (d *Dispatcher) record(del Delivery) {
+	if d.opts.LogFile == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	line, err := json.Marshal(del)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(d.opts.LogFile), 0755)
+	}
+	var f *os.File
+	if err == nil {
+		f, err = os.OpenFile(d.opts.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	}
+	if err == nil {
+		_, err = f.Write(append(line, '\n'))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		d.logger.Error("Failed to write webhook delivery log", &logging.LogFields{File: d.opts.LogFile, Error: err.Error()})
+	}
+}
+
+// ReadLog reads the deliveries of a delivery log, oldest first. A missing log has none.
+func ReadLog(path string) ([]Delivery, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var deliveries []Delivery
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var del Delivery
+		if err := json.Unmarshal(scanner.Bytes(), &del); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		deliveries = append(deliveries, del)
+	}
+	return deliveries, scanner.Err()
+}
+
+// Sign returns the signature header of a body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature header of a received body. Signatures older than
+// tolerance are rejected to prevent replays; a zero tolerance accepts any age.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sigs = append(sigs, value)
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrSignature)
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrSignature)
+		}
+	}
+	want := signature(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrSignature
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/repository"
+	"invoiceformats/testutils"
+)
+
+func sampleRecord() *repository.Record {
+	return &repository.Record{
+		Number:     "RE-2025-0001",
+		Type:       models.InvoiceTypeStandard,
+		Status:     models.StatusPaid,
+		Client:     "Pixel Dynamics GmbH",
+		Currency:   "EUR",
+		GrandTotal: decimal.NewFromInt(119),
+		AmountDue:  decimal.NewFromInt(119),
+		PaidAmount: decimal.NewFromInt(119),
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"type":"invoice.paid"}`)
+	header := Sign("s3cret", time.Now(), body)
+	assert.NoError(t, Verify("s3cret", header, body, 5*time.Minute))
+	assert.ErrorIs(t, Verify("other", header, body, 5*time.Minute), ErrSignature)
+	assert.ErrorIs(t, Verify("s3cret", header, []byte(`{"type":"invoice.sent"}`), 5*time.Minute), ErrSignature)
+	assert.ErrorIs(t, Verify("s3cret", "garbage", body, 0), ErrSignature)
+
+	old := Sign("s3cret", time.Now().Add(-time.Hour), body)
+	assert.ErrorIs(t, Verify("s3cret", old, body, 5*time.Minute), ErrSignature)
+	assert.NoError(t, Verify("s3cret", old, body, 0))
+}
+
+func TestDispatch(t *testing.T) {
+	var calls int32
+	stand := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, Verify("s3cret", r.Header.Get(SignatureHeader), body, time.Minute))
+		assert.Equal(t, "invoice.paid", r.Header.Get(EventHeader))
+		assert.NotEmpty(t, r.Header.Get(DeliveryHeader))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer stand.Close()
+
+	logFile := filepath.Join(t.TempDir(), "webhooks.jsonl")
+	d, err := NewDispatcher([]Endpoint{
+		{URL: stand.URL, Events: []EventType{EventPaid}, Secret: "s3cret"},
+		{URL: stand.URL + "/sent", Events: []EventType{EventSent}},
+	}, Options{LogFile: logFile}, &testutils.TestLogger{})
+	require.NoError(t, err)
+	var delays []time.Duration
+	d.sleep = func(delay time.Duration) { delays = append(delays, delay) }
+
+	results, err := d.Dispatch(NewEvent(EventPaid, sampleRecord()))
+	require.NoError(t, err)
+	require.Len(t, results, 1, "only subscribed endpoints receive the event")
+	assert.True(t, results[0].Delivered)
+	assert.Equal(t, 3, results[0].Attempt)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, delays)
+
+	log, err := ReadLog(logFile)
+	require.NoError(t, err)
+	require.Len(t, log, 3)
+	assert.False(t, log[0].Delivered)
+	assert.Equal(t, http.StatusServiceUnavailable, log[0].StatusCode)
+	assert.True(t, log[2].Delivered)
+	assert.Equal(t, "RE-2025-0001", log[2].Invoice)
+	assert.Equal(t, log[0].EventID, log[2].EventID)
+}
+
+func TestDispatch_Failure(t *testing.T) {
+	stand := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer stand.Close()
+
+	d, err := NewDispatcher([]Endpoint{{URL: stand.URL, Events: []EventType{EventAll}}}, Options{MaxAttempts: 2}, &testutils.TestLogger{})
+	require.NoError(t, err)
+	d.sleep = func(time.Duration) {}
+	results, err := d.Dispatch(NewEvent(EventOverdue, sampleRecord()))
+	assert.ErrorContains(t, err, "invoice.overdue")
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Delivered)
+	assert.Equal(t, 2, results[0].Attempt)
+}
+
+func TestNewDispatcher_Invalid(t *testing.T) {
+	_, err := NewDispatcher([]Endpoint{{URL: "ftp://erp.example", Events: []EventType{EventPaid}}}, Options{}, &testutils.TestLogger{})
+	assert.Error(t, err)
+	_, err = NewDispatcher([]Endpoint{{URL: "https://erp.example", Events: []EventType{"invoice.printed"}}}, Options{}, &testutils.TestLogger{})
+	assert.ErrorContains(t, err, "unknown event")
+}
+
+func TestStatusEvent(t *testing.T) {
+	e, ok := StatusEvent(models.StatusOverdue)
+	assert.True(t, ok)
+	assert.Equal(t, EventOverdue, e)
+	_, ok = StatusEvent(models.StatusDraft)
+	assert.False(t, ok)
+}
@@ -2,6 +2,7 @@
 package xrechnung
 
 import (
+	"context"
 	"fmt"
 	"invoiceformats/pkg/models"
 )
@@ -10,19 +11,19 @@ import (
 type XRechnungProvider struct{}
 
 // GenerateXML generates XRechnung-compliant XML from invoice data.
-func (p *XRechnungProvider) GenerateXML(data models.InvoiceData) ([]byte, error) {
+func (p *XRechnungProvider) GenerateXML(ctx context.Context, data models.InvoiceData) ([]byte, error) {
 	// TODO [context=xrechnung xml, priority=high, effort=2h]: Implement XRechnung XML generation logic
 	return nil, fmt.Errorf("XRechnung XML generation not implemented")
 }
 
 // ValidateXML validates XRechnung XML against schemas and business rules.
-func (p *XRechnungProvider) ValidateXML(xml []byte) error {
+func (p *XRechnungProvider) ValidateXML(ctx context.Context, xml []byte) error {
 	// TODO [context=xrechnung validation, priority=high, effort=1h]: Implement XRechnung XML validation logic
 	return nil
 }
 
 // EmbedXMLIntoPDF embeds XRechnung XML into a PDF document.
-func (p *XRechnungProvider) EmbedXMLIntoPDF(pdf []byte, xml []byte, description string) ([]byte, error) {
+func (p *XRechnungProvider) EmbedXMLIntoPDF(ctx context.Context, pdf []byte, xml []byte, description string) ([]byte, error) {
 	// TODO [context=xrechnung pdf embedding, priority=high, effort=1h]: Implement PDF/A-3 embedding logic
 	return nil, fmt.Errorf("PDF embedding not implemented")
 }
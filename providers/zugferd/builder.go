@@ -48,21 +48,21 @@ func MapInvoiceToXML(inv models.ZUGFeRDInvoice, format InvoiceFormat) (ZUGFeRDIn
 		XmlnsRam: "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:12",
 		XmlnsUdt: "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:15",
 		Context: DocumentContextXML{GuidelineID: inv.Profile},
-		Agreement: TradeAgreementXML{
-			Seller: mapParty(inv.Seller),
-			Buyer:  mapParty(inv.Buyer),
-		},
 		Document: DocumentXML{
 			ID:        inv.DocumentID,
-			IssueDate: DateTimeXML{DateString: inv.IssueDate},
+			IssueDate: issueDate(inv.IssueDate),
 		},
 		Transaction: SupplyChainTradeTransactionXML{
 			LineItems: mapLineItems(inv.LineItems),
-		},
-		Settlement: TradeSettlementXML{
-			GrandTotal: inv.GrandTotal,
-			Currency:   inv.Currency,
-			Taxes:      mapTaxDetails(inv.Taxes),
+			Agreement: TradeAgreementXML{
+				Seller: mapParty(inv.Seller),
+				Buyer:  mapParty(inv.Buyer),
+			},
+			Settlement: TradeSettlementXML{
+				Currency:  inv.Currency,
+				Taxes:     mapTaxDetails(inv.Taxes),
+				Summation: mapSummation(inv),
+			},
 		},
 	}, nil
 }
@@ -93,25 +93,49 @@ func mapLineItems(items []models.LineItem) []LineItemXML {
 	result := make([]LineItemXML, len(items))
 	for i, item := range items {
 		result[i] = LineItemXML{
+			LineID:      fmt.Sprintf("%d", i+1),
 			Description: item.Description,
-			Quantity:    item.Quantity,
+			Quantity:    QuantityXML{Value: item.Quantity},
 			UnitPrice:   fmt.Sprintf("%.2f", item.UnitPrice),
 			Total:       fmt.Sprintf("%.2f", item.Total),
-			TaxRate:     item.TaxRate, // Correctly map TaxRate
+			TaxType:     "VAT",
+			TaxRate:     applicablePercent("", item.TaxRate), // Correctly map TaxRate
 			// TODO [context: Line item XML, priority: medium, effort: medium]: Add product codes, units, etc.
 		}
 	}
 	return result
 }
 
+// mapSummation maps the document totals (BG-22). Line totals are taken as net
+// amounts and the grand total as payable.
+func mapSummation(inv models.ZUGFeRDInvoice) MonetarySummationXML {
+	var lineTotal, taxTotal float64
+	for _, item := range inv.LineItems {
+		lineTotal += item.Total
+	}
+	for _, tax := range inv.Taxes {
+		taxTotal += tax.Amount
+	}
+	return MonetarySummationXML{
+		LineTotal:     fmt.Sprintf("%.2f", lineTotal),
+		TaxBasisTotal: fmt.Sprintf("%.2f", lineTotal),
+		TaxTotals:     []AmountXML{{Value: fmt.Sprintf("%.2f", taxTotal), CurrencyID: inv.Currency}},
+		GrandTotal:    inv.GrandTotal,
+		DuePayable:    inv.GrandTotal,
+	}
+}
+
 func mapTaxDetails(taxes []models.TaxDetail) []TaxDetailXML {
 	result := make([]TaxDetailXML, len(taxes))
 	for i, tax := range taxes {
 		result[i] = TaxDetailXML{
 			Type:   tax.Type,
 			Amount: fmt.Sprintf("%.2f", tax.Amount),
-			Rate:   tax.Rate,
-			// TODO [context: Tax details XML, priority: medium, effort: medium]: Add support for multi-rate VAT, exemptions, etc.
+			Rate:   applicablePercent(tax.Category, tax.Rate),
+			Category:            tax.Category,
+			ExemptionReason:     tax.ExemptionReason,
+			ExemptionReasonCode: tax.ExemptionReasonCode,
+			// TODO [context: Tax details XML, priority: medium, effort: medium]: Add support for multi-rate VAT.
 		}
 	}
 	return result
@@ -53,13 +53,13 @@ func TestMapInvoiceToXML_EN16931_Valid(t *testing.T) {
 	if xml.XmlnsRsm != "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100" {
 		t.Errorf("expected EN16931 namespace, got %s", xml.XmlnsRsm)
 	}
-	if xml.Agreement.Seller.VATID != "DE123456789" {
-		t.Errorf("expected Seller VATID, got %s", xml.Agreement.Seller.VATID)
+	if xml.Transaction.Agreement.Seller.VATID != "DE123456789" {
+		t.Errorf("expected Seller VATID, got %s", xml.Transaction.Agreement.Seller.VATID)
 	}
-	if xml.Agreement.Seller.Address.PostCode != "10115" {
-		t.Errorf("expected Seller PostCode, got %s", xml.Agreement.Seller.Address.PostCode)
+	if xml.Transaction.Agreement.Seller.Address.PostCode != "10115" {
+		t.Errorf("expected Seller PostCode, got %s", xml.Transaction.Agreement.Seller.Address.PostCode)
 	}
-	if len(xml.Transaction.LineItems) == 0 || xml.Transaction.LineItems[0].TaxRate != 19.0 {
+	if len(xml.Transaction.LineItems) == 0 || xml.Transaction.LineItems[0].TaxRate == nil || *xml.Transaction.LineItems[0].TaxRate != 19.0 {
 		t.Errorf("expected line item TaxRate 19.0, got %v", xml.Transaction.LineItems)
 	}
 }
@@ -2,9 +2,12 @@
 package zugferd
 
 import (
+	"context"
+
 	"invoiceformats/pkg/logging"
 	"invoiceformats/pkg/models"
 	"invoiceformats/pkg/pdf"
+	"invoiceformats/pkg/tracing"
 	xmlutil "invoiceformats/pkg/xml"
 )
 
@@ -24,17 +27,23 @@ func NewZUGFeRDProvider(logger logging.Logger) *ZUGFeRDProvider {
 }
 
 // GenerateXML generates ZUGFeRD-compliant XML from invoice data.
-func (p *ZUGFeRDProvider) GenerateXML(data models.InvoiceData) ([]byte, error) {
+func (p *ZUGFeRDProvider) GenerateXML(ctx context.Context, data models.InvoiceData) (xmlData []byte, err error) {
+	_, span := tracing.Start(ctx, "xml.build", tracing.InvoiceNumber.String(data.Invoice.Number))
+	defer func() { tracing.End(span, err) }()
 	return p.Builder.BuildXML(data)
 }
 
 // ValidateXML validates ZUGFeRD XML against schemas and business rules.
-func (p *ZUGFeRDProvider) ValidateXML(xmlData []byte, xsdPath string) error {
+func (p *ZUGFeRDProvider) ValidateXML(ctx context.Context, xmlData []byte, xsdPath string) (err error) {
+	_, span := tracing.Start(ctx, "xml.validate")
+	defer func() { tracing.End(span, err) }()
 	return xmlutil.ValidateXMLWithSchema(xmlData, xsdPath)
 }
 
 // EmbedXMLIntoPDF embeds ZUGFeRD XML into a PDF document.
-func (p *ZUGFeRDProvider) EmbedXMLIntoPDF(pdfBytes, xmlBytes []byte, description string) ([]byte, error) {
+func (p *ZUGFeRDProvider) EmbedXMLIntoPDF(ctx context.Context, pdfBytes, xmlBytes []byte, description string) (out []byte, err error) {
+	_, span := tracing.Start(ctx, "pdf.embed")
+	defer func() { tracing.End(span, err) }()
 	return p.Embedder.EmbedXML(pdfBytes, xmlBytes, description)
 }
 
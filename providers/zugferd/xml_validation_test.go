@@ -1,6 +1,7 @@
 package zugferd_test
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -50,8 +51,11 @@ func TestGeneratedXMLValidatesAgainstSchema(t *testing.T) {
 			},
 		},
 	}
-	// TODO: Fill invoice with valid test data for schema validation
-	xsdPath := "../../xrechnung_schema/resources/cii/16b/xsd/CrossIndustryInvoice_100pD16B.xsd"
+	invoice.Invoice.CalculateTotals()
+	invoice.Invoice.PrecedingInvoices = []models.InvoiceReference{{Number: "INV-000", Date: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), Amount: decimal.NewFromInt(50)}}
+	invoice.Invoice.PrepaidAmount = decimal.NewFromInt(50)
+	invoice.Invoice.BillingPeriod = models.BillingPeriod{Start: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)}
+	xsdPath := requireSchema(t)
 
 	xmlData, err := builder.BuildXML(invoice)
 	if err != nil {
@@ -70,6 +74,19 @@ func TestGeneratedXMLValidatesAgainstSchema(t *testing.T) {
 	}
 }
 
+// schemaPath is the CII D16B schema of the XRechnung resources in external/
+const schemaPath = "../../external/xrechnung_schema/resources/cii/16b/xsd/CrossIndustryInvoice_100pD16B.xsd"
+
+// requireSchema returns the path of the CII schema, skipping the test if the schema
+// resources are not checked out.
+func requireSchema(t *testing.T) string {
+	t.Helper()
+	if _, err := os.Stat(schemaPath); err != nil {
+		t.Skipf("CII schema not available: %v", err)
+	}
+	return schemaPath
+}
+
 func containsNamespace(xml string, ns string) bool {
 	return len(xml) > 0 && ns != "" && (len(xml) >= len(ns) && (xml[0:len(ns)] == ns || containsNamespace(xml[1:], ns)))
 }
@@ -21,25 +21,25 @@ func (b ZUGFeRDBasicXMLBuilder) BuildXML(data models.InvoiceData) ([]byte, error
 	if mapped.Context.GuidelineID == "" {
 		return nil, errors.New("missing Profile (GuidelineID)")
 	}
-	if mapped.Agreement.Seller.Name == "" {
+	if mapped.Transaction.Agreement.Seller.Name == "" {
 		return nil, errors.New("missing Seller name")
 	}
-	if mapped.Agreement.Buyer.Name == "" {
+	if mapped.Transaction.Agreement.Buyer.Name == "" {
 		return nil, errors.New("missing Buyer name")
 	}
 	if mapped.Document.ID == "" {
 		return nil, errors.New("missing DocumentID")
 	}
-	if mapped.Document.IssueDate.DateString == "" {
+	if mapped.Document.IssueDate.DateString.Value == "" {
 		return nil, errors.New("missing IssueDate")
 	}
-	if !regexp.MustCompile(`^\d{8}$`).MatchString(mapped.Document.IssueDate.DateString) {
+	if !regexp.MustCompile(`^\d{8}$`).MatchString(mapped.Document.IssueDate.DateString.Value) {
 		return nil, errors.New("invalid IssueDate format, expected YYYYMMDD")
 	}
-	if mapped.Settlement.GrandTotal == "" {
+	if mapped.Transaction.Settlement.Summation.GrandTotal == "" {
 		return nil, errors.New("missing GrandTotal")
 	}
-	if mapped.Settlement.Currency == "" {
+	if mapped.Transaction.Settlement.Currency == "" {
 		return nil, errors.New("missing Currency")
 	}
 	return xml.MarshalIndent(mapped, "", "  ")
@@ -1,10 +1,13 @@
 package zugferd_test
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"invoiceformats/pkg/models"
+	"invoiceformats/pkg/xmlgen"
 	"invoiceformats/providers/zugferd"
 
 	"github.com/shopspring/decimal"
@@ -87,3 +90,413 @@ func TestBuildBasicXML_InvalidDate(t *testing.T) {
 }
 
 // TODO: Add more tests for line items, taxes, and edge cases as model expands
+
+func TestBuildBasicXML_TaxCurrency(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "US"}},
+		Invoice: models.InvoiceDetails{
+			Number:      "INV-002",
+			Date:        time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC),
+			Currency:    models.Currency{Code: "USD", Rate: decimal.NewFromFloat(0.8)},
+			TaxCurrency: "EUR",
+			Lines: []models.InvoiceLine{
+				{
+					Description: "Service",
+					Quantity:    decimal.NewFromInt(1),
+					UnitPrice:   decimal.NewFromInt(100),
+					TaxRate:     decimal.NewFromInt(19),
+				},
+			},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := string(xmlData)
+	for _, want := range []string{
+		"<ram:TaxCurrencyCode>EUR</ram:TaxCurrencyCode>",
+		`<ram:TaxTotalAmount currencyID="USD">19.00</ram:TaxTotalAmount>`,
+		`<ram:TaxTotalAmount currencyID="EUR">15.20</ram:TaxTotalAmount>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in XML:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuildBasicXML_ReverseChargeCodes(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", VATID: "DE343785817", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", VATID: "ATU12345678", Address: models.Address{Country: "AT"}},
+		Invoice: models.InvoiceDetails{
+			Number:   "INV-003",
+			Date:     time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC),
+			Currency: models.Currency{Code: "EUR"},
+			Lines: []models.InvoiceLine{
+				{
+					Description:        "Service",
+					Quantity:           decimal.NewFromInt(1),
+					UnitPrice:          decimal.NewFromInt(100),
+					TaxCategory:        models.TaxCategoryReverseCharge,
+					TaxExemptionReason: "Reverse charge",
+				},
+			},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := string(xmlData)
+	for _, want := range []string{
+		"<ram:CategoryCode>AE</ram:CategoryCode>",
+		"<ram:ExemptionReason>Reverse charge</ram:ExemptionReason>",
+		"<ram:ExemptionReasonCode>VATEX-EU-AE</ram:ExemptionReasonCode>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in XML:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuildBasicXML_OutOfScopeHasNoRate(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", VATID: "DE343785817", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "US"}},
+		Invoice: models.InvoiceDetails{
+			Number:   "INV-004",
+			Date:     time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC),
+			Currency: models.Currency{Code: "EUR"},
+			Lines: []models.InvoiceLine{
+				{Description: "Consulting", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromInt(100), TaxCategory: models.TaxCategoryOutOfScope, TaxExemptionReason: "Not subject to VAT"},
+				{Description: "Hosting", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromInt(100), TaxRate: decimal.NewFromInt(19), TaxCategory: models.TaxCategoryStandard},
+			},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := string(xmlData)
+	if !strings.Contains(out, "<ram:CategoryCode>O</ram:CategoryCode>") {
+		t.Errorf("expected category O in XML:\n%s", out)
+	}
+	// BR-O-05: only the standard-rated line and its breakdown carry a rate.
+	if got := strings.Count(out, "<ram:RateApplicablePercent>"); got != 2 {
+		t.Errorf("expected 2 RateApplicablePercent elements, got %d:\n%s", got, out)
+	}
+	if strings.Contains(out, "<ram:RateApplicablePercent>0</ram:RateApplicablePercent>") {
+		t.Errorf("expected no zero rate for category O:\n%s", out)
+	}
+}
+
+func TestBuildBasicXML_WithholdingNote(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: "ES"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "ES"}},
+		Invoice: models.InvoiceDetails{
+			Number:   "INV-004",
+			Date:     time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC),
+			Currency: models.Currency{Code: "EUR"},
+			Lines: []models.InvoiceLine{
+				{
+					Description: "Consultoría",
+					Quantity:    decimal.NewFromInt(1),
+					UnitPrice:   decimal.NewFromInt(1000),
+					TaxRate:     decimal.NewFromInt(21),
+				},
+			},
+			Withholdings: []models.Withholding{{Type: models.WithholdingIRPF, Rate: decimal.NewFromInt(15)}},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := string(xmlData)
+	for _, want := range []string{
+		"<ram:Content>IRPF 15%: 150.00 EUR (base 1000.00 EUR, amount due 1060.00 EUR)</ram:Content>",
+		"<ram:SubjectCode>TXD</ram:SubjectCode>",
+		"<ram:GrandTotalAmount>1210.00</ram:GrandTotalAmount>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in XML:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuildBasicXML_FinalInvoiceWithPrepayments(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "DE"}},
+		Invoice: models.InvoiceDetails{
+			Number:   "RE-2025-010",
+			Type:     models.InvoiceTypeFinal,
+			Date:     time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC),
+			Currency: models.Currency{Code: "EUR"},
+			Lines: []models.InvoiceLine{
+				{
+					Description: "Project",
+					Quantity:    decimal.NewFromInt(1),
+					UnitPrice:   decimal.NewFromInt(10000),
+					TaxRate:     decimal.NewFromInt(19),
+				},
+			},
+			PrecedingInvoices: []models.InvoiceReference{
+				{Number: "RE-2025-004", Date: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Amount: decimal.NewFromInt(3570)},
+				{Number: "RE-2025-007", Amount: decimal.NewFromInt(3570)},
+			},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := string(xmlData)
+	for _, want := range []string{
+		"<ram:TypeCode>380</ram:TypeCode>",
+		"<ram:TotalPrepaidAmount>7140.00</ram:TotalPrepaidAmount>",
+		"<ram:DuePayableAmount>4760.00</ram:DuePayableAmount>",
+		"<ram:IssuerAssignedID>RE-2025-004</ram:IssuerAssignedID>",
+		"<ram:FormattedIssueDateTime>",
+		`<qdt:DateTimeString format="102">20250701</qdt:DateTimeString>`,
+		"<ram:IssuerAssignedID>RE-2025-007</ram:IssuerAssignedID>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in XML:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuildBasicXML_CreditNote(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "DE"}},
+		Invoice: models.InvoiceDetails{
+			Number:   "RE-2025-007-CN",
+			Type:     models.InvoiceTypeCreditNote,
+			Date:     time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+			Currency: models.Currency{Code: "EUR"},
+			Lines: []models.InvoiceLine{
+				{Description: "Web design", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromInt(100), TaxRate: decimal.NewFromInt(19)},
+			},
+			PrecedingInvoices: []models.InvoiceReference{{Number: "RE-2025-007", Date: time.Date(2025, 7, 13, 0, 0, 0, 0, time.UTC)}},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := string(xmlData)
+	for _, want := range []string{
+		"<ram:TypeCode>381</ram:TypeCode>",
+		"<ram:IssuerAssignedID>RE-2025-007</ram:IssuerAssignedID>",
+		"<ram:DuePayableAmount>119.00</ram:DuePayableAmount>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in XML:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "TotalPrepaidAmount") {
+		t.Errorf("credit note must not carry a prepaid amount:\n%s", out)
+	}
+}
+
+func TestBuildBasicXML_BillingPeriod(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "DE"}},
+		Invoice: models.InvoiceDetails{
+			Number:   "RE-2025-0042",
+			Date:     time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+			Currency: models.Currency{Code: "EUR"},
+			Lines: []models.InvoiceLine{
+				{Description: "Hosting July 2025", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromInt(49), TaxRate: decimal.NewFromInt(19)},
+			},
+			BillingPeriod: models.BillingPeriod{
+				Start: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2025, 7, 31, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := string(xmlData)
+	for _, want := range []string{
+		"<ram:BillingSpecifiedPeriod>",
+		`<ram:StartDateTime>`,
+		`<udt:DateTimeString format="102">20250701</udt:DateTimeString>`,
+		`<udt:DateTimeString format="102">20250731</udt:DateTimeString>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in XML:\n%s", want, out)
+		}
+	}
+
+	inv.Invoice.BillingPeriod = models.BillingPeriod{}
+	xmlData, err = zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(string(xmlData), "BillingSpecifiedPeriod") {
+		t.Errorf("expected no billing period:\n%s", xmlData)
+	}
+}
+
+func TestBuildBasicXML_SellerOrderReference(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "DE"}},
+		Invoice: models.InvoiceDetails{
+			Number:         "RE-2025-0043",
+			Date:           time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+			Currency:       models.Currency{Code: "EUR"},
+			OrderReference: "Q-2025-0007",
+			Lines: []models.InvoiceLine{
+				{Description: "Website relaunch", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromInt(4800), TaxRate: decimal.NewFromInt(19)},
+			},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	compact := strings.Join(strings.Fields(string(xmlData)), "")
+	want := "<ram:SellerOrderReferencedDocument><ram:IssuerAssignedID>Q-2025-0007</ram:IssuerAssignedID></ram:SellerOrderReferencedDocument>"
+	if !strings.Contains(compact, want) {
+		t.Errorf("expected seller order reference %s in:\n%s", want, xmlData)
+	}
+}
+
+func TestBuildBasicXML_ItemIdentifiers(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "DE"}},
+		Invoice: models.InvoiceDetails{
+			Number:   "RE-2025-0044",
+			Date:     time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+			Currency: models.Currency{Code: "EUR"},
+			Lines: []models.InvoiceLine{{
+				Description:        "USB-C docking station",
+				Quantity:           decimal.NewFromInt(2),
+				Unit:               "C62",
+				UnitPrice:          decimal.NewFromFloat(189.90),
+				TaxRate:            decimal.NewFromInt(19),
+				SellerItemID:       "DOCK-1",
+				BuyerItemID:        "MAT-4711",
+				StandardItemID:     "4012345678901",
+				StandardItemScheme: "0160",
+			}},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	compact := strings.Join(strings.Fields(string(xmlData)), "")
+	for _, want := range []string{
+		`<ram:SpecifiedTradeProduct><ram:GlobalIDschemeID="0160">4012345678901</ram:GlobalID><ram:SellerAssignedID>DOCK-1</ram:SellerAssignedID><ram:BuyerAssignedID>MAT-4711</ram:BuyerAssignedID><ram:Name>USB-Cdockingstation</ram:Name></ram:SpecifiedTradeProduct>`,
+		`<ram:BilledQuantityunitCode="C62">2</ram:BilledQuantity>`,
+	} {
+		if !strings.Contains(compact, want) {
+			t.Errorf("expected %s in:\n%s", want, xmlData)
+		}
+	}
+}
+
+func TestBuildBasicXML_MonetarySummation(t *testing.T) {
+	inv := models.InvoiceData{
+		Provider: models.CompanyInfo{Name: "Seller", Address: models.Address{Country: "DE"}},
+		Client:   models.ClientInfo{Name: "Buyer", Address: models.Address{Country: "DE"}},
+		Invoice: models.InvoiceDetails{
+			Number:            "RE-2025-008",
+			Type:              models.InvoiceTypeFinal,
+			Date:              time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+			Currency:          models.Currency{Code: "USD", Rate: decimal.NewFromFloat(0.9)},
+			TaxCurrency:       "EUR",
+			PrecedingInvoices: []models.InvoiceReference{{Number: "RE-2025-004", Amount: decimal.NewFromInt(100)}},
+			Lines: []models.InvoiceLine{
+				{Description: "Design", Quantity: decimal.NewFromInt(2), UnitPrice: decimal.NewFromInt(150), TaxRate: decimal.NewFromInt(19), TaxCategory: models.TaxCategoryStandard},
+				{Description: "Hosting", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromInt(100), TaxRate: decimal.NewFromInt(19), TaxCategory: models.TaxCategoryStandard},
+			},
+		},
+	}
+	inv.Invoice.CalculateTotals()
+
+	xmlData, err := zugferd.ZUGFeRDBasicXMLBuilder{}.BuildXML(inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	doc := xmlgen.ParseXML(t, string(xmlData))
+	childTags := func(path string) []string {
+		elem := doc.Root()
+		if path != "" {
+			elem = xmlgen.FindElementByPath(elem, path)
+		}
+		if elem == nil {
+			t.Fatalf("element %s not found in XML:\n%s", path, xmlData)
+		}
+		var tags []string
+		for _, child := range elem.ChildElements() {
+			tags = append(tags, child.Tag)
+		}
+		return tags
+	}
+
+	// Elements appear in the sequence of the CII D16B schema
+	want := []string{"ExchangedDocumentContext", "ExchangedDocument", "SupplyChainTradeTransaction"}
+	if got := childTags(""); !reflect.DeepEqual(got, want) {
+		t.Errorf("document: got %v, want %v", got, want)
+	}
+	want = []string{"IncludedSupplyChainTradeLineItem", "IncludedSupplyChainTradeLineItem", "ApplicableHeaderTradeAgreement", "ApplicableHeaderTradeDelivery", "ApplicableHeaderTradeSettlement"}
+	if got := childTags("SupplyChainTradeTransaction"); !reflect.DeepEqual(got, want) {
+		t.Errorf("transaction: got %v, want %v", got, want)
+	}
+	want = []string{"TaxCurrencyCode", "InvoiceCurrencyCode", "ApplicableTradeTax", "ApplicableTradeTax", "SpecifiedTradeSettlementHeaderMonetarySummation", "InvoiceReferencedDocument"}
+	if got := childTags("SupplyChainTradeTransaction/ApplicableHeaderTradeSettlement"); !reflect.DeepEqual(got, want) {
+		t.Errorf("settlement: got %v, want %v", got, want)
+	}
+	want = []string{"LineTotalAmount", "TaxBasisTotalAmount", "TaxTotalAmount", "TaxTotalAmount", "GrandTotalAmount", "TotalPrepaidAmount", "DuePayableAmount"}
+	summation := "SupplyChainTradeTransaction/ApplicableHeaderTradeSettlement/SpecifiedTradeSettlementHeaderMonetarySummation"
+	if got := childTags(summation); !reflect.DeepEqual(got, want) {
+		t.Errorf("monetary summation: got %v, want %v", got, want)
+	}
+	want = []string{"AssociatedDocumentLineDocument", "SpecifiedTradeProduct", "SpecifiedLineTradeAgreement", "SpecifiedLineTradeDelivery", "SpecifiedLineTradeSettlement"}
+	if got := childTags("SupplyChainTradeTransaction/IncludedSupplyChainTradeLineItem"); !reflect.DeepEqual(got, want) {
+		t.Errorf("line item: got %v, want %v", got, want)
+	}
+
+	for path, value := range map[string]string{
+		"LineTotalAmount":     "400.00",
+		"TaxBasisTotalAmount": "400.00",
+		"TaxTotalAmount":      "76.00",
+		"GrandTotalAmount":    "476.00",
+		"TotalPrepaidAmount":  "100.00",
+		"DuePayableAmount":    "376.00",
+	} {
+		xmlgen.AssertElementValue(t, doc, summation+"/"+path, value)
+	}
+	xmlgen.AssertElementValue(t, doc, "SupplyChainTradeTransaction/IncludedSupplyChainTradeLineItem/SpecifiedLineTradeSettlement/SpecifiedTradeSettlementLineMonetarySummation/LineTotalAmount", "300.00")
+}
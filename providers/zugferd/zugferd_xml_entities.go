@@ -2,7 +2,12 @@ package zugferd
 
 import (
 	"encoding/xml"
+	"fmt"
 	"invoiceformats/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // ZUGFeRDProfile enumerates supported ZUGFeRD profiles.
@@ -26,6 +31,7 @@ type ZUGFeRDInvoiceXML struct {
 	XmlnsRsm    string   `xml:"xmlns:rsm,attr"`
 	XmlnsRam    string   `xml:"xmlns:ram,attr"`
 	XmlnsUdt    string   `xml:"xmlns:udt,attr"`
+	XmlnsQdt    string   `xml:"xmlns:qdt,attr,omitempty"`
 	Context     DocumentContextXML `xml:"rsm:ExchangedDocumentContext"` // Fixed to use root namespace
 	Document    DocumentXML        `xml:"rsm:ExchangedDocument"`
 	Transaction SupplyChainTradeTransactionXML `xml:"rsm:SupplyChainTradeTransaction"`
@@ -38,66 +44,162 @@ type DocumentContextXML struct {
 
 // TradeAgreementXML for seller/buyer details
 type TradeAgreementXML struct {
-	Seller PartyXML `xml:"ram:SellerTradeParty"`
-	Buyer  PartyXML `xml:"ram:BuyerTradeParty"`
+	Seller      PartyXML              `xml:"ram:SellerTradeParty"`
+	Buyer       PartyXML              `xml:"ram:BuyerTradeParty"`
+	SellerOrder *ReferencedDocumentXML `xml:"ram:SellerOrderReferencedDocument,omitempty"` // BT-14
 }
 
+
 // DocumentXML for document ID and issue date
 type DocumentXML struct {
 	ID        string `xml:"ram:ID"`
+	TypeCode  string `xml:"ram:TypeCode,omitempty"` // BT-3
 	IssueDate DateTimeXML `xml:"ram:IssueDateTime"`
-	Agreement   TradeAgreementXML  `xml:"ram:ApplicableHeaderTradeAgreement"`
-	Settlement  TradeSettlementXML `xml:"ram:ApplicableHeaderTradeSettlement"`
+	Notes     []NoteXML   `xml:"ram:IncludedNote,omitempty"` // BG-1
 }
 
+// DateTimeXML for dates in format 102 (YYYYMMDD)
 type DateTimeXML struct {
-	DateString string `xml:"udt:DateTimeString"`
+	DateString DateStringXML `xml:"udt:DateTimeString"`
+}
+
+// DateStringXML is a date with its UNTDID 2379 format code
+type DateStringXML struct {
+	Value  string `xml:",chardata"`
+	Format string `xml:"format,attr"`
+}
+
+// issueDate returns a date in format 102 from a YYYYMMDD string
+func issueDate(date string) DateTimeXML {
+	return DateTimeXML{DateString: DateStringXML{Value: date, Format: "102"}}
 }
 
-// SupplyChainTradeTransactionXML for line items
+// NoteXML for invoice notes (BT-22) with an optional UNTDID 4451 subject code (BT-21)
+type NoteXML struct {
+	Content     string `xml:"ram:Content"`
+	SubjectCode string `xml:"ram:SubjectCode,omitempty"`
+}
+
+// SupplyChainTradeTransactionXML for line items and the header trade agreement,
+// delivery and settlement
 type SupplyChainTradeTransactionXML struct {
-	LineItems []LineItemXML `xml:"ram:IncludedSupplyChainTradeLineItem"`
+	LineItems  []LineItemXML      `xml:"ram:IncludedSupplyChainTradeLineItem"`
+	Agreement  TradeAgreementXML  `xml:"ram:ApplicableHeaderTradeAgreement"`
+	Delivery   TradeDeliveryXML   `xml:"ram:ApplicableHeaderTradeDelivery"`
+	Settlement TradeSettlementXML `xml:"ram:ApplicableHeaderTradeSettlement"`
 }
 
-// TradeSettlementXML for totals, currency, taxes
+// TradeDeliveryXML for delivery details; the schema requires the element even if empty
+type TradeDeliveryXML struct{}
+
+// TradeSettlementXML for currency, taxes and totals, in the order of the schema
 type TradeSettlementXML struct {
-	GrandTotal string        `xml:"ram:GrandTotalAmount"`
-	Currency   string        `xml:"ram:InvoiceCurrencyCode"`
-	Taxes      []TaxDetailXML `xml:"ram:ApplicableTradeTax"`
+	TaxCurrency string         `xml:"ram:TaxCurrencyCode,omitempty"` // BT-6
+	Currency    string         `xml:"ram:InvoiceCurrencyCode"`
+	Taxes       []TaxDetailXML `xml:"ram:ApplicableTradeTax"`
+	BillingPeriod *BillingPeriodXML `xml:"ram:BillingSpecifiedPeriod,omitempty"` // BG-14
+	Summation   MonetarySummationXML `xml:"ram:SpecifiedTradeSettlementHeaderMonetarySummation"` // BG-22
+	References  []ReferencedDocumentXML `xml:"ram:InvoiceReferencedDocument,omitempty"` // BG-3
+}
+
+// MonetarySummationXML for the document totals (BG-22), in the order of the schema
+type MonetarySummationXML struct {
+	LineTotal      string      `xml:"ram:LineTotalAmount"`                // BT-106
+	ChargeTotal    string      `xml:"ram:ChargeTotalAmount,omitempty"`    // BT-108
+	AllowanceTotal string      `xml:"ram:AllowanceTotalAmount,omitempty"` // BT-107
+	TaxBasisTotal  string      `xml:"ram:TaxBasisTotalAmount"`            // BT-109
+	TaxTotals      []AmountXML `xml:"ram:TaxTotalAmount,omitempty"`       // BT-110 and, for foreign currency, BT-111
+	Rounding       string      `xml:"ram:RoundingAmount,omitempty"`       // BT-114
+	GrandTotal     string      `xml:"ram:GrandTotalAmount"`               // BT-112
+	PrepaidTotal   string      `xml:"ram:TotalPrepaidAmount,omitempty"`   // BT-113
+	DuePayable     string      `xml:"ram:DuePayableAmount"`               // BT-115
+}
+
+// ReferencedDocumentXML for preceding invoice and order references
+type ReferencedDocumentXML struct {
+	ID        string               `xml:"ram:IssuerAssignedID"`                   // BT-25
+	IssueDate *FormattedDateTimeXML `xml:"ram:FormattedIssueDateTime,omitempty"` // BT-26
+}
+
+// BillingPeriodXML for the invoicing period (BG-14)
+type BillingPeriodXML struct {
+	Start *PeriodDateXML `xml:"ram:StartDateTime,omitempty"` // BT-73
+	End   *PeriodDateXML `xml:"ram:EndDateTime,omitempty"`   // BT-74
+}
+
+// PeriodDateXML for unqualified dates in format 102 (YYYYMMDD)
+type PeriodDateXML struct {
+	DateString struct {
+		Value  string `xml:",chardata"`
+		Format string `xml:"format,attr"`
+	} `xml:"udt:DateTimeString"`
+}
+
+// FormattedDateTimeXML for qualified dates in format 102 (YYYYMMDD)
+type FormattedDateTimeXML struct {
+	DateString DateStringXML `xml:"qdt:DateTimeString"`
+}
+
+// AmountXML for amounts that carry an explicit currency
+type AmountXML struct {
+	Value      string `xml:",chardata"`
+	CurrencyID string `xml:"currencyID,attr"`
 }
 
 // PartyXML for invoice parties
 type PartyXML struct {
 	Name    string     `xml:"ram:Name"`
-	VATID   string     `xml:"ram:SpecifiedTaxRegistration>ram:ID,omitempty"`
 	Address AddressXML `xml:"ram:PostalTradeAddress"`
+	VATID   string     `xml:"ram:SpecifiedTaxRegistration>ram:ID,omitempty"`
 	// TODO [context: Party XML, priority: medium, effort: medium]: Add more party details as required by EN-16931
 }
 
 // AddressXML for party addresses
 type AddressXML struct {
+	PostCode string `xml:"ram:PostcodeCode"`
 	Street   string `xml:"ram:LineOne"`
 	City     string `xml:"ram:CityName"`
-	PostCode string `xml:"ram:PostcodeCode"`
 	Country  string `xml:"ram:CountryID"`
 }
 
 // LineItemXML for invoice line items
 type LineItemXML struct {
-	Description string  `xml:"ram:SpecifiedTradeProduct>ram:Name"`
-	Quantity    float64 `xml:"ram:SpecifiedLineTradeDelivery>ram:BilledQuantity"`
-	UnitPrice   string  `xml:"ram:SpecifiedLineTradeAgreement>ram:GrossPriceProductTradePrice>ram:ChargeAmount"`
-	Total       string  `xml:"ram:LineTotalAmount"`
-	TaxRate     float64 `xml:"ram:ApplicableTradeTax>ram:RateApplicablePercent"`
+	LineID      string       `xml:"ram:AssociatedDocumentLineDocument>ram:LineID"`            // BT-126
+	GlobalID    *GlobalIDXML `xml:"ram:SpecifiedTradeProduct>ram:GlobalID,omitempty"`          // BT-157
+	SellerID    string       `xml:"ram:SpecifiedTradeProduct>ram:SellerAssignedID,omitempty"` // BT-155
+	BuyerID     string       `xml:"ram:SpecifiedTradeProduct>ram:BuyerAssignedID,omitempty"`  // BT-156
+	Description string       `xml:"ram:SpecifiedTradeProduct>ram:Name"`
+	UnitPrice   string  `xml:"ram:SpecifiedLineTradeAgreement>ram:NetPriceProductTradePrice>ram:ChargeAmount"` // BT-146
+	Quantity    QuantityXML  `xml:"ram:SpecifiedLineTradeDelivery>ram:BilledQuantity"`
+	TaxType     string  `xml:"ram:SpecifiedLineTradeSettlement>ram:ApplicableTradeTax>ram:TypeCode"`
+	TaxCategory string  `xml:"ram:SpecifiedLineTradeSettlement>ram:ApplicableTradeTax>ram:CategoryCode"`              // BT-151
+	TaxRate     *float64 `xml:"ram:SpecifiedLineTradeSettlement>ram:ApplicableTradeTax>ram:RateApplicablePercent,omitempty"` // BT-152; nil for category O
+	Total       string  `xml:"ram:SpecifiedLineTradeSettlement>ram:SpecifiedTradeSettlementLineMonetarySummation>ram:LineTotalAmount"` // BT-131: net amount
 	// TODO [context: Line item XML, priority: medium, effort: medium]: Add product codes, units, etc.
 }
 
-// TaxDetailXML for tax details
+// GlobalIDXML is an item standard identifier with its ISO 6523 scheme (BT-157, BT-157-1)
+type GlobalIDXML struct {
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+// QuantityXML is a quantity with its UN/ECE Rec. 20 unit code (BT-129, BT-130)
+type QuantityXML struct {
+	UnitCode string  `xml:"unitCode,attr,omitempty"`
+	Value    float64 `xml:",chardata"`
+}
+
+// TaxDetailXML for tax details, in the order of the schema
 type TaxDetailXML struct {
+	Amount string  `xml:"ram:CalculatedAmount"` // BT-117
 	Type   string  `xml:"ram:TypeCode"`
-	Amount string  `xml:"ram:CalculatedAmount"`
-	Rate   float64 `xml:"ram:RateApplicablePercent"`
-	// TODO [context: Tax details XML, priority: medium, effort: medium]: Add support for multi-rate VAT, exemptions, etc.
+	ExemptionReason     string `xml:"ram:ExemptionReason,omitempty"`     // BT-120
+	Basis               string `xml:"ram:BasisAmount,omitempty"`         // BT-116
+	Category            string `xml:"ram:CategoryCode"`                  // BT-118
+	ExemptionReasonCode string `xml:"ram:ExemptionReasonCode,omitempty"` // BT-121
+	Rate   *float64 `xml:"ram:RateApplicablePercent,omitempty"` // BT-119; nil for category O
+	// TODO [context: Tax details XML, priority: medium, effort: medium]: Add support for multi-rate VAT.
 }
 
 // MapInvoiceDataToZUGFeRD maps models.InvoiceData to ZUGFeRDInvoiceXML for XML generation
@@ -107,12 +209,36 @@ func MapInvoiceDataToZUGFeRD(data *models.InvoiceData) ZUGFeRDInvoiceXML {
 		XmlnsRsm: "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100",
 		XmlnsRam: "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100",
 		XmlnsUdt: "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100",
+		XmlnsQdt: "urn:un:unece:uncefact:data:standard:QualifiedDataType:100",
 		Context: DocumentContextXML{
 			GuidelineID: string(ProfileEN16931),
 		},
 		Document: DocumentXML{
 			ID: inv.Number,
-			IssueDate: DateTimeXML{DateString: inv.Date.Format("20060102")},
+			TypeCode: inv.Type.TypeCode(),
+			IssueDate: issueDate(inv.Date.Format("20060102")),
+			Notes: withholdingNotes(inv),
+		},
+		Transaction: SupplyChainTradeTransactionXML{
+			LineItems: func() []LineItemXML {
+				items := make([]LineItemXML, len(inv.Lines))
+				for i, line := range inv.Lines {
+					items[i] = LineItemXML{
+						LineID: strconv.Itoa(i + 1),
+						GlobalID: globalID(line),
+						SellerID: line.SellerItemID,
+						BuyerID: line.BuyerItemID,
+						Description: line.Description,
+						UnitPrice: line.UnitPrice.String(),
+						Quantity: QuantityXML{UnitCode: line.Unit, Value: line.Quantity.InexactFloat64()},
+						TaxType: "VAT",
+						TaxCategory: string(line.VATCategory()),
+						TaxRate: applicablePercent(string(line.VATCategory()), line.TaxRate.InexactFloat64()),
+						Total: lineNetAmount(line).StringFixed(2),
+					}
+				}
+				return items
+			}(),
 			Agreement: TradeAgreementXML{
 				Seller: PartyXML{
 					Name: data.Provider.Name,
@@ -134,37 +260,130 @@ func MapInvoiceDataToZUGFeRD(data *models.InvoiceData) ZUGFeRDInvoiceXML {
 						Country: data.Client.Address.Country,
 					},
 				},
+				SellerOrder: referencedDocument(inv.OrderReference),
 			},
 			Settlement: TradeSettlementXML{
-				GrandTotal: inv.GrandTotal.String(),
+				TaxCurrency: func() string {
+					if inv.HasTaxCurrency() {
+						return inv.TaxCurrency
+					}
+					return ""
+				}(),
 				Currency: inv.Currency.Code,
 				Taxes: func() []TaxDetailXML {
 					taxes := make([]TaxDetailXML, 0)
 					for _, line := range inv.Lines {
 						taxes = append(taxes, TaxDetailXML{
+							Amount: line.TaxAmount.StringFixed(2),
 							Type: "VAT",
-							Amount: line.TaxAmount.String(),
-							Rate: line.TaxRate.InexactFloat64(),
+							ExemptionReason: line.TaxExemptionReason,
+							Basis: lineNetAmount(line).StringFixed(2),
+							Category: string(line.VATCategory()),
+							ExemptionReasonCode: line.VATExemptionCode(),
+							Rate: applicablePercent(string(line.VATCategory()), line.TaxRate.InexactFloat64()),
 						})
 					}
 					return taxes
 				}(),
+				BillingPeriod: billingPeriod(inv.BillingPeriod),
+				Summation: monetarySummation(inv),
+				References: precedingInvoiceRefs(inv),
 			},
 		},
-		Transaction: SupplyChainTradeTransactionXML{
-			LineItems: func() []LineItemXML {
-				items := make([]LineItemXML, len(inv.Lines))
-				for i, line := range inv.Lines {
-					items[i] = LineItemXML{
-						Description: line.Description,
-						Quantity: line.Quantity.InexactFloat64(),
-						UnitPrice: line.UnitPrice.String(),
-						Total: line.Total.String(),
-						TaxRate: line.TaxRate.InexactFloat64(),
-					}
-				}
-				return items
-			}(),
-		},
 	}
 }
+
+// applicablePercent returns the rate for RateApplicablePercent, which supplies not
+// subject to VAT (category O) must not carry (BR-O-05).
+func applicablePercent(category string, rate float64) *float64 {
+	if category == string(models.TaxCategoryOutOfScope) {
+		return nil
+	}
+	return &rate
+}
+
+// monetarySummation maps the document totals (BG-22). The invoice has no document
+// level allowances or charges, so the line total is the tax basis.
+func monetarySummation(inv models.InvoiceDetails) MonetarySummationXML {
+	sum := MonetarySummationXML{
+		LineTotal: inv.Subtotal.StringFixed(2),
+		TaxBasisTotal: inv.Subtotal.StringFixed(2),
+		TaxTotals: []AmountXML{{Value: inv.TotalTax.StringFixed(2), CurrencyID: inv.Currency.Code}},
+		GrandTotal: inv.GrandTotal.StringFixed(2),
+		DuePayable: inv.DuePayableAmount().StringFixed(2),
+	}
+	if inv.HasTaxCurrency() {
+		sum.TaxTotals = append(sum.TaxTotals, AmountXML{Value: inv.TotalTaxAccounting.StringFixed(2), CurrencyID: inv.TaxCurrency})
+	}
+	if inv.HasPrepayment() {
+		sum.PrepaidTotal = inv.PrepaidAmount.StringFixed(2)
+	}
+	return sum
+}
+
+// lineNetAmount returns the line amount without VAT (BT-131)
+func lineNetAmount(line models.InvoiceLine) decimal.Decimal {
+	return line.Total.Sub(line.TaxAmount)
+}
+
+// withholdingNotes states withheld taxes as tax declaration notes. EN 16931 has no
+// withholding element and the payable amount must equal the invoice total, so the
+// deduction is only declared.
+func withholdingNotes(inv models.InvoiceDetails) []NoteXML {
+	notes := make([]NoteXML, 0, len(inv.Withholdings))
+	for _, w := range inv.Withholdings {
+		notes = append(notes, NoteXML{
+			Content: fmt.Sprintf("%s %s%%: %s %s (base %s %s, amount due %s %s)",
+				w.Label(), w.Rate.String(), w.Amount.StringFixed(2), inv.Currency.Code,
+				w.BasisAmount.StringFixed(2), inv.Currency.Code, inv.AmountDue.StringFixed(2), inv.Currency.Code),
+			SubjectCode: "TXD",
+		})
+	}
+	return notes
+}
+
+// globalID returns the standard identifier of the line's item, nil if there is none
+func globalID(line models.InvoiceLine) *GlobalIDXML {
+	if line.StandardItemID == "" {
+		return nil
+	}
+	return &GlobalIDXML{SchemeID: line.StandardItemScheme, Value: line.StandardItemID}
+}
+
+// referencedDocument returns the reference to a document number, nil if there is none
+func referencedDocument(id string) *ReferencedDocumentXML {
+	if id == "" {
+		return nil
+	}
+	return &ReferencedDocumentXML{ID: id}
+}
+
+// billingPeriod maps the invoicing period (BG-14); nil if none is set
+func billingPeriod(p models.BillingPeriod) *BillingPeriodXML {
+	if p.IsZero() {
+		return nil
+	}
+	date := func(t time.Time) *PeriodDateXML {
+		if t.IsZero() {
+			return nil
+		}
+		d := &PeriodDateXML{}
+		d.DateString.Value = t.Format("20060102")
+		d.DateString.Format = "102"
+		return d
+	}
+	return &BillingPeriodXML{Start: date(p.Start), End: date(p.End)}
+}
+
+// precedingInvoiceRefs maps the invoices a document refers to (BG-3)
+func precedingInvoiceRefs(inv models.InvoiceDetails) []ReferencedDocumentXML {
+	refs := make([]ReferencedDocumentXML, 0, len(inv.PrecedingInvoices))
+	for _, ref := range inv.PrecedingInvoices {
+		doc := ReferencedDocumentXML{ID: ref.Number}
+		if !ref.Date.IsZero() {
+			doc.IssueDate = &FormattedDateTimeXML{DateString: DateStringXML{Value: ref.Date.Format("20060102"), Format: "102"}}
+		}
+		refs = append(refs, doc)
+	}
+	return refs
+}